@@ -1,15 +1,23 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/dkr290/go-advanced-projects/rest-api-school-management/cmd/router"
 	"github.com/dkr290/go-advanced-projects/rest-api-school-management/config"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops"
 	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/middleware"
 	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/mailer"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/repository/migrations"
 	"github.com/dkr290/go-advanced-projects/rest-api-school-management/repository/sqlconnect"
 )
 
@@ -18,28 +26,154 @@ var port = ":8082"
 func main() {
 	conf := config.LoadConfig()
 
-	db, err := sqlconnect.ConnectDB(conf)
+	var (
+		db  *sqlconnect.DB
+		err error
+	)
+	if conf.Repository == "memory" {
+		db, err = memoryDB()
+	} else {
+		db, err = sqlconnect.ConnectDB(conf)
+	}
 	if err != nil {
 		fmt.Println("Error ", err)
 		return
 	}
 	llogger := logging.Init(conf.Debug)
 
-	router := router.Router(db, *conf)
+	if args := flag.Args(); len(args) > 0 {
+		if args[0] != "migrate" {
+			fmt.Println("Unknown command", args[0])
+			os.Exit(2)
+		}
+		migrator, err := migrations.NewMigrator(db, llogger, conf.MigrateLockTimeout)
+		if err != nil {
+			fmt.Println("Error ", err)
+			os.Exit(1)
+		}
+		if err := migrator.Run(args[1:], os.Stdout); err != nil {
+			fmt.Println("Error ", err)
+			os.Exit(1)
+		}
+		return
+	}
+	// the in-memory database starts empty and always needs the schema
+	if conf.MigrateOnStart || conf.Repository == "memory" {
+		migrator, err := migrations.NewMigrator(db, llogger, conf.MigrateLockTimeout)
+		if err != nil {
+			fmt.Println("Error ", err)
+			return
+		}
+		if _, err := migrator.Up(); err != nil {
+			fmt.Println("Error migrating the database ", err)
+			return
+		}
+	}
+
+	repos, err := router.NewRepositories(db, *conf, llogger)
+	if err != nil {
+		fmt.Println("Error ", err)
+		return
+	}
+	authCache := middleware.NewAuthCache(
+		dataops.NewSessionsDB(db, llogger),
+		repos.Execs,
+		conf.AuthCacheTTL,
+	)
+	apiKeysDB := dataops.NewAPIKeysDB(db, llogger)
+	mail, err := newMailer(conf)
+	if err != nil {
+		fmt.Println("Error ", err)
+		return
+	}
+	mailQueue := mailer.NewQueue(mail, mailer.QueueConfig{
+		Size:        conf.MailQueueSize,
+		MaxAttempts: conf.MailMaxAttempts,
+		Backoff:     conf.MailRetryBackoff,
+	}, llogger)
+	mailQueue.Start()
+	purge := dataops.NewPurge(conf.SoftDeleteRetention, llogger).
+		Add("students", repos.Students).
+		Add("teachers", repos.Teachers).
+		Add("execs", repos.Execs)
+	if conf.PurgeInterval > 0 {
+		purge.Start(conf.PurgeInterval)
+	}
+	router := router.Router(db, repos, *conf, authCache, mailQueue)
 
 	rl := middleware.NewRateLimit(200, time.Minute)
 	server := &http.Server{
 		Addr: port,
 		Handler: rl.Middleware(middleware.ResponseTimeMiddleware(
 			middleware.SecurityHeaders(
-				middleware.Cors(middleware.JWTMiddleware(router, *conf, *llogger)),
+				middleware.Cors(middleware.ClientInfo(
+					middleware.JWTMiddleware(router, *conf, *llogger, authCache, repos.Execs, apiKeysDB),
+				)),
 			),
 		),
 		),
 	}
 
-	fmt.Println("The server is starting on port", port)
-	if err := server.ListenAndServe(); err != nil {
+	serverErr := make(chan error, 1)
+	go func() {
+		fmt.Println("The server is starting on port", port)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	// on SIGINT or SIGTERM the server stops accepting requests and waits for the running
+	// ones, then the purge is stopped and the queued emails are delivered
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	select {
+	case err := <-serverErr:
 		log.Fatalln("Error Starting the server", err)
+	case sig := <-stop:
+		fmt.Println("Shutting down the server on", sig)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), conf.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		fmt.Println("Error shutting down the server ", err)
+	}
+	purge.Stop()
+	if err := mailQueue.Close(ctx); err != nil {
+		fmt.Println("Error delivering the queued emails ", err)
+	}
+	if err := db.Close(); err != nil {
+		fmt.Println("Error closing the database ", err)
+	}
+}
+
+// memoryDB - the sessions, api keys and the other auth stores of the memory repository are
+// kept in an in-memory sqlite database. Its execs table stays empty as the execs are in
+// memory, so the foreign keys to it are off
+func memoryDB() (*sqlconnect.DB, error) {
+	db, err := sqlconnect.Open(sqlconnect.SQLite, ":memory:")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec("PRAGMA foreign_keys = OFF"); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// newMailer - the mail delivery selected with the mail driver
+func newMailer(conf *config.Config) (mailer.Mailer, error) {
+	switch conf.MailDriver {
+	case "smtp":
+		return mailer.NewSMTPMailer(mailer.SMTPConfig{
+			Host:     conf.SMTPHost,
+			Port:     conf.SMTPPort,
+			Username: conf.SMTPUsername,
+			Password: conf.SMTPPassword,
+			From:     conf.SMTPFrom,
+			StartTLS: conf.SMTPStartTLS,
+		}), nil
+	case "file":
+		return mailer.NewFileMailer(conf.MailFileDir, conf.SMTPFrom)
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", conf.MailDriver)
 	}
 }
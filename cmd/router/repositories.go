@@ -0,0 +1,65 @@
+package router
+
+import (
+	"fmt"
+
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/config"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops/memory"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/repository/sqlconnect"
+)
+
+// Repositories - the classes, subjects, teachers, students, enrollments, teaching
+// assignments, execs and their invitations kept in the database or in memory selected
+// with the repository config. The invitations are with the execs as the activation sets
+// the password of the exec
+type Repositories struct {
+	Classes     dataops.ClassesInf
+	Subjects    dataops.SubjectsInf
+	Teachers    dataops.TeachersInf
+	Students    dataops.StudentInf
+	Enrollments dataops.EnrollmentsInf
+	Assignments dataops.AssignmentsInf
+	Execs       dataops.ExecsInf
+	Invitations dataops.ExecInvitationsInf
+}
+
+// NewRepositories - the repositories of the config, the memory ones start with the
+// records of the fixture file when it is set
+func NewRepositories(db *sqlconnect.DB, conf config.Config, logger *logging.Logger) (Repositories, error) {
+	switch conf.Repository {
+	case "", "sql":
+		return Repositories{
+			Classes:     dataops.NewClassesDB(db, logger, conf.DBQueryTimeout),
+			Subjects:    dataops.NewSubjectsDB(db, logger, conf.DBQueryTimeout),
+			Teachers:    dataops.NewTeachersDB(db, logger, conf.DBQueryTimeout),
+			Students:    dataops.NewStudentsDB(db, logger, conf.DBQueryTimeout),
+			Enrollments: dataops.NewEnrollmentsDB(db, logger, conf.DBQueryTimeout),
+			Assignments: dataops.NewAssignmentsDB(db, logger, conf.DBQueryTimeout),
+			Execs:       dataops.NewExecsDB(db, logger, conf.DBQueryTimeout),
+			Invitations: dataops.NewExecInvitationsDB(db, logger),
+		}, nil
+	case "memory":
+		store := memory.NewStore()
+		if conf.RepositoryFixture != "" {
+			var err error
+			if store, err = memory.LoadFixture(conf.RepositoryFixture); err != nil {
+				return Repositories{}, err
+			}
+		}
+		// the change history stays in the database like the sessions and the keys
+		store.SetHistory(dataops.NewChangeHistoryDB(db, logger))
+		return Repositories{
+			Classes:     store.Classes,
+			Subjects:    store.Subjects,
+			Teachers:    store.Teachers,
+			Students:    store.Students,
+			Enrollments: store.Enrollments,
+			Assignments: store.Assignments,
+			Execs:       store.Execs,
+			Invitations: store.Invitations,
+		}, nil
+	}
+	return Repositories{}, fmt.Errorf("unknown repository %q, sql or memory", conf.Repository)
+}
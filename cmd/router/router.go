@@ -2,7 +2,6 @@
 package router
 
 import (
-	"database/sql"
 	"net/http"
 
 	"github.com/danielgtaylor/huma/v2"
@@ -10,21 +9,90 @@ import (
 	"github.com/dkr290/go-advanced-projects/rest-api-school-management/config"
 	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops"
 	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/handlers"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/middleware"
 	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/mailer"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/oidc"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/password"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/repository/sqlconnect"
 )
 
-func Router(db *sql.DB, conf config.Config) *http.ServeMux {
+func Router(
+	db *sqlconnect.DB,
+	repos Repositories,
+	conf config.Config,
+	authCache middleware.AuthInvalidator,
+	mail mailer.Mailer,
+) *http.ServeMux {
 	llogger := logging.Init(conf.Debug)
 	router := http.NewServeMux()
-	teachersDB := dataops.NewTeachersDB(db, llogger)
-	studentsDB := dataops.NewStudentsDB(db, llogger)
-	execDB := dataops.NewExecsDB(db, llogger)
-
-	teacherHandler := handlers.NewTeachersHandler(teachersDB)
-	studetnsHandler := handlers.NewStudentsHandler(studentsDB)
-	execHandler := handlers.NewExecsHandler(execDB, llogger, conf)
-
-	api := humago.New(router, huma.DefaultConfig("My API", "1.0.0"))
+	teachersDB := repos.Teachers
+	studentsDB := repos.Students
+	execDB := repos.Execs
+	sessionsDB := dataops.NewSessionsDB(db, llogger)
+	loginAttemptsDB := dataops.NewLoginAttemptsDB(db, llogger)
+	mfaDB := dataops.NewMFADB(db, llogger)
+	apiKeysDB := dataops.NewAPIKeysDB(db, llogger)
+	auditDB := dataops.NewAuthAuditDB(db, llogger)
+	historyDB := dataops.NewChangeHistoryDB(db, llogger)
+
+	passwordPolicy, err := password.PolicyFromConfig(conf)
+	if err != nil {
+		llogger.Logging.Fatalf("failed to load the breached passwords %v", err)
+	}
+
+	templates, err := mailer.LoadTemplates(conf.MailTemplatesDir)
+	if err != nil {
+		llogger.Logging.Fatalf("failed to load the email templates %v", err)
+	}
+
+	teacherHandler := handlers.NewTeachersHandler(teachersDB, historyDB, llogger)
+	studetnsHandler := handlers.NewStudentsHandler(studentsDB, historyDB, llogger)
+	classHandler := handlers.NewClassesHandler(repos.Classes, teachersDB, llogger)
+	subjectHandler := handlers.NewSubjectsHandler(repos.Subjects, llogger)
+	enrollmentHandler := handlers.NewEnrollmentsHandler(repos.Enrollments, studentsDB, repos.Classes, llogger)
+	assignmentHandler := handlers.NewAssignmentsHandler(
+		repos.Assignments,
+		teachersDB,
+		repos.Subjects,
+		repos.Classes,
+		llogger,
+	)
+	execHandler := handlers.NewExecsHandler(handlers.ExecsDeps{
+		Execs:          execDB,
+		Sessions:       sessionsDB,
+		LoginAttempts:  loginAttemptsDB,
+		MFA:            mfaDB,
+		Invitations:    repos.Invitations,
+		Audit:          auditDB,
+		History:        historyDB,
+		AuthCache:      authCache,
+		Logger:         llogger,
+		Config:         conf,
+		PasswordPolicy: passwordPolicy,
+		Mail:           mailer.NewSender(mail, templates),
+	})
+
+	if conf.OIDCIssuer != "" {
+		execHandler.EnableOIDC(
+			oidc.NewProvider(oidc.Config{
+				Issuer:       conf.OIDCIssuer,
+				ClientID:     conf.OIDCClientID,
+				ClientSecret: conf.OIDCClientSecret,
+				RedirectURL:  conf.OIDCRedirectURL,
+			}, nil),
+			dataops.NewExecIdentitiesDB(db, llogger),
+		)
+	}
+	apiKeysHandler := handlers.NewAPIKeysHandler(apiKeysDB, llogger)
+	auditHandler := handlers.NewAuditHandler(auditDB, llogger)
+
+	humaConfig := huma.DefaultConfig("My API", "1.0.0")
+	humaConfig.Components.SecuritySchemes = middleware.SecuritySchemes(conf.CookieName)
+	humaConfig.OnAddOperation = append(humaConfig.OnAddOperation, middleware.DocumentPermissions)
+
+	api := humago.New(router, humaConfig)
+	api.UseMiddleware(middleware.Authorize(api))
 
 	huma.Get(api, "/", teacherHandler.RootHandler)
 
@@ -32,8 +100,20 @@ func Router(db *sql.DB, conf config.Config) *http.ServeMux {
 
 	routesStudents(api, studetnsHandler)
 
+	routesEnrollments(api, enrollmentHandler)
+
+	routesClasses(api, classHandler)
+
+	routesSubjects(api, subjectHandler)
+
+	routesAssignments(api, assignmentHandler)
+
 	routesExec(api, execHandler)
 
+	routesAPIKeys(api, apiKeysHandler)
+	routesMe(api, execHandler)
+	routesAudit(api, auditHandler)
+
 	return router
 }
 
@@ -45,6 +125,7 @@ func routesTeachers(api huma.API, teacherHandler *handlers.TeacherHandlers) {
 		Summary:     "Get a teacher",
 		Description: "Get a teacher by ID.",
 		Tags:        []string{"Teachers"},
+		Security:    middleware.Require(middleware.PermTeachersRead),
 	}, teacherHandler.TeacherGet)
 
 	huma.Register(api, huma.Operation{
@@ -54,6 +135,7 @@ func routesTeachers(api huma.API, teacherHandler *handlers.TeacherHandlers) {
 		Summary:     "Create teachers",
 		Description: "Create teachers.",
 		Tags:        []string{"Teachers"},
+		Security:    middleware.Require(middleware.PermTeachersWrite),
 	}, teacherHandler.TeachersAdd)
 
 	huma.Register(api, huma.Operation{
@@ -63,6 +145,7 @@ func routesTeachers(api huma.API, teacherHandler *handlers.TeacherHandlers) {
 		Summary:     "Get all teachers",
 		Description: "Get all teachers or with filtering.",
 		Tags:        []string{"Teachers"},
+		Security:    middleware.Require(middleware.PermTeachersRead),
 	}, teacherHandler.TeachersGet)
 
 	huma.Register(api, huma.Operation{
@@ -72,6 +155,7 @@ func routesTeachers(api huma.API, teacherHandler *handlers.TeacherHandlers) {
 		Summary:     "Update all fields of a teacher",
 		Description: "Update all fields of a teacher mandatory.",
 		Tags:        []string{"Teachers"},
+		Security:    middleware.Require(middleware.PermTeachersWrite),
 	}, teacherHandler.UpdateTeacherHandler)
 
 	huma.Register(api, huma.Operation{
@@ -81,6 +165,7 @@ func routesTeachers(api huma.API, teacherHandler *handlers.TeacherHandlers) {
 		Summary:     "Patch teacher",
 		Description: "Patch some teacher fields only.",
 		Tags:        []string{"Teachers"},
+		Security:    middleware.Require(middleware.PermTeachersWrite),
 	}, teacherHandler.PatchTeacherHandler)
 
 	huma.Register(api, huma.Operation{
@@ -88,8 +173,9 @@ func routesTeachers(api huma.API, teacherHandler *handlers.TeacherHandlers) {
 		Method:      http.MethodDelete,
 		Path:        "/teachers/{id}",
 		Summary:     "Delete Teacher by ID",
-		Description: "Delete a teacher record by ID.",
+		Description: "Delete a teacher record by ID, it can be restored until it is purged.",
 		Tags:        []string{"Teachers"},
+		Security:    middleware.Require(middleware.PermTeachersWrite),
 	}, teacherHandler.DeleteTeacherHandler)
 
 	huma.Register(api, huma.Operation{
@@ -99,6 +185,7 @@ func routesTeachers(api huma.API, teacherHandler *handlers.TeacherHandlers) {
 		Summary:     "Patch teachers",
 		Description: "Patch bulk many teachers fields.",
 		Tags:        []string{"Teachers"},
+		Security:    middleware.Require(middleware.PermTeachersWrite),
 	}, teacherHandler.PatchTeachersHandler)
 
 	huma.Register(api, huma.Operation{
@@ -108,6 +195,7 @@ func routesTeachers(api huma.API, teacherHandler *handlers.TeacherHandlers) {
 		Summary:     "Get students by id of the teacher",
 		Description: "Get students by teacher id",
 		Tags:        []string{"Teachers"},
+		Security:    middleware.Require(middleware.PermTeachersRead, middleware.PermStudentsRead),
 	}, teacherHandler.GetStudentsByTeacherId)
 
 	huma.Register(api, huma.Operation{
@@ -115,9 +203,256 @@ func routesTeachers(api huma.API, teacherHandler *handlers.TeacherHandlers) {
 		Method:      http.MethodDelete,
 		Path:        "/teachers",
 		Summary:     "Delete teachers",
-		Description: "Delete bulk many teachers fields.",
+		Description: "Delete bulk many teachers fields, they can be restored until they are purged.",
 		Tags:        []string{"Teachers"},
+		Security:    middleware.Require(middleware.PermTeachersWrite),
 	}, teacherHandler.DeleteTeachersHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "teacher-history",
+		Method:      http.MethodGet,
+		Path:        "/teachers/{id}/history",
+		Summary:     "Get teacher history",
+		Description: "List the revisions of the teacher, the newest first, with the changed fields and the exec which made them.",
+		Tags:        []string{"Teachers"},
+		Security:    middleware.Require(middleware.PermTeachersRead),
+	}, teacherHandler.TeacherHistoryHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "restore-teacher-revision",
+		Method:      http.MethodPost,
+		Path:        "/teachers/{id}/history/{revision}/restore",
+		Summary:     "Restore teacher revision",
+		Description: "Set the teacher back to its state after the revision.",
+		Tags:        []string{"Teachers"},
+		Security:    middleware.Require(middleware.PermTeachersWrite),
+	}, teacherHandler.RestoreTeacherRevisionHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "restore-teacher",
+		Method:      http.MethodPost,
+		Path:        "/teachers/{id}/restore",
+		Summary:     "Restore deleted teacher",
+		Description: "Bring back the deleted teacher before it is purged.",
+		Tags:        []string{"Teachers"},
+		Security:    middleware.Require(middleware.PermTeachersWrite, middleware.PermDeletedManage),
+	}, teacherHandler.RestoreTeacherHandler)
+}
+
+func routesClasses(api huma.API, classHandler *handlers.ClassHandlers) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-class",
+		Method:      http.MethodGet,
+		Path:        "/classes/{id}",
+		Summary:     "Get a class",
+		Description: "Get a class by ID.",
+		Tags:        []string{"Classes"},
+		Security:    middleware.Require(middleware.PermClassesRead),
+	}, classHandler.ClassGet)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "post-classes",
+		Method:      http.MethodPost,
+		Path:        "/classes",
+		Summary:     "Create classes",
+		Description: "Create classes, the name is unique in the academic year.",
+		Tags:        []string{"Classes"},
+		Security:    middleware.Require(middleware.PermClassesWrite),
+	}, classHandler.ClassesAdd)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-classes",
+		Method:      http.MethodGet,
+		Path:        "/classes",
+		Summary:     "Get all classes",
+		Description: "Get all classes or with filtering.",
+		Tags:        []string{"Classes"},
+		Security:    middleware.Require(middleware.PermClassesRead),
+	}, classHandler.ClassesGet)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "update-class",
+		Method:      http.MethodPut,
+		Path:        "/classes/{id}",
+		Summary:     "Update all fields of a class",
+		Description: "Update all fields of a class, the homeroom teacher 0 removes it.",
+		Tags:        []string{"Classes"},
+		Security:    middleware.Require(middleware.PermClassesWrite),
+	}, classHandler.UpdateClassHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "patch-class",
+		Method:      http.MethodPatch,
+		Path:        "/classes/{id}",
+		Summary:     "Patch class",
+		Description: "Patch some class fields only.",
+		Tags:        []string{"Classes"},
+		Security:    middleware.Require(middleware.PermClassesWrite),
+	}, classHandler.PatchClassHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "delete-class",
+		Method:      http.MethodDelete,
+		Path:        "/classes/{id}",
+		Summary:     "Delete class by ID",
+		Description: "Delete the class for good, the class with teachers, students, also the deleted ones, teaching assignments or enrollments is kept.",
+		Tags:        []string{"Classes"},
+		Security:    middleware.Require(middleware.PermClassesWrite),
+	}, classHandler.DeleteClassHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "students-by-class-id",
+		Method:      http.MethodGet,
+		Path:        "/classes/{id}/students",
+		Summary:     "Get students of the class",
+		Description: "Get the students of the class by class id.",
+		Tags:        []string{"Classes"},
+		Security:    middleware.Require(middleware.PermClassesRead, middleware.PermStudentsRead),
+	}, classHandler.ClassStudentsHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "teachers-by-class-id",
+		Method:      http.MethodGet,
+		Path:        "/classes/{id}/teachers",
+		Summary:     "Get teachers of the class",
+		Description: "Get the teachers of the class by class id.",
+		Tags:        []string{"Classes"},
+		Security:    middleware.Require(middleware.PermClassesRead, middleware.PermTeachersRead),
+	}, classHandler.ClassTeachersHandler)
+}
+
+func routesSubjects(api huma.API, subjectHandler *handlers.SubjectHandlers) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-subject",
+		Method:      http.MethodGet,
+		Path:        "/subjects/{id}",
+		Summary:     "Get a subject",
+		Description: "Get a subject of the catalog by ID.",
+		Tags:        []string{"Subjects"},
+		Security:    middleware.Require(middleware.PermSubjectsRead),
+	}, subjectHandler.SubjectGet)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "post-subjects",
+		Method:      http.MethodPost,
+		Path:        "/subjects",
+		Summary:     "Create subjects",
+		Description: "Add subjects to the catalog, the name is unique.",
+		Tags:        []string{"Subjects"},
+		Security:    middleware.Require(middleware.PermSubjectsWrite),
+	}, subjectHandler.SubjectsAdd)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-subjects",
+		Method:      http.MethodGet,
+		Path:        "/subjects",
+		Summary:     "Get all subjects",
+		Description: "Get all subjects of the catalog or with filtering.",
+		Tags:        []string{"Subjects"},
+		Security:    middleware.Require(middleware.PermSubjectsRead),
+	}, subjectHandler.SubjectsGet)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "update-subject",
+		Method:      http.MethodPut,
+		Path:        "/subjects/{id}",
+		Summary:     "Update all fields of a subject",
+		Description: "Update all fields of a subject mandatory.",
+		Tags:        []string{"Subjects"},
+		Security:    middleware.Require(middleware.PermSubjectsWrite),
+	}, subjectHandler.UpdateSubjectHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "patch-subject",
+		Method:      http.MethodPatch,
+		Path:        "/subjects/{id}",
+		Summary:     "Patch subject",
+		Description: "Patch some subject fields only.",
+		Tags:        []string{"Subjects"},
+		Security:    middleware.Require(middleware.PermSubjectsWrite),
+	}, subjectHandler.PatchSubjectHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "delete-subject",
+		Method:      http.MethodDelete,
+		Path:        "/subjects/{id}",
+		Summary:     "Delete subject by ID",
+		Description: "Delete a subject for good, the subject with teaching assignments can't be deleted.",
+		Tags:        []string{"Subjects"},
+		Security:    middleware.Require(middleware.PermSubjectsWrite),
+	}, subjectHandler.DeleteSubjectHandler)
+}
+
+func routesAssignments(api huma.API, assignmentHandler *handlers.AssignmentHandlers) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-assignment",
+		Method:      http.MethodGet,
+		Path:        "/assignments/{id}",
+		Summary:     "Get a teaching assignment",
+		Description: "Get a teaching assignment by ID.",
+		Tags:        []string{"Assignments"},
+		Security:    middleware.Require(middleware.PermAssignmentsRead),
+	}, assignmentHandler.AssignmentGet)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "post-assignments",
+		Method:      http.MethodPost,
+		Path:        "/assignments",
+		Summary:     "Create teaching assignments",
+		Description: "Assign teachers to teach subjects in classes, a subject is taught in a class by one teacher in a term.",
+		Tags:        []string{"Assignments"},
+		Security:    middleware.Require(middleware.PermAssignmentsWrite),
+	}, assignmentHandler.AssignmentsAdd)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-assignments",
+		Method:      http.MethodGet,
+		Path:        "/assignments",
+		Summary:     "Get all teaching assignments",
+		Description: "Get all teaching assignments or with filtering.",
+		Tags:        []string{"Assignments"},
+		Security:    middleware.Require(middleware.PermAssignmentsRead),
+	}, assignmentHandler.AssignmentsGet)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "update-assignment",
+		Method:      http.MethodPut,
+		Path:        "/assignments/{id}",
+		Summary:     "Update all fields of a teaching assignment",
+		Description: "Update all fields of a teaching assignment mandatory.",
+		Tags:        []string{"Assignments"},
+		Security:    middleware.Require(middleware.PermAssignmentsWrite),
+	}, assignmentHandler.UpdateAssignmentHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "delete-assignment",
+		Method:      http.MethodDelete,
+		Path:        "/assignments/{id}",
+		Summary:     "Delete teaching assignment by ID",
+		Description: "Delete a teaching assignment.",
+		Tags:        []string{"Assignments"},
+		Security:    middleware.Require(middleware.PermAssignmentsWrite),
+	}, assignmentHandler.DeleteAssignmentHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "assignments-by-teacher-id",
+		Method:      http.MethodGet,
+		Path:        "/teachers/{id}/assignments",
+		Summary:     "Get the load of the teacher",
+		Description: "Get the subjects and classes the teacher teaches, in the term when it is set.",
+		Tags:        []string{"Teachers", "Assignments"},
+		Security:    middleware.Require(middleware.PermTeachersRead, middleware.PermAssignmentsRead),
+	}, assignmentHandler.TeacherAssignmentsHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "subjects-by-class-id",
+		Method:      http.MethodGet,
+		Path:        "/classes/{id}/subjects",
+		Summary:     "Get subject teachers of the class",
+		Description: "Get the subjects of the class and their teachers, in the term when it is set.",
+		Tags:        []string{"Classes", "Assignments"},
+		Security:    middleware.Require(middleware.PermClassesRead, middleware.PermAssignmentsRead),
+	}, assignmentHandler.ClassSubjectsHandler)
 }
 
 func routesStudents(api huma.API, studentHandler *handlers.StudentHandlers) {
@@ -128,6 +463,7 @@ func routesStudents(api huma.API, studentHandler *handlers.StudentHandlers) {
 		Summary:     "Get a student",
 		Description: "Get a student by ID.",
 		Tags:        []string{"Students"},
+		Security:    middleware.Require(middleware.PermStudentsRead),
 	}, studentHandler.StudentGet)
 
 	huma.Register(api, huma.Operation{
@@ -137,6 +473,7 @@ func routesStudents(api huma.API, studentHandler *handlers.StudentHandlers) {
 		Summary:     "Create students",
 		Description: "Create students.",
 		Tags:        []string{"Students"},
+		Security:    middleware.Require(middleware.PermStudentsWrite),
 	}, studentHandler.StudentsAdd)
 
 	huma.Register(api, huma.Operation{
@@ -146,6 +483,7 @@ func routesStudents(api huma.API, studentHandler *handlers.StudentHandlers) {
 		Summary:     "Get all students",
 		Description: "Get all students or with filtering.",
 		Tags:        []string{"Students"},
+		Security:    middleware.Require(middleware.PermStudentsRead),
 	}, studentHandler.StudentsGet)
 
 	huma.Register(api, huma.Operation{
@@ -155,6 +493,7 @@ func routesStudents(api huma.API, studentHandler *handlers.StudentHandlers) {
 		Summary:     "Update all fields of a student",
 		Description: "Update all fields of a student mandatory.",
 		Tags:        []string{"Students"},
+		Security:    middleware.Require(middleware.PermStudentsWrite),
 	}, studentHandler.UpdateStudentHandler)
 
 	huma.Register(api, huma.Operation{
@@ -164,6 +503,7 @@ func routesStudents(api huma.API, studentHandler *handlers.StudentHandlers) {
 		Summary:     "Patch student",
 		Description: "Patch some student fields only.",
 		Tags:        []string{"Students"},
+		Security:    middleware.Require(middleware.PermStudentsWrite),
 	}, studentHandler.PatchStudentHandler)
 
 	huma.Register(api, huma.Operation{
@@ -171,8 +511,9 @@ func routesStudents(api huma.API, studentHandler *handlers.StudentHandlers) {
 		Method:      http.MethodDelete,
 		Path:        "/students/{id}",
 		Summary:     "Delete Student by ID",
-		Description: "Delete a student record by ID.",
+		Description: "Delete a student record by ID, it can be restored until it is purged.",
 		Tags:        []string{"Students"},
+		Security:    middleware.Require(middleware.PermStudentsWrite),
 	}, studentHandler.DeleteStudentHandler)
 
 	huma.Register(api, huma.Operation{
@@ -182,6 +523,7 @@ func routesStudents(api huma.API, studentHandler *handlers.StudentHandlers) {
 		Summary:     "Patch students",
 		Description: "Patch bulk many students fields.",
 		Tags:        []string{"Students"},
+		Security:    middleware.Require(middleware.PermStudentsWrite),
 	}, studentHandler.PatchStudentsHandler)
 
 	huma.Register(api, huma.Operation{
@@ -189,9 +531,82 @@ func routesStudents(api huma.API, studentHandler *handlers.StudentHandlers) {
 		Method:      http.MethodDelete,
 		Path:        "/students",
 		Summary:     "Delete students",
-		Description: "Delete bulk many students fields.",
+		Description: "Delete bulk many students fields, they can be restored until they are purged.",
 		Tags:        []string{"Students"},
+		Security:    middleware.Require(middleware.PermStudentsWrite),
 	}, studentHandler.DeleteStudentsHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "student-history",
+		Method:      http.MethodGet,
+		Path:        "/students/{id}/history",
+		Summary:     "Get student history",
+		Description: "List the revisions of the student, the newest first, with the changed fields and the exec which made them.",
+		Tags:        []string{"Students"},
+		Security:    middleware.Require(middleware.PermStudentsRead),
+	}, studentHandler.StudentHistoryHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "restore-student-revision",
+		Method:      http.MethodPost,
+		Path:        "/students/{id}/history/{revision}/restore",
+		Summary:     "Restore student revision",
+		Description: "Set the student back to its state after the revision.",
+		Tags:        []string{"Students"},
+		Security:    middleware.Require(middleware.PermStudentsWrite),
+	}, studentHandler.RestoreStudentRevisionHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "restore-student",
+		Method:      http.MethodPost,
+		Path:        "/students/{id}/restore",
+		Summary:     "Restore deleted student",
+		Description: "Bring back the deleted student before it is purged.",
+		Tags:        []string{"Students"},
+		Security:    middleware.Require(middleware.PermStudentsWrite, middleware.PermDeletedManage),
+	}, studentHandler.RestoreStudentHandler)
+}
+
+func routesEnrollments(api huma.API, enrollmentHandler *handlers.EnrollmentHandlers) {
+	huma.Register(api, huma.Operation{
+		OperationID: "enrollments-by-student-id",
+		Method:      http.MethodGet,
+		Path:        "/students/{id}/enrollments",
+		Summary:     "Get student enrollments",
+		Description: "Get the classes of the student over the academic years, the first enrollment first.",
+		Tags:        []string{"Students", "Enrollments"},
+		Security:    middleware.Require(middleware.PermStudentsRead),
+	}, enrollmentHandler.StudentEnrollmentsHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "enroll-student",
+		Method:      http.MethodPost,
+		Path:        "/students/{id}/enroll",
+		Summary:     "Enroll student",
+		Description: "Enroll the student without a class, new or graduated, in the class from the date, today by default.",
+		Tags:        []string{"Students", "Enrollments"},
+		Security:    middleware.Require(middleware.PermStudentsWrite),
+	}, enrollmentHandler.EnrollStudentHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "transfer-student",
+		Method:      http.MethodPost,
+		Path:        "/students/{id}/transfer",
+		Summary:     "Transfer student",
+		Description: "End the active enrollment of the student as transferred and enroll the student in the other class from the date, today by default.",
+		Tags:        []string{"Students", "Enrollments"},
+		Security:    middleware.Require(middleware.PermStudentsWrite),
+	}, enrollmentHandler.TransferStudentHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "graduate-student",
+		Method:      http.MethodPost,
+		Path:        "/students/{id}/graduate",
+		Summary:     "Graduate student",
+		Description: "End the active enrollment of the student as graduated on the date, today by default.",
+		Tags:        []string{"Students", "Enrollments"},
+		Security:    middleware.Require(middleware.PermStudentsWrite),
+	}, enrollmentHandler.GraduateStudentHandler)
 }
 
 func routesExec(api huma.API, execHandler *handlers.ExecsHandlers) {
@@ -202,6 +617,7 @@ func routesExec(api huma.API, execHandler *handlers.ExecsHandlers) {
 		Summary:     "Get exec",
 		Description: "Get exec.",
 		Tags:        []string{"Exec"},
+		Security:    middleware.Require(middleware.PermExecsWrite),
 	}, execHandler.ExecGetHandler)
 
 	huma.Register(api, huma.Operation{
@@ -211,6 +627,7 @@ func routesExec(api huma.API, execHandler *handlers.ExecsHandlers) {
 		Summary:     "Add exec",
 		Description: "Add exec.",
 		Tags:        []string{"Exec"},
+		Security:    middleware.Require(middleware.PermExecsWrite),
 	}, execHandler.ExecAddHandler)
 
 	huma.Register(api, huma.Operation{
@@ -220,6 +637,7 @@ func routesExec(api huma.API, execHandler *handlers.ExecsHandlers) {
 		Summary:     "Get execs",
 		Description: "Get execs.",
 		Tags:        []string{"Exec"},
+		Security:    middleware.Require(middleware.PermExecsRead),
 	}, execHandler.ExecsGetHandler)
 
 	huma.Register(api, huma.Operation{
@@ -229,6 +647,7 @@ func routesExec(api huma.API, execHandler *handlers.ExecsHandlers) {
 		Summary:     "Patch execs",
 		Description: "Patch bulk many execs.",
 		Tags:        []string{"Exec"},
+		Security:    middleware.Require(middleware.PermExecsWrite),
 	}, execHandler.PatchExecsHandler)
 
 	huma.Register(api, huma.Operation{
@@ -236,18 +655,50 @@ func routesExec(api huma.API, execHandler *handlers.ExecsHandlers) {
 		Method:      http.MethodDelete,
 		Path:        "/execs/{id}",
 		Summary:     "Delete exec by id",
-		Description: "Delete exec by id.",
+		Description: "Delete exec by id and revoke its sessions, it can be restored until it is purged.",
 		Tags:        []string{"Exec"},
+		Security:    middleware.Require(middleware.PermExecsWrite),
 	}, execHandler.ExecDeleteByIDHandler)
 
+	huma.Register(api, huma.Operation{
+		OperationID: "exec-history",
+		Method:      http.MethodGet,
+		Path:        "/execs/{id}/history",
+		Summary:     "Get exec history",
+		Description: "List the revisions of the exec, the newest first, with the changed fields and the exec which made them.",
+		Tags:        []string{"Exec"},
+		Security:    middleware.Require(middleware.PermExecsWrite),
+	}, execHandler.ExecHistoryHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "restore-exec-revision",
+		Method:      http.MethodPost,
+		Path:        "/execs/{id}/history/{revision}/restore",
+		Summary:     "Restore exec revision",
+		Description: "Set the names, email and username of the exec back to their state after the revision.",
+		Tags:        []string{"Exec"},
+		Security:    middleware.Require(middleware.PermExecsWrite),
+	}, execHandler.RestoreExecRevisionHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "restore-exec",
+		Method:      http.MethodPost,
+		Path:        "/execs/{id}/restore",
+		Summary:     "Restore deleted exec",
+		Description: "Bring back the deleted exec before it is purged, the exec has to login again.",
+		Tags:        []string{"Exec"},
+		Security:    middleware.Require(middleware.PermExecsWrite, middleware.PermDeletedManage),
+	}, execHandler.RestoreExecHandler)
+
 	huma.Register(api, huma.Operation{
 		OperationID: "password-update-exec",
 		Method:      http.MethodPost,
 		Path:        "/execs/{id}/updatepassword",
-		Summary:     "Change exec password by id",
-		Description: "Change exec password by id.",
+		Summary:     "Set exec password by id",
+		Description: "Set new password of exec by id and revoke its sessions.",
 		Tags:        []string{"Exec"},
-	}, execHandler.UpdatePasswordHandler)
+		Security:    middleware.Require(middleware.PermExecsWrite),
+	}, execHandler.SetExecPasswordHandler)
 
 	huma.Register(api, huma.Operation{
 		OperationID: "login-exec",
@@ -258,6 +709,43 @@ func routesExec(api huma.API, execHandler *handlers.ExecsHandlers) {
 		Tags:        []string{"Exec"},
 	}, execHandler.ExecLoginHandler)
 
+	huma.Register(api, huma.Operation{
+		OperationID: "login-mfa-exec",
+		Method:      http.MethodPost,
+		Path:        "/execs/login/mfa",
+		Summary:     "Complete exec login with two factor",
+		Description: "Exchange the mfa token from the login and a totp or recovery code for the session.",
+		Tags:        []string{"Exec"},
+	}, execHandler.ExecLoginMFAHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "oidc-login-exec",
+		Method:      http.MethodGet,
+		Path:        "/execs/oidc/login",
+		Summary:     "Login exec with identity provider",
+		Description: "Redirect to the login at the configured OpenID Connect identity provider.",
+		Tags:        []string{"Exec"},
+	}, execHandler.OIDCLoginHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "oidc-callback-exec",
+		Method:      http.MethodGet,
+		Path:        "/execs/oidc/callback",
+		Summary:     "Identity provider callback",
+		Description: "Complete the login at the identity provider and issue the exec session.",
+		Tags:        []string{"Exec"},
+	}, execHandler.OIDCCallbackHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "disable-exec-mfa",
+		Method:      http.MethodDelete,
+		Path:        "/execs/{id}/mfa",
+		Summary:     "Disable exec two factor",
+		Description: "Disable two factor of exec by id, e.g. after the authenticator is lost.",
+		Tags:        []string{"Exec"},
+		Security:    middleware.Require(middleware.PermExecsWrite),
+	}, execHandler.DisableMFAHandler)
+
 	huma.Register(api, huma.Operation{
 		OperationID: "logout-execs",
 		Method:      http.MethodPost,
@@ -265,8 +753,107 @@ func routesExec(api huma.API, execHandler *handlers.ExecsHandlers) {
 		Summary:     "Logout exec",
 		Description: "Logout execs.",
 		Tags:        []string{"Exec"},
+		Security:    middleware.Require(),
 	}, execHandler.LogoutExecsHandler)
 
+	huma.Register(api, huma.Operation{
+		OperationID: "refresh-execs",
+		Method:      http.MethodPost,
+		Path:        "/execs/refresh",
+		Summary:     "Refresh exec token",
+		Description: "Rotate the refresh token and issue a new access token.",
+		Tags:        []string{"Exec"},
+	}, execHandler.RefreshExecsHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "logoutall-execs",
+		Method:      http.MethodPost,
+		Path:        "/execs/logoutall",
+		Summary:     "Logout exec everywhere",
+		Description: "Revoke all sessions of the logged in exec.",
+		Tags:        []string{"Exec"},
+		Security:    middleware.Require(),
+	}, execHandler.LogoutAllExecsHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "revoke-exec-sessions",
+		Method:      http.MethodDelete,
+		Path:        "/execs/{id}/sessions",
+		Summary:     "Revoke exec sessions",
+		Description: "Revoke all sessions of exec by id.",
+		Tags:        []string{"Exec"},
+		Security:    middleware.Require(middleware.PermExecsWrite),
+	}, execHandler.RevokeExecSessionsHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "lock-exec",
+		Method:      http.MethodPost,
+		Path:        "/execs/{id}/lock",
+		Summary:     "Lock exec login",
+		Description: "Temporary lock the login of exec by id.",
+		Tags:        []string{"Exec"},
+		Security:    middleware.Require(middleware.PermExecsWrite),
+	}, execHandler.LockExecHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "unlock-exec",
+		Method:      http.MethodPost,
+		Path:        "/execs/{id}/unlock",
+		Summary:     "Unlock exec login",
+		Description: "Unlock the login of exec by id and reset the failed attempts.",
+		Tags:        []string{"Exec"},
+		Security:    middleware.Require(middleware.PermExecsWrite),
+	}, execHandler.UnlockExecHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "invite-exec",
+		Method:      http.MethodPost,
+		Path:        "/execs/invite",
+		Summary:     "Invite exec",
+		Description: "Create inactive exec without password and email the single use activation link.",
+		Tags:        []string{"Exec"},
+		Security:    middleware.Require(middleware.PermExecsWrite),
+	}, execHandler.InviteExecHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "resend-exec-invitation",
+		Method:      http.MethodPost,
+		Path:        "/execs/{id}/invite",
+		Summary:     "Resend exec invitation",
+		Description: "Email new activation link to exec which did not activate the account, the old link stops working.",
+		Tags:        []string{"Exec"},
+		Security:    middleware.Require(middleware.PermExecsWrite),
+	}, execHandler.ResendInvitationHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "activate-exec",
+		Method:      http.MethodPost,
+		Path:        "/execs/invitations/{activationcode}/activate",
+		Summary:     "Activate exec account",
+		Description: "Set the password with the code from the invitation email and activate the account.",
+		Tags:        []string{"Exec"},
+	}, execHandler.ActivateExecHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "deactivate-exec",
+		Method:      http.MethodPost,
+		Path:        "/execs/{id}/deactivate",
+		Summary:     "Deactivate exec",
+		Description: "Deactivate exec by id and revoke all of its sessions.",
+		Tags:        []string{"Exec"},
+		Security:    middleware.Require(middleware.PermExecsWrite),
+	}, execHandler.DeactivateExecHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "reactivate-exec",
+		Method:      http.MethodPost,
+		Path:        "/execs/{id}/reactivate",
+		Summary:     "Reactivate exec",
+		Description: "Reactivate deactivated exec by id.",
+		Tags:        []string{"Exec"},
+		Security:    middleware.Require(middleware.PermExecsWrite),
+	}, execHandler.ReactivateExecHandler)
+
 	huma.Register(api, huma.Operation{
 		OperationID: "forgotpassword-execs",
 		Method:      http.MethodPost,
@@ -285,3 +872,149 @@ func routesExec(api huma.API, execHandler *handlers.ExecsHandlers) {
 		Tags:        []string{"Exec"},
 	}, execHandler.PasswordresetExecsHandler)
 }
+
+func routesAPIKeys(api huma.API, apiKeysHandler *handlers.APIKeysHandlers) {
+	huma.Register(api, huma.Operation{
+		OperationID: "create-my-apikey",
+		Method:      http.MethodPost,
+		Path:        "/me/apikeys",
+		Summary:     "Create api key",
+		Description: "Create api key for the logged in exec, the key is returned only once.",
+		Tags:        []string{"API Keys"},
+		Security:    middleware.Require(),
+	}, apiKeysHandler.CreateAPIKeyHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "list-my-apikeys",
+		Method:      http.MethodGet,
+		Path:        "/me/apikeys",
+		Summary:     "List own api keys",
+		Description: "List the api keys of the logged in exec.",
+		Tags:        []string{"API Keys"},
+		Security:    middleware.Require(),
+	}, apiKeysHandler.ListMyAPIKeysHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "revoke-my-apikey",
+		Method:      http.MethodDelete,
+		Path:        "/me/apikeys/{keyid}",
+		Summary:     "Revoke own api key",
+		Description: "Revoke api key of the logged in exec.",
+		Tags:        []string{"API Keys"},
+		Security:    middleware.Require(),
+	}, apiKeysHandler.RevokeMyAPIKeyHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "list-exec-apikeys",
+		Method:      http.MethodGet,
+		Path:        "/execs/{id}/apikeys",
+		Summary:     "List api keys",
+		Description: "List the api keys of exec by id.",
+		Tags:        []string{"API Keys"},
+		Security:    middleware.Require(middleware.PermExecsWrite),
+	}, apiKeysHandler.ListAPIKeysHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "revoke-exec-apikey",
+		Method:      http.MethodDelete,
+		Path:        "/execs/{id}/apikeys/{keyid}",
+		Summary:     "Revoke api key",
+		Description: "Revoke api key of exec by id.",
+		Tags:        []string{"API Keys"},
+		Security:    middleware.Require(middleware.PermExecsWrite),
+	}, apiKeysHandler.RevokeAPIKeyHandler)
+}
+
+func routesMe(api huma.API, execHandler *handlers.ExecsHandlers) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-me",
+		Method:      http.MethodGet,
+		Path:        "/me",
+		Summary:     "Get own profile",
+		Description: "Get the profile of the logged in exec.",
+		Tags:        []string{"Me"},
+		Security:    middleware.Require(),
+	}, execHandler.GetMeHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "patch-me",
+		Method:      http.MethodPatch,
+		Path:        "/me",
+		Summary:     "Update own profile",
+		Description: "Update the names and the email of the logged in exec.",
+		Tags:        []string{"Me"},
+		Security:    middleware.Require(),
+	}, execHandler.PatchMeHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "change-my-password",
+		Method:      http.MethodPost,
+		Path:        "/me/password",
+		Summary:     "Change own password",
+		Description: "Change the password of the logged in exec with the current password, the other sessions are revoked.",
+		Tags:        []string{"Me"},
+		Security:    middleware.Require(),
+	}, execHandler.UpdatePasswordHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "list-my-sessions",
+		Method:      http.MethodGet,
+		Path:        "/me/sessions",
+		Summary:     "List own sessions",
+		Description: "List the active login sessions of the logged in exec.",
+		Tags:        []string{"Me"},
+		Security:    middleware.Require(),
+	}, execHandler.ListMySessionsHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "enroll-my-mfa",
+		Method:      http.MethodPost,
+		Path:        "/me/mfa/enroll",
+		Summary:     "Enroll two factor",
+		Description: "Generate new totp secret and provisioning URI for the logged in exec.",
+		Tags:        []string{"Me"},
+		Security:    middleware.Require(),
+	}, execHandler.EnrollMFAHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "confirm-my-mfa",
+		Method:      http.MethodPost,
+		Path:        "/me/mfa/confirm",
+		Summary:     "Confirm two factor",
+		Description: "Enable two factor with the first code from the authenticator and get the recovery codes.",
+		Tags:        []string{"Me"},
+		Security:    middleware.Require(),
+	}, execHandler.ConfirmMFAHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "recovery-codes-my-mfa",
+		Method:      http.MethodPost,
+		Path:        "/me/mfa/recovery-codes",
+		Summary:     "Regenerate recovery codes",
+		Description: "Replace the recovery codes, the old ones can not be used anymore.",
+		Tags:        []string{"Me"},
+		Security:    middleware.Require(),
+	}, execHandler.RegenerateRecoveryCodesHandler)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "disable-my-mfa",
+		Method:      http.MethodPost,
+		Path:        "/me/mfa/disable",
+		Summary:     "Disable two factor",
+		Description: "Disable two factor of the logged in exec with a current code.",
+		Tags:        []string{"Me"},
+		Security:    middleware.Require(),
+	}, execHandler.DisableMyMFAHandler)
+}
+
+func routesAudit(api huma.API, auditHandler *handlers.AuditHandlers) {
+	huma.Register(api, huma.Operation{
+		OperationID: "list-auth-events",
+		Method:      http.MethodGet,
+		Path:        "/audit/auth",
+		Summary:     "List authentication events",
+		Description: "List the authentication audit log, the newest first, filtered by exec, event type, outcome and time range.",
+		Tags:        []string{"Audit"},
+		Security:    middleware.Require(middleware.PermAuditRead),
+	}, auditHandler.ListAuthEventsHandler)
+}
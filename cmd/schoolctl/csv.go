@@ -0,0 +1,276 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/utils"
+)
+
+// exportPageSize - the students are read in pages as GetAllStudents always paginates,
+// the records are ordered by the unique email so the pages don't overlap
+const exportPageSize = 500
+
+var (
+	teacherColumns = []string{"id", "first_name", "last_name", "email", "class_id", "subject", "deleted_at"}
+	studentColumns = []string{"id", "first_name", "last_name", "email", "class_id", "deleted_at"}
+	execColumns    = []string{
+		"id", "first_name", "last_name", "email", "username", "role", "inactive_status", "deleted_at",
+	}
+)
+
+// entityArgs - the entity and the flags of the import and export commands, the
+// entity can be before or after the flags
+func entityArgs(fs *flag.FlagSet, args []string) (string, error) {
+	var entity string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		entity, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return "", err
+	}
+	if entity == "" && fs.NArg() > 0 {
+		entity = fs.Arg(0)
+	}
+	if entity == "" {
+		return "", errors.New("the entity is required")
+	}
+	return entity, nil
+}
+
+func (c *ctl) export(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	file := fs.String("file", "", "csv file to write, stdout when empty")
+	includeDeleted := fs.Bool("include-deleted", false, "export also the soft deleted records")
+	entity, err := entityArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	out := c.out
+	if *file != "" {
+		f, err := os.Create(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	w := csv.NewWriter(out)
+
+	var count int
+	switch entity {
+	case "teachers":
+		count, err = c.exportTeachers(w, *includeDeleted)
+	case "students":
+		count, err = c.exportStudents(w, *includeDeleted)
+	case "execs":
+		count, err = c.exportExecs(w, *includeDeleted)
+	default:
+		return fmt.Errorf("unknown entity %q, teachers, students or execs", entity)
+	}
+	if err != nil {
+		return err
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	if *file != "" {
+		fmt.Fprintf(c.out, "exported %d %s to %s\n", count, entity, *file)
+	}
+	return nil
+}
+
+func (c *ctl) exportTeachers(w *csv.Writer, includeDeleted bool) (int, error) {
+	teachers, _, err := c.teachersDB.GetAllTeachers(c.ctx, map[string]string{}, []string{"email:asc"}, includeDeleted)
+	if err != nil {
+		return 0, err
+	}
+	if err := w.Write(teacherColumns); err != nil {
+		return 0, err
+	}
+	for i, t := range teachers {
+		err := w.Write([]string{strconv.Itoa(t.ID), t.FirstName, t.LastName, t.Email, strconv.Itoa(t.ClassID), t.Subject, t.DeletedAt})
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(teachers), nil
+}
+
+func (c *ctl) exportStudents(w *csv.Writer, includeDeleted bool) (int, error) {
+	if err := w.Write(studentColumns); err != nil {
+		return 0, err
+	}
+	count := 0
+	for page := 1; ; page++ {
+		students, total, err := c.studentsDB.GetAllStudents(c.ctx, map[string]string{},
+			[]string{"email:asc"},
+			page,
+			exportPageSize,
+			includeDeleted,
+		)
+		if err != nil {
+			return count, err
+		}
+		for _, s := range students {
+			if err := w.Write([]string{strconv.Itoa(s.ID), s.FirstName, s.LastName, s.Email, strconv.Itoa(s.ClassID), s.DeletedAt}); err != nil {
+				return count, err
+			}
+			count++
+		}
+		if len(students) < exportPageSize || count >= total {
+			return count, nil
+		}
+	}
+}
+
+// exportExecs - without the passwords and the tokens
+func (c *ctl) exportExecs(w *csv.Writer, includeDeleted bool) (int, error) {
+	execs, _, err := c.execsDB.GetAllExecs(c.ctx, map[string]string{}, []string{"email:asc"}, includeDeleted)
+	if err != nil {
+		return 0, err
+	}
+	if err := w.Write(execColumns); err != nil {
+		return 0, err
+	}
+	for i, e := range execs {
+		err := w.Write([]string{
+			strconv.Itoa(e.ID),
+			e.FirstName,
+			e.LastName,
+			e.Email,
+			e.Username,
+			e.Role,
+			strconv.FormatBool(e.InactiveStatus),
+			e.DeletedAt,
+		})
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(execs), nil
+}
+
+// importCSV - the header names the columns, the id and deleted_at columns of an export
+// are ignored so the exported file can be imported into another database with the same
+// classes. All rows are checked before the first one is added
+func (c *ctl) importCSV(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	file := fs.String("file", "", "csv file to read, stdin when empty")
+	entity, err := entityArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	var required []string
+	switch entity {
+	case "teachers":
+		required = []string{"first_name", "last_name", "email", "class_id", "subject"}
+	case "students":
+		required = []string{"first_name", "last_name", "email", "class_id"}
+	default:
+		return fmt.Errorf("unknown entity %q, teachers or students", entity)
+	}
+
+	in := io.Reader(c.in)
+	if *file != "" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	records, err := readCSV(in, required)
+	if err != nil {
+		return err
+	}
+	classIDs := make([]int, len(records))
+	for i, record := range records {
+		if err := utils.EmailCheck(record["email"]); err != nil {
+			return fmt.Errorf("line %d: %w", i+2, err)
+		}
+		if classIDs[i], err = strconv.Atoi(record["class_id"]); err != nil || classIDs[i] < 1 {
+			return fmt.Errorf("line %d: invalid class_id %q", i+2, record["class_id"])
+		}
+	}
+
+	for i, record := range records {
+		var err error
+		switch entity {
+		case "teachers":
+			_, err = c.teachersDB.InsertTeachers(c.ctx, &models.Teacher{
+				FirstName: record["first_name"],
+				LastName:  record["last_name"],
+				Email:     record["email"],
+				ClassID:   classIDs[i],
+				Subject:   record["subject"],
+			})
+		case "students":
+			_, err = c.studentsDB.InsertStudents(c.ctx, &models.Student{
+				FirstName: record["first_name"],
+				LastName:  record["last_name"],
+				Email:     record["email"],
+				ClassID:   classIDs[i],
+			})
+		}
+		if err != nil {
+			return fmt.Errorf("line %d: %w, %d %s imported before it", i+2, err, i, entity)
+		}
+	}
+	fmt.Fprintf(c.out, "imported %d %s\n", len(records), entity)
+	return nil
+}
+
+// readCSV - the rows as maps by the header, the required columns must be in the
+// header and not empty in any row
+func readCSV(in io.Reader, required []string) ([]map[string]string, error) {
+	r := csv.NewReader(in)
+	r.TrimLeadingSpace = true
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading the header %w", err)
+	}
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+	for _, column := range required {
+		found := false
+		for _, h := range header {
+			found = found || h == column
+		}
+		if !found {
+			return nil, fmt.Errorf("the header has no %s column", column)
+		}
+	}
+
+	var records []map[string]string
+	for line := 2; ; line++ {
+		row, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		record := make(map[string]string, len(header))
+		for i, value := range row {
+			record[header[i]] = strings.TrimSpace(value)
+		}
+		for _, column := range required {
+			if record[column] == "" {
+				return nil, fmt.Errorf("line %d: %s is empty", line, column)
+			}
+		}
+		records = append(records, record)
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadCSV(t *testing.T) {
+	// an export of teachers, the extra columns are ignored
+	export := `id,first_name,last_name,email,class,subject,deleted_at
+101,Ann,Smith,ann@school.test,10B,History,
+102, Bob ,Brown,bob@school.test,9A,Math,
+`
+	records, err := readCSV(strings.NewReader(export), []string{"first_name", "last_name", "email", "class", "subject"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[1]["first_name"] != "Bob" || records[0]["class"] != "10B" {
+		t.Fatalf("Expected two trimmed records, got %v", records)
+	}
+
+	if _, err := readCSV(strings.NewReader("first_name,email\nAnn,ann@school.test\n"), []string{"class"}); err == nil {
+		t.Fatal("Expected error for missing class column")
+	}
+	_, err = readCSV(strings.NewReader("first_name,class\nAnn,\n"), []string{"first_name", "class"})
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("Expected error for empty class on line 2, got %v", err)
+	}
+}
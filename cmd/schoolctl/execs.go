@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/config"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/middleware"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/password"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/utils"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/repository/sqlconnect"
+)
+
+// auditDetail - the detail of the audit events written by schoolctl, there is no
+// logged in exec or client address for them
+const auditDetail = "schoolctl"
+
+type ctl struct {
+	// ctx - the context of the queries, every query is still limited by the query timeout
+	ctx           context.Context
+	execsDB       dataops.ExecsInf
+	sessionsDB    dataops.SessionsInf
+	invitationsDB dataops.ExecInvitationsInf
+	auditDB       dataops.AuthAuditInf
+	teachersDB    dataops.TeachersInf
+	studentsDB    dataops.StudentInf
+	statsDB       dataops.StatsInf
+	conf          *config.Config
+	logger        *logging.Logger
+	in            *bufio.Reader
+	out           io.Writer
+}
+
+func newCtl(db *sqlconnect.DB, conf *config.Config, logger *logging.Logger, in io.Reader, out io.Writer) *ctl {
+	return &ctl{
+		ctx:           context.Background(),
+		execsDB:       dataops.NewExecsDB(db, logger, conf.DBQueryTimeout),
+		sessionsDB:    dataops.NewSessionsDB(db, logger),
+		invitationsDB: dataops.NewExecInvitationsDB(db, logger),
+		auditDB:       dataops.NewAuthAuditDB(db, logger),
+		teachersDB:    dataops.NewTeachersDB(db, logger, conf.DBQueryTimeout),
+		studentsDB:    dataops.NewStudentsDB(db, logger, conf.DBQueryTimeout),
+		statsDB:       dataops.NewStatsDB(db, logger),
+		conf:          conf,
+		logger:        logger,
+		in:            bufio.NewReader(in),
+		out:           out,
+	}
+}
+
+// passwordPolicy - the same policy as the api checks for the new passwords
+func (c *ctl) passwordPolicy() (password.Policy, error) {
+	policy, err := password.PolicyFromConfig(*c.conf)
+	if err != nil {
+		return password.Policy{}, fmt.Errorf("failed to load the breached passwords %w", err)
+	}
+	return policy, nil
+}
+
+// newPassword - reads the password from the first line of stdin, checks it with the
+// policy and returns the hash
+func (c *ctl) newPassword(exec models.Exec) (string, error) {
+	fmt.Fprint(os.Stderr, "Password: ")
+	line, err := c.in.ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		return "", fmt.Errorf("failed to read the password %w", err)
+	}
+	pw := strings.TrimRight(line, "\r\n")
+
+	policy, err := c.passwordPolicy()
+	if err != nil {
+		return "", err
+	}
+	violations := policy.Check(pw, exec.Username, exec.Email)
+	if exec.ID > 0 && policy.HistorySize > 0 {
+		hashes, err := c.execsDB.GetPasswordHistory(c.ctx, exec.ID, policy.HistorySize)
+		if err != nil {
+			return "", err
+		}
+		violations = append(violations, policy.CheckHistory(pw, hashes, password.ParamsFromConfig(*c.conf))...)
+	}
+	if len(violations) > 0 {
+		return "", fmt.Errorf("the password %s", strings.Join(violations, ", "))
+	}
+	return password.Hash(pw, password.ParamsFromConfig(*c.conf))
+}
+
+func (c *ctl) recordPasswordHistory(id int, hash string) {
+	if c.conf.PasswordHistory <= 0 {
+		return
+	}
+	if err := c.execsDB.AddPasswordHistory(c.ctx, id, hash, c.conf.PasswordHistory); err != nil {
+		c.logger.Logging.Errorf("failed to store the password history %v", err)
+	}
+}
+
+func (c *ctl) audit(eventType string, exec models.Exec) {
+	err := c.auditDB.RecordAuthEvent(models.AuthEvent{
+		EventType: eventType,
+		ExecID:    exec.ID,
+		Username:  exec.Username,
+		Outcome:   models.AuthOutcomeSuccess,
+		Detail:    auditDetail,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		c.logger.Logging.Errorf("failed to write the audit event %s %v", eventType, err)
+	}
+}
+
+// createAdmin - the first exec can not be added over the api as all exec routes
+// need a logged in admin
+func (c *ctl) createAdmin(args []string) error {
+	fs := flag.NewFlagSet("create-admin", flag.ContinueOnError)
+	firstName := fs.String("first-name", "", "first name of the admin")
+	lastName := fs.String("last-name", "", "last name of the admin")
+	email := fs.String("email", "", "email of the admin")
+	username := fs.String("username", "", "username of the admin, the email when it is empty")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *firstName == "" || *lastName == "" || *email == "" {
+		return errors.New("-first-name, -last-name and -email are required")
+	}
+	if err := utils.EmailCheck(*email); err != nil {
+		return err
+	}
+	if *username == "" {
+		*username = *email
+	}
+	if _, err := c.execsDB.GetIdFromEmail(c.ctx, *email); err == nil {
+		return fmt.Errorf("exec with email %s already exists", *email)
+	}
+	if exists, _, _ := c.execsDB.SearchUsername(c.ctx, *username); exists {
+		return fmt.Errorf("exec with username %s already exists", *username)
+	}
+
+	exec := models.Exec{
+		FirstName: *firstName,
+		LastName:  *lastName,
+		Email:     *email,
+		Username:  *username,
+		Role:      middleware.RoleAdmin,
+	}
+	hash, err := c.newPassword(exec)
+	if err != nil {
+		return err
+	}
+	exec.Password = hash
+	id, err := c.execsDB.InsertExecs(c.ctx, &exec)
+	if err != nil {
+		return err
+	}
+	exec.ID = int(id)
+	c.recordPasswordHistory(exec.ID, hash)
+	c.audit(models.AuthEventPasswordSet, exec)
+
+	fmt.Fprintf(c.out, "created admin %s with id %d\n", exec.Username, exec.ID)
+	return nil
+}
+
+func (c *ctl) resetPassword(args []string) error {
+	fs := flag.NewFlagSet("reset-password", flag.ContinueOnError)
+	id := fs.Int("id", 0, "id of the exec")
+	username := fs.String("username", "", "username of the exec")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var (
+		exec models.Exec
+		err  error
+	)
+	switch {
+	case *id > 0:
+		exec, err = c.execsDB.GetExecsByID(c.ctx, *id)
+	case *username != "":
+		exec, err = c.execsDB.GetLoginDetailsForUsername(c.ctx, *username)
+	default:
+		return errors.New("-id or -username is required")
+	}
+	if err != nil {
+		return fmt.Errorf("exec not found: %w", err)
+	}
+
+	hash, err := c.newPassword(exec)
+	if err != nil {
+		return err
+	}
+	if err := c.execsDB.UpdatePasswordChange(c.ctx, exec.ID, hash); err != nil {
+		return err
+	}
+	c.recordPasswordHistory(exec.ID, hash)
+	if _, err := c.sessionsDB.RevokeAllSessions(exec.ID); err != nil {
+		c.logger.Logging.Errorf("failed to revoke the sessions %v", err)
+	}
+	c.audit(models.AuthEventPasswordSet, exec)
+
+	fmt.Fprintf(c.out, "password of %s updated, the sessions are revoked\n", exec.Username)
+	return nil
+}
+
+// setInactive - the running api instances notice the change when their auth cache expires
+func (c *ctl) setInactive(args []string, inactive bool) error {
+	if len(args) != 1 {
+		return errors.New("the id of the exec is required")
+	}
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid id %q", args[0])
+	}
+	exec, err := c.execsDB.GetExecsByID(c.ctx, id)
+	if err != nil {
+		return fmt.Errorf("exec not found: %w", err)
+	}
+
+	if !inactive {
+		pending, err := c.invitationsDB.HasInvitation(exec.ID)
+		if err != nil {
+			return err
+		}
+		if pending {
+			return errors.New("exec has not activated the account yet")
+		}
+	}
+	if err := c.execsDB.SetInactiveStatus(c.ctx, exec.ID, inactive); err != nil {
+		return err
+	}
+
+	if inactive {
+		if _, err := c.sessionsDB.RevokeAllSessions(exec.ID); err != nil {
+			c.logger.Logging.Errorf("failed to revoke sessions of deactivated exec %v", err)
+		}
+		c.audit(models.AuthEventDeactivate, exec)
+		fmt.Fprintf(c.out, "exec %s deactivated\n", exec.Username)
+		return nil
+	}
+	c.audit(models.AuthEventReactivate, exec)
+	fmt.Fprintf(c.out, "exec %s activated\n", exec.Username)
+	return nil
+}
@@ -0,0 +1,81 @@
+// Command schoolctl - administration of the school database without the http api, it uses
+// the same flags and env variables as the api for the database connection
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/config"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/repository/migrations"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/repository/sqlconnect"
+)
+
+const usage = `usage: schoolctl [flags] <command> [arguments]
+
+commands:
+  create-admin -first-name NAME -last-name NAME -email EMAIL [-username USERNAME]
+                    create an admin exec, the password is read from stdin
+  reset-password -id ID | -username USERNAME
+                    set the password of the exec read from stdin and revoke its sessions
+  activate ID       reactivate a deactivated exec
+  deactivate ID     deactivate the exec and revoke its sessions
+  migrate up|down [steps]|status
+                    apply, revert or show the schema migrations
+  export teachers|students|execs [-file FILE] [-include-deleted]
+                    write the records as csv, to stdout without -file
+  import teachers|students [-file FILE]
+                    add the records from csv, from stdin without -file
+  stats             print the counts of the records
+
+the flags are the flags of the api, run with -h to list them
+`
+
+func main() {
+	conf := config.LoadConfig()
+	args := flag.Args()
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	db, err := sqlconnect.ConnectDB(conf)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error ", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+	ctl := newCtl(db, conf, logging.Init(conf.Debug), os.Stdin, os.Stdout)
+
+	switch args[0] {
+	case "create-admin":
+		err = ctl.createAdmin(args[1:])
+	case "reset-password":
+		err = ctl.resetPassword(args[1:])
+	case "activate":
+		err = ctl.setInactive(args[1:], false)
+	case "deactivate":
+		err = ctl.setInactive(args[1:], true)
+	case "migrate":
+		var migrator *migrations.Migrator
+		migrator, err = migrations.NewMigrator(db, ctl.logger, conf.MigrateLockTimeout)
+		if err == nil {
+			err = migrator.Run(args[1:], os.Stdout)
+		}
+	case "export":
+		err = ctl.export(args[1:])
+	case "import":
+		err = ctl.importCSV(args[1:])
+	case "stats":
+		err = ctl.stats()
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", args[0], usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error ", err)
+		os.Exit(1)
+	}
+}
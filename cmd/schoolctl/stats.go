@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"text/tabwriter"
+)
+
+func (c *ctl) stats() error {
+	stats, err := c.statsDB.GetStats()
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "teachers\t%d\t(%d deleted)\n", stats.Teachers, stats.DeletedTeachers)
+	fmt.Fprintf(tw, "students\t%d\t(%d deleted)\n", stats.Students, stats.DeletedStudents)
+	fmt.Fprintf(tw, "execs\t%d\t(%d inactive, %d deleted)\n", stats.Execs, stats.InactiveExecs, stats.DeletedExecs)
+	for _, role := range sortedKeys(stats.ExecsByRole) {
+		fmt.Fprintf(tw, "  role %s\t%d\t\n", role, stats.ExecsByRole[role])
+	}
+	fmt.Fprintln(tw, "students by class\t\t")
+	for _, class := range sortedKeys(stats.StudentsByClass) {
+		fmt.Fprintf(tw, "  %s\t%d\t\n", class, stats.StudentsByClass[class])
+	}
+	return tw.Flush()
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"flag"
+	"net/http"
 	"os"
 	"strconv"
 	"strings"
@@ -10,6 +11,9 @@ import (
 
 type Config struct {
 	Port                       string
+	DBDriver                   string
+	Repository                 string
+	RepositoryFixture          string
 	DBUser                     string
 	DBPassword                 string
 	DBDatabase                 string
@@ -20,6 +24,54 @@ type Config struct {
 	JWTExpiresIn               time.Duration
 	ExcludedAuthMiddlewarePath []string
 	ResetTokenExpDuration      time.Duration
+	InviteTokenExpiresIn       time.Duration
+	RefreshTokenExpiresIn      time.Duration
+	AuthCacheTTL               time.Duration
+	CookieName                 string
+	CookieDomain               string
+	CookieSecure               bool
+	CookieSameSite             http.SameSite
+	LoginMaxAttempts           int
+	LoginMaxAttemptsIP         int
+	LoginLockDuration          time.Duration
+	LoginBackoffBase           time.Duration
+	MFAIssuer                  string
+	MFASecretKey               string
+	MFAChallengeExpiresIn      time.Duration
+	OIDCIssuer                 string
+	OIDCClientID               string
+	OIDCClientSecret           string
+	OIDCRedirectURL            string
+	PasswordMinLength          int
+	PasswordRequireUpper       bool
+	PasswordRequireLower       bool
+	PasswordRequireDigit       bool
+	PasswordRequireSymbol      bool
+	PasswordDisallowIdentity   bool
+	PasswordHistory            int
+	BreachedPasswordsFile      string
+	Argon2Memory               uint
+	Argon2Iterations           uint
+	Argon2Parallelism          uint
+	PublicBaseURL              string
+	MailDriver                 string
+	MailFileDir                string
+	MailTemplatesDir           string
+	MailQueueSize              int
+	MailMaxAttempts            int
+	MailRetryBackoff           time.Duration
+	SMTPHost                   string
+	SMTPPort                   string
+	SMTPUsername               string
+	SMTPPassword               string
+	SMTPFrom                   string
+	SMTPStartTLS               bool
+	SoftDeleteRetention        time.Duration
+	PurgeInterval              time.Duration
+	MigrateOnStart             bool
+	DBQueryTimeout             time.Duration
+	MigrateLockTimeout         time.Duration
+	ShutdownTimeout            time.Duration
 }
 
 func LoadConfig() *Config {
@@ -32,15 +84,46 @@ func (c *Config) GetFlags() {
 	var JwtStringExpireValue string
 	var exclPaths string
 	var resetTokenExpDuration string
+	var inviteTokenExpiresIn string
+	var refreshTokenExpiresIn string
+	var authCacheTTL string
+	var cookieSameSite string
+	var loginLockDuration string
+	var loginBackoffBase string
+	var mfaChallengeExpiresIn string
+	var mailRetryBackoff string
+	var softDeleteRetention string
+	var purgeInterval string
+	var migrateLockTimeout string
+	var dbQueryTimeout string
+	var shutdownTimeout string
 	flag.StringVar(
 		&c.Port,
 		"app-port",
 		"8082",
 		"Application port",
 	)
+	flag.StringVar(&c.DBDriver, "database-driver", "mysql", "Database driver mysql (mariadb), postgres or sqlite")
+	flag.StringVar(
+		&c.Repository,
+		"repository",
+		"sql",
+		"where the teachers, students and execs are kept, sql for the database or memory",
+	)
+	flag.StringVar(
+		&c.RepositoryFixture,
+		"repository-fixture",
+		"",
+		"json file with the teachers, students and execs loaded into the memory repository on startup",
+	)
 	flag.StringVar(&c.DBUser, "database-user", "root", "Database user")
 	flag.StringVar(&c.DBPassword, "database-password", "password", "Database password")
-	flag.StringVar(&c.DBDatabase, "database", "school_test", "Database to use")
+	flag.StringVar(
+		&c.DBDatabase,
+		"database",
+		"school_test",
+		"Database to use, for sqlite the database file or :memory:",
+	)
 	flag.StringVar(&c.DBHost, "database-host", "mariadb", "The database host to use or ip")
 	flag.StringVar(
 		&c.DBPort,
@@ -50,22 +133,169 @@ func (c *Config) GetFlags() {
 	)
 	flag.BoolVar(&c.Debug, "debug", false, "Using debug true or false")
 	flag.StringVar(&c.JWTSecret, "jwt-secret", "jwtsecret", "use the jwt secret")
-	flag.StringVar(&JwtStringExpireValue, "jwt-expire", "1800s", "expiration time of jwt token")
+	flag.StringVar(&JwtStringExpireValue, "jwt-expire", "900s", "expiration time of jwt token")
+	flag.StringVar(
+		&refreshTokenExpiresIn,
+		"refresh-tkn-exp",
+		"168h",
+		"expiration time of the refresh token and the login session",
+	)
+	flag.StringVar(
+		&authCacheTTL,
+		"auth-cache-ttl",
+		"30s",
+		"how long the session and exec status lookups of the jwt middleware are cached",
+	)
 	flag.StringVar(
 		&resetTokenExpDuration,
 		"reset-tkn-exp",
 		"600s",
 		"expiry duration of reset token in s min etc",
 	)
+	flag.StringVar(
+		&inviteTokenExpiresIn,
+		"invite-tkn-exp",
+		"72h",
+		"expiry duration of the activation link in the exec invitation",
+	)
+	flag.StringVar(&c.CookieName, "cookie-name", "Bearer", "name of the jwt access token cookie")
+	flag.StringVar(&c.CookieDomain, "cookie-domain", "", "domain of the auth cookies, empty for host only")
+	flag.BoolVar(&c.CookieSecure, "cookie-secure", true, "send the auth cookies only over https")
+	flag.StringVar(
+		&cookieSameSite,
+		"cookie-samesite",
+		"strict",
+		"SameSite of the auth cookies strict, lax or none",
+	)
+	flag.IntVar(
+		&c.LoginMaxAttempts,
+		"login-max-attempts",
+		5,
+		"failed logins for a username before it is temporary locked",
+	)
+	flag.IntVar(
+		&c.LoginMaxAttemptsIP,
+		"login-max-attempts-ip",
+		20,
+		"failed logins from an ip address before it is temporary locked",
+	)
+	flag.StringVar(
+		&loginLockDuration,
+		"login-lock-duration",
+		"15m",
+		"how long username or ip is locked after too many failed logins",
+	)
+	flag.StringVar(
+		&loginBackoffBase,
+		"login-backoff-base",
+		"1s",
+		"first delay after failed login, doubled on every next failure",
+	)
+	flag.StringVar(&c.MFAIssuer, "mfa-issuer", "School API", "issuer shown in the authenticator apps")
+	flag.StringVar(
+		&c.MFASecretKey,
+		"mfa-secret-key",
+		"",
+		"key to encrypt the totp secrets in the database, the jwt secret is used when empty",
+	)
+	flag.StringVar(
+		&mfaChallengeExpiresIn,
+		"mfa-challenge-exp",
+		"5m",
+		"how long the second login step with the totp code can be done after the password",
+	)
+	flag.StringVar(&c.OIDCIssuer, "oidc-issuer", "", "issuer url of the identity provider, empty disables the oidc login")
+	flag.StringVar(&c.OIDCClientID, "oidc-client-id", "", "client id registered at the identity provider")
+	flag.StringVar(&c.OIDCClientSecret, "oidc-client-secret", "", "client secret registered at the identity provider")
+	flag.StringVar(
+		&c.OIDCRedirectURL,
+		"oidc-redirect-url",
+		"http://localhost:8082/execs/oidc/callback",
+		"callback url registered at the identity provider",
+	)
+	flag.IntVar(&c.PasswordMinLength, "password-min-length", 12, "minimum length of exec passwords")
+	flag.BoolVar(&c.PasswordRequireUpper, "password-require-upper", true, "passwords must contain upper case letter")
+	flag.BoolVar(&c.PasswordRequireLower, "password-require-lower", true, "passwords must contain lower case letter")
+	flag.BoolVar(&c.PasswordRequireDigit, "password-require-digit", true, "passwords must contain digit")
+	flag.BoolVar(&c.PasswordRequireSymbol, "password-require-symbol", false, "passwords must contain symbol")
+	flag.BoolVar(
+		&c.PasswordDisallowIdentity,
+		"password-disallow-identity",
+		true,
+		"passwords must not contain the username or the email",
+	)
+	flag.IntVar(&c.PasswordHistory, "password-history", 5, "how many previous passwords can not be reused")
+	flag.StringVar(
+		&c.BreachedPasswordsFile,
+		"breached-passwords-file",
+		"",
+		"file with SHA-1 hashes of breached passwords, one HASH or HASH:COUNT per line",
+	)
+	flag.UintVar(&c.Argon2Memory, "argon2-memory", 64*1024, "argon2id memory in KiB for new password hashes")
+	flag.UintVar(&c.Argon2Iterations, "argon2-iterations", 1, "argon2id iterations for new password hashes")
+	flag.UintVar(&c.Argon2Parallelism, "argon2-parallelism", 4, "argon2id parallelism for new password hashes")
+	flag.StringVar(
+		&c.PublicBaseURL,
+		"public-base-url",
+		"http://localhost:8082",
+		"url of the api as seen by the users, used for the links in the emails",
+	)
+	flag.StringVar(&c.MailDriver, "mail-driver", "smtp", "how the emails are delivered smtp or file")
+	flag.StringVar(&c.MailFileDir, "mail-file-dir", "mail", "directory for the emails with the file mail driver")
+	flag.StringVar(&c.MailTemplatesDir, "mail-templates-dir", "templates/email", "directory with the email templates")
+	flag.IntVar(&c.MailQueueSize, "mail-queue-size", 100, "how many emails can wait for delivery")
+	flag.IntVar(&c.MailMaxAttempts, "mail-max-attempts", 5, "delivery attempts of an email before it is dropped")
+	flag.StringVar(&mailRetryBackoff, "mail-retry-backoff", "5s", "delay before the first retry, doubled on every next one")
+	flag.StringVar(
+		&softDeleteRetention,
+		"soft-delete-retention",
+		"720h",
+		"how long the deleted teachers, students and execs can be restored before they are purged",
+	)
+	flag.StringVar(&purgeInterval, "purge-interval", "24h", "how often the purge of the deleted records runs, 0 disables it")
+	flag.StringVar(
+		&dbQueryTimeout,
+		"db-query-timeout",
+		"5s",
+		"how long a query of the teachers, students and execs can run, 0 for no limit",
+	)
+	flag.BoolVar(&c.MigrateOnStart, "migrate-on-start", true, "apply the pending schema migrations when the server starts")
+	flag.StringVar(
+		&migrateLockTimeout,
+		"migrate-lock-timeout",
+		"60s",
+		"how long to wait for the migration lock held by another replica",
+	)
+	flag.StringVar(
+		&shutdownTimeout,
+		"shutdown-timeout",
+		"30s",
+		"how long the shutdown waits for the running requests and the queued emails",
+	)
+	flag.StringVar(&c.SMTPHost, "smtp-host", "localhost", "smtp server host")
+	flag.StringVar(&c.SMTPPort, "smtp-port", "1025", "smtp server port")
+	flag.StringVar(&c.SMTPUsername, "smtp-username", "", "smtp username, empty for no authentication")
+	flag.StringVar(&c.SMTPPassword, "smtp-password", "", "smtp password")
+	flag.StringVar(&c.SMTPFrom, "smtp-from", "noreply@school-rest-api.example.com", "from address of the emails")
+	flag.BoolVar(&c.SMTPStartTLS, "smtp-starttls", false, "require STARTTLS for the smtp connection")
 	flag.StringVar(
 		&exclPaths,
 		"login-path-to-exclude",
-		"/docs,/openapi,/schemas,/execs/login,/execs/forgotpassword,/execs/resetpassword/reset",
+		"/docs,/openapi,/schemas,/execs/login,/execs/refresh,/execs/oidc,/execs/forgotpassword,/execs/resetpassword/reset,/execs/invitations",
 		"paths to exclude when making login middleware check",
 	)
 
 	flag.Parse()
 
+	if dbdriver := getEnv("DATABASE_DRIVER"); dbdriver != "" {
+		c.DBDriver = dbdriver
+	}
+	if repository := getEnv("REPOSITORY"); repository != "" {
+		c.Repository = repository
+	}
+	if fixture := getEnv("REPOSITORY_FIXTURE"); fixture != "" {
+		c.RepositoryFixture = fixture
+	}
 	if dbhost := getEnv("DATABASE_HOST"); dbhost != "" {
 		c.DBHost = dbhost
 	}
@@ -118,6 +348,159 @@ func (c *Config) GetFlags() {
 		c.ResetTokenExpDuration = d
 	}
 
+	c.InviteTokenExpiresIn = durationFromEnv("INVITE_TOKEN_EXPIRES_IN", inviteTokenExpiresIn)
+
+	if refreshTokenExp := getEnv("REFRESH_TOKEN_EXPIRES_IN"); refreshTokenExp != "" {
+		d, err := time.ParseDuration(refreshTokenExp)
+		if err != nil {
+			panic(err)
+		}
+		c.RefreshTokenExpiresIn = d
+	} else {
+		d, err := time.ParseDuration(refreshTokenExpiresIn)
+		if err != nil {
+			panic(err)
+		}
+		c.RefreshTokenExpiresIn = d
+	}
+
+	if cacheTTL := getEnv("AUTH_CACHE_TTL"); cacheTTL != "" {
+		d, err := time.ParseDuration(cacheTTL)
+		if err != nil {
+			panic(err)
+		}
+		c.AuthCacheTTL = d
+	} else {
+		d, err := time.ParseDuration(authCacheTTL)
+		if err != nil {
+			panic(err)
+		}
+		c.AuthCacheTTL = d
+	}
+
+	if cookieName := getEnv("COOKIE_NAME"); cookieName != "" {
+		c.CookieName = cookieName
+	}
+	if cookieDomain := getEnv("COOKIE_DOMAIN"); cookieDomain != "" {
+		c.CookieDomain = cookieDomain
+	}
+	if cookieSecure := getEnv("COOKIE_SECURE"); cookieSecure != "" {
+		if secure, err := strconv.ParseBool(cookieSecure); err == nil {
+			c.CookieSecure = secure
+		}
+	}
+	if sameSite := getEnv("COOKIE_SAMESITE"); sameSite != "" {
+		cookieSameSite = sameSite
+	}
+	c.CookieSameSite = parseSameSite(cookieSameSite)
+
+	if maxAttempts := getEnv("LOGIN_MAX_ATTEMPTS"); maxAttempts != "" {
+		if n, err := strconv.Atoi(maxAttempts); err == nil {
+			c.LoginMaxAttempts = n
+		}
+	}
+	if maxAttempts := getEnv("LOGIN_MAX_ATTEMPTS_IP"); maxAttempts != "" {
+		if n, err := strconv.Atoi(maxAttempts); err == nil {
+			c.LoginMaxAttemptsIP = n
+		}
+	}
+	c.LoginLockDuration = durationFromEnv("LOGIN_LOCK_DURATION", loginLockDuration)
+	c.LoginBackoffBase = durationFromEnv("LOGIN_BACKOFF_BASE", loginBackoffBase)
+
+	if issuer := getEnv("MFA_ISSUER"); issuer != "" {
+		c.MFAIssuer = issuer
+	}
+	if mfaKey := getEnv("MFA_SECRET_KEY"); mfaKey != "" {
+		c.MFASecretKey = mfaKey
+	}
+	if c.MFASecretKey == "" {
+		c.MFASecretKey = c.JWTSecret
+	}
+	c.MFAChallengeExpiresIn = durationFromEnv("MFA_CHALLENGE_EXPIRES_IN", mfaChallengeExpiresIn)
+
+	if issuer := getEnv("OIDC_ISSUER"); issuer != "" {
+		c.OIDCIssuer = issuer
+	}
+	if clientID := getEnv("OIDC_CLIENT_ID"); clientID != "" {
+		c.OIDCClientID = clientID
+	}
+	if clientSecret := getEnv("OIDC_CLIENT_SECRET"); clientSecret != "" {
+		c.OIDCClientSecret = clientSecret
+	}
+	if redirectURL := getEnv("OIDC_REDIRECT_URL"); redirectURL != "" {
+		c.OIDCRedirectURL = redirectURL
+	}
+
+	if minLength := getEnv("PASSWORD_MIN_LENGTH"); minLength != "" {
+		if n, err := strconv.Atoi(minLength); err == nil {
+			c.PasswordMinLength = n
+		}
+	}
+	c.PasswordRequireUpper = boolFromEnv("PASSWORD_REQUIRE_UPPER", c.PasswordRequireUpper)
+	c.PasswordRequireLower = boolFromEnv("PASSWORD_REQUIRE_LOWER", c.PasswordRequireLower)
+	c.PasswordRequireDigit = boolFromEnv("PASSWORD_REQUIRE_DIGIT", c.PasswordRequireDigit)
+	c.PasswordRequireSymbol = boolFromEnv("PASSWORD_REQUIRE_SYMBOL", c.PasswordRequireSymbol)
+	c.PasswordDisallowIdentity = boolFromEnv("PASSWORD_DISALLOW_IDENTITY", c.PasswordDisallowIdentity)
+	if history := getEnv("PASSWORD_HISTORY"); history != "" {
+		if n, err := strconv.Atoi(history); err == nil {
+			c.PasswordHistory = n
+		}
+	}
+	if breachedFile := getEnv("BREACHED_PASSWORDS_FILE"); breachedFile != "" {
+		c.BreachedPasswordsFile = breachedFile
+	}
+
+	c.Argon2Memory = uintFromEnv("ARGON2_MEMORY", c.Argon2Memory)
+	c.Argon2Iterations = uintFromEnv("ARGON2_ITERATIONS", c.Argon2Iterations)
+	c.Argon2Parallelism = uintFromEnv("ARGON2_PARALLELISM", c.Argon2Parallelism)
+
+	if baseURL := getEnv("PUBLIC_BASE_URL"); baseURL != "" {
+		c.PublicBaseURL = baseURL
+	}
+	c.PublicBaseURL = strings.TrimRight(c.PublicBaseURL, "/")
+	if driver := getEnv("MAIL_DRIVER"); driver != "" {
+		c.MailDriver = driver
+	}
+	if dir := getEnv("MAIL_FILE_DIR"); dir != "" {
+		c.MailFileDir = dir
+	}
+	if dir := getEnv("MAIL_TEMPLATES_DIR"); dir != "" {
+		c.MailTemplatesDir = dir
+	}
+	if size := getEnv("MAIL_QUEUE_SIZE"); size != "" {
+		if n, err := strconv.Atoi(size); err == nil {
+			c.MailQueueSize = n
+		}
+	}
+	if attempts := getEnv("MAIL_MAX_ATTEMPTS"); attempts != "" {
+		if n, err := strconv.Atoi(attempts); err == nil {
+			c.MailMaxAttempts = n
+		}
+	}
+	c.MailRetryBackoff = durationFromEnv("MAIL_RETRY_BACKOFF", mailRetryBackoff)
+	c.SoftDeleteRetention = durationFromEnv("SOFT_DELETE_RETENTION", softDeleteRetention)
+	c.PurgeInterval = durationFromEnv("PURGE_INTERVAL", purgeInterval)
+	c.DBQueryTimeout = durationFromEnv("DB_QUERY_TIMEOUT", dbQueryTimeout)
+	c.MigrateOnStart = boolFromEnv("MIGRATE_ON_START", c.MigrateOnStart)
+	c.MigrateLockTimeout = durationFromEnv("MIGRATE_LOCK_TIMEOUT", migrateLockTimeout)
+	c.ShutdownTimeout = durationFromEnv("SHUTDOWN_TIMEOUT", shutdownTimeout)
+	if smtpHost := getEnv("SMTP_HOST"); smtpHost != "" {
+		c.SMTPHost = smtpHost
+	}
+	if smtpPort := getEnv("SMTP_PORT"); smtpPort != "" {
+		c.SMTPPort = smtpPort
+	}
+	if smtpUsername := getEnv("SMTP_USERNAME"); smtpUsername != "" {
+		c.SMTPUsername = smtpUsername
+	}
+	if smtpPassword := getEnv("SMTP_PASSWORD"); smtpPassword != "" {
+		c.SMTPPassword = smtpPassword
+	}
+	if smtpFrom := getEnv("SMTP_FROM"); smtpFrom != "" {
+		c.SMTPFrom = smtpFrom
+	}
+	c.SMTPStartTLS = boolFromEnv("SMTP_STARTTLS", c.SMTPStartTLS)
+
 	envPaths := getEnv("LOGIN_EXCLUDE_PATHS")
 
 	if envPaths != "" {
@@ -134,6 +517,52 @@ func (c *Config) GetFlags() {
 	}
 }
 
+// durationFromEnv - parses the env variable if it is set or else the flag value
+func durationFromEnv(key, flagValue string) time.Duration {
+	value := flagValue
+	if envValue := getEnv(key); envValue != "" {
+		value = envValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// boolFromEnv - the env variable if it is set and valid or else the flag value
+func boolFromEnv(key string, flagValue bool) bool {
+	if envValue := getEnv(key); envValue != "" {
+		if b, err := strconv.ParseBool(envValue); err == nil {
+			return b
+		}
+	}
+	return flagValue
+}
+
+// uintFromEnv - the env variable if it is set and valid or else the flag value
+func uintFromEnv(key string, flagValue uint) uint {
+	if envValue := getEnv(key); envValue != "" {
+		if n, err := strconv.ParseUint(envValue, 10, 0); err == nil {
+			return uint(n)
+		}
+	}
+	return flagValue
+}
+
+func parseSameSite(value string) http.SameSite {
+	switch strings.ToLower(value) {
+	case "lax":
+		return http.SameSiteLaxMode
+	case "none":
+		return http.SameSiteNoneMode
+	case "strict":
+		return http.SameSiteStrictMode
+	default:
+		panic("invalid cookie samesite value " + value)
+	}
+}
+
 func getEnv(key string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
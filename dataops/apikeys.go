@@ -0,0 +1,154 @@
+package dataops
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/repository/sqlconnect"
+)
+
+// apiKeyTouchInterval - last_used_at is updated at most once in this interval to save writes
+const apiKeyTouchInterval = time.Minute
+
+type APIKeys struct {
+	db     *sqlconnect.DB
+	logger *logging.Logger
+}
+
+func NewAPIKeysDB(db *sqlconnect.DB, logger *logging.Logger) *APIKeys {
+	return &APIKeys{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (a *APIKeys) CreateAPIKey(key *models.APIKey) (int64, error) {
+	var expiresAt sql.NullString
+	if key.ExpiresAt != "" {
+		expiresAt = sql.NullString{String: key.ExpiresAt, Valid: true}
+	}
+	lastID, err := a.db.InsertContext(
+		context.Background(),
+		"INSERT INTO api_keys (exec_id, name, key_prefix, key_hash, scopes, expires_at, created_at) VALUES (?,?,?,?,?,?,?)",
+		key.ExecID,
+		key.Name,
+		key.Prefix,
+		key.KeyHash,
+		strings.Join(key.Scopes, ","),
+		expiresAt,
+		time.Now().UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		a.logger.Logging.Debugf("error insert api key to the database %v", err)
+		return 0, a.logger.ErrorMessage("sql database error")
+	}
+	return lastID, nil
+}
+
+// GetAPIKeyByHash - the key for the auth middleware, the owner is looked up through the
+// execs repository, which in the memory mode is not backed by the execs table
+func (a *APIKeys) GetAPIKeyByHash(hashedKey string) (models.APIKey, error) {
+	row := a.db.QueryRow(
+		"SELECT id, exec_id, name, key_prefix, scopes, last_used_at, expires_at, revoked_at, created_at FROM api_keys WHERE key_hash = ?",
+		hashedKey,
+	)
+	var key models.APIKey
+	err := scanAPIKey(row, &key)
+	if err == sql.ErrNoRows {
+		a.logger.Logging.Debugf("api key not found %v", err)
+		return models.APIKey{}, a.logger.ErrorMessage("api key not found")
+	} else if err != nil {
+		a.logger.Logging.Debugf("error quering the database %v", err)
+		return models.APIKey{}, a.logger.ErrorMessage("sql api key error")
+	}
+	return key, nil
+}
+
+func (a *APIKeys) ListAPIKeys(execID int) ([]models.APIKey, error) {
+	rows, err := a.db.Query(
+		"SELECT id, exec_id, name, key_prefix, scopes, last_used_at, expires_at, revoked_at, created_at FROM api_keys WHERE exec_id = ? ORDER BY id",
+		execID,
+	)
+	if err != nil {
+		a.logger.Logging.Debugf("error quering the api keys %v", err)
+		return nil, a.logger.ErrorMessage("sql api key error")
+	}
+	defer rows.Close()
+
+	keys := make([]models.APIKey, 0)
+	for rows.Next() {
+		var key models.APIKey
+		if err := scanAPIKey(rows, &key); err != nil {
+			a.logger.Logging.Debugf("error scanning the api keys %v", err)
+			return nil, a.logger.ErrorMessage("sql api key error")
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// RevokeAPIKey - revokes the key of the exec, false when there is no such active key
+func (a *APIKeys) RevokeAPIKey(execID, id int) (bool, error) {
+	result, err := a.db.Exec(
+		"UPDATE api_keys SET revoked_at = ? WHERE id = ? AND exec_id = ? AND revoked_at IS NULL",
+		time.Now().UTC().Format(time.RFC3339),
+		id,
+		execID,
+	)
+	if err != nil {
+		a.logger.Logging.Debugf("error revoking the api key %v", err)
+		return false, a.logger.ErrorMessage("sql api key error")
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, a.logger.ErrorMessage("sql api key error")
+	}
+	return rowsAffected > 0, nil
+}
+
+func (a *APIKeys) TouchAPIKey(id int) error {
+	now := time.Now().UTC()
+	_, err := a.db.Exec(
+		"UPDATE api_keys SET last_used_at = ? WHERE id = ? AND (last_used_at IS NULL OR last_used_at < ?)",
+		now.Format(time.RFC3339),
+		id,
+		now.Add(-apiKeyTouchInterval).Format(time.RFC3339),
+	)
+	if err != nil {
+		a.logger.Logging.Debugf("error updating api key last used %v", err)
+		return a.logger.ErrorMessage("sql api key error")
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAPIKey(row rowScanner, key *models.APIKey) error {
+	var scopes string
+	var lastUsedAt, expiresAt, revokedAt, createdAt sql.NullString
+	if err := row.Scan(
+		&key.ID,
+		&key.ExecID,
+		&key.Name,
+		&key.Prefix,
+		&scopes,
+		&lastUsedAt,
+		&expiresAt,
+		&revokedAt,
+		&createdAt,
+	); err != nil {
+		return err
+	}
+	key.Scopes = strings.Split(scopes, ",")
+	key.LastUsedAt = lastUsedAt.String
+	key.ExpiresAt = expiresAt.String
+	key.RevokedAt = revokedAt.String
+	key.CreatedAt = createdAt.String
+	return nil
+}
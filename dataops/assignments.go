@@ -0,0 +1,201 @@
+package dataops
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/repository/sqlconnect"
+)
+
+// Assignments - the teaching assignments of the teachers, the subject is unique in the
+// class in the term. The assignments of the soft deleted teachers are kept until the
+// teachers are purged
+type Assignments struct {
+	db      *sqlconnect.DB
+	logger  *logging.Logger
+	timeout time.Duration
+}
+
+func NewAssignmentsDB(db *sqlconnect.DB, logger *logging.Logger, timeout time.Duration) *Assignments {
+	return &Assignments{
+		db:      db,
+		logger:  logger,
+		timeout: timeout,
+	}
+}
+
+// assignmentQuery - the assignments with the names of their teachers, subjects and classes
+const assignmentQuery = `SELECT a.id, a.teacher_id, a.subject_id, a.class_id, a.term,
+	t.first_name, t.last_name, s.name, c.name
+	FROM teaching_assignments a
+	JOIN teachers t ON t.id = a.teacher_id
+	JOIN subjects s ON s.id = a.subject_id
+	JOIN classes c ON c.id = a.class_id`
+
+func scanAssignment(scan func(...any) error) (models.Assignment, error) {
+	var (
+		assignment          models.Assignment
+		firstName, lastName string
+	)
+	err := scan(
+		&assignment.ID,
+		&assignment.TeacherID,
+		&assignment.SubjectID,
+		&assignment.ClassID,
+		&assignment.Term,
+		&firstName,
+		&lastName,
+		&assignment.SubjectName,
+		&assignment.ClassName,
+	)
+	assignment.TeacherName = firstName + " " + lastName
+	return assignment, err
+}
+
+func (a *Assignments) InsertAssignment(ctx context.Context, assignment *models.Assignment) (int64, error) {
+	ctx, cancel := queryContext(ctx, a.timeout)
+	defer cancel()
+
+	id, err := a.db.InsertContext(ctx,
+		"INSERT INTO teaching_assignments (teacher_id, subject_id, class_id, term) VALUES (?,?,?,?)",
+		assignment.TeacherID,
+		assignment.SubjectID,
+		assignment.ClassID,
+		assignment.Term,
+	)
+	if err != nil {
+		a.logger.Logging.Debugf("error insert assignment to the database %v", err)
+		return 0, dbError(ctx, a.logger, err, "error database assignment insert")
+	}
+	return id, nil
+}
+
+func (a *Assignments) GetAssignmentByID(ctx context.Context, id int) (models.Assignment, error) {
+	ctx, cancel := queryContext(ctx, a.timeout)
+	defer cancel()
+
+	assignment, err := scanAssignment(a.db.QueryRowContext(ctx, assignmentQuery+" WHERE a.id = ?", id).Scan)
+	if err == sql.ErrNoRows {
+		a.logger.Logging.Debugf("assignment not found %v", err)
+		return models.Assignment{}, dbError(ctx, a.logger, err, "assignment not found")
+	} else if err != nil {
+		a.logger.Logging.Debugf("error quering the database %v", err)
+		return models.Assignment{}, dbError(ctx, a.logger, err, "error quering the database error")
+	}
+	return assignment, nil
+}
+
+// GetAllAssignments - the assignments matching the teacher_id, subject_id, class_id and
+// term params and their count, ordered by term, class name and subject name by default
+func (a *Assignments) GetAllAssignments(
+	ctx context.Context,
+	params map[string]string,
+	sortBy []string,
+) ([]models.Assignment, int, error) {
+	ctx, cancel := queryContext(ctx, a.timeout)
+	defer cancel()
+
+	query := assignmentQuery + " WHERE 1=1"
+	var args []any
+	for param, value := range params {
+		if value != "" {
+			query += " AND a." + param + " = ?"
+			args = append(args, value)
+		}
+	}
+
+	allowedColumns := map[string]string{
+		"term":         "a.term",
+		"teacher_id":   "a.teacher_id",
+		"subject_name": "s.name",
+		"class_name":   "c.name",
+	}
+	var orderByParts []string
+	for _, criteria := range sortBy {
+		parts := strings.Split(criteria, ":")
+		if len(parts) == 2 {
+			sortColumn, ok := allowedColumns[parts[0]]
+			sortOrder := strings.ToUpper(parts[1])
+
+			if ok && (sortOrder == "ASC" || sortOrder == "DESC") {
+				orderByParts = append(orderByParts, fmt.Sprintf("%s %s", sortColumn, sortOrder))
+			}
+		}
+	}
+	if len(orderByParts) == 0 {
+		orderByParts = append(orderByParts, "a.term ASC", "c.name ASC", "s.name ASC")
+	}
+	query += " ORDER BY " + strings.Join(orderByParts, ", ") + ", a.id ASC"
+
+	rows, err := a.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		a.logger.Logging.Debugf("error retreiving the assignments %v", err)
+		return nil, 0, dbError(ctx, a.logger, err, "error retreiving data")
+	}
+	defer rows.Close()
+
+	assignments := make([]models.Assignment, 0)
+	for rows.Next() {
+		assignment, err := scanAssignment(rows.Scan)
+		if err != nil {
+			a.logger.Logging.Debugf("error scanning the assignment %v", err)
+			return nil, 0, dbError(ctx, a.logger, err, "error scanning database results")
+		}
+		assignments = append(assignments, assignment)
+	}
+	if err := rows.Err(); err != nil {
+		a.logger.Logging.Debugf("error reading the assignments %v", err)
+		return nil, 0, dbError(ctx, a.logger, err, "error retreiving data")
+	}
+	return assignments, len(assignments), nil
+}
+
+// UpdateAssignment - sets all fields of the assignment and returns it with the names of
+// the new teacher, subject and class
+func (a *Assignments) UpdateAssignment(
+	ctx context.Context,
+	id int,
+	updated models.Assignment,
+) (models.Assignment, error) {
+	qctx, cancel := queryContext(ctx, a.timeout)
+	defer cancel()
+
+	_, err := a.db.ExecContext(qctx,
+		"UPDATE teaching_assignments SET teacher_id = ?, subject_id = ?, class_id = ?, term = ? WHERE id = ?",
+		updated.TeacherID,
+		updated.SubjectID,
+		updated.ClassID,
+		updated.Term,
+		id,
+	)
+	if err != nil {
+		a.logger.Logging.Debugf("error updating the assignment %v", err)
+		return models.Assignment{}, dbError(qctx, a.logger, err, "error assignment database error")
+	}
+	return a.GetAssignmentByID(ctx, id)
+}
+
+func (a *Assignments) DeleteAssignment(ctx context.Context, id int) error {
+	ctx, cancel := queryContext(ctx, a.timeout)
+	defer cancel()
+
+	result, err := a.db.ExecContext(ctx, "DELETE FROM teaching_assignments WHERE id = ?", id)
+	if err != nil {
+		a.logger.Logging.Debugf("error deleting assignment %v", err)
+		return dbError(ctx, a.logger, err, "Error deleting assignment")
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		a.logger.Logging.Debugf("error retreiving deleted assignment %v", err)
+		return dbError(ctx, a.logger, err, "Error deleting assignment")
+	}
+	if rowsAffected == 0 {
+		return a.logger.ErrorMessage("Assignment not found")
+	}
+	return nil
+}
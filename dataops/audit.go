@@ -0,0 +1,135 @@
+package dataops
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/repository/sqlconnect"
+)
+
+// AuthAudit - the audit log of the authentication events, the entries are kept also
+// after the exec is deleted
+type AuthAudit struct {
+	db     *sqlconnect.DB
+	logger *logging.Logger
+}
+
+func NewAuthAuditDB(db *sqlconnect.DB, logger *logging.Logger) *AuthAudit {
+	return &AuthAudit{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func nullID(id int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id > 0}
+}
+
+func (a *AuthAudit) RecordAuthEvent(event models.AuthEvent) error {
+	_, err := a.db.Exec(
+		`INSERT INTO auth_audit_log (event_type, exec_id, actor_id, username, ip_address, user_agent, outcome, detail, created_at)
+		VALUES (?,?,?,?,?,?,?,?,?)`,
+		event.EventType,
+		nullID(event.ExecID),
+		nullID(event.ActorID),
+		event.Username,
+		event.IPAddress,
+		event.UserAgent,
+		event.Outcome,
+		event.Detail,
+		event.CreatedAt,
+	)
+	if err != nil {
+		a.logger.Logging.Debugf("error writing the audit event %v", err)
+		return a.logger.ErrorMessage("database error")
+	}
+	return nil
+}
+
+// ListAuthEvents - one page of the events matching the filter, the newest first,
+// together with the count of all matching events
+func (a *AuthAudit) ListAuthEvents(filter models.AuthEventFilter) ([]models.AuthEvent, int, error) {
+	var where []string
+	var args []any
+	if filter.ExecID > 0 {
+		where = append(where, "exec_id = ?")
+		args = append(args, filter.ExecID)
+	}
+	if filter.EventType != "" {
+		where = append(where, "event_type = ?")
+		args = append(args, filter.EventType)
+	}
+	if filter.Outcome != "" {
+		where = append(where, "outcome = ?")
+		args = append(args, filter.Outcome)
+	}
+	if filter.From != "" {
+		where = append(where, "created_at >= ?")
+		args = append(args, filter.From)
+	}
+	if filter.To != "" {
+		where = append(where, "created_at < ?")
+		args = append(args, filter.To)
+	}
+	whereClause := ""
+	if len(where) > 0 {
+		whereClause = " WHERE " + strings.Join(where, " AND ")
+	}
+
+	var total int
+	err := a.db.QueryRow("SELECT COUNT(*) FROM auth_audit_log"+whereClause, args...).Scan(&total)
+	if err != nil {
+		a.logger.Logging.Debugf("error counting the audit events %v", err)
+		return nil, 0, a.logger.ErrorMessage("database error")
+	}
+
+	page, limit := filter.Page, filter.Limit
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
+	rows, err := a.db.Query(
+		"SELECT id, event_type, exec_id, actor_id, username, ip_address, user_agent, outcome, detail, created_at FROM auth_audit_log"+
+			whereClause+" ORDER BY id DESC LIMIT ? OFFSET ?",
+		append(args, limit, (page-1)*limit)...,
+	)
+	if err != nil {
+		a.logger.Logging.Debugf("error quering the audit events %v", err)
+		return nil, 0, a.logger.ErrorMessage("database error")
+	}
+	defer rows.Close()
+
+	events := make([]models.AuthEvent, 0)
+	for rows.Next() {
+		var event models.AuthEvent
+		var execID, actorID sql.NullInt64
+		err := rows.Scan(
+			&event.ID,
+			&event.EventType,
+			&execID,
+			&actorID,
+			&event.Username,
+			&event.IPAddress,
+			&event.UserAgent,
+			&event.Outcome,
+			&event.Detail,
+			&event.CreatedAt,
+		)
+		if err != nil {
+			a.logger.Logging.Debugf("error scanning the audit event %v", err)
+			return nil, 0, a.logger.ErrorMessage("database error")
+		}
+		event.ExecID = int(execID.Int64)
+		event.ActorID = int(actorID.Int64)
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		a.logger.Logging.Debugf("error reading the audit events %v", err)
+		return nil, 0, a.logger.ErrorMessage("database error")
+	}
+	return events, total, nil
+}
@@ -0,0 +1,317 @@
+package dataops
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/repository/sqlconnect"
+)
+
+// Classes - the classes of the teachers and students, a class is deleted for good and
+// only when nobody references it
+type Classes struct {
+	db      *sqlconnect.DB
+	logger  *logging.Logger
+	timeout time.Duration
+}
+
+func NewClassesDB(db *sqlconnect.DB, logger *logging.Logger, timeout time.Duration) *Classes {
+	return &Classes{
+		db:      db,
+		logger:  logger,
+		timeout: timeout,
+	}
+}
+
+// filterCondition - the condition of the filter param of the teachers and students, the
+// class param is the name of the class which is resolved through the classes table
+func filterCondition(param string) string {
+	if param == "class" {
+		return " AND class_id IN (SELECT id FROM classes WHERE name = ?)"
+	}
+	return " AND " + param + " = ?"
+}
+
+const classColumns = "id, name, grade_level, academic_year, COALESCE(homeroom_teacher_id, 0), room, capacity"
+
+func scanClass(scan func(...any) error) (models.Class, error) {
+	var class models.Class
+	err := scan(
+		&class.ID,
+		&class.Name,
+		&class.GradeLevel,
+		&class.AcademicYear,
+		&class.HomeroomTeacherID,
+		&class.Room,
+		&class.Capacity,
+	)
+	return class, err
+}
+
+func (c *Classes) InsertClass(ctx context.Context, class *models.Class) (int64, error) {
+	ctx, cancel := queryContext(ctx, c.timeout)
+	defer cancel()
+
+	id, err := c.db.InsertContext(ctx,
+		`INSERT INTO classes (name, grade_level, academic_year, homeroom_teacher_id, room, capacity)
+		VALUES (?,?,?,?,?,?)`,
+		class.Name,
+		class.GradeLevel,
+		class.AcademicYear,
+		nullID(class.HomeroomTeacherID),
+		class.Room,
+		class.Capacity,
+	)
+	if err != nil {
+		c.logger.Logging.Debugf("error insert class to the database %v", err)
+		return 0, dbError(ctx, c.logger, err, "error database class insert")
+	}
+	return id, nil
+}
+
+func (c *Classes) GetClassByID(ctx context.Context, id int) (models.Class, error) {
+	ctx, cancel := queryContext(ctx, c.timeout)
+	defer cancel()
+
+	class, err := scanClass(c.db.QueryRowContext(ctx, "SELECT "+classColumns+" FROM classes WHERE id = ?", id).Scan)
+	if err == sql.ErrNoRows {
+		c.logger.Logging.Debugf("class not found %v", err)
+		return models.Class{}, dbError(ctx, c.logger, err, "class not found")
+	} else if err != nil {
+		c.logger.Logging.Debugf("error quering the database %v", err)
+		return models.Class{}, dbError(ctx, c.logger, err, "error quering the database error")
+	}
+	return class, nil
+}
+
+// GetAllClasses - the classes matching the params and their count, ordered by name by
+// default
+func (c *Classes) GetAllClasses(
+	ctx context.Context,
+	params map[string]string,
+	sortBy []string,
+) ([]models.Class, int, error) {
+	ctx, cancel := queryContext(ctx, c.timeout)
+	defer cancel()
+
+	query := "SELECT " + classColumns + " FROM classes WHERE 1=1"
+	var args []any
+	for param, value := range params {
+		if value != "" {
+			query += " AND " + param + " = ?"
+			args = append(args, value)
+		}
+	}
+
+	allowedColumns := map[string]bool{
+		"name":          true,
+		"grade_level":   true,
+		"academic_year": true,
+		"room":          true,
+		"capacity":      true,
+	}
+	var orderByParts []string
+	for _, criteria := range sortBy {
+		parts := strings.Split(criteria, ":")
+		if len(parts) == 2 {
+			sortColumn := parts[0]
+			sortOrder := strings.ToUpper(parts[1])
+
+			if allowedColumns[sortColumn] && (sortOrder == "ASC" || sortOrder == "DESC") {
+				orderByParts = append(orderByParts, fmt.Sprintf("%s %s", sortColumn, sortOrder))
+			}
+		}
+	}
+	if len(orderByParts) == 0 {
+		orderByParts = append(orderByParts, "name ASC")
+	}
+	query += " ORDER BY " + strings.Join(orderByParts, ", ") + ", id ASC"
+
+	rows, err := c.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		c.logger.Logging.Debugf("error retreiving the classes %v", err)
+		return nil, 0, dbError(ctx, c.logger, err, "error retreiving data")
+	}
+	defer rows.Close()
+
+	classes := make([]models.Class, 0)
+	for rows.Next() {
+		class, err := scanClass(rows.Scan)
+		if err != nil {
+			c.logger.Logging.Debugf("error scanning the class %v", err)
+			return nil, 0, dbError(ctx, c.logger, err, "error scanning database results")
+		}
+		classes = append(classes, class)
+	}
+	if err := rows.Err(); err != nil {
+		c.logger.Logging.Debugf("error reading the classes %v", err)
+		return nil, 0, dbError(ctx, c.logger, err, "error retreiving data")
+	}
+	return classes, len(classes), nil
+}
+
+func (c *Classes) UpdateClass(ctx context.Context, id int, updated models.Class) (models.Class, error) {
+	ctx, cancel := queryContext(ctx, c.timeout)
+	defer cancel()
+
+	result, err := c.db.ExecContext(ctx,
+		`UPDATE classes SET name = ?, grade_level = ?, academic_year = ?, homeroom_teacher_id = ?, room = ?, capacity = ?
+		WHERE id = ?`,
+		updated.Name,
+		updated.GradeLevel,
+		updated.AcademicYear,
+		nullID(updated.HomeroomTeacherID),
+		updated.Room,
+		updated.Capacity,
+		id,
+	)
+	if err != nil {
+		c.logger.Logging.Debugf("error updating the class %v", err)
+		return models.Class{}, dbError(ctx, c.logger, err, "error class database error")
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		c.logger.Logging.Debugf("error retreiving updated class %v", err)
+		return models.Class{}, dbError(ctx, c.logger, err, "error class database error")
+	}
+	// mariadb counts only the changed rows, the class which is the same is still found
+	if rowsAffected == 0 {
+		if _, err := c.GetClassByID(ctx, id); err != nil {
+			return models.Class{}, err
+		}
+	}
+	updated.ID = id
+	return updated, nil
+}
+
+// PatchClass - sets the not empty fields of the class, the homeroom teacher is removed
+// only by UpdateClass
+func (c *Classes) PatchClass(ctx context.Context, id int, updated models.Class) (models.Class, error) {
+	existing, err := c.GetClassByID(ctx, id)
+	if err != nil {
+		return models.Class{}, err
+	}
+	if updated.Name != "" {
+		existing.Name = updated.Name
+	}
+	if updated.GradeLevel != 0 {
+		existing.GradeLevel = updated.GradeLevel
+	}
+	if updated.AcademicYear != "" {
+		existing.AcademicYear = updated.AcademicYear
+	}
+	if updated.HomeroomTeacherID != 0 {
+		existing.HomeroomTeacherID = updated.HomeroomTeacherID
+	}
+	if updated.Room != "" {
+		existing.Room = updated.Room
+	}
+	if updated.Capacity != 0 {
+		existing.Capacity = updated.Capacity
+	}
+	return c.UpdateClass(ctx, id, existing)
+}
+
+// DeleteClass - removes the class which has no teachers, students, teaching assignments
+// and enrollments, the soft deleted members count too as they still reference it until
+// they are purged
+func (c *Classes) DeleteClass(ctx context.Context, id int) error {
+	ctx, cancel := queryContext(ctx, c.timeout)
+	defer cancel()
+
+	var members int
+	err := c.db.QueryRowContext(ctx,
+		`SELECT (SELECT COUNT(*) FROM teachers WHERE class_id = ?) + (SELECT COUNT(*) FROM students WHERE class_id = ?)
+		+ (SELECT COUNT(*) FROM teaching_assignments WHERE class_id = ?) + (SELECT COUNT(*) FROM enrollments WHERE class_id = ?)`,
+		id, id, id, id,
+	).Scan(&members)
+	if err != nil {
+		c.logger.Logging.Debugf("error counting the members of the class %v", err)
+		return dbError(ctx, c.logger, err, "Error deleting class")
+	}
+	if members > 0 {
+		return c.logger.ErrorMessage("Class still has teachers, students, teaching assignments or enrollments")
+	}
+
+	result, err := c.db.ExecContext(ctx, "DELETE FROM classes WHERE id = ?", id)
+	if err != nil {
+		c.logger.Logging.Debugf("error deleting class %v", err)
+		return dbError(ctx, c.logger, err, "Error deleting class")
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		c.logger.Logging.Debugf("error retreiving deleted class %v", err)
+		return dbError(ctx, c.logger, err, "Error deleting class")
+	}
+	if rowsAffected == 0 {
+		return c.logger.ErrorMessage("Class not found")
+	}
+	return nil
+}
+
+// GetStudentsByClassID - the not deleted students enrolled in the class ordered by id
+func (c *Classes) GetStudentsByClassID(ctx context.Context, id int) ([]models.Student, error) {
+	ctx, cancel := queryContext(ctx, c.timeout)
+	defer cancel()
+
+	rows, err := c.db.QueryContext(ctx,
+		`SELECT s.id, s.first_name, s.last_name, s.email, s.class_id FROM students s
+		JOIN enrollments e ON e.student_id = s.id AND e.status = 'active'
+		WHERE e.class_id = ? AND s.deleted_at IS NULL ORDER BY s.id`,
+		id,
+	)
+	if err != nil {
+		c.logger.Logging.Debugf("error retreiving the students of the class %v", err)
+		return nil, dbError(ctx, c.logger, err, "error retreiving data")
+	}
+	defer rows.Close()
+
+	students := make([]models.Student, 0)
+	for rows.Next() {
+		var student models.Student
+		if err := rows.Scan(&student.ID, &student.FirstName, &student.LastName, &student.Email, &student.ClassID); err != nil {
+			c.logger.Logging.Debugf("error scanning the student %v", err)
+			return nil, dbError(ctx, c.logger, err, "error scanning database results")
+		}
+		students = append(students, student)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(ctx, c.logger, err, "error retreiving data")
+	}
+	return students, nil
+}
+
+// GetTeachersByClassID - the not deleted teachers of the class ordered by id
+func (c *Classes) GetTeachersByClassID(ctx context.Context, id int) ([]models.Teacher, error) {
+	ctx, cancel := queryContext(ctx, c.timeout)
+	defer cancel()
+
+	rows, err := c.db.QueryContext(ctx,
+		"SELECT id, first_name, last_name, email, class_id, subject FROM teachers WHERE class_id = ? AND deleted_at IS NULL ORDER BY id",
+		id,
+	)
+	if err != nil {
+		c.logger.Logging.Debugf("error retreiving the teachers of the class %v", err)
+		return nil, dbError(ctx, c.logger, err, "error retreiving data")
+	}
+	defer rows.Close()
+
+	teachers := make([]models.Teacher, 0)
+	for rows.Next() {
+		var teacher models.Teacher
+		if err := rows.Scan(&teacher.ID, &teacher.FirstName, &teacher.LastName, &teacher.Email, &teacher.ClassID, &teacher.Subject); err != nil {
+			c.logger.Logging.Debugf("error scanning the teacher %v", err)
+			return nil, dbError(ctx, c.logger, err, "error scanning database results")
+		}
+		teachers = append(teachers, teacher)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(ctx, c.logger, err, "error retreiving data")
+	}
+	return teachers, nil
+}
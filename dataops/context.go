@@ -0,0 +1,41 @@
+package dataops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
+)
+
+var (
+	// ErrQueryTimeout - the query did not finish before the query timeout of the store
+	ErrQueryTimeout = errors.New("database query timed out")
+	// ErrQueryCanceled - the context of the query was canceled, usually the client went away
+	ErrQueryCanceled = errors.New("database query canceled")
+)
+
+// queryContext - the context of one query, limited by the timeout of the store when it
+// is set, the context of the request still cancels it
+func queryContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// dbError - the error returned for the failed query, the timeout and the cancel of the
+// context are kept so the handlers can answer with 504 or 503, the other errors only
+// carry the message
+func dbError(ctx context.Context, logger *logging.Logger, err error, message string) error {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded):
+		logger.Logging.Warnf("%s: %v", message, ErrQueryTimeout)
+		return fmt.Errorf("%s: %w", message, ErrQueryTimeout)
+	case errors.Is(err, context.Canceled) || errors.Is(ctx.Err(), context.Canceled):
+		logger.Logging.Debugf("%s: %v", message, ErrQueryCanceled)
+		return fmt.Errorf("%s: %w", message, ErrQueryCanceled)
+	}
+	return logger.ErrorMessage(message)
+}
@@ -0,0 +1,862 @@
+package dataops_test
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/repository/migrations"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/repository/sqlconnect"
+)
+
+// testDSNs - the databases the stores are tested against, sqlite always runs in memory,
+// mariadb and postgres when the environment variable has the dsn of an empty database
+var testDSNs = map[sqlconnect.Dialect]string{
+	sqlconnect.SQLite:   ":memory:",
+	sqlconnect.MariaDB:  os.Getenv("TEST_MYSQL_DSN"),
+	sqlconnect.Postgres: os.Getenv("TEST_POSTGRES_DSN"),
+}
+
+// TestStores - the same tests of the stores for every dialect, on the migrated schema
+func TestStores(t *testing.T) {
+	for _, dialect := range sqlconnect.Dialects {
+		t.Run(string(dialect), func(t *testing.T) {
+			dsn := testDSNs[dialect]
+			if dsn == "" {
+				t.Skipf("no test database for %s", dialect)
+			}
+			db := migratedDB(t, dialect, dsn)
+			logger := logging.Init(false)
+
+			t.Run("classes", func(t *testing.T) { testClasses(t, db, logger) })
+			t.Run("teachers", func(t *testing.T) { testTeachers(t, db, logger) })
+			t.Run("students", func(t *testing.T) { testStudents(t, db, logger) })
+			t.Run("enrollments", func(t *testing.T) { testEnrollments(t, db, logger) })
+			t.Run("subjects and assignments", func(t *testing.T) { testAssignments(t, db, logger) })
+			t.Run("execs", func(t *testing.T) { testExecs(t, db, logger) })
+			t.Run("auth", func(t *testing.T) { testAuth(t, db, logger) })
+			t.Run("audit and history", func(t *testing.T) { testAuditAndHistory(t, db, logger) })
+			t.Run("revisions", func(t *testing.T) { testRevisions(t, db, logger) })
+			t.Run("stats", func(t *testing.T) { testStats(t, db, logger) })
+		})
+	}
+}
+
+// migratedDB - the database with all migrations applied, they are reverted at the end
+// so the mariadb and postgres databases are empty for the next run
+func migratedDB(t *testing.T, dialect sqlconnect.Dialect, dsn string) *sqlconnect.DB {
+	t.Helper()
+	db, err := sqlconnect.Open(dialect, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := migrations.NewMigrator(db, logging.Init(false), 10*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		statuses, err := m.Status()
+		if err == nil {
+			_, err = m.Down(len(statuses))
+		}
+		if err != nil {
+			t.Errorf("reverting the migrations: %v", err)
+		}
+		db.Close()
+	})
+	return db
+}
+
+// insertClass - the class the teachers and students of the test belong to
+func insertClass(t *testing.T, classes *dataops.Classes, name string) int {
+	t.Helper()
+	id, err := classes.InsertClass(context.Background(), &models.Class{Name: name, AcademicYear: "2025/2026"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return int(id)
+}
+
+func testClasses(t *testing.T, db *sqlconnect.DB, logger *logging.Logger) {
+	ctx := context.Background()
+	classes := dataops.NewClassesDB(db, logger, 5*time.Second)
+
+	id := insertClass(t, classes, "0A")
+	if _, err := classes.InsertClass(ctx, &models.Class{Name: "0A", AcademicYear: "2025/2026"}); err == nil {
+		t.Fatal("Expected an error for the duplicate class of the academic year")
+	}
+	if _, err := classes.InsertClass(ctx, &models.Class{Name: "0B", HomeroomTeacherID: 99999}); err == nil {
+		t.Fatal("Expected an error for the missing homeroom teacher")
+	}
+	other, err := classes.InsertClass(ctx, &models.Class{Name: "0A", AcademicYear: "2026/2027", GradeLevel: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list, count, err := classes.GetAllClasses(ctx, map[string]string{"name": "0A"}, []string{"academic_year:desc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 || list[0].ID != int(other) {
+		t.Fatalf("Expected the 2 classes 0A with the later year first, got %+v", list)
+	}
+
+	patched, err := classes.PatchClass(ctx, id, models.Class{Room: "B204", Capacity: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if patched.Room != "B204" || patched.Capacity != 30 || patched.Name != "0A" {
+		t.Fatalf("Expected only the room and capacity patched, got %+v", patched)
+	}
+	updated, err := classes.UpdateClass(ctx, id, models.Class{Name: "0C", GradeLevel: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Name != "0C" || updated.Room != "" || updated.HomeroomTeacherID != 0 {
+		t.Fatalf("Expected all fields of the class updated, got %+v", updated)
+	}
+	if _, err := classes.UpdateClass(ctx, 99999, models.Class{Name: "0D"}); err == nil {
+		t.Fatal("Expected an error updating the missing class")
+	}
+
+	for _, classID := range []int{id, int(other)} {
+		if err := classes.DeleteClass(ctx, classID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := classes.GetClassByID(ctx, id); err == nil {
+		t.Fatal("Expected the deleted class to be gone")
+	}
+	if err := classes.DeleteClass(ctx, id); err == nil {
+		t.Fatal("Expected an error deleting the missing class")
+	}
+}
+
+func testTeachers(t *testing.T, db *sqlconnect.DB, logger *logging.Logger) {
+	ctx := context.Background()
+	classes := dataops.NewClassesDB(db, logger, 5*time.Second)
+	teachers := dataops.NewTeachersDB(db, logger, 5*time.Second)
+	class1A, class2B := insertClass(t, classes, "1A"), insertClass(t, classes, "2B")
+
+	first := models.Teacher{FirstName: "Ann", LastName: "Lee", Email: "ann@school.test", ClassID: class1A, Subject: "Math"}
+	id, err := teachers.InsertTeachers(ctx, &first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id < 100 {
+		t.Fatalf("Expected the teacher ids to start at 100, got %d", id)
+	}
+	second := models.Teacher{FirstName: "Bob", LastName: "Kim", Email: "bob@school.test", ClassID: class2B, Subject: "Art"}
+	if _, err := teachers.InsertTeachers(ctx, &second); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := teachers.InsertTeachers(ctx, &models.Teacher{
+		FirstName: "Dup", LastName: "Dup", Email: "ann@school.test", ClassID: class2B, Subject: "Art",
+	}); err == nil {
+		t.Fatal("Expected an error for the duplicate email")
+	}
+
+	got, err := teachers.GetTeacherByID(ctx, int(id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Email != first.Email || got.ClassID != class1A {
+		t.Fatalf("Expected the inserted teacher, got %+v", got)
+	}
+
+	list, count, err := teachers.GetAllTeachers(ctx, map[string]string{"subject": "Art"}, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 || list[0].FirstName != "Bob" {
+		t.Fatalf("Expected only Bob teaching art, got %+v", list)
+	}
+	list, _, err = teachers.GetAllTeachers(ctx, nil, []string{"first_name:desc"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].FirstName != "Bob" {
+		t.Fatalf("Expected the teachers sorted by first name desc, got %+v", list)
+	}
+
+	patched, err := teachers.PatchTeacher(ctx, int(id), models.Teacher{Subject: "Physics"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if patched.Subject != "Physics" || patched.FirstName != "Ann" {
+		t.Fatalf("Expected only the subject patched, got %+v", patched)
+	}
+
+	if err := teachers.DeleteTeacher(ctx, int(id)); err != nil {
+		t.Fatal(err)
+	}
+	if _, count, _ := teachers.GetAllTeachers(ctx, nil, nil, false); count != 1 {
+		t.Fatalf("Expected the deleted teacher hidden, got %d teachers", count)
+	}
+	if _, count, _ := teachers.GetAllTeachers(ctx, nil, nil, true); count != 2 {
+		t.Fatalf("Expected the deleted teacher with include deleted, got %d teachers", count)
+	}
+	if err := teachers.RestoreTeacher(ctx, int(id)); err != nil {
+		t.Fatal(err)
+	}
+	if err := teachers.DeleteTeacher(ctx, int(id)); err != nil {
+		t.Fatal(err)
+	}
+	purged, err := teachers.PurgeDeleted(ctx, time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged != 1 {
+		t.Fatalf("Expected 1 purged teacher, got %d", purged)
+	}
+	if _, err := teachers.GetTeacherByID(ctx, int(id)); err == nil {
+		t.Fatal("Expected the purged teacher to be gone")
+	}
+}
+
+func testStudents(t *testing.T, db *sqlconnect.DB, logger *logging.Logger) {
+	ctx := context.Background()
+	classes := dataops.NewClassesDB(db, logger, 5*time.Second)
+	teachers := dataops.NewTeachersDB(db, logger, 5*time.Second)
+	students := dataops.NewStudentsDB(db, logger, 5*time.Second)
+	class5E := insertClass(t, classes, "5E")
+
+	teacherID, err := teachers.InsertTeachers(ctx, &models.Teacher{
+		FirstName: "Cleo", LastName: "Moss", Email: "cleo@school.test", ClassID: class5E, Subject: "Music",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := students.InsertStudents(ctx, &models.Student{
+		FirstName: "No", LastName: "Class", Email: "noclass@school.test", ClassID: 99999,
+	}); err == nil {
+		t.Fatal("Expected an error for the missing class")
+	}
+
+	var ids []int64
+	for _, name := range []string{"Dan", "Eve", "Fay"} {
+		id, err := students.InsertStudents(ctx, &models.Student{
+			FirstName: name, LastName: "Park", Email: name + "@school.test", ClassID: class5E,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+
+	page, total, err := students.GetAllStudents(ctx, map[string]string{"class": "5E"}, []string{"first_name:asc"}, 2, 2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 3 || len(page) != 1 || page[0].FirstName != "Fay" {
+		t.Fatalf("Expected Fay on the second page of 3 students, got %d %+v", total, page)
+	}
+
+	if _, err := students.PatchiStudent(ctx, int(ids[0]), models.Student{ClassID: 99999}); err == nil {
+		t.Fatal("Expected an error moving the student to the missing class")
+	}
+	if err := classes.DeleteClass(ctx, class5E); err == nil {
+		t.Fatal("Expected an error deleting the class with teachers and students")
+	}
+	inClassStudents, err := classes.GetStudentsByClassID(ctx, class5E)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inClassTeachers, err := classes.GetTeachersByClassID(ctx, class5E)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inClassStudents) != 3 || len(inClassTeachers) != 1 || inClassTeachers[0].ID != int(teacherID) {
+		t.Fatalf("Expected the 3 students and the teacher of 5E, got %+v %+v", inClassStudents, inClassTeachers)
+	}
+
+	inClass, err := teachers.GetStudentsByTeacherID(ctx, int(teacherID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inClass) != 3 {
+		t.Fatalf("Expected the 3 students of the teacher, got %+v", inClass)
+	}
+
+	deleted, err := students.DeleteBulkStudents(ctx, []int{int(ids[1]), int(ids[2])})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 2 {
+		t.Fatalf("Expected 2 deleted students, got %v", deleted)
+	}
+	if err := students.RestoreStudent(ctx, int(ids[1])); err != nil {
+		t.Fatal(err)
+	}
+	purged, err := students.PurgeDeleted(ctx, time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged != 1 {
+		t.Fatalf("Expected 1 purged student, got %d", purged)
+	}
+}
+
+func testAssignments(t *testing.T, db *sqlconnect.DB, logger *logging.Logger) {
+	ctx := context.Background()
+	classes := dataops.NewClassesDB(db, logger, 5*time.Second)
+	subjects := dataops.NewSubjectsDB(db, logger, 5*time.Second)
+	teachers := dataops.NewTeachersDB(db, logger, 5*time.Second)
+	assignments := dataops.NewAssignmentsDB(db, logger, 5*time.Second)
+
+	// the class of the students test, it is kept for the stats
+	inClass, _, err := classes.GetAllClasses(ctx, map[string]string{"name": "5E"}, nil)
+	if err != nil || len(inClass) != 1 {
+		t.Fatalf("Expected the class 5E, got %+v %v", inClass, err)
+	}
+	class5E := inClass[0].ID
+	teacherID, err := teachers.InsertTeachers(ctx, &models.Teacher{
+		FirstName: "Hal", LastName: "Ng", Email: "hal@school.test", ClassID: class5E, Subject: "Music",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	musicID, err := subjects.InsertSubject(ctx, &models.Subject{Name: "Music", Code: "MUS"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := subjects.InsertSubject(ctx, &models.Subject{Name: "Music"}); err == nil {
+		t.Fatal("Expected an error for the duplicate subject")
+	}
+	patched, err := subjects.PatchSubject(ctx, int(musicID), models.Subject{Description: "Choir and band"})
+	if err != nil || patched.Code != "MUS" || patched.Description != "Choir and band" {
+		t.Fatalf("Expected only the description patched, got %+v %v", patched, err)
+	}
+
+	assignment := models.Assignment{TeacherID: int(teacherID), SubjectID: int(musicID), ClassID: class5E, Term: "T1"}
+	id, err := assignments.InsertAssignment(ctx, &assignment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := assignments.InsertAssignment(ctx, &assignment); err == nil {
+		t.Fatal("Expected an error for the subject assigned twice in the class in the term")
+	}
+	assignment.Term = "T2"
+	if _, err := assignments.InsertAssignment(ctx, &assignment); err != nil {
+		t.Fatal(err)
+	}
+
+	load, count, err := assignments.GetAllAssignments(ctx, map[string]string{"teacher_id": strconv.Itoa(int(teacherID))}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 || load[0].ID != int(id) || load[0].TeacherName != "Hal Ng" ||
+		load[0].SubjectName != "Music" || load[0].ClassName != "5E" {
+		t.Fatalf("Expected the 2 terms of the teacher with the names, got %+v", load)
+	}
+	updated, err := assignments.UpdateAssignment(ctx, int(id), models.Assignment{
+		TeacherID: int(teacherID), SubjectID: int(musicID), ClassID: class5E, Term: "T0",
+	})
+	if err != nil || updated.Term != "T0" || updated.SubjectName != "Music" {
+		t.Fatalf("Expected the assignment moved to T0, got %+v %v", updated, err)
+	}
+
+	if err := subjects.DeleteSubject(ctx, int(musicID)); err == nil {
+		t.Fatal("Expected an error deleting the assigned subject")
+	}
+	if err := assignments.DeleteAssignment(ctx, int(id)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := assignments.GetAssignmentByID(ctx, int(id)); err == nil {
+		t.Fatal("Expected the deleted assignment to be gone")
+	}
+
+	// the purged teacher takes the assignments along
+	if err := teachers.DeleteTeacher(ctx, int(teacherID)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := teachers.PurgeDeleted(ctx, time.Now().Add(time.Hour).UTC().Format(time.RFC3339)); err != nil {
+		t.Fatal(err)
+	}
+	if _, count, _ := assignments.GetAllAssignments(ctx, nil, nil); count != 0 {
+		t.Fatalf("Expected the assignments of the purged teacher removed, got %d", count)
+	}
+	if err := subjects.DeleteSubject(ctx, int(musicID)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testExecs(t *testing.T, db *sqlconnect.DB, logger *logging.Logger) {
+	ctx := context.Background()
+	execs := dataops.NewExecsDB(db, logger, 5*time.Second)
+
+	id, err := execs.InsertExecs(ctx, &models.Exec{
+		FirstName: "Gil", LastName: "Ray", Email: "gil@school.test", Username: "gil", Password: "hash1", Role: "admin",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	found, err, _ := execs.SearchUsername(ctx, "gil")
+	if err != nil || !found {
+		t.Fatalf("Expected the username found, got %v %v", found, err)
+	}
+	if _, inactive, err := execs.GetAuthStatus(ctx, int(id)); err != nil || inactive {
+		t.Fatalf("Expected an active exec, got %v %v", inactive, err)
+	}
+	if err := execs.SetInactiveStatus(ctx, int(id), true); err != nil {
+		t.Fatal(err)
+	}
+	if inactive, _ := execs.IsInactiveUser(ctx, "gil"); !inactive {
+		t.Fatal("Expected the exec inactive")
+	}
+
+	for _, hash := range []string{"hash2", "hash3", "hash4"} {
+		if err := execs.AddPasswordHistory(ctx, int(id), hash, 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+	history, err := execs.GetPasswordHistory(ctx, int(id), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[0] != "hash4" {
+		t.Fatalf("Expected the last 2 password hashes, got %v", history)
+	}
+
+	if err := execs.DeleteExec(ctx, int(id)); err != nil {
+		t.Fatal(err)
+	}
+	if err := execs.RestoreExec(ctx, int(id)); err != nil {
+		t.Fatal(err)
+	}
+	list, count, err := execs.GetAllExecs(ctx, map[string]string{"role": "admin"}, []string{"username:asc"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 || list[0].Username != "gil" {
+		t.Fatalf("Expected the restored exec, got %+v", list)
+	}
+}
+
+// testAuth - the sessions, mfa, api keys, invitations, identities and login attempts of an exec
+func testAuth(t *testing.T, db *sqlconnect.DB, logger *logging.Logger) {
+	ctx := context.Background()
+	execID, err := dataops.NewExecsDB(db, logger, 5*time.Second).InsertExecs(ctx, &models.Exec{
+		FirstName: "Hal", LastName: "Ito", Email: "hal@school.test", Username: "hal", Password: "hash", Role: "manager",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exec := int(execID)
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+
+	sessions := dataops.NewSessionsDB(db, logger)
+	sessionID, err := sessions.CreateSession(&models.ExecSession{
+		ExecID: exec, RefreshTokenHash: "refresh", UserAgent: "test", IPAddress: "127.0.0.1", ExpiresAt: future,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if active, err := sessions.IsSessionActive(int(sessionID)); err != nil || !active {
+		t.Fatalf("Expected the session active, got %v %v", active, err)
+	}
+	if revoked, err := sessions.RevokeAllSessions(exec); err != nil || revoked != 1 {
+		t.Fatalf("Expected 1 revoked session, got %d %v", revoked, err)
+	}
+	if revoked, err := sessions.RevokeSession(int(sessionID)); err != nil || revoked {
+		t.Fatalf("Expected the revoked session not revoked again, got %v %v", revoked, err)
+	}
+
+	mfa := dataops.NewMFADB(db, logger)
+	if err := mfa.SaveMFASecret(exec, "first"); err != nil {
+		t.Fatal(err)
+	}
+	if err := mfa.EnableMFA(exec, 10, []string{"code1", "code2"}); err != nil {
+		t.Fatal(err)
+	}
+	// saving the secret again starts the enrollment over
+	if err := mfa.SaveMFASecret(exec, "second"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := mfa.GetMFA(exec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Secret != "second" || got.Enabled || got.LastUsedStep != 0 {
+		t.Fatalf("Expected the new secret not enabled, got %+v", got)
+	}
+	if used, err := mfa.UseRecoveryCode(exec, "code1"); err != nil || !used {
+		t.Fatalf("Expected the recovery code used, got %v %v", used, err)
+	}
+	if used, _ := mfa.UseRecoveryCode(exec, "code1"); used {
+		t.Fatal("Expected the recovery code to work only once")
+	}
+
+	apiKeys := dataops.NewAPIKeysDB(db, logger)
+	keyID, err := apiKeys.CreateAPIKey(&models.APIKey{
+		ExecID: exec, Name: "ci", Prefix: "sk_test", KeyHash: "keyhash", Scopes: []string{"read"}, ExpiresAt: future,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := apiKeys.GetAPIKeyByHash("keyhash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.ID != int(keyID) || key.Name != "ci" {
+		t.Fatalf("Expected the created api key, got %+v", key)
+	}
+	if revoked, err := apiKeys.RevokeAPIKey(exec, int(keyID)); err != nil || !revoked {
+		t.Fatalf("Expected the api key revoked, got %v %v", revoked, err)
+	}
+
+	invitations := dataops.NewExecInvitationsDB(db, logger)
+	for _, tokenHash := range []string{"old", "new"} {
+		err := invitations.SaveInvitation(models.ExecInvitation{
+			ExecID: exec, TokenHash: tokenHash, ExpiresAt: future, CreatedAt: future,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := invitations.GetInvitationByTokenHash("old"); err == nil {
+		t.Fatal("Expected the new invitation to replace the old one")
+	}
+	activated, err := invitations.ActivateExec("new", models.Exec{ID: exec, FirstName: "Hal", LastName: "Ito", Password: "new"})
+	if err != nil || !activated {
+		t.Fatalf("Expected the exec activated, got %v %v", activated, err)
+	}
+
+	identities := dataops.NewExecIdentitiesDB(db, logger)
+	if err := identities.LinkIdentity("https://idp.test", "sub", exec); err != nil {
+		t.Fatal(err)
+	}
+	if linked, err := identities.GetExecIDBySubject("https://idp.test", "sub"); err != nil || linked != exec {
+		t.Fatalf("Expected the linked exec, got %d %v", linked, err)
+	}
+
+	attempts := dataops.NewLoginAttemptsDB(db, logger)
+	for i := 1; i <= 2; i++ {
+		err := attempts.SaveLoginAttempt(models.LoginAttempt{
+			Key: "hal|127.0.0.1", FailedAttempts: i, LastFailedAt: future, LockedUntil: future,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	attempt, err := attempts.GetLoginAttempt("hal|127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempt.FailedAttempts != 2 {
+		t.Fatalf("Expected the saved attempt updated, got %+v", attempt)
+	}
+	if err := attempts.ResetLoginAttempts("hal|127.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the parallel failures are all counted, each request sees its own count
+	now := time.Now()
+	if _, err := attempts.AddLoginFailure("user:hal", now, now.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	counts := make(chan int, 10)
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			failures, err := attempts.AddLoginFailure("user:hal", now, now.Add(-time.Hour))
+			if err != nil {
+				t.Error(err)
+			}
+			counts <- failures
+		}()
+	}
+	wg.Wait()
+	close(counts)
+	seen := map[int]bool{}
+	for failures := range counts {
+		seen[failures] = true
+	}
+	if len(seen) != 10 || !seen[2] || !seen[11] {
+		t.Fatalf("Expected the counts 2 to 11, got %v", seen)
+	}
+	if err := attempts.LockLogin("user:hal", 5, now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := attempts.LockLogin("user:hal", 11, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	attempt, err = attempts.GetLoginAttempt("user:hal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempt.FailedAttempts != 11 || attempt.LockedUntil != now.Add(time.Minute).UTC().Format(time.RFC3339) {
+		t.Fatalf("Expected 11 failures locked by the last one, got %+v", attempt)
+	}
+	// the failures older than the reset are forgotten
+	if failures, err := attempts.AddLoginFailure("user:hal", now, now.Add(time.Second)); err != nil || failures != 1 {
+		t.Fatalf("Expected the failures reset, got %d %v", failures, err)
+	}
+	if err := attempts.ResetLoginAttempts("user:hal"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testAuditAndHistory(t *testing.T, db *sqlconnect.DB, logger *logging.Logger) {
+	now := time.Now().UTC().Format(time.RFC3339)
+	audit := dataops.NewAuthAuditDB(db, logger)
+	for _, outcome := range []string{"success", "failure", "failure"} {
+		err := audit.RecordAuthEvent(models.AuthEvent{
+			EventType: "login", Username: "ivy", Outcome: outcome, CreatedAt: now,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	events, total, err := audit.ListAuthEvents(models.AuthEventFilter{Outcome: "failure", Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 || len(events) != 1 {
+		t.Fatalf("Expected 1 of the 2 failed logins, got %d %+v", total, events)
+	}
+
+	history := dataops.NewChangeHistoryDB(db, logger)
+	err = history.RecordRevision(models.Revision{
+		Entity:   models.EntityTeacher,
+		EntityID: 7,
+		Action:   models.ChangeUpdate,
+		Before:   []byte(`{"subject":"Art"}`),
+		After:    []byte(`{"subject":"Math"}`),
+		Changes:  []models.FieldChange{{Field: "subject", From: "Art", To: "Math"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	revisions, total, err := history.ListRevisions(models.EntityTeacher, 7, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || len(revisions[0].Changes) != 1 || revisions[0].Changes[0].Field != "subject" {
+		t.Fatalf("Expected the recorded revision, got %d %+v", total, revisions)
+	}
+	if _, err := history.GetRevision(models.EntityTeacher, 7, revisions[0].ID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testRevisions - the changes made outside of the handlers are in the history too, with the
+// exec of the context and nothing for the failed change
+func testRevisions(t *testing.T, db *sqlconnect.DB, logger *logging.Logger) {
+	classes := dataops.NewClassesDB(db, logger, 5*time.Second)
+	students := dataops.NewStudentsDB(db, logger, 5*time.Second)
+	enrollments := dataops.NewEnrollmentsDB(db, logger, 5*time.Second)
+	execs := dataops.NewExecsDB(db, logger, 5*time.Second)
+	history := dataops.NewChangeHistoryDB(db, logger)
+	class12C := insertClass(t, classes, "12C")
+	class12D := insertClass(t, classes, "12D")
+
+	actorID, err := execs.InsertExecs(context.Background(), &models.Exec{
+		FirstName: "Ada", LastName: "Moss", Email: "ada@school.test", Username: "ada", Password: "hash1", Role: "admin",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := dataops.WithActor(context.Background(), int(actorID))
+
+	id, err := students.InsertStudents(ctx, &models.Student{
+		FirstName: "Lea", LastName: "Fox", Email: "lea@school.test", ClassID: class12C,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	studentID := int(id)
+	if _, err := enrollments.TransferStudent(ctx, studentID, 99999, "2099-01-01"); err == nil {
+		t.Fatal("Expected the transfer to the missing class to fail")
+	}
+	if _, err := enrollments.TransferStudent(ctx, studentID, class12D, "2099-01-01"); err != nil {
+		t.Fatal(err)
+	}
+	revisions, total, err := history.ListRevisions(models.EntityStudent, studentID, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 || revisions[1].Action != models.ChangeCreate || revisions[0].ActorID != int(actorID) ||
+		len(revisions[0].Changes) != 1 || revisions[0].Changes[0].Field != "class_id" {
+		t.Fatalf("Expected the create and the transfer by exec %d, got %d %+v", actorID, total, revisions)
+	}
+
+	if err := execs.UpdatePasswordChange(ctx, int(actorID), "hash2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := execs.SetInactiveStatus(context.Background(), int(actorID), true); err != nil {
+		t.Fatal(err)
+	}
+	revisions, total, err = history.ListRevisions(models.EntityExec, int(actorID), 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 3 || revisions[1].ActorID != int(actorID) || revisions[1].Changes[0].Field != "password_changed_at" ||
+		revisions[0].ActorID != 0 || revisions[0].Changes[0].Field != "inactive_status" {
+		t.Fatalf("Expected the password change by the exec and the deactivation without actor, got %d %+v", total, revisions)
+	}
+	for _, rev := range revisions {
+		if strings.Contains(string(rev.After), "hash") {
+			t.Fatalf("Expected no password in the revision, got %s", rev.After)
+		}
+	}
+
+	if err := students.DeleteStudent(ctx, studentID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := students.PurgeDeleted(ctx, time.Now().Add(time.Hour).UTC().Format(time.RFC3339)); err != nil {
+		t.Fatal(err)
+	}
+	for _, class := range []int{class12C, class12D} {
+		if err := classes.DeleteClass(ctx, class); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := execs.DeleteExec(ctx, int(actorID)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := execs.PurgeDeleted(ctx, time.Now().Add(time.Hour).UTC().Format(time.RFC3339)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testEnrollments(t *testing.T, db *sqlconnect.DB, logger *logging.Logger) {
+	ctx := context.Background()
+	classes := dataops.NewClassesDB(db, logger, 5*time.Second)
+	students := dataops.NewStudentsDB(db, logger, 5*time.Second)
+	enrollments := dataops.NewEnrollmentsDB(db, logger, 5*time.Second)
+	class10B := insertClass(t, classes, "10B")
+	class11B := insertClass(t, classes, "11B")
+
+	id, err := students.InsertStudents(ctx, &models.Student{
+		FirstName: "Gil", LastName: "Hart", Email: "gil@school.test", ClassID: class10B,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	studentID := int(id)
+	if _, err := enrollments.EnrollStudent(ctx, studentID, class11B, "2025-09-01"); err == nil ||
+		!strings.Contains(err.Error(), "already enrolled") {
+		t.Fatalf("Expected the enrolled student to be rejected, got %v", err)
+	}
+	if _, err := enrollments.TransferStudent(ctx, studentID, class10B, "2099-01-01"); err == nil ||
+		!strings.Contains(err.Error(), "already enrolled in the class") {
+		t.Fatalf("Expected the transfer to the same class to be rejected, got %v", err)
+	}
+	if _, err := enrollments.TransferStudent(ctx, studentID, class11B, "2000-01-01"); err == nil ||
+		!strings.Contains(err.Error(), "before the start") {
+		t.Fatalf("Expected the transfer before the enrollment to be rejected, got %v", err)
+	}
+	if _, err := enrollments.TransferStudent(ctx, studentID, 99999, "2099-01-01"); err == nil ||
+		!strings.Contains(err.Error(), "class not found") {
+		t.Fatalf("Expected the transfer to the missing class to be rejected, got %v", err)
+	}
+	if _, err := enrollments.GraduateStudent(ctx, 99999, "2099-01-01"); err == nil ||
+		!strings.Contains(err.Error(), "student not found") {
+		t.Fatalf("Expected the missing student to be rejected, got %v", err)
+	}
+
+	transferred, err := enrollments.TransferStudent(ctx, studentID, class11B, "2099-01-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transferred.ClassName != "11B" || transferred.Status != models.EnrollmentActive ||
+		transferred.AcademicYear != "2025/2026" || transferred.StartDate != "2099-01-01" {
+		t.Fatalf("Expected the active enrollment in 11B, got %+v", transferred)
+	}
+	student, err := students.GetStudentByID(ctx, studentID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if student.ClassID != class11B {
+		t.Fatalf("Expected the student in 11B, got %+v", student)
+	}
+	in10B, _, err := students.GetAllStudents(ctx, map[string]string{"class": "10B"}, nil, 1, 10, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in11B, _, err := students.GetAllStudents(ctx, map[string]string{"class": "11B"}, nil, 1, 10, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(in10B) != 0 || len(in11B) != 1 {
+		t.Fatalf("Expected the student only in 11B, got %+v %+v", in10B, in11B)
+	}
+
+	graduated, err := enrollments.GraduateStudent(ctx, studentID, "2099-06-30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if graduated.Status != models.EnrollmentGraduated || graduated.EndDate != "2099-06-30" {
+		t.Fatalf("Expected the graduated enrollment, got %+v", graduated)
+	}
+	if _, err := enrollments.GraduateStudent(ctx, studentID, "2099-06-30"); err == nil ||
+		!strings.Contains(err.Error(), "not enrolled") {
+		t.Fatalf("Expected the graduated student to be rejected, got %v", err)
+	}
+	in11B, _, err = students.GetAllStudents(ctx, map[string]string{"class_id": strconv.Itoa(class11B)}, nil, 1, 10, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inClass, err := classes.GetStudentsByClassID(ctx, class11B)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(in11B) != 0 || len(inClass) != 0 {
+		t.Fatalf("Expected the graduated student in no class, got %+v %+v", in11B, inClass)
+	}
+
+	history, err := enrollments.GetEnrollmentsByStudentID(ctx, studentID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[0].ClassName != "10B" || history[0].Status != models.EnrollmentTransferred ||
+		history[0].EndDate != "2099-01-01" || history[1].ClassName != "11B" {
+		t.Fatalf("Expected 10B transferred and 11B graduated, got %+v", history)
+	}
+	if err := classes.DeleteClass(ctx, class10B); err == nil {
+		t.Fatal("Expected an error deleting the class with enrollments")
+	}
+
+	if err := students.DeleteStudent(ctx, studentID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := enrollments.EnrollStudent(ctx, studentID, class10B, "2099-09-01"); err == nil ||
+		!strings.Contains(err.Error(), "student not found") {
+		t.Fatalf("Expected the deleted student to be rejected, got %v", err)
+	}
+	if _, err := students.PurgeDeleted(ctx, time.Now().Add(time.Hour).UTC().Format(time.RFC3339)); err != nil {
+		t.Fatal(err)
+	}
+	if err := classes.DeleteClass(ctx, class10B); err != nil {
+		t.Fatalf("Expected the enrollments to go with the purged student, got %v", err)
+	}
+	if err := classes.DeleteClass(ctx, class11B); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testStats(t *testing.T, db *sqlconnect.DB, logger *logging.Logger) {
+	stats, err := dataops.NewStatsDB(db, logger).GetStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Students != 2 || stats.StudentsByClass["5E"] != 2 || stats.Classes != 3 {
+		t.Fatalf("Expected the 3 classes and the 2 students of 5E left by the tests, got %+v", stats)
+	}
+	if stats.ExecsByRole["admin"] != 1 || stats.InactiveExecs != 1 {
+		t.Fatalf("Expected the inactive admin of the execs test, got %+v", stats)
+	}
+}
@@ -0,0 +1,241 @@
+package dataops
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/repository/sqlconnect"
+)
+
+// Enrollments - the classes of the students over the academic years, the student has at
+// most one active enrollment and its class is the class_id of the student. The
+// enrollments go with the purged student
+type Enrollments struct {
+	db      *sqlconnect.DB
+	logger  *logging.Logger
+	timeout time.Duration
+}
+
+func NewEnrollmentsDB(db *sqlconnect.DB, logger *logging.Logger, timeout time.Duration) *Enrollments {
+	return &Enrollments{
+		db:      db,
+		logger:  logger,
+		timeout: timeout,
+	}
+}
+
+// studentFilterCondition - the condition of the filter param of the students, the class
+// and class_id params are resolved against the active enrollments so the graduated
+// students are in no class
+func studentFilterCondition(param string) string {
+	switch param {
+	case "class":
+		return ` AND id IN (SELECT e.student_id FROM enrollments e JOIN classes c ON c.id = e.class_id
+			WHERE e.status = 'active' AND c.name = ?)`
+	case "class_id":
+		return " AND id IN (SELECT student_id FROM enrollments WHERE status = 'active' AND class_id = ?)"
+	}
+	return " AND " + param + " = ?"
+}
+
+// today - the date of the enrollments which are started or ended without a date
+func today() string {
+	return time.Now().UTC().Format(time.DateOnly)
+}
+
+// startEnrollment - ends the active enrollment of the student as transferred and starts
+// the active enrollment in the class, the caller sets the class of the student
+func startEnrollment(ctx context.Context, tx *sqlconnect.Tx, studentID, classID int, date string) (int64, error) {
+	var academicYear string
+	if err := tx.QueryRowContext(ctx, "SELECT academic_year FROM classes WHERE id = ?", classID).
+		Scan(&academicYear); err != nil {
+		return 0, err
+	}
+	_, err := tx.ExecContext(ctx,
+		"UPDATE enrollments SET status = ?, end_date = ? WHERE student_id = ? AND status = ?",
+		models.EnrollmentTransferred, date, studentID, models.EnrollmentActive,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return tx.InsertContext(ctx,
+		"INSERT INTO enrollments (student_id, class_id, academic_year, start_date, status) VALUES (?,?,?,?,?)",
+		studentID, classID, academicYear, date, models.EnrollmentActive,
+	)
+}
+
+const enrollmentQuery = `SELECT e.id, e.student_id, e.class_id, c.name, e.academic_year, e.start_date,
+	COALESCE(e.end_date, ''), e.status
+	FROM enrollments e JOIN classes c ON c.id = e.class_id`
+
+func scanEnrollment(scan func(...any) error) (models.Enrollment, error) {
+	var enrollment models.Enrollment
+	err := scan(
+		&enrollment.ID,
+		&enrollment.StudentID,
+		&enrollment.ClassID,
+		&enrollment.ClassName,
+		&enrollment.AcademicYear,
+		&enrollment.StartDate,
+		&enrollment.EndDate,
+		&enrollment.Status,
+	)
+	return enrollment, err
+}
+
+// GetEnrollmentsByStudentID - the enrollments of the student from the first one
+func (e *Enrollments) GetEnrollmentsByStudentID(ctx context.Context, id int) ([]models.Enrollment, error) {
+	ctx, cancel := queryContext(ctx, e.timeout)
+	defer cancel()
+
+	rows, err := e.db.QueryContext(ctx, enrollmentQuery+" WHERE e.student_id = ? ORDER BY e.start_date, e.id", id)
+	if err != nil {
+		e.logger.Logging.Debugf("error retreiving the enrollments of the student %v", err)
+		return nil, dbError(ctx, e.logger, err, "error retreiving data")
+	}
+	defer rows.Close()
+
+	enrollments := make([]models.Enrollment, 0)
+	for rows.Next() {
+		enrollment, err := scanEnrollment(rows.Scan)
+		if err != nil {
+			e.logger.Logging.Debugf("error scanning the enrollment %v", err)
+			return nil, dbError(ctx, e.logger, err, "error scanning database results")
+		}
+		enrollments = append(enrollments, enrollment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError(ctx, e.logger, err, "error retreiving data")
+	}
+	return enrollments, nil
+}
+
+// EnrollStudent - the student without active enrollment, new or graduated, is enrolled
+// in the class from the date
+func (e *Enrollments) EnrollStudent(ctx context.Context, studentID, classID int, date string) (models.Enrollment, error) {
+	return e.change(ctx, studentID, func(tx *sqlconnect.Tx, active *models.Enrollment) (int64, error) {
+		if active != nil {
+			return 0, e.logger.ErrorMessage("student already enrolled, the student is transferred to another class")
+		}
+		return e.moveStudent(ctx, tx, studentID, classID, date)
+	})
+}
+
+// TransferStudent - the active enrollment of the student ends as transferred on the date
+// and the student is enrolled in the other class from the date
+func (e *Enrollments) TransferStudent(ctx context.Context, studentID, classID int, date string) (models.Enrollment, error) {
+	return e.change(ctx, studentID, func(tx *sqlconnect.Tx, active *models.Enrollment) (int64, error) {
+		switch {
+		case active == nil:
+			return 0, e.logger.ErrorMessage("student not enrolled in any class")
+		case active.ClassID == classID:
+			return 0, e.logger.ErrorMessage("student already enrolled in the class")
+		case date < active.StartDate:
+			return 0, e.logger.ErrorMessage("date is before the start of the enrollment")
+		}
+		return e.moveStudent(ctx, tx, studentID, classID, date)
+	})
+}
+
+// GraduateStudent - the active enrollment of the student ends as graduated on the date,
+// the student keeps the class_id of the last class
+func (e *Enrollments) GraduateStudent(ctx context.Context, studentID int, date string) (models.Enrollment, error) {
+	return e.change(ctx, studentID, func(tx *sqlconnect.Tx, active *models.Enrollment) (int64, error) {
+		switch {
+		case active == nil:
+			return 0, e.logger.ErrorMessage("student not enrolled in any class")
+		case date < active.StartDate:
+			return 0, e.logger.ErrorMessage("date is before the start of the enrollment")
+		}
+		_, err := tx.ExecContext(ctx,
+			"UPDATE enrollments SET status = ?, end_date = ? WHERE id = ?",
+			models.EnrollmentGraduated, date, active.ID,
+		)
+		return int64(active.ID), err
+	})
+}
+
+// moveStudent - the student is enrolled in the class and the class of the student is set,
+// the change of the class is written to the revisions of the student
+func (e *Enrollments) moveStudent(
+	ctx context.Context,
+	tx *sqlconnect.Tx,
+	studentID, classID int,
+	date string,
+) (int64, error) {
+	before, err := lockStudent(ctx, tx, studentID)
+	if err != nil {
+		return 0, err
+	}
+	id, err := startEnrollment(ctx, tx, studentID, classID, date)
+	if err == sql.ErrNoRows {
+		return 0, e.logger.ErrorMessage("class not found")
+	} else if err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE students SET class_id = ? WHERE id = ?", classID, studentID); err != nil {
+		return 0, err
+	}
+	after := before
+	after.ClassID = classID
+	return id, writeRevision(ctx, tx, models.EntityStudent, studentID, models.ChangeUpdate, before, after)
+}
+
+// change - runs the change of the enrollments of the not deleted student in a transaction
+// with the active enrollment, nil when there is none, and returns the changed enrollment
+func (e *Enrollments) change(
+	ctx context.Context,
+	studentID int,
+	fn func(tx *sqlconnect.Tx, active *models.Enrollment) (int64, error),
+) (models.Enrollment, error) {
+	ctx, cancel := queryContext(ctx, e.timeout)
+	defer cancel()
+
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		e.logger.Logging.Debugf("Error starting transaction %v", err)
+		return models.Enrollment{}, dbError(ctx, e.logger, err, "database error")
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var students int
+	err = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM students WHERE id = ? AND deleted_at IS NULL", studentID).
+		Scan(&students)
+	if err != nil {
+		e.logger.Logging.Debugf("error retreiving the student %v", err)
+		return models.Enrollment{}, dbError(ctx, e.logger, err, "error retreiving data")
+	}
+	if students == 0 {
+		return models.Enrollment{}, e.logger.ErrorMessage("student not found")
+	}
+
+	var active *models.Enrollment
+	enrollment, err := scanEnrollment(tx.QueryRowContext(ctx,
+		enrollmentQuery+" WHERE e.student_id = ? AND e.status = ?", studentID, models.EnrollmentActive,
+	).Scan)
+	if err == nil {
+		active = &enrollment
+	} else if err != sql.ErrNoRows {
+		e.logger.Logging.Debugf("error retreiving the active enrollment %v", err)
+		return models.Enrollment{}, dbError(ctx, e.logger, err, "error retreiving data")
+	}
+
+	id, err := fn(tx, active)
+	if err != nil {
+		e.logger.Logging.Debugf("error changing the enrollment %v", err)
+		return models.Enrollment{}, dbError(ctx, e.logger, err, err.Error())
+	}
+	changed, err := scanEnrollment(tx.QueryRowContext(ctx, enrollmentQuery+" WHERE e.id = ?", id).Scan)
+	if err != nil {
+		e.logger.Logging.Debugf("error retreiving the enrollment %v", err)
+		return models.Enrollment{}, dbError(ctx, e.logger, err, "error retreiving data")
+	}
+	if err := tx.Commit(); err != nil {
+		e.logger.Logging.Debugf("error commiting the transaction %v", err)
+		return models.Enrollment{}, dbError(ctx, e.logger, err, "database error")
+	}
+	return changed, nil
+}
@@ -1,6 +1,7 @@
 package dataops
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"reflect"
@@ -10,47 +11,125 @@ import (
 	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
 	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
 	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/utils"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/repository/sqlconnect"
 )
 
 type Execs struct {
-	db     *sql.DB
-	logger *logging.Logger
+	db      *sqlconnect.DB
+	logger  *logging.Logger
+	timeout time.Duration
 }
 
-func NewExecsDB(db *sql.DB, logger *logging.Logger) *Execs {
+func NewExecsDB(db *sqlconnect.DB, logger *logging.Logger, timeout time.Duration) *Execs {
 	return &Execs{
-		db:     db,
-		logger: logger,
+		db:      db,
+		logger:  logger,
+		timeout: timeout,
 	}
 }
 
-func (e *Execs) InsertExecs(ex *models.Exec) (int64, error) {
-	stmt, err := e.db.Prepare(utils.GenereateInsertQuery(models.Exec{}, "execs"))
+func (e *Execs) InsertExecs(ctx context.Context, ex *models.Exec) (int64, error) {
+	ctx, cancel := queryContext(ctx, e.timeout)
+	defer cancel()
+
+	tx, err := e.db.BeginTx(ctx, nil)
 	if err != nil {
-		e.logger.Logging.Debugf("error prepare insert statement %v", err)
-		return 0, e.logger.ErrorMessage("sql database insert exec error ")
+		e.logger.Logging.Debugf("Error starting transaction %v", err)
+		return 0, dbError(ctx, e.logger, err, "sql database error")
 	}
-	defer stmt.Close()
-	values := utils.GetStructValues(ex)
+	defer func() { _ = tx.Rollback() }()
 
-	sqlResp, err := stmt.Exec(values...)
+	values := utils.GetStructValues(ex)
+	lastID, err := tx.InsertContext(ctx, utils.GenereateInsertQuery(models.Exec{}, "execs"), values...)
 	if err != nil {
-
 		e.logger.Logging.Debugf("error insert exec to the database %v", err)
-		return 0, e.logger.ErrorMessage("sql database error")
+		return 0, dbError(ctx, e.logger, err, "sql database error")
+	}
+	created := *ex
+	created.ID = int(lastID)
+	if err := e.commitChange(ctx, tx, created.ID, models.ChangeCreate, nil, NewExecRevision(created)); err != nil {
+		return 0, err
 	}
+	return lastID, nil
+}
+
+// lockExec - the not deleted exec read in the transaction of its change without the
+// password and the tokens, the row stays locked until the end of the transaction so the
+// revision has the state the change is made on
+func lockExec(ctx context.Context, tx *sqlconnect.Tx, id int) (models.Exec, error) {
+	var exec models.Exec
+	err := tx.QueryRowContext(ctx,
+		"SELECT id, first_name, last_name, email, username, inactive_status, role, password_changed_at FROM execs WHERE id = ? AND deleted_at IS NULL"+
+			tx.Dialect().ForUpdate(),
+		id,
+	).Scan(
+		&exec.ID,
+		&exec.FirstName,
+		&exec.LastName,
+		&exec.Email,
+		&exec.Username,
+		&exec.InactiveStatus,
+		&exec.Role,
+		&exec.PasswordChangedAt,
+	)
+	return exec, err
+}
+
+// commitChange - writes the revision of the change of the exec and commits its
+// transaction, before is nil for the created exec and after for the deleted one
+func (e *Execs) commitChange(ctx context.Context, tx *sqlconnect.Tx, id int, action string, before, after any) error {
+	if err := writeRevision(ctx, tx, models.EntityExec, id, action, before, after); err != nil {
+		e.logger.Logging.Debugf("error writing the exec revision %v", err)
+		return dbError(ctx, e.logger, err, "database error")
+	}
+	if err := tx.Commit(); err != nil {
+		e.logger.Logging.Debugf("error commiting the transaction %v", err)
+		return dbError(ctx, e.logger, err, "database error")
+	}
+	return nil
+}
 
-	lastID, err := sqlResp.LastInsertId()
+// update - runs the update of the exec in a transaction together with the revision of the
+// change, apply sets the columns changed by the update on the state of the exec
+func (e *Execs) update(ctx context.Context, id int, msg string, apply func(exec *models.Exec), query string, args ...any) error {
+	tx, err := e.db.BeginTx(ctx, nil)
 	if err != nil {
+		e.logger.Logging.Debugf("Error starting transaction %v", err)
+		return dbError(ctx, e.logger, err, msg)
+	}
+	defer func() { _ = tx.Rollback() }()
 
-		e.logger.Logging.Debugf("eror get last insert teacher %v", err)
-		return 0, e.logger.ErrorMessage("sql database error")
+	existingExec, err := lockExec(ctx, tx, id)
+	if err == sql.ErrNoRows {
+		return e.logger.ErrorMessage("user not found")
+	} else if err != nil {
+		e.logger.Logging.Debugf("error retreiving the exec %v", err)
+		return dbError(ctx, e.logger, err, msg)
 	}
-	return lastID, nil
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		e.logger.Logging.Debugf("error updating the exec %v", err)
+		return dbError(ctx, e.logger, err, msg)
+	}
+	updatedExec := existingExec
+	apply(&updatedExec)
+	return e.commitChange(ctx, tx, id, models.ChangeUpdate, NewExecRevision(existingExec), NewExecRevision(updatedExec))
 }
 
-func (e *Execs) GetAllExecs(params map[string]string, sortBy []string) (*sql.Rows, error) {
-	query := "SELECT id, first_name,last_name,email, username, user_created_at, inactive_status, role FROM execs WHERE 1=1"
+// GetAllExecs - the execs matching the params and their count, the soft deleted execs
+// are returned only with includeDeleted
+func (e *Execs) GetAllExecs(
+	ctx context.Context,
+	params map[string]string,
+	sortBy []string,
+	includeDeleted bool,
+) ([]models.Exec, int, error) {
+	ctx, cancel := queryContext(ctx, e.timeout)
+	defer cancel()
+
+	query := "SELECT id, first_name,last_name,email, username, user_created_at, inactive_status, role, COALESCE(deleted_at, '') FROM execs WHERE 1=1"
+	if !includeDeleted {
+		query += " AND deleted_at IS NULL"
+	}
 	var args []any
 	var orderByParts []string
 
@@ -88,18 +167,47 @@ func (e *Execs) GetAllExecs(params map[string]string, sortBy []string) (*sql.Row
 		query += " ORDER BY " + strings.Join(orderByParts, ", ")
 	}
 
-	rows, err := e.db.Query(query, args...)
+	rows, err := e.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		e.logger.Logging.Debugf("error retreiving the data %v", err)
-		return nil, e.logger.ErrorMessage("error retrtreiving data")
+		return nil, 0, dbError(ctx, e.logger, err, "error retrtreiving data")
+	}
+	defer rows.Close()
+
+	execs := make([]models.Exec, 0)
+	for rows.Next() {
+		var exec models.Exec
+		err := rows.Scan(
+			&exec.ID,
+			&exec.FirstName,
+			&exec.LastName,
+			&exec.Email,
+			&exec.Username,
+			&exec.UserCreatedAt,
+			&exec.InactiveStatus,
+			&exec.Role,
+			&exec.DeletedAt,
+		)
+		if err != nil {
+			e.logger.Logging.Debugf("error scanning the exec %v", err)
+			return nil, 0, dbError(ctx, e.logger, err, "error scanning database results")
+		}
+		execs = append(execs, exec)
 	}
-	return rows, nil
+	if err := rows.Err(); err != nil {
+		e.logger.Logging.Debugf("error reading the execs %v", err)
+		return nil, 0, dbError(ctx, e.logger, err, "error retrtreiving data")
+	}
+	return execs, len(execs), nil
 }
 
-func (e *Execs) GetExecsByID(id int) (models.Exec, error) {
+func (e *Execs) GetExecsByID(ctx context.Context, id int) (models.Exec, error) {
+	ctx, cancel := queryContext(ctx, e.timeout)
+	defer cancel()
+
 	var exec models.Exec
 
-	err := e.db.QueryRow("SELECT id, first_name, last_name ,email, username, inactive_status, role FROM execs WHERE id = ?", id).
+	err := e.db.QueryRowContext(ctx, "SELECT id, first_name, last_name ,email, username, inactive_status, role FROM execs WHERE id = ? AND deleted_at IS NULL", id).
 		Scan(
 			&exec.ID,
 			&exec.FirstName,
@@ -111,35 +219,33 @@ func (e *Execs) GetExecsByID(id int) (models.Exec, error) {
 		)
 	if err == sql.ErrNoRows {
 		e.logger.Logging.Debugf("error exec not found %v", err)
-		return models.Exec{}, e.logger.ErrorMessage("sql exec error")
+		return models.Exec{}, dbError(ctx, e.logger, err, "sql exec error")
 	} else if err != nil {
 		e.logger.Logging.Debugf("error quring the database %v", err)
-		return models.Exec{}, e.logger.ErrorMessage("sql exec error")
+		return models.Exec{}, dbError(ctx, e.logger, err, "sql exec error")
 	}
 	return exec, nil
 }
 
-func (e *Execs) PatchExec(id int, updatedExec models.Exec) (models.Exec, error) {
-	var existingExec models.Exec
+func (e *Execs) PatchExec(ctx context.Context, id int, updatedExec models.Exec) (models.Exec, error) {
+	ctx, cancel := queryContext(ctx, e.timeout)
+	defer cancel()
 
-	row := e.db.QueryRow(
-		"SELECT id ,first_name,last_name,email, username  from execs WHERE id = ?",
-		id,
-	)
-	err := row.Scan(
-		&existingExec.ID,
-		&existingExec.FirstName,
-		&existingExec.LastName,
-		&existingExec.Email,
-		&existingExec.Username,
-	)
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		e.logger.Logging.Debugf("Error starting transaction %v", err)
+		return models.Exec{}, dbError(ctx, e.logger, err, "database error")
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	before, err := lockExec(ctx, tx, id)
 	if err != nil {
 		if err != sql.ErrNoRows {
 			e.logger.Logging.Debugf("Exec not found %v", err)
-			return models.Exec{}, e.logger.ErrorMessage("database error")
+			return models.Exec{}, dbError(ctx, e.logger, err, "database error")
 		} else {
 			e.logger.Logging.Debugf("unable to retreive data %v", err)
-			return models.Exec{}, e.logger.ErrorMessage("database error")
+			return models.Exec{}, dbError(ctx, e.logger, err, "database error")
 		}
 	}
 
@@ -162,6 +268,13 @@ func (e *Execs) PatchExec(id int, updatedExec models.Exec) (models.Exec, error)
 
 	// apply updates using reflect package
 
+	existingExec := models.Exec{
+		ID:        before.ID,
+		FirstName: before.FirstName,
+		LastName:  before.LastName,
+		Email:     before.Email,
+		Username:  before.Username,
+	}
 	execVal := reflect.ValueOf(&existingExec).Elem()
 
 	updatedVal := reflect.ValueOf(updatedExec)
@@ -186,8 +299,8 @@ func (e *Execs) PatchExec(id int, updatedExec models.Exec) (models.Exec, error)
 		}
 	}
 
-	_, err = e.db.Exec(
-		"UPDATE execs SET first_name = ?, last_name = ? ,email = ?, username = ?  WHERE id = ?  ",
+	_, err = tx.ExecContext(ctx,
+		"UPDATE execs SET first_name = ?, last_name = ? ,email = ?, username = ?  WHERE id = ? AND deleted_at IS NULL",
 		existingExec.FirstName,
 		existingExec.LastName,
 		existingExec.Email,
@@ -196,50 +309,77 @@ func (e *Execs) PatchExec(id int, updatedExec models.Exec) (models.Exec, error)
 	)
 	if err != nil {
 		e.logger.Logging.Debugf("error updating exec %v", err)
-		return models.Exec{}, e.logger.ErrorMessage("database error")
+		return models.Exec{}, dbError(ctx, e.logger, err, "database error")
+	}
+	after := before
+	after.FirstName = existingExec.FirstName
+	after.LastName = existingExec.LastName
+	after.Email = existingExec.Email
+	after.Username = existingExec.Username
+	if err := e.commitChange(ctx, tx, id, models.ChangeUpdate, NewExecRevision(before), NewExecRevision(after)); err != nil {
+		return models.Exec{}, err
 	}
 
 	return existingExec, nil
 }
 
-func (e *Execs) DeleteExec(id int) error {
-	result, err := e.db.Exec("DELETE from execs WHERE id = ?", id)
-	if err != nil {
-		e.logger.Logging.Debugf("error deleting exec %v", err)
-		return e.logger.ErrorMessage("database delete error")
-	}
-	rowsAffected, err := result.RowsAffected()
+// DeleteExec - soft deletes the exec, it can not login anymore and is removed for good
+// by PurgeDeleted together with its sessions and keys
+func (e *Execs) DeleteExec(ctx context.Context, id int) error {
+	ctx, cancel := queryContext(ctx, e.timeout)
+	defer cancel()
+
+	tx, err := e.db.BeginTx(ctx, nil)
 	if err != nil {
-		e.logger.Logging.Debugf("error retreiving delete result %v", err)
-		return e.logger.ErrorMessage("error database delete operation")
+		e.logger.Logging.Debugf("Error starting transaction %v", err)
+		return dbError(ctx, e.logger, err, "database delete error")
 	}
+	defer func() { _ = tx.Rollback() }()
 
-	if rowsAffected == 0 {
-		e.logger.Logging.Debugf("exec not found %v", err)
+	existingExec, err := lockExec(ctx, tx, id)
+	if err == sql.ErrNoRows {
+		e.logger.Logging.Debugf("exec %d not found", id)
 		return e.logger.ErrorMessage("exec not found")
+	} else if err != nil {
+		e.logger.Logging.Debugf("error retreiving the exec %v", err)
+		return dbError(ctx, e.logger, err, "database delete error")
 	}
-
-	return nil
+	_, err = tx.ExecContext(ctx,
+		"UPDATE execs SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL",
+		time.Now().UTC().Format(time.RFC3339),
+		id,
+	)
+	if err != nil {
+		e.logger.Logging.Debugf("error deleting exec %v", err)
+		return dbError(ctx, e.logger, err, "database delete error")
+	}
+	return e.commitChange(ctx, tx, id, models.ChangeDelete, NewExecRevision(existingExec), nil)
 }
 
-func (e *Execs) SearchUsername(username string) (bool, error, string) {
+func (e *Execs) SearchUsername(ctx context.Context, username string) (bool, error, string) {
+	ctx, cancel := queryContext(ctx, e.timeout)
+	defer cancel()
+
 	var exec models.Exec
-	err := e.db.QueryRow("SELECT id ,first_name, last_name , email, username, password ,inactive_status, role FROM execs WHERE username = ?", username).
+	err := e.db.QueryRowContext(ctx, "SELECT id ,first_name, last_name , email, username, password ,inactive_status, role FROM execs WHERE username = ? AND deleted_at IS NULL", username).
 		Scan(&exec.ID, &exec.FirstName, &exec.LastName, &exec.Email, &exec.Username, &exec.Password, &exec.InactiveStatus, &exec.Role)
 	if err != nil {
 		e.logger.Logging.Debugf("error scanning the exec to the SQL %v", err)
-		return false, e.logger.ErrorMessage("user not found"), ""
+		return false, dbError(ctx, e.logger, err, "user not found"), ""
 	}
 	return true, nil, exec.Password
 }
 
-func (e *Execs) IsInactiveUser(username string) (bool, error) {
+func (e *Execs) IsInactiveUser(ctx context.Context, username string) (bool, error) {
+	ctx, cancel := queryContext(ctx, e.timeout)
+	defer cancel()
+
 	var exec models.Exec
-	err := e.db.QueryRow("SELECT id ,first_name, last_name , email, username, password ,inactive_status, role FROM execs WHERE username = ?", username).
+	err := e.db.QueryRowContext(ctx, "SELECT id ,first_name, last_name , email, username, password ,inactive_status, role FROM execs WHERE username = ? AND deleted_at IS NULL", username).
 		Scan(&exec.ID, &exec.FirstName, &exec.LastName, &exec.Email, &exec.Username, &exec.Password, &exec.InactiveStatus, &exec.Role)
 	if err != nil {
 		e.logger.Logging.Debugf("error scanning the exec to the SQL %v", err)
-		return false, e.logger.ErrorMessage("database query error")
+		return false, dbError(ctx, e.logger, err, "database query error")
 	}
 	if exec.InactiveStatus {
 		return true, e.logger.ErrorMessage("User is inactive")
@@ -247,66 +387,96 @@ func (e *Execs) IsInactiveUser(username string) (bool, error) {
 	return false, nil
 }
 
-func (e *Execs) GetLoginDetailsForUsername(username string) (models.Exec, error) {
+func (e *Execs) GetLoginDetailsForUsername(ctx context.Context, username string) (models.Exec, error) {
+	ctx, cancel := queryContext(ctx, e.timeout)
+	defer cancel()
+
 	var exec models.Exec
-	err := e.db.QueryRow("SELECT id ,first_name, last_name , email, username, password ,inactive_status, role FROM execs WHERE username = ?", username).
+	err := e.db.QueryRowContext(ctx, "SELECT id ,first_name, last_name , email, username, password ,inactive_status, role FROM execs WHERE username = ? AND deleted_at IS NULL", username).
 		Scan(&exec.ID, &exec.FirstName, &exec.LastName, &exec.Email, &exec.Username, &exec.Password, &exec.InactiveStatus, &exec.Role)
 	if err != nil {
 		e.logger.Logging.Debugf("error scanning the exec to the SQL %v", err)
-		return models.Exec{}, e.logger.ErrorMessage("user not found")
+		return models.Exec{}, dbError(ctx, e.logger, err, "user not found")
 	}
 	return exec, nil
 }
 
-func (e *Execs) GetUserPasswordFromId(id int) (string, string, string, error) {
+func (e *Execs) GetUserPasswordFromId(ctx context.Context, id int) (string, string, string, error) {
+	ctx, cancel := queryContext(ctx, e.timeout)
+	defer cancel()
+
 	var username string
 	var password string
 	var role string
 
-	err := e.db.QueryRow("SELECT username,password,role FROM execs WHERE id = ?", id).
+	err := e.db.QueryRowContext(ctx, "SELECT username,password,role FROM execs WHERE id = ? AND deleted_at IS NULL", id).
 		Scan(&username, &password, &role)
 	if err != nil {
 		e.logger.Logging.Debugf("error scanning the exec to the SQL %v", err)
-		return "", "", "", e.logger.ErrorMessage("user not found")
+		return "", "", "", dbError(ctx, e.logger, err, "user not found")
 
 	}
 	return username, password, role, nil
 }
 
-func (e *Execs) UpdatePasswordChange(id int, password string) error {
+// GetAuthStatus - password_changed_at and inactive_status needed to validate the tokens,
+// the deleted exec is reported as inactive
+func (e *Execs) GetAuthStatus(ctx context.Context, id int) (string, bool, error) {
+	ctx, cancel := queryContext(ctx, e.timeout)
+	defer cancel()
+
+	var passwordChangedAt sql.NullString
+	var inactive bool
+
+	err := e.db.QueryRowContext(ctx, "SELECT password_changed_at, inactive_status OR deleted_at IS NOT NULL FROM execs WHERE id = ?", id).
+		Scan(&passwordChangedAt, &inactive)
+	if err == sql.ErrNoRows {
+		return "", false, e.logger.ErrorMessage("user not found")
+	} else if err != nil {
+		e.logger.Logging.Debugf("error scanning the exec to the SQL %v", err)
+		return "", false, dbError(ctx, e.logger, err, "error retreiving the exec status")
+	}
+	return passwordChangedAt.String, inactive, nil
+}
+
+func (e *Execs) UpdatePasswordChange(ctx context.Context, id int, password string) error {
+	ctx, cancel := queryContext(ctx, e.timeout)
+	defer cancel()
+
 	currentTime := time.Now().Format(time.RFC3339)
-	_, err := e.db.Exec(
+	return e.update(ctx, id, "faile to update password",
+		func(exec *models.Exec) {
+			exec.PasswordChangedAt = sql.NullString{String: currentTime, Valid: true}
+		},
 		"UPDATE execs set password = ?, password_changed_at = ? WHERE id = ?",
 		password,
 		currentTime,
 		id,
 	)
-	if err != nil {
-		e.logger.Logging.Errorf(
-			"failed to update the password %v",
-			err,
-		)
-		return e.logger.ErrorMessage("faile to update password")
-	}
-	return nil
 }
 
-func (e *Execs) GetIdFromEmail(email string) (models.Exec, error) {
+func (e *Execs) GetIdFromEmail(ctx context.Context, email string) (models.Exec, error) {
+	ctx, cancel := queryContext(ctx, e.timeout)
+	defer cancel()
+
 	var exec models.Exec
-	err := e.db.QueryRow("SELECT id FROM execs WHERE email = ?", email).Scan(&exec.ID)
+	err := e.db.QueryRowContext(ctx, "SELECT id FROM execs WHERE email = ? AND deleted_at IS NULL", email).Scan(&exec.ID)
 	if err != nil {
 		e.logger.Logging.Debugf(
 			"error from GetIdFromEmail and scanning the exec to the SQL %v",
 			err,
 		)
-		return models.Exec{}, e.logger.ErrorMessage("user not found")
+		return models.Exec{}, dbError(ctx, e.logger, err, "user not found")
 
 	}
 	return exec, nil
 }
 
-func (e *Execs) StoreResetToken(id int, hashedResetToken string, expiry string) error {
-	_, err := e.db.Exec(
+func (e *Execs) StoreResetToken(ctx context.Context, id int, hashedResetToken string, expiry string) error {
+	ctx, cancel := queryContext(ctx, e.timeout)
+	defer cancel()
+
+	_, err := e.db.ExecContext(ctx,
 		"UPDATE execs SET password_reset_token = ? , password_token_expires = ? WHERE id = ? ",
 		hashedResetToken,
 		expiry,
@@ -314,32 +484,177 @@ func (e *Execs) StoreResetToken(id int, hashedResetToken string, expiry string)
 	)
 	if err != nil {
 		e.logger.Logging.Debugf("Failed to stoer reset toe  %v", err)
-		return e.logger.ErrorMessage("database error")
+		return dbError(ctx, e.logger, err, "database error")
 	}
 
 	return nil
 }
 
-func (e *Execs) GetEmailFromToken(hashedTokenString string) (models.Exec, error) {
+func (e *Execs) GetEmailFromToken(ctx context.Context, hashedTokenString string) (models.Exec, error) {
+	ctx, cancel := queryContext(ctx, e.timeout)
+	defer cancel()
+
 	var user models.Exec
-	err := e.db.QueryRow("SELECT id, email FROM execs WHERE password_reset_token = ? AND password_token_expires > ?", hashedTokenString, time.Now().Format(time.RFC3339)).
+	err := e.db.QueryRowContext(ctx, "SELECT id, email FROM execs WHERE password_reset_token = ? AND password_token_expires > ? AND deleted_at IS NULL", hashedTokenString, time.Now().Format(time.RFC3339)).
 		Scan(&user.ID, &user.Email)
 	if err != nil {
-		return models.Exec{}, e.logger.ErrorLogger(err, "invalid or expired reset code")
+		return models.Exec{}, dbError(ctx, e.logger, err, "invalid or expired reset code")
 	}
 
 	return user, nil
 }
 
-func (e *Execs) UpdateResetedPassword(hashedPassword string, id int) error {
-	_, err := e.db.Exec(
+func (e *Execs) UpdateResetedPassword(ctx context.Context, hashedPassword string, id int) error {
+	ctx, cancel := queryContext(ctx, e.timeout)
+	defer cancel()
+
+	currentTime := time.Now().Format(time.RFC3339)
+	return e.update(ctx, id, "Internal error",
+		func(exec *models.Exec) {
+			exec.PasswordChangedAt = sql.NullString{String: currentTime, Valid: true}
+		},
 		"UPDATE execs SET password = ? , password_reset_token = NULL, password_token_expires = NULL ,password_changed_at =? WHERE id = ?",
 		hashedPassword,
+		currentTime,
+		id,
+	)
+}
+
+// GetPasswordHistory - the last n password hashes of the exec, newest first
+func (e *Execs) GetPasswordHistory(ctx context.Context, id int, n int) ([]string, error) {
+	ctx, cancel := queryContext(ctx, e.timeout)
+	defer cancel()
+
+	rows, err := e.db.QueryContext(ctx,
+		"SELECT password_hash FROM exec_password_history WHERE exec_id = ? ORDER BY id DESC LIMIT ?",
+		id,
+		n,
+	)
+	if err != nil {
+		e.logger.Logging.Debugf("error quering the password history %v", err)
+		return nil, dbError(ctx, e.logger, err, "database error")
+	}
+	defer rows.Close()
+
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			e.logger.Logging.Debugf("error scanning the password history %v", err)
+			return nil, dbError(ctx, e.logger, err, "database error")
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, rows.Err()
+}
+
+// AddPasswordHistory - stores the new password hash and keeps only the last n of them
+func (e *Execs) AddPasswordHistory(ctx context.Context, id int, hash string, keep int) error {
+	ctx, cancel := queryContext(ctx, e.timeout)
+	defer cancel()
+
+	_, err := e.db.ExecContext(ctx,
+		"INSERT INTO exec_password_history (exec_id, password_hash, created_at) VALUES (?,?,?)",
+		id,
+		hash,
 		time.Now().Format(time.RFC3339),
+	)
+	if err != nil {
+		e.logger.Logging.Debugf("error storing the password history %v", err)
+		return dbError(ctx, e.logger, err, "database error")
+	}
+	// the derived table is needed as mariadb does not allow LIMIT in IN subquery
+	_, err = e.db.ExecContext(ctx,
+		`DELETE FROM exec_password_history WHERE exec_id = ? AND id NOT IN (
+			SELECT id FROM (
+				SELECT id FROM exec_password_history WHERE exec_id = ? ORDER BY id DESC LIMIT ?
+			) AS newest
+		)`,
+		id,
 		id,
+		keep,
 	)
 	if err != nil {
-		return e.logger.ErrorLogger(err, "Internal error")
+		e.logger.Logging.Debugf("error pruning the password history %v", err)
+		return dbError(ctx, e.logger, err, "database error")
 	}
 	return nil
 }
+
+// RehashPassword - replaces the password hash with the same password hashed with new parameters,
+// password_changed_at is not touched as the password is the same and the tokens stay valid
+func (e *Execs) RehashPassword(ctx context.Context, id int, oldHash, newHash string) error {
+	ctx, cancel := queryContext(ctx, e.timeout)
+	defer cancel()
+
+	_, err := e.db.ExecContext(ctx,
+		"UPDATE execs SET password = ? WHERE id = ? AND password = ?",
+		newHash,
+		id,
+		oldHash,
+	)
+	if err != nil {
+		e.logger.Logging.Debugf("error rehashing the password %v", err)
+		return dbError(ctx, e.logger, err, "database error")
+	}
+	return nil
+}
+
+// SetInactiveStatus - deactivates or reactivates the exec
+func (e *Execs) SetInactiveStatus(ctx context.Context, id int, inactive bool) error {
+	ctx, cancel := queryContext(ctx, e.timeout)
+	defer cancel()
+
+	return e.update(ctx, id, "database error",
+		func(exec *models.Exec) { exec.InactiveStatus = inactive },
+		"UPDATE execs SET inactive_status = ? WHERE id = ?",
+		inactive,
+		id,
+	)
+}
+
+// RestoreExec - brings back the soft deleted exec
+func (e *Execs) RestoreExec(ctx context.Context, id int) error {
+	ctx, cancel := queryContext(ctx, e.timeout)
+	defer cancel()
+
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		e.logger.Logging.Debugf("Error starting transaction %v", err)
+		return dbError(ctx, e.logger, err, "database error")
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.ExecContext(ctx, "UPDATE execs SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL", id)
+	if err != nil {
+		e.logger.Logging.Debugf("error restoring exec %v", err)
+		return dbError(ctx, e.logger, err, "database error")
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		e.logger.Logging.Debugf("error retreiving restore result %v", err)
+		return dbError(ctx, e.logger, err, "database error")
+	}
+	if rowsAffected == 0 {
+		return e.logger.ErrorMessage("deleted exec not found")
+	}
+	restoredExec, err := lockExec(ctx, tx, id)
+	if err != nil {
+		e.logger.Logging.Debugf("error retreiving restored exec %v", err)
+		return dbError(ctx, e.logger, err, "database error")
+	}
+	return e.commitChange(ctx, tx, id, models.ChangeUndelete, nil, NewExecRevision(restoredExec))
+}
+
+// PurgeDeleted - removes for good the execs soft deleted before the RFC3339 time
+func (e *Execs) PurgeDeleted(ctx context.Context, before string) (int64, error) {
+	ctx, cancel := queryContext(ctx, e.timeout)
+	defer cancel()
+
+	result, err := e.db.ExecContext(ctx, "DELETE FROM execs WHERE deleted_at IS NOT NULL AND deleted_at < ?", before)
+	if err != nil {
+		e.logger.Logging.Debugf("error purging deleted execs %v", err)
+		return 0, dbError(ctx, e.logger, err, "database error")
+	}
+	return result.RowsAffected()
+}
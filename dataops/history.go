@@ -0,0 +1,147 @@
+package dataops
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/repository/sqlconnect"
+)
+
+// ChangeHistory - the revisions of the teachers, students and execs, the revisions are kept
+// also after the record is deleted so it can be seen who deleted it
+type ChangeHistory struct {
+	db     *sqlconnect.DB
+	logger *logging.Logger
+}
+
+func NewChangeHistoryDB(db *sqlconnect.DB, logger *logging.Logger) *ChangeHistory {
+	return &ChangeHistory{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func nullJSON(data json.RawMessage) sql.NullString {
+	return sql.NullString{String: string(data), Valid: len(data) > 0}
+}
+
+// RecordRevision - writes the revision of the change made outside of a transaction of
+// dataops, like the changes of the in-memory repository
+func (c *ChangeHistory) RecordRevision(rev models.Revision) error {
+	if err := insertRevision(context.Background(), c.db, rev); err != nil {
+		c.logger.Logging.Debugf("error writing the revision %v", err)
+		return c.logger.ErrorMessage("database error")
+	}
+	return nil
+}
+
+const revisionColumns = "id, entity, entity_id, action, actor_id, before_data, after_data, changes, restored_from, created_at"
+
+func scanRevision(scan func(...any) error) (models.Revision, error) {
+	var rev models.Revision
+	var actorID, restoredFrom sql.NullInt64
+	var before, after, changes sql.NullString
+	err := scan(
+		&rev.ID,
+		&rev.Entity,
+		&rev.EntityID,
+		&rev.Action,
+		&actorID,
+		&before,
+		&after,
+		&changes,
+		&restoredFrom,
+		&rev.CreatedAt,
+	)
+	if err != nil {
+		return models.Revision{}, err
+	}
+	rev.ActorID = int(actorID.Int64)
+	rev.RestoredFrom = int(restoredFrom.Int64)
+	if before.Valid {
+		rev.Before = json.RawMessage(before.String)
+	}
+	if after.Valid {
+		rev.After = json.RawMessage(after.String)
+	}
+	rev.Changes = []models.FieldChange{}
+	if changes.Valid && changes.String != "" {
+		if err := json.Unmarshal([]byte(changes.String), &rev.Changes); err != nil {
+			return models.Revision{}, err
+		}
+	}
+	return rev, nil
+}
+
+// ListRevisions - one page of the revisions of the record, the newest first, together
+// with the count of all its revisions
+func (c *ChangeHistory) ListRevisions(
+	entity string,
+	entityID, page, limit int,
+) ([]models.Revision, int, error) {
+	var total int
+	err := c.db.QueryRow(
+		"SELECT COUNT(*) FROM change_history WHERE entity = ? AND entity_id = ?",
+		entity,
+		entityID,
+	).Scan(&total)
+	if err != nil {
+		c.logger.Logging.Debugf("error counting the revisions %v", err)
+		return nil, 0, c.logger.ErrorMessage("database error")
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
+	rows, err := c.db.Query(
+		"SELECT "+revisionColumns+" FROM change_history WHERE entity = ? AND entity_id = ? ORDER BY id DESC LIMIT ? OFFSET ?",
+		entity,
+		entityID,
+		limit,
+		(page-1)*limit,
+	)
+	if err != nil {
+		c.logger.Logging.Debugf("error quering the revisions %v", err)
+		return nil, 0, c.logger.ErrorMessage("database error")
+	}
+	defer rows.Close()
+
+	revisions := make([]models.Revision, 0)
+	for rows.Next() {
+		rev, err := scanRevision(rows.Scan)
+		if err != nil {
+			c.logger.Logging.Debugf("error scanning the revision %v", err)
+			return nil, 0, c.logger.ErrorMessage("database error")
+		}
+		revisions = append(revisions, rev)
+	}
+	if err := rows.Err(); err != nil {
+		c.logger.Logging.Debugf("error reading the revisions %v", err)
+		return nil, 0, c.logger.ErrorMessage("database error")
+	}
+	return revisions, total, nil
+}
+
+func (c *ChangeHistory) GetRevision(entity string, entityID, id int) (models.Revision, error) {
+	row := c.db.QueryRow(
+		"SELECT "+revisionColumns+" FROM change_history WHERE id = ? AND entity = ? AND entity_id = ?",
+		id,
+		entity,
+		entityID,
+	)
+	rev, err := scanRevision(row.Scan)
+	if err == sql.ErrNoRows {
+		c.logger.Logging.Debugf("revision not found %v", err)
+		return models.Revision{}, c.logger.ErrorMessage("revision not found")
+	} else if err != nil {
+		c.logger.Logging.Debugf("error quering the revision %v", err)
+		return models.Revision{}, c.logger.ErrorMessage("database error")
+	}
+	return rev, nil
+}
@@ -0,0 +1,52 @@
+package dataops
+
+import (
+	"database/sql"
+
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/repository/sqlconnect"
+)
+
+// ExecIdentities - links of the identity provider subjects to the execs
+type ExecIdentities struct {
+	db     *sqlconnect.DB
+	logger *logging.Logger
+}
+
+func NewExecIdentitiesDB(db *sqlconnect.DB, logger *logging.Logger) *ExecIdentities {
+	return &ExecIdentities{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// GetExecIDBySubject - returns 0 when the subject is not linked to any exec
+func (e *ExecIdentities) GetExecIDBySubject(issuer, subject string) (int, error) {
+	var execID int
+	err := e.db.QueryRow(
+		"SELECT exec_id FROM exec_identities WHERE issuer = ? AND subject = ?",
+		issuer,
+		subject,
+	).Scan(&execID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	} else if err != nil {
+		e.logger.Logging.Debugf("error quering the identity %v", err)
+		return 0, e.logger.ErrorMessage("database error")
+	}
+	return execID, nil
+}
+
+func (e *ExecIdentities) LinkIdentity(issuer, subject string, execID int) error {
+	_, err := e.db.Exec(
+		"INSERT INTO exec_identities (issuer, subject, exec_id) VALUES (?,?,?)",
+		issuer,
+		subject,
+		execID,
+	)
+	if err != nil {
+		e.logger.Logging.Debugf("error linking the identity %v", err)
+		return e.logger.ErrorMessage("database error")
+	}
+	return nil
+}
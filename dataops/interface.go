@@ -1,44 +1,153 @@
 package dataops
 
 import (
-	"database/sql"
+	"context"
+	"time"
 
 	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
 )
 
 type TeachersInf interface {
-	InsertTeachers(*models.Teacher) (int64, error)
-	GetTeacherByID(int) (models.Teacher, error)
-	GetAllTeachers(map[string]string, []string) (*sql.Rows, error)
-	UpdateTeacher(int, models.Teacher) (models.Teacher, error)
-	PatchTeacher(int, models.Teacher) (models.Teacher, error)
-	DeleteTeacher(int) error
-	DeleteBulkTeachers([]int) ([]int, error)
-	GetStudentsByTeacherID(int) ([]models.Student, error)
+	InsertTeachers(context.Context, *models.Teacher) (int64, error)
+	GetTeacherByID(context.Context, int) (models.Teacher, error)
+	GetAllTeachers(context.Context, map[string]string, []string, bool) ([]models.Teacher, int, error)
+	UpdateTeacher(context.Context, int, models.Teacher) (models.Teacher, error)
+	PatchTeacher(context.Context, int, models.Teacher) (models.Teacher, error)
+	DeleteTeacher(context.Context, int) error
+	DeleteBulkTeachers(context.Context, []int) ([]int, error)
+	GetStudentsByTeacherID(context.Context, int) ([]models.Student, error)
+	RestoreTeacher(context.Context, int) error
+	PurgeDeleted(context.Context, string) (int64, error)
 }
 type StudentInf interface {
-	InsertStudents(*models.Student) (int64, error)
-	GetStudentByID(int) (models.Student, error)
-	GetAllStudents(map[string]string, []string, int, int) (*sql.Rows, int, error)
-	UpdateStudent(int, models.Student) (models.Student, error)
-	PatchiStudent(int, models.Student) (models.Student, error)
-	DeleteStudent(int) error
-	DeleteBulkStudents([]int) ([]int, error)
+	InsertStudents(context.Context, *models.Student) (int64, error)
+	GetStudentByID(context.Context, int) (models.Student, error)
+	GetAllStudents(context.Context, map[string]string, []string, int, int, bool) ([]models.Student, int, error)
+	UpdateStudent(context.Context, int, models.Student) (models.Student, error)
+	PatchiStudent(context.Context, int, models.Student) (models.Student, error)
+	DeleteStudent(context.Context, int) error
+	DeleteBulkStudents(context.Context, []int) ([]int, error)
+	RestoreStudent(context.Context, int) error
+	PurgeDeleted(context.Context, string) (int64, error)
+}
+
+type ClassesInf interface {
+	InsertClass(context.Context, *models.Class) (int64, error)
+	GetClassByID(context.Context, int) (models.Class, error)
+	GetAllClasses(context.Context, map[string]string, []string) ([]models.Class, int, error)
+	UpdateClass(context.Context, int, models.Class) (models.Class, error)
+	PatchClass(context.Context, int, models.Class) (models.Class, error)
+	DeleteClass(context.Context, int) error
+	GetStudentsByClassID(context.Context, int) ([]models.Student, error)
+	GetTeachersByClassID(context.Context, int) ([]models.Teacher, error)
+}
+
+type SubjectsInf interface {
+	InsertSubject(context.Context, *models.Subject) (int64, error)
+	GetSubjectByID(context.Context, int) (models.Subject, error)
+	GetAllSubjects(context.Context, map[string]string, []string) ([]models.Subject, int, error)
+	UpdateSubject(context.Context, int, models.Subject) (models.Subject, error)
+	PatchSubject(context.Context, int, models.Subject) (models.Subject, error)
+	DeleteSubject(context.Context, int) error
+}
+
+type EnrollmentsInf interface {
+	GetEnrollmentsByStudentID(context.Context, int) ([]models.Enrollment, error)
+	EnrollStudent(ctx context.Context, studentID, classID int, date string) (models.Enrollment, error)
+	TransferStudent(ctx context.Context, studentID, classID int, date string) (models.Enrollment, error)
+	GraduateStudent(ctx context.Context, studentID int, date string) (models.Enrollment, error)
+}
+
+type AssignmentsInf interface {
+	InsertAssignment(context.Context, *models.Assignment) (int64, error)
+	GetAssignmentByID(context.Context, int) (models.Assignment, error)
+	GetAllAssignments(context.Context, map[string]string, []string) ([]models.Assignment, int, error)
+	UpdateAssignment(context.Context, int, models.Assignment) (models.Assignment, error)
+	DeleteAssignment(context.Context, int) error
 }
 
 type ExecsInf interface {
-	InsertExecs(*models.Exec) (int64, error)
-	GetExecsByID(int) (models.Exec, error)
-	GetAllExecs(map[string]string, []string) (*sql.Rows, error)
-	PatchExec(int, models.Exec) (models.Exec, error)
-	DeleteExec(int) error
-	SearchUsername(string) (bool, error, string)
-	IsInactiveUser(string) (bool, error)
-	GetLoginDetailsForUsername(string) (models.Exec, error)
-	GetUserPasswordFromId(int) (string, string, string, error)
-	UpdatePasswordChange(int, string) error
-	GetIdFromEmail(string) (models.Exec, error)
-	StoreResetToken(int, string, string) error
-	GetEmailFromToken(string) (models.Exec, error)
-	UpdateResetedPassword(string, int) error
+	InsertExecs(context.Context, *models.Exec) (int64, error)
+	GetExecsByID(context.Context, int) (models.Exec, error)
+	GetAllExecs(context.Context, map[string]string, []string, bool) ([]models.Exec, int, error)
+	PatchExec(context.Context, int, models.Exec) (models.Exec, error)
+	DeleteExec(context.Context, int) error
+	SearchUsername(context.Context, string) (bool, error, string)
+	IsInactiveUser(context.Context, string) (bool, error)
+	GetLoginDetailsForUsername(context.Context, string) (models.Exec, error)
+	GetUserPasswordFromId(context.Context, int) (string, string, string, error)
+	UpdatePasswordChange(context.Context, int, string) error
+	GetIdFromEmail(context.Context, string) (models.Exec, error)
+	StoreResetToken(context.Context, int, string, string) error
+	GetEmailFromToken(context.Context, string) (models.Exec, error)
+	UpdateResetedPassword(context.Context, string, int) error
+	GetAuthStatus(context.Context, int) (string, bool, error)
+	GetPasswordHistory(context.Context, int, int) ([]string, error)
+	AddPasswordHistory(context.Context, int, string, int) error
+	RehashPassword(context.Context, int, string, string) error
+	SetInactiveStatus(context.Context, int, bool) error
+	RestoreExec(context.Context, int) error
+	PurgeDeleted(context.Context, string) (int64, error)
+}
+
+type SessionsInf interface {
+	CreateSession(*models.ExecSession) (int64, error)
+	GetSessionByTokenHash(string) (models.ExecSession, error)
+	IsSessionActive(int) (bool, error)
+	RevokeSession(int) (bool, error)
+	RevokeAllSessions(int) (int64, error)
+	ListActiveSessions(int) ([]models.ExecSession, error)
+}
+
+type LoginAttemptsInf interface {
+	GetLoginAttempt(string) (models.LoginAttempt, error)
+	SaveLoginAttempt(models.LoginAttempt) error
+	AddLoginFailure(string, time.Time, time.Time) (int, error)
+	LockLogin(string, int, time.Time) error
+	ResetLoginAttempts(string) error
+}
+
+type MFAInf interface {
+	GetMFA(int) (models.ExecMFA, error)
+	SaveMFASecret(int, string) error
+	EnableMFA(int, int64, []string) error
+	DisableMFA(int) error
+	UseMFAStep(int, int64) (bool, error)
+	ReplaceRecoveryCodes(int, []string) error
+	UseRecoveryCode(int, string) (bool, error)
+}
+
+type APIKeysInf interface {
+	CreateAPIKey(*models.APIKey) (int64, error)
+	GetAPIKeyByHash(string) (models.APIKey, error)
+	ListAPIKeys(int) ([]models.APIKey, error)
+	RevokeAPIKey(int, int) (bool, error)
+	TouchAPIKey(int) error
+}
+
+type ExecIdentitiesInf interface {
+	GetExecIDBySubject(string, string) (int, error)
+	LinkIdentity(string, string, int) error
+}
+
+type ExecInvitationsInf interface {
+	SaveInvitation(models.ExecInvitation) error
+	GetInvitationByTokenHash(string) (models.ExecInvitation, error)
+	HasInvitation(int) (bool, error)
+	ActivateExec(string, models.Exec) (bool, error)
+}
+
+type AuthAuditInf interface {
+	RecordAuthEvent(models.AuthEvent) error
+	ListAuthEvents(models.AuthEventFilter) ([]models.AuthEvent, int, error)
+}
+
+type ChangeHistoryInf interface {
+	RecordRevision(models.Revision) error
+	ListRevisions(string, int, int, int) ([]models.Revision, int, error)
+	GetRevision(string, int, int) (models.Revision, error)
+}
+
+type StatsInf interface {
+	GetStats() (models.SchoolStats, error)
 }
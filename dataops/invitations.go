@@ -0,0 +1,131 @@
+package dataops
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/repository/sqlconnect"
+)
+
+// ExecInvitations - the activation codes of the invited execs
+type ExecInvitations struct {
+	db     *sqlconnect.DB
+	logger *logging.Logger
+}
+
+func NewExecInvitationsDB(db *sqlconnect.DB, logger *logging.Logger) *ExecInvitations {
+	return &ExecInvitations{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// SaveInvitation - stores the invitation, the code of previous invitation of the exec is replaced
+func (e *ExecInvitations) SaveInvitation(inv models.ExecInvitation) error {
+	_, err := e.db.Exec(
+		"INSERT INTO exec_invitations (exec_id, token_hash, expires_at, created_at) VALUES (?,?,?,?) "+
+			e.db.Dialect.Upsert("exec_id", "token_hash", "expires_at", "created_at"),
+		inv.ExecID,
+		inv.TokenHash,
+		inv.ExpiresAt,
+		inv.CreatedAt,
+	)
+	if err != nil {
+		e.logger.Logging.Debugf("error storing the invitation %v", err)
+		return e.logger.ErrorMessage("database error")
+	}
+	return nil
+}
+
+// GetInvitationByTokenHash - the invitation with the code if it is not expired
+func (e *ExecInvitations) GetInvitationByTokenHash(tokenHash string) (models.ExecInvitation, error) {
+	var inv models.ExecInvitation
+	err := e.db.QueryRow(
+		"SELECT exec_id, token_hash, expires_at, created_at FROM exec_invitations WHERE token_hash = ? AND expires_at > ?",
+		tokenHash,
+		time.Now().Format(time.RFC3339),
+	).Scan(&inv.ExecID, &inv.TokenHash, &inv.ExpiresAt, &inv.CreatedAt)
+	if err != nil {
+		return models.ExecInvitation{}, e.logger.ErrorLogger(err, "invalid or expired activation code")
+	}
+	return inv, nil
+}
+
+// HasInvitation - reports whether the exec was invited and did not activate the account yet
+func (e *ExecInvitations) HasInvitation(execID int) (bool, error) {
+	var count int
+	err := e.db.QueryRow("SELECT COUNT(*) FROM exec_invitations WHERE exec_id = ?", execID).
+		Scan(&count)
+	if err != nil {
+		e.logger.Logging.Debugf("error quering the invitation %v", err)
+		return false, e.logger.ErrorMessage("database error")
+	}
+	return count > 0, nil
+}
+
+// ActivateExec - uses the invitation and sets the password and the names of the exec.
+// Returns false when the code was already used so the activation link works only once.
+// The activation is written to the revisions of the exec as the change of the exec
+func (e *ExecInvitations) ActivateExec(tokenHash string, exec models.Exec) (bool, error) {
+	ctx := WithActor(context.Background(), exec.ID)
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, e.logger.ErrorLogger(err, "Error starting Transaction")
+	}
+	res, err := tx.Exec(
+		"DELETE FROM exec_invitations WHERE exec_id = ? AND token_hash = ?",
+		exec.ID,
+		tokenHash,
+	)
+	if err != nil {
+		_ = tx.Rollback()
+		e.logger.Logging.Debugf("error deleting the invitation %v", err)
+		return false, e.logger.ErrorMessage("database error")
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		_ = tx.Rollback()
+		return false, nil
+	}
+	// the invitation is used only when the exec is activated
+	invited, err := lockExec(ctx, tx, exec.ID)
+	if err == sql.ErrNoRows {
+		_ = tx.Rollback()
+		return false, e.logger.ErrorMessage("user not found")
+	} else if err != nil {
+		_ = tx.Rollback()
+		e.logger.Logging.Debugf("error retreiving the exec %v", err)
+		return false, e.logger.ErrorMessage("database error")
+	}
+	activated := invited
+	activated.FirstName = exec.FirstName
+	activated.LastName = exec.LastName
+	activated.PasswordChangedAt = sql.NullString{String: time.Now().Format(time.RFC3339), Valid: true}
+	activated.InactiveStatus = false
+	_, err = tx.Exec(
+		"UPDATE execs SET first_name = ?, last_name = ?, password = ?, password_changed_at = ?, inactive_status = FALSE WHERE id = ?",
+		exec.FirstName,
+		exec.LastName,
+		exec.Password,
+		activated.PasswordChangedAt.String,
+		exec.ID,
+	)
+	if err != nil {
+		_ = tx.Rollback()
+		e.logger.Logging.Debugf("error activating the exec %v", err)
+		return false, e.logger.ErrorMessage("database error")
+	}
+	err = writeRevision(ctx, tx, models.EntityExec, exec.ID, models.ChangeUpdate, NewExecRevision(invited), NewExecRevision(activated))
+	if err != nil {
+		_ = tx.Rollback()
+		e.logger.Logging.Debugf("error writing the exec revision %v", err)
+		return false, e.logger.ErrorMessage("database error")
+	}
+	if err := tx.Commit(); err != nil {
+		e.logger.Logging.Debugf("error commiting the transaction %v", err)
+		return false, e.logger.ErrorMessage("database error")
+	}
+	return true, nil
+}
@@ -0,0 +1,113 @@
+package dataops
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/repository/sqlconnect"
+)
+
+type LoginAttempts struct {
+	db     *sqlconnect.DB
+	logger *logging.Logger
+}
+
+func NewLoginAttemptsDB(db *sqlconnect.DB, logger *logging.Logger) *LoginAttempts {
+	return &LoginAttempts{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// GetLoginAttempt - returns empty attempt with the key when there are no failures recorded
+func (l *LoginAttempts) GetLoginAttempt(key string) (models.LoginAttempt, error) {
+	attempt := models.LoginAttempt{Key: key}
+	err := l.db.QueryRow("SELECT failed_attempts, last_failed_at, locked_until FROM login_attempts WHERE attempt_key = ?", key).
+		Scan(&attempt.FailedAttempts, &attempt.LastFailedAt, &attempt.LockedUntil)
+	if err == sql.ErrNoRows {
+		return attempt, nil
+	} else if err != nil {
+		l.logger.Logging.Debugf("error quering the login attempts %v", err)
+		return models.LoginAttempt{}, l.logger.ErrorMessage("database error")
+	}
+	return attempt, nil
+}
+
+func (l *LoginAttempts) SaveLoginAttempt(attempt models.LoginAttempt) error {
+	_, err := l.db.Exec(
+		"INSERT INTO login_attempts (attempt_key, failed_attempts, last_failed_at, locked_until) VALUES (?,?,?,?) "+
+			l.db.Dialect.Upsert("attempt_key", "failed_attempts", "last_failed_at", "locked_until"),
+		attempt.Key,
+		attempt.FailedAttempts,
+		attempt.LastFailedAt,
+		attempt.LockedUntil,
+	)
+	if err != nil {
+		l.logger.Logging.Debugf("error saving the login attempt %v", err)
+		return l.logger.ErrorMessage("database error")
+	}
+	return nil
+}
+
+// AddLoginFailure - counts the failure in a single statement so the parallel failures are
+// all counted, the failures older than resetBefore are forgotten. Returns the failures
+func (l *LoginAttempts) AddLoginFailure(key string, failedAt, resetBefore time.Time) (int, error) {
+	tx, err := l.db.Begin()
+	if err != nil {
+		return 0, l.logger.ErrorLogger(err, "Error starting Transaction")
+	}
+	_, err = tx.Exec(
+		"INSERT INTO login_attempts (attempt_key, failed_attempts, last_failed_at, locked_until) VALUES (?,1,?,'') "+
+			l.db.Dialect.UpsertSet("attempt_key",
+				"failed_attempts = CASE WHEN login_attempts.last_failed_at < ? THEN 1 ELSE login_attempts.failed_attempts + 1 END",
+				"last_failed_at = "+l.db.Dialect.Inserted("last_failed_at"),
+			),
+		key,
+		failedAt.UTC().Format(time.RFC3339),
+		resetBefore.UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		_ = tx.Rollback()
+		l.logger.Logging.Debugf("error counting the login failure %v", err)
+		return 0, l.logger.ErrorMessage("database error")
+	}
+	var failures int
+	err = tx.QueryRow("SELECT failed_attempts FROM login_attempts WHERE attempt_key = ?", key).Scan(&failures)
+	if err != nil {
+		_ = tx.Rollback()
+		l.logger.Logging.Debugf("error quering the login attempts %v", err)
+		return 0, l.logger.ErrorMessage("database error")
+	}
+	if err := tx.Commit(); err != nil {
+		l.logger.Logging.Debugf("error commiting the transaction %v", err)
+		return 0, l.logger.ErrorMessage("database error")
+	}
+	return failures, nil
+}
+
+// LockLogin - sets the lock computed from the failures, skipped when a parallel failure
+// has been counted since, its lock is the longer one
+func (l *LoginAttempts) LockLogin(key string, failures int, lockedUntil time.Time) error {
+	_, err := l.db.Exec(
+		"UPDATE login_attempts SET locked_until = ? WHERE attempt_key = ? AND failed_attempts = ?",
+		lockedUntil.UTC().Format(time.RFC3339),
+		key,
+		failures,
+	)
+	if err != nil {
+		l.logger.Logging.Debugf("error locking the login %v", err)
+		return l.logger.ErrorMessage("database error")
+	}
+	return nil
+}
+
+func (l *LoginAttempts) ResetLoginAttempts(key string) error {
+	_, err := l.db.Exec("DELETE FROM login_attempts WHERE attempt_key = ?", key)
+	if err != nil {
+		l.logger.Logging.Debugf("error deleting the login attempts %v", err)
+		return l.logger.ErrorMessage("database error")
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+)
+
+var _ dataops.AssignmentsInf = (*Assignments)(nil)
+
+var assignmentSortColumns = map[string]bool{
+	"term":         true,
+	"teacher_id":   true,
+	"subject_name": true,
+	"class_name":   true,
+}
+
+// Assignments - in-memory dataops.AssignmentsInf, the subject is unique in the class in
+// the term like in the table. The assignments are locked last, after the classes, the
+// subjects and the teachers
+type Assignments struct {
+	// Classes, Subjects and Teachers - the referenced records have to exist like the
+	// foreign keys of the table and give the names, without them any id is accepted
+	Classes  *Classes
+	Subjects *Subjects
+	Teachers *Teachers
+
+	mu          sync.Mutex
+	nextID      int
+	assignments map[int]models.Assignment
+}
+
+// NewAssignments - the repository with the assignments, the ones without id get the next
+// free id
+func NewAssignments(assignments ...models.Assignment) *Assignments {
+	a := &Assignments{nextID: 1, assignments: map[int]models.Assignment{}}
+	for _, assignment := range assignments {
+		if assignment.ID >= a.nextID {
+			a.nextID = assignment.ID + 1
+		}
+	}
+	for _, assignment := range assignments {
+		if assignment.ID == 0 {
+			assignment.ID = a.nextID
+			a.nextID++
+		}
+		a.assignments[assignment.ID] = assignment
+	}
+	return a
+}
+
+func assignmentColumn(a models.Assignment, column string) (string, bool) {
+	switch column {
+	case "teacher_id":
+		return strconv.Itoa(a.TeacherID), true
+	case "subject_id":
+		return strconv.Itoa(a.SubjectID), true
+	case "class_id":
+		return strconv.Itoa(a.ClassID), true
+	case "term":
+		return a.Term, true
+	case "subject_name":
+		return a.SubjectName, true
+	case "class_name":
+		return a.ClassName, true
+	}
+	return "", false
+}
+
+func assignmentID(a models.Assignment) int { return a.ID }
+
+// lock - the classes, subjects and teachers are locked before the assignments so the
+// referenced records can't go while the assignments are read or written
+func (a *Assignments) lock() func() {
+	var unlock []func()
+	if a.Classes != nil {
+		a.Classes.mu.Lock()
+		unlock = append(unlock, a.Classes.mu.Unlock)
+	}
+	if a.Subjects != nil {
+		a.Subjects.mu.Lock()
+		unlock = append(unlock, a.Subjects.mu.Unlock)
+	}
+	if a.Teachers != nil {
+		a.Teachers.mu.Lock()
+		unlock = append(unlock, a.Teachers.mu.Unlock)
+	}
+	a.mu.Lock()
+	unlock = append(unlock, a.mu.Unlock)
+	return func() {
+		for i := len(unlock) - 1; i >= 0; i-- {
+			unlock[i]()
+		}
+	}
+}
+
+// named - the assignment with the names of the teacher, subject and class, the caller
+// holds the lock
+func (a *Assignments) named(assignment models.Assignment) models.Assignment {
+	if a.Teachers != nil {
+		teacher := a.Teachers.teachers[assignment.TeacherID]
+		assignment.TeacherName = teacher.FirstName + " " + teacher.LastName
+	}
+	if a.Subjects != nil {
+		assignment.SubjectName = a.Subjects.subjects[assignment.SubjectID].Name
+	}
+	if a.Classes != nil {
+		assignment.ClassName = a.Classes.classes[assignment.ClassID].Name
+	}
+	return assignment
+}
+
+// check - the teacher, subject and class exist and the subject is free in the class in
+// the term, the caller holds the lock
+func (a *Assignments) check(assignment models.Assignment, exceptID int) error {
+	if a.Teachers != nil {
+		if _, ok := a.Teachers.teachers[assignment.TeacherID]; !ok {
+			return fmt.Errorf("teacher %d not found", assignment.TeacherID)
+		}
+	}
+	if a.Subjects != nil {
+		if _, ok := a.Subjects.subjects[assignment.SubjectID]; !ok {
+			return fmt.Errorf("subject %d not found", assignment.SubjectID)
+		}
+	}
+	if a.Classes != nil && !a.Classes.exists(assignment.ClassID) {
+		return fmt.Errorf("class %d not found", assignment.ClassID)
+	}
+	for id, other := range a.assignments {
+		if id != exceptID && other.SubjectID == assignment.SubjectID &&
+			other.ClassID == assignment.ClassID && other.Term == assignment.Term {
+			return fmt.Errorf("duplicate assignment of subject %d in class %d", assignment.SubjectID, assignment.ClassID)
+		}
+	}
+	return nil
+}
+
+// references - any assignment matches, used by the classes and subjects which are kept
+// while they have assignments
+func (a *Assignments) references(match func(models.Assignment) bool) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, assignment := range a.assignments {
+		if match(assignment) {
+			return true
+		}
+	}
+	return false
+}
+
+// removeTeachers - the assignments of the purged teachers go like ON DELETE CASCADE
+func (a *Assignments) removeTeachers(teacherIDs map[int]bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for id, assignment := range a.assignments {
+		if teacherIDs[assignment.TeacherID] {
+			delete(a.assignments, id)
+		}
+	}
+}
+
+func (a *Assignments) InsertAssignment(_ context.Context, assignment *models.Assignment) (int64, error) {
+	defer a.lock()()
+
+	if err := a.check(*assignment, 0); err != nil {
+		return 0, errors.New("error database assignment insert")
+	}
+	stored := models.Assignment{
+		ID:        a.nextID,
+		TeacherID: assignment.TeacherID,
+		SubjectID: assignment.SubjectID,
+		ClassID:   assignment.ClassID,
+		Term:      assignment.Term,
+	}
+	a.nextID++
+	a.assignments[stored.ID] = stored
+	return int64(stored.ID), nil
+}
+
+func (a *Assignments) GetAssignmentByID(_ context.Context, id int) (models.Assignment, error) {
+	defer a.lock()()
+
+	assignment, ok := a.assignments[id]
+	if !ok {
+		return models.Assignment{}, errors.New("assignment not found")
+	}
+	return a.named(assignment), nil
+}
+
+// GetAllAssignments - the matching assignments ordered by term, class name and subject
+// name by default and their count
+func (a *Assignments) GetAllAssignments(
+	_ context.Context,
+	params map[string]string,
+	sortBy []string,
+) ([]models.Assignment, int, error) {
+	defer a.lock()()
+
+	assignments := make([]models.Assignment, 0)
+	for _, assignment := range a.assignments {
+		if matches(assignment, params, assignmentColumn) {
+			assignments = append(assignments, a.named(assignment))
+		}
+	}
+	if len(sortBy) == 0 {
+		sortBy = []string{"term:asc", "class_name:asc", "subject_name:asc"}
+	}
+	sortRecords(assignments, sortBy, assignmentSortColumns, assignmentColumn, assignmentID)
+	return assignments, len(assignments), nil
+}
+
+func (a *Assignments) UpdateAssignment(
+	_ context.Context,
+	id int,
+	updated models.Assignment,
+) (models.Assignment, error) {
+	defer a.lock()()
+
+	if _, ok := a.assignments[id]; !ok {
+		return models.Assignment{}, errors.New("assignment not found")
+	}
+	if err := a.check(updated, id); err != nil {
+		return models.Assignment{}, errors.New("error assignment database error")
+	}
+	stored := models.Assignment{
+		ID:        id,
+		TeacherID: updated.TeacherID,
+		SubjectID: updated.SubjectID,
+		ClassID:   updated.ClassID,
+		Term:      updated.Term,
+	}
+	a.assignments[id] = stored
+	return a.named(stored), nil
+}
+
+func (a *Assignments) DeleteAssignment(_ context.Context, id int) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, ok := a.assignments[id]; !ok {
+		return errors.New("Assignment not found")
+	}
+	delete(a.assignments, id)
+	return nil
+}
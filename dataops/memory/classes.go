@@ -0,0 +1,251 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+)
+
+var _ dataops.ClassesInf = (*Classes)(nil)
+
+var classSortColumns = map[string]bool{
+	"name":          true,
+	"grade_level":   true,
+	"academic_year": true,
+	"room":          true,
+	"capacity":      true,
+}
+
+// Classes - in-memory dataops.ClassesInf, the name is unique in the academic year like
+// in the table. The classes are locked before the teachers and the students
+type Classes struct {
+	// Teachers and Students - the members of the classes, a class with members can't be
+	// deleted and the homeroom teacher has to exist
+	Teachers *Teachers
+	Students *Students
+	// Assignments and Enrollments - a class with teaching assignments or enrollments
+	// can't be deleted
+	Assignments *Assignments
+	Enrollments *Enrollments
+
+	mu      sync.Mutex
+	nextID  int
+	classes map[int]models.Class
+}
+
+// NewClasses - the repository with the classes, the ones without id get the next free id
+func NewClasses(classes ...models.Class) *Classes {
+	c := &Classes{nextID: 1, classes: map[int]models.Class{}}
+	for _, class := range classes {
+		if class.ID >= c.nextID {
+			c.nextID = class.ID + 1
+		}
+	}
+	for _, class := range classes {
+		if class.ID == 0 {
+			class.ID = c.nextID
+			c.nextID++
+		}
+		c.classes[class.ID] = class
+	}
+	return c
+}
+
+func classColumn(c models.Class, column string) (string, bool) {
+	switch column {
+	case "name":
+		return c.Name, true
+	case "grade_level":
+		return strconv.Itoa(c.GradeLevel), true
+	case "academic_year":
+		return c.AcademicYear, true
+	case "room":
+		return c.Room, true
+	case "capacity":
+		return strconv.Itoa(c.Capacity), true
+	}
+	return "", false
+}
+
+func classID(c models.Class) int { return c.ID }
+
+// exists - the class with the id exists, the caller holds the lock
+func (c *Classes) exists(id int) bool {
+	_, ok := c.classes[id]
+	return ok
+}
+
+// named - the ids of the classes with the name in any academic year
+func (c *Classes) named(name string) map[int]bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ids := map[int]bool{}
+	for id, class := range c.classes {
+		if class.Name == name {
+			ids[id] = true
+		}
+	}
+	return ids
+}
+
+// check - the name is free in the academic year and the homeroom teacher exists, the
+// caller holds the lock
+func (c *Classes) check(class models.Class, exceptID int) error {
+	for id, other := range c.classes {
+		if id != exceptID && other.Name == class.Name && other.AcademicYear == class.AcademicYear {
+			return fmt.Errorf("duplicate class %s %s", class.Name, class.AcademicYear)
+		}
+	}
+	if class.HomeroomTeacherID != 0 && c.Teachers != nil && !c.Teachers.exists(class.HomeroomTeacherID) {
+		return fmt.Errorf("homeroom teacher %d not found", class.HomeroomTeacherID)
+	}
+	return nil
+}
+
+// clearHomeroom - the purged teachers are no longer homeroom teachers like ON DELETE
+// SET NULL, the caller holds the lock
+func (c *Classes) clearHomeroom(teacherIDs map[int]bool) {
+	for id, class := range c.classes {
+		if teacherIDs[class.HomeroomTeacherID] {
+			class.HomeroomTeacherID = 0
+			c.classes[id] = class
+		}
+	}
+}
+
+func (c *Classes) InsertClass(_ context.Context, class *models.Class) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.check(*class, 0); err != nil {
+		return 0, errors.New("error database class insert")
+	}
+	stored := *class
+	stored.ID = c.nextID
+	c.nextID++
+	c.classes[stored.ID] = stored
+	return int64(stored.ID), nil
+}
+
+func (c *Classes) GetClassByID(_ context.Context, id int) (models.Class, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	class, ok := c.classes[id]
+	if !ok {
+		return models.Class{}, errors.New("class not found")
+	}
+	return class, nil
+}
+
+// GetAllClasses - the matching classes ordered by name by default and their count
+func (c *Classes) GetAllClasses(
+	_ context.Context,
+	params map[string]string,
+	sortBy []string,
+) ([]models.Class, int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	classes := make([]models.Class, 0)
+	for _, class := range c.classes {
+		if matches(class, params, classColumn) {
+			classes = append(classes, class)
+		}
+	}
+	if len(sortBy) == 0 {
+		sortBy = []string{"name:asc"}
+	}
+	sortRecords(classes, sortBy, classSortColumns, classColumn, classID)
+	return classes, len(classes), nil
+}
+
+func (c *Classes) UpdateClass(_ context.Context, id int, updated models.Class) (models.Class, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.exists(id) {
+		return models.Class{}, errors.New("class not found")
+	}
+	if err := c.check(updated, id); err != nil {
+		return models.Class{}, errors.New("error class database error")
+	}
+	updated.ID = id
+	c.classes[id] = updated
+	return updated, nil
+}
+
+func (c *Classes) PatchClass(_ context.Context, id int, updated models.Class) (models.Class, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	class, ok := c.classes[id]
+	if !ok {
+		return models.Class{}, errors.New("class not found")
+	}
+	if updated.Name != "" {
+		class.Name = updated.Name
+	}
+	if updated.GradeLevel != 0 {
+		class.GradeLevel = updated.GradeLevel
+	}
+	if updated.AcademicYear != "" {
+		class.AcademicYear = updated.AcademicYear
+	}
+	if updated.HomeroomTeacherID != 0 {
+		class.HomeroomTeacherID = updated.HomeroomTeacherID
+	}
+	if updated.Room != "" {
+		class.Room = updated.Room
+	}
+	if updated.Capacity != 0 {
+		class.Capacity = updated.Capacity
+	}
+	if err := c.check(class, id); err != nil {
+		return models.Class{}, errors.New("error class database error")
+	}
+	c.classes[id] = class
+	return class, nil
+}
+
+// DeleteClass - the class with teachers, students, teaching assignments or enrollments
+// is kept, the deleted members and the ended enrollments count too
+func (c *Classes) DeleteClass(_ context.Context, id int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.exists(id) {
+		return errors.New("Class not found")
+	}
+	if (c.Teachers != nil && c.Teachers.inClass(id)) || (c.Students != nil && c.Students.inClass(id)) {
+		return errors.New("Class still has teachers, students, teaching assignments or enrollments")
+	}
+	if c.Assignments != nil && c.Assignments.references(func(a models.Assignment) bool { return a.ClassID == id }) {
+		return errors.New("Class still has teachers, students, teaching assignments or enrollments")
+	}
+	if c.Enrollments != nil && c.Enrollments.inClass(id) {
+		return errors.New("Class still has teachers, students, teaching assignments or enrollments")
+	}
+	delete(c.classes, id)
+	return nil
+}
+
+func (c *Classes) GetStudentsByClassID(_ context.Context, id int) ([]models.Student, error) {
+	if c.Students == nil {
+		return []models.Student{}, nil
+	}
+	return c.Students.byClass(id), nil
+}
+
+func (c *Classes) GetTeachersByClassID(_ context.Context, id int) ([]models.Teacher, error) {
+	if c.Teachers == nil {
+		return []models.Teacher{}, nil
+	}
+	return c.Teachers.byClass(id), nil
+}
@@ -0,0 +1,253 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+)
+
+var _ dataops.EnrollmentsInf = (*Enrollments)(nil)
+
+// Enrollments - in-memory dataops.EnrollmentsInf, the student has at most one active
+// enrollment and its class is the class_id of the student. The enrollments are locked
+// after the classes and the students
+type Enrollments struct {
+	// Classes and Students - the class and the student have to exist, the classes give the
+	// names and the academic years, without them any id is accepted
+	Classes  *Classes
+	Students *Students
+
+	mu          sync.Mutex
+	nextID      int
+	enrollments map[int]models.Enrollment
+}
+
+// NewEnrollments - the repository with the enrollments, the ones without id get the next
+// free id
+func NewEnrollments(enrollments ...models.Enrollment) *Enrollments {
+	e := &Enrollments{nextID: 1, enrollments: map[int]models.Enrollment{}}
+	for _, enrollment := range enrollments {
+		if enrollment.ID >= e.nextID {
+			e.nextID = enrollment.ID + 1
+		}
+	}
+	for _, enrollment := range enrollments {
+		if enrollment.ID == 0 {
+			enrollment.ID = e.nextID
+			e.nextID++
+		}
+		e.enrollments[enrollment.ID] = enrollment
+	}
+	return e
+}
+
+// lock - the classes and students are locked before the enrollments so the class and the
+// student can't change while the enrollments are written
+func (e *Enrollments) lock() func() {
+	var unlock []func()
+	if e.Classes != nil {
+		e.Classes.mu.Lock()
+		unlock = append(unlock, e.Classes.mu.Unlock)
+	}
+	if e.Students != nil {
+		e.Students.mu.Lock()
+		unlock = append(unlock, e.Students.mu.Unlock)
+	}
+	e.mu.Lock()
+	unlock = append(unlock, e.mu.Unlock)
+	return func() {
+		for i := len(unlock) - 1; i >= 0; i-- {
+			unlock[i]()
+		}
+	}
+}
+
+// active - the active enrollment of the student, the caller holds the lock
+func (e *Enrollments) active(studentID int) (models.Enrollment, bool) {
+	for _, enrollment := range e.enrollments {
+		if enrollment.StudentID == studentID && enrollment.Status == models.EnrollmentActive {
+			return enrollment, true
+		}
+	}
+	return models.Enrollment{}, false
+}
+
+// start - ends the active enrollment of the student as transferred and starts the active
+// enrollment in the class, the caller holds the lock of the enrollments and of the classes
+func (e *Enrollments) start(studentID, classID int, date string) models.Enrollment {
+	if current, ok := e.active(studentID); ok {
+		current.Status = models.EnrollmentTransferred
+		current.EndDate = date
+		e.enrollments[current.ID] = current
+	}
+	enrollment := models.Enrollment{
+		ID:        e.nextID,
+		StudentID: studentID,
+		ClassID:   classID,
+		StartDate: date,
+		Status:    models.EnrollmentActive,
+	}
+	if e.Classes != nil {
+		enrollment.AcademicYear = e.Classes.classes[classID].AcademicYear
+	}
+	e.nextID++
+	e.enrollments[enrollment.ID] = enrollment
+	return enrollment
+}
+
+// activeClasses - the class of the active enrollment of every enrolled student
+func (e *Enrollments) activeClasses() map[int]int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	classes := map[int]int{}
+	for _, enrollment := range e.enrollments {
+		if enrollment.Status == models.EnrollmentActive {
+			classes[enrollment.StudentID] = enrollment.ClassID
+		}
+	}
+	return classes
+}
+
+// inClass - any enrollment is in the class, the ended ones too
+func (e *Enrollments) inClass(classID int) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, enrollment := range e.enrollments {
+		if enrollment.ClassID == classID {
+			return true
+		}
+	}
+	return false
+}
+
+// removeStudents - the enrollments of the purged students go like ON DELETE CASCADE
+func (e *Enrollments) removeStudents(studentIDs map[int]bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for id, enrollment := range e.enrollments {
+		if studentIDs[enrollment.StudentID] {
+			delete(e.enrollments, id)
+		}
+	}
+}
+
+// named - the enrollment with the name of the class, the caller holds the lock
+func (e *Enrollments) named(enrollment models.Enrollment) models.Enrollment {
+	if e.Classes != nil {
+		enrollment.ClassName = e.Classes.classes[enrollment.ClassID].Name
+	}
+	return enrollment
+}
+
+// GetEnrollmentsByStudentID - the enrollments of the student from the first one
+func (e *Enrollments) GetEnrollmentsByStudentID(_ context.Context, id int) ([]models.Enrollment, error) {
+	defer e.lock()()
+
+	enrollments := make([]models.Enrollment, 0)
+	for _, enrollment := range e.enrollments {
+		if enrollment.StudentID == id {
+			enrollments = append(enrollments, e.named(enrollment))
+		}
+	}
+	sort.Slice(enrollments, func(i, j int) bool {
+		if enrollments[i].StartDate != enrollments[j].StartDate {
+			return enrollments[i].StartDate < enrollments[j].StartDate
+		}
+		return enrollments[i].ID < enrollments[j].ID
+	})
+	return enrollments, nil
+}
+
+// EnrollStudent - the student without active enrollment, new or graduated, is enrolled
+// in the class from the date
+func (e *Enrollments) EnrollStudent(ctx context.Context, studentID, classID int, date string) (models.Enrollment, error) {
+	return e.change(studentID, func(active *models.Enrollment) (models.Enrollment, error) {
+		if active != nil {
+			return models.Enrollment{}, errors.New("student already enrolled, the student is transferred to another class")
+		}
+		return e.moveStudent(ctx, studentID, classID, date)
+	})
+}
+
+// TransferStudent - the active enrollment of the student ends as transferred on the date
+// and the student is enrolled in the other class from the date
+func (e *Enrollments) TransferStudent(ctx context.Context, studentID, classID int, date string) (models.Enrollment, error) {
+	return e.change(studentID, func(active *models.Enrollment) (models.Enrollment, error) {
+		switch {
+		case active == nil:
+			return models.Enrollment{}, errors.New("student not enrolled in any class")
+		case active.ClassID == classID:
+			return models.Enrollment{}, errors.New("student already enrolled in the class")
+		case date < active.StartDate:
+			return models.Enrollment{}, errors.New("date is before the start of the enrollment")
+		}
+		return e.moveStudent(ctx, studentID, classID, date)
+	})
+}
+
+// GraduateStudent - the active enrollment of the student ends as graduated on the date,
+// the student keeps the class_id of the last class
+func (e *Enrollments) GraduateStudent(_ context.Context, studentID int, date string) (models.Enrollment, error) {
+	return e.change(studentID, func(active *models.Enrollment) (models.Enrollment, error) {
+		switch {
+		case active == nil:
+			return models.Enrollment{}, errors.New("student not enrolled in any class")
+		case date < active.StartDate:
+			return models.Enrollment{}, errors.New("date is before the start of the enrollment")
+		}
+		active.Status = models.EnrollmentGraduated
+		active.EndDate = date
+		e.enrollments[active.ID] = *active
+		return *active, nil
+	})
+}
+
+// moveStudent - the student is enrolled in the class and the class of the student is
+// set, the change of the class is written to the revisions of the student. The caller
+// holds the lock
+func (e *Enrollments) moveStudent(ctx context.Context, studentID, classID int, date string) (models.Enrollment, error) {
+	if e.Classes != nil && !e.Classes.exists(classID) {
+		return models.Enrollment{}, errors.New("class not found")
+	}
+	if e.Students != nil {
+		before := e.Students.students[studentID]
+		student := before
+		student.ClassID = classID
+		if err := record(e.Students.History, ctx, models.EntityStudent, studentID, models.ChangeUpdate, before, student); err != nil {
+			return models.Enrollment{}, err
+		}
+		e.Students.students[studentID] = student
+	}
+	return e.start(studentID, classID, date), nil
+}
+
+// change - runs the change of the enrollments of the not deleted student with the active
+// enrollment, nil when there is none, and returns the changed enrollment
+func (e *Enrollments) change(
+	studentID int,
+	fn func(active *models.Enrollment) (models.Enrollment, error),
+) (models.Enrollment, error) {
+	defer e.lock()()
+
+	if e.Students != nil {
+		if _, ok := e.Students.active(studentID); !ok {
+			return models.Enrollment{}, errors.New("student not found")
+		}
+	}
+	var active *models.Enrollment
+	if enrollment, ok := e.active(studentID); ok {
+		active = &enrollment
+	}
+	changed, err := fn(active)
+	if err != nil {
+		return models.Enrollment{}, err
+	}
+	return e.named(changed), nil
+}
@@ -0,0 +1,450 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+)
+
+var _ dataops.ExecsInf = (*Execs)(nil)
+
+var execSortColumns = map[string]bool{
+	"first_name":      true,
+	"last_name":       true,
+	"email":           true,
+	"username":        true,
+	"user_created_at": true,
+	"inactive_status": true,
+	"role":            true,
+}
+
+// Execs - in-memory dataops.ExecsInf, the emails and the usernames are unique like in the table
+type Execs struct {
+	// History - the revisions of the changed execs, without it the changes are not recorded
+	History dataops.ChangeHistoryInf
+
+	mu              sync.Mutex
+	nextID          int
+	execs           map[int]models.Exec
+	passwordHistory map[int][]string
+}
+
+// NewExecs - the repository with the execs, the ones without id get the next free id
+func NewExecs(execs ...models.Exec) *Execs {
+	e := &Execs{nextID: 1, execs: map[int]models.Exec{}, passwordHistory: map[int][]string{}}
+	for _, exec := range execs {
+		if exec.ID >= e.nextID {
+			e.nextID = exec.ID + 1
+		}
+	}
+	for _, exec := range execs {
+		if exec.ID == 0 {
+			exec.ID = e.nextID
+			e.nextID++
+		}
+		e.execs[exec.ID] = exec
+	}
+	return e
+}
+
+func execColumn(e models.Exec, column string) (string, bool) {
+	switch column {
+	case "first_name":
+		return e.FirstName, true
+	case "last_name":
+		return e.LastName, true
+	case "email":
+		return e.Email, true
+	case "username":
+		return e.Username, true
+	case "user_created_at":
+		return e.UserCreatedAt.String, true
+	case "inactive_status":
+		if e.InactiveStatus {
+			return "1", true
+		}
+		return "0", true
+	case "role":
+		return e.Role, true
+	}
+	return "", false
+}
+
+func execID(e models.Exec) int { return e.ID }
+
+// public - the exec without the password and the tokens like the queries select it
+func public(e models.Exec) models.Exec {
+	e.Password = ""
+	e.PasswordResetToken = sql.NullString{}
+	e.PasswordTokenExpires = sql.NullString{}
+	return e
+}
+
+func (e *Execs) active(id int) (models.Exec, bool) {
+	exec, ok := e.execs[id]
+	return exec, ok && exec.DeletedAt == ""
+}
+
+func (e *Execs) byUsername(username string) (models.Exec, bool) {
+	for _, exec := range e.execs {
+		if exec.DeletedAt == "" && exec.Username == username {
+			return exec, true
+		}
+	}
+	return models.Exec{}, false
+}
+
+func (e *Execs) taken(email, username string, exceptID int) error {
+	for id, exec := range e.execs {
+		if id == exceptID {
+			continue
+		}
+		if email != "" && exec.Email == email {
+			return fmt.Errorf("duplicate email %s", email)
+		}
+		if username != "" && exec.Username == username {
+			return fmt.Errorf("duplicate username %s", username)
+		}
+	}
+	return nil
+}
+
+// record - writes the revision of the change of the exec without the password and the
+// tokens, nil is the missing state of the created or deleted exec
+func (e *Execs) record(ctx context.Context, id int, action string, before, after *models.Exec) error {
+	var from, to any
+	if before != nil {
+		from = dataops.NewExecRevision(*before)
+	}
+	if after != nil {
+		to = dataops.NewExecRevision(*after)
+	}
+	return record(e.History, ctx, models.EntityExec, id, action, from, to)
+}
+
+// update - changes the not deleted exec and writes the revision of the change, the caller
+// holds the lock
+func (e *Execs) update(ctx context.Context, id int, apply func(exec *models.Exec)) error {
+	exec, ok := e.active(id)
+	if !ok {
+		return errors.New("user not found")
+	}
+	before := exec
+	apply(&exec)
+	if err := e.record(ctx, id, models.ChangeUpdate, &before, &exec); err != nil {
+		return err
+	}
+	e.execs[id] = exec
+	return nil
+}
+
+func (e *Execs) InsertExecs(ctx context.Context, exec *models.Exec) (int64, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err := e.taken(exec.Email, exec.Username, 0); err != nil {
+		return 0, err
+	}
+	stored := *exec
+	stored.ID = e.nextID
+	stored.DeletedAt = ""
+	stored.UserCreatedAt = sql.NullString{String: now(), Valid: true}
+	if err := e.record(ctx, stored.ID, models.ChangeCreate, nil, &stored); err != nil {
+		return 0, err
+	}
+	e.nextID++
+	e.execs[stored.ID] = stored
+	return int64(stored.ID), nil
+}
+
+func (e *Execs) GetAllExecs(
+	_ context.Context,
+	params map[string]string,
+	sortBy []string,
+	includeDeleted bool,
+) ([]models.Exec, int, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	execs := make([]models.Exec, 0)
+	for _, exec := range e.execs {
+		if exec.DeletedAt != "" && !includeDeleted {
+			continue
+		}
+		if matches(exec, params, execColumn) {
+			execs = append(execs, public(exec))
+		}
+	}
+	sortRecords(execs, sortBy, execSortColumns, execColumn, execID)
+	return execs, len(execs), nil
+}
+
+func (e *Execs) GetExecsByID(_ context.Context, id int) (models.Exec, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	exec, ok := e.active(id)
+	if !ok {
+		return models.Exec{}, errors.New("exec not found")
+	}
+	return public(exec), nil
+}
+
+// PatchExec - changes the not empty names, email and username
+func (e *Execs) PatchExec(ctx context.Context, id int, updated models.Exec) (models.Exec, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	before, ok := e.active(id)
+	exec := before
+	if !ok {
+		return models.Exec{}, errors.New("database error")
+	}
+	if err := e.taken(updated.Email, updated.Username, id); err != nil {
+		return models.Exec{}, errors.New("database error")
+	}
+	if updated.FirstName != "" {
+		exec.FirstName = updated.FirstName
+	}
+	if updated.LastName != "" {
+		exec.LastName = updated.LastName
+	}
+	if updated.Email != "" {
+		exec.Email = updated.Email
+	}
+	if updated.Username != "" {
+		exec.Username = updated.Username
+	}
+	if err := e.record(ctx, id, models.ChangeUpdate, &before, &exec); err != nil {
+		return models.Exec{}, err
+	}
+	e.execs[id] = exec
+	return models.Exec{
+		ID:        exec.ID,
+		FirstName: exec.FirstName,
+		LastName:  exec.LastName,
+		Email:     exec.Email,
+		Username:  exec.Username,
+	}, nil
+}
+
+func (e *Execs) DeleteExec(ctx context.Context, id int) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	exec, ok := e.active(id)
+	if !ok {
+		return errors.New("exec not found")
+	}
+	if err := e.record(ctx, id, models.ChangeDelete, &exec, nil); err != nil {
+		return err
+	}
+	exec.DeletedAt = now()
+	e.execs[id] = exec
+	return nil
+}
+
+func (e *Execs) SearchUsername(_ context.Context, username string) (bool, error, string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	exec, ok := e.byUsername(username)
+	if !ok {
+		return false, errors.New("user not found"), ""
+	}
+	return true, nil, exec.Password
+}
+
+func (e *Execs) IsInactiveUser(_ context.Context, username string) (bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	exec, ok := e.byUsername(username)
+	if !ok {
+		return false, errors.New("database query error")
+	}
+	if exec.InactiveStatus {
+		return true, errors.New("User is inactive")
+	}
+	return false, nil
+}
+
+func (e *Execs) GetLoginDetailsForUsername(_ context.Context, username string) (models.Exec, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	exec, ok := e.byUsername(username)
+	if !ok {
+		return models.Exec{}, errors.New("user not found")
+	}
+	return models.Exec{
+		ID:             exec.ID,
+		FirstName:      exec.FirstName,
+		LastName:       exec.LastName,
+		Email:          exec.Email,
+		Username:       exec.Username,
+		Password:       exec.Password,
+		InactiveStatus: exec.InactiveStatus,
+		Role:           exec.Role,
+	}, nil
+}
+
+func (e *Execs) GetUserPasswordFromId(_ context.Context, id int) (string, string, string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	exec, ok := e.active(id)
+	if !ok {
+		return "", "", "", errors.New("user not found")
+	}
+	return exec.Username, exec.Password, exec.Role, nil
+}
+
+func (e *Execs) UpdatePasswordChange(ctx context.Context, id int, password string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.update(ctx, id, func(exec *models.Exec) {
+		exec.Password = password
+		exec.PasswordChangedAt = sql.NullString{String: now(), Valid: true}
+	})
+}
+
+func (e *Execs) GetIdFromEmail(_ context.Context, email string) (models.Exec, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, exec := range e.execs {
+		if exec.DeletedAt == "" && exec.Email == email {
+			return models.Exec{ID: exec.ID}, nil
+		}
+	}
+	return models.Exec{}, errors.New("user not found")
+}
+
+func (e *Execs) StoreResetToken(_ context.Context, id int, hashedResetToken string, expiry string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if exec, ok := e.execs[id]; ok {
+		exec.PasswordResetToken = sql.NullString{String: hashedResetToken, Valid: true}
+		exec.PasswordTokenExpires = sql.NullString{String: expiry, Valid: true}
+		e.execs[id] = exec
+	}
+	return nil
+}
+
+func (e *Execs) GetEmailFromToken(_ context.Context, hashedToken string) (models.Exec, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	current := time.Now().Format(time.RFC3339)
+	for _, exec := range e.execs {
+		if exec.DeletedAt == "" && exec.PasswordResetToken.Valid &&
+			exec.PasswordResetToken.String == hashedToken && exec.PasswordTokenExpires.String > current {
+			return models.Exec{ID: exec.ID, Email: exec.Email}, nil
+		}
+	}
+	return models.Exec{}, errors.New("invalid or expired reset code")
+}
+
+func (e *Execs) UpdateResetedPassword(ctx context.Context, hashedPassword string, id int) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.update(ctx, id, func(exec *models.Exec) {
+		exec.Password = hashedPassword
+		exec.PasswordResetToken = sql.NullString{}
+		exec.PasswordTokenExpires = sql.NullString{}
+		exec.PasswordChangedAt = sql.NullString{String: now(), Valid: true}
+	})
+}
+
+func (e *Execs) GetAuthStatus(_ context.Context, id int) (string, bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	exec, ok := e.execs[id]
+	if !ok {
+		return "", false, errors.New("user not found")
+	}
+	return exec.PasswordChangedAt.String, exec.InactiveStatus || exec.DeletedAt != "", nil
+}
+
+func (e *Execs) GetPasswordHistory(_ context.Context, id int, n int) ([]string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	hashes := e.passwordHistory[id]
+	if len(hashes) > n {
+		hashes = hashes[:n]
+	}
+	return append([]string(nil), hashes...), nil
+}
+
+func (e *Execs) AddPasswordHistory(_ context.Context, id int, hash string, keep int) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	hashes := append([]string{hash}, e.passwordHistory[id]...)
+	if len(hashes) > keep {
+		hashes = hashes[:keep]
+	}
+	e.passwordHistory[id] = hashes
+	return nil
+}
+
+func (e *Execs) RehashPassword(_ context.Context, id int, oldHash, newHash string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if exec, ok := e.execs[id]; ok && exec.Password == oldHash {
+		exec.Password = newHash
+		e.execs[id] = exec
+	}
+	return nil
+}
+
+func (e *Execs) SetInactiveStatus(ctx context.Context, id int, inactive bool) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.update(ctx, id, func(exec *models.Exec) { exec.InactiveStatus = inactive })
+}
+
+func (e *Execs) RestoreExec(ctx context.Context, id int) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	exec, ok := e.execs[id]
+	if !ok || exec.DeletedAt == "" {
+		return errors.New("deleted exec not found")
+	}
+	exec.DeletedAt = ""
+	if err := e.record(ctx, id, models.ChangeUndelete, nil, &exec); err != nil {
+		return err
+	}
+	e.execs[id] = exec
+	return nil
+}
+
+func (e *Execs) PurgeDeleted(_ context.Context, before string) (int64, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var purged int64
+	for id, exec := range e.execs {
+		if exec.DeletedAt != "" && exec.DeletedAt < before {
+			delete(e.execs, id)
+			delete(e.passwordHistory, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
@@ -0,0 +1,90 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+)
+
+var _ dataops.ExecInvitationsInf = (*Invitations)(nil)
+
+// Invitations - in-memory dataops.ExecInvitationsInf, an exec has at most one invitation.
+// The invitations are locked after the execs
+type Invitations struct {
+	// Execs - the activation sets the password of the exec, without them the activation fails
+	Execs *Execs
+
+	mu          sync.Mutex
+	invitations map[int]models.ExecInvitation
+}
+
+// NewInvitations - the repository without invitations
+func NewInvitations() *Invitations {
+	return &Invitations{invitations: map[int]models.ExecInvitation{}}
+}
+
+// SaveInvitation - stores the invitation, the code of previous invitation of the exec is replaced
+func (i *Invitations) SaveInvitation(inv models.ExecInvitation) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.invitations[inv.ExecID] = inv
+	return nil
+}
+
+// GetInvitationByTokenHash - the invitation with the code if it is not expired
+func (i *Invitations) GetInvitationByTokenHash(tokenHash string) (models.ExecInvitation, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	current := time.Now().Format(time.RFC3339)
+	for _, inv := range i.invitations {
+		if inv.TokenHash == tokenHash && inv.ExpiresAt > current {
+			return inv, nil
+		}
+	}
+	return models.ExecInvitation{}, errors.New("invalid or expired activation code")
+}
+
+// HasInvitation - reports whether the exec was invited and did not activate the account yet
+func (i *Invitations) HasInvitation(execID int) (bool, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	_, ok := i.invitations[execID]
+	return ok, nil
+}
+
+// ActivateExec - uses the invitation and sets the password and the names of the exec.
+// Returns false when the code was already used, the invitation stays when the exec is gone
+func (i *Invitations) ActivateExec(tokenHash string, exec models.Exec) (bool, error) {
+	if i.Execs == nil {
+		return false, errors.New("user not found")
+	}
+	i.Execs.mu.Lock()
+	defer i.Execs.mu.Unlock()
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if inv, ok := i.invitations[exec.ID]; !ok || inv.TokenHash != tokenHash {
+		return false, nil
+	}
+	ctx := dataops.WithActor(context.Background(), exec.ID)
+	err := i.Execs.update(ctx, exec.ID, func(current *models.Exec) {
+		current.FirstName = exec.FirstName
+		current.LastName = exec.LastName
+		current.Password = exec.Password
+		current.PasswordChangedAt = sql.NullString{String: now(), Valid: true}
+		current.InactiveStatus = false
+	})
+	if err != nil {
+		return false, err
+	}
+	delete(i.invitations, exec.ID)
+	return true, nil
+}
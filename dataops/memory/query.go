@@ -0,0 +1,136 @@
+// Package memory - in-memory implementations of the dataops interfaces for running the api
+// without a database and for the handler tests, they keep the records in maps and behave
+// like the sql implementations
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops"
+)
+
+// firstID - the first id given to the records like AUTO_INCREMENT=100 of the tables
+const firstID = 100
+
+// columnFunc - the value of the column of the record and whether the column exists
+type columnFunc[T any] func(record T, column string) (string, bool)
+
+// matches - the record has all not empty params, the unknown columns never match
+// like the unknown column fails the sql query
+func matches[T any](record T, params map[string]string, column columnFunc[T]) bool {
+	for param, value := range params {
+		if value == "" {
+			continue
+		}
+		if v, ok := column(record, param); !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+// sortRecords - orders by the "column:asc|desc" criteria of the allowed columns, the
+// other criteria are skipped as in the sql implementations. The records are ordered by
+// id before so the order is the same on every call
+func sortRecords[T any](
+	records []T,
+	sortBy []string,
+	allowed map[string]bool,
+	column columnFunc[T],
+	id func(T) int,
+) {
+	sort.Slice(records, func(i, j int) bool { return id(records[i]) < id(records[j]) })
+
+	type criteria struct {
+		column string
+		desc   bool
+	}
+	var order []criteria
+	for _, c := range sortBy {
+		parts := strings.Split(c, ":")
+		if len(parts) != 2 || !allowed[parts[0]] {
+			continue
+		}
+		switch strings.ToUpper(parts[1]) {
+		case "ASC":
+			order = append(order, criteria{column: parts[0]})
+		case "DESC":
+			order = append(order, criteria{column: parts[0], desc: true})
+		}
+	}
+	if len(order) == 0 {
+		return
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		for _, c := range order {
+			a, _ := column(records[i], c.column)
+			b, _ := column(records[j], c.column)
+			if a == b {
+				continue
+			}
+			if c.desc {
+				return a > b
+			}
+			return a < b
+		}
+		return false
+	})
+}
+
+// page - the records of the page, page and limit are defaulted like GetAllStudents does
+func page[T any](records []T, page, limit int) []T {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
+	start := (page - 1) * limit
+	if start >= len(records) {
+		return []T{}
+	}
+	end := min(start+limit, len(records))
+	return records[start:end]
+}
+
+func now() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
+
+// record - writes the revision of the change made with the context before the change is
+// made, the change fails when its revision can't be written. Without the history the
+// changes are not recorded
+func record(history dataops.ChangeHistoryInf, ctx context.Context, entity string, id int, action string, before, after any) error {
+	if history == nil {
+		return nil
+	}
+	return history.RecordRevision(dataops.NewRevision(ctx, entity, id, action, before, after))
+}
+
+// today - the date of the enrollments which are started without a date
+func today() string {
+	return time.Now().UTC().Format(time.DateOnly)
+}
+
+// classParam - the params without the class param and the ids of the classes with the
+// name of the class param, nil without the param. The classes are looked up before the
+// teachers or students are locked as the classes are locked first
+func classParam(params map[string]string, classes *Classes) (map[string]string, map[int]bool) {
+	name := params["class"]
+	if name == "" {
+		return params, nil
+	}
+	rest := make(map[string]string, len(params))
+	for param, value := range params {
+		if param != "class" {
+			rest[param] = value
+		}
+	}
+	if classes == nil {
+		return rest, map[int]bool{}
+	}
+	return rest, classes.named(name)
+}
@@ -0,0 +1,276 @@
+package memory
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/password"
+)
+
+// Store - the classes, subjects, teachers, students, enrollments, teaching assignments,
+// execs and their invitations of the in-memory repository, the repositories know each other
+// so the referenced records have to exist
+type Store struct {
+	Classes     *Classes
+	Subjects    *Subjects
+	Teachers    *Teachers
+	Students    *Students
+	Enrollments *Enrollments
+	Assignments *Assignments
+	Execs       *Execs
+	Invitations *Invitations
+}
+
+// Fixture - the records of the json file the store is loaded from, the records without
+// id get the next free id and the passwords of the execs are in plain text or argon2id
+// hashes. The students without active enrollment are enrolled in their class
+type Fixture struct {
+	Classes     []models.Class      `json:"classes"`
+	Subjects    []models.Subject    `json:"subjects"`
+	Teachers    []models.Teacher    `json:"teachers"`
+	Students    []models.Student    `json:"students"`
+	Enrollments []models.Enrollment `json:"enrollments"`
+	Assignments []models.Assignment `json:"assignments"`
+	Execs       []models.Exec       `json:"execs"`
+}
+
+// NewStore - the empty store
+func NewStore() *Store {
+	return link(
+		NewClasses(),
+		NewSubjects(),
+		NewTeachers(),
+		NewStudents(),
+		NewEnrollments(),
+		NewAssignments(),
+		NewExecs(),
+	)
+}
+
+// NewStoreFromFixture - the store with the records of the fixture, the fixture is
+// rejected when the records break the rules of the tables
+func NewStoreFromFixture(f Fixture) (*Store, error) {
+	if err := f.validate(); err != nil {
+		return nil, err
+	}
+	execs := make([]models.Exec, len(f.Execs))
+	for i, exec := range f.Execs {
+		if !strings.HasPrefix(exec.Password, "$argon2id$") {
+			hash, err := password.Hash(exec.Password, password.DefaultParams)
+			if err != nil {
+				return nil, err
+			}
+			exec.Password = hash
+		}
+		execs[i] = exec
+	}
+	store := link(
+		NewClasses(f.Classes...),
+		NewSubjects(f.Subjects...),
+		NewTeachers(f.Teachers...),
+		NewStudents(f.Students...),
+		NewEnrollments(f.Enrollments...),
+		NewAssignments(f.Assignments...),
+		NewExecs(execs...),
+	)
+	enrolled := store.Enrollments.activeClasses()
+	for id, student := range store.Students.students {
+		if _, ok := enrolled[id]; !ok {
+			store.Enrollments.start(id, student.ClassID, today())
+		}
+	}
+	return store, nil
+}
+
+// LoadFixture - the store with the records of the json fixture file
+func LoadFixture(path string) (*Store, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f Fixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("fixture %s: %w", path, err)
+	}
+	store, err := NewStoreFromFixture(f)
+	if err != nil {
+		return nil, fmt.Errorf("fixture %s: %w", path, err)
+	}
+	return store, nil
+}
+
+// SetHistory - the changes of the teachers, students and execs are written to the history,
+// the enrollment transfers and the activations of the execs too
+func (s *Store) SetHistory(history dataops.ChangeHistoryInf) {
+	s.Teachers.History = history
+	s.Students.History = history
+	s.Execs.History = history
+}
+
+func link(
+	classes *Classes,
+	subjects *Subjects,
+	teachers *Teachers,
+	students *Students,
+	enrollments *Enrollments,
+	assignments *Assignments,
+	execs *Execs,
+) *Store {
+	classes.Teachers = teachers
+	classes.Students = students
+	classes.Assignments = assignments
+	classes.Enrollments = enrollments
+	subjects.Assignments = assignments
+	teachers.Classes = classes
+	teachers.Students = students
+	teachers.Assignments = assignments
+	students.Classes = classes
+	students.Enrollments = enrollments
+	enrollments.Classes = classes
+	enrollments.Students = students
+	assignments.Classes = classes
+	assignments.Subjects = subjects
+	assignments.Teachers = teachers
+	invitations := NewInvitations()
+	invitations.Execs = execs
+	return &Store{
+		Classes:     classes,
+		Subjects:    subjects,
+		Teachers:    teachers,
+		Students:    students,
+		Enrollments: enrollments,
+		Assignments: assignments,
+		Execs:       execs,
+		Invitations: invitations,
+	}
+}
+
+// validate - the ids, emails, usernames, class and subject names are unique, the classes
+// of the teachers and students exist and the enrollments and teaching assignments
+// reference existing records, a student has at most one active enrollment in its class.
+// The classes, subjects and enrolled students need their ids in the fixture as the other
+// records reference them
+func (f Fixture) validate() error {
+	unique := func(kind, field string, values []string) error {
+		seen := map[string]bool{}
+		for _, v := range values {
+			if v == "" || v == "0" {
+				continue
+			}
+			if seen[v] {
+				return fmt.Errorf("duplicate %s %s %s", kind, field, v)
+			}
+			seen[v] = true
+		}
+		return nil
+	}
+
+	classes := map[int]bool{}
+	var classIDs, classNames []string
+	for _, c := range f.Classes {
+		if c.ID == 0 {
+			return fmt.Errorf("class %s needs an id", c.Name)
+		}
+		classes[c.ID] = true
+		classIDs = append(classIDs, fmt.Sprint(c.ID))
+		classNames = append(classNames, c.Name+" "+c.AcademicYear)
+	}
+	subjects := map[int]bool{}
+	var subjectIDs, subjectNames []string
+	for _, s := range f.Subjects {
+		if s.ID == 0 {
+			return fmt.Errorf("subject %s needs an id", s.Name)
+		}
+		subjects[s.ID] = true
+		subjectIDs = append(subjectIDs, fmt.Sprint(s.ID))
+		subjectNames = append(subjectNames, s.Name)
+	}
+	teachers := map[int]bool{}
+	var teacherIDs, teacherEmails []string
+	for _, t := range f.Teachers {
+		if !classes[t.ClassID] {
+			return fmt.Errorf("class %d of teacher %s not found", t.ClassID, t.Email)
+		}
+		teachers[t.ID] = true
+		teacherIDs = append(teacherIDs, fmt.Sprint(t.ID))
+		teacherEmails = append(teacherEmails, t.Email)
+	}
+	for _, c := range f.Classes {
+		if c.HomeroomTeacherID != 0 && !teachers[c.HomeroomTeacherID] {
+			return fmt.Errorf("homeroom teacher %d of class %s not found", c.HomeroomTeacherID, c.Name)
+		}
+	}
+	students := map[int]models.Student{}
+	var studentIDs, studentEmails []string
+	for _, s := range f.Students {
+		if !classes[s.ClassID] {
+			return fmt.Errorf("class %d of student %s not found", s.ClassID, s.Email)
+		}
+		if s.ID != 0 {
+			students[s.ID] = s
+		}
+		studentIDs = append(studentIDs, fmt.Sprint(s.ID))
+		studentEmails = append(studentEmails, s.Email)
+	}
+	var enrollmentIDs, activeStudents []string
+	for _, e := range f.Enrollments {
+		student, ok := students[e.StudentID]
+		if !ok || !classes[e.ClassID] {
+			return fmt.Errorf("student %d or class %d of enrollment not found", e.StudentID, e.ClassID)
+		}
+		enrollmentIDs = append(enrollmentIDs, fmt.Sprint(e.ID))
+		if e.Status == models.EnrollmentActive {
+			if e.ClassID != student.ClassID {
+				return fmt.Errorf("active enrollment of student %d is not in class %d", e.StudentID, student.ClassID)
+			}
+			activeStudents = append(activeStudents, fmt.Sprint(e.StudentID))
+		}
+	}
+	var assignmentIDs, assignmentKeys []string
+	for _, a := range f.Assignments {
+		if !teachers[a.TeacherID] || !subjects[a.SubjectID] || !classes[a.ClassID] {
+			return fmt.Errorf("teacher %d, subject %d or class %d of assignment not found", a.TeacherID, a.SubjectID, a.ClassID)
+		}
+		assignmentIDs = append(assignmentIDs, fmt.Sprint(a.ID))
+		assignmentKeys = append(assignmentKeys, fmt.Sprint(a.SubjectID, " ", a.ClassID, " ", a.Term))
+	}
+	var execIDs, execEmails, usernames []string
+	for _, e := range f.Execs {
+		if e.Username == "" || e.Password == "" {
+			return fmt.Errorf("exec %s needs a username and a password", e.Email)
+		}
+		execIDs = append(execIDs, fmt.Sprint(e.ID))
+		execEmails = append(execEmails, e.Email)
+		usernames = append(usernames, e.Username)
+	}
+
+	for _, check := range []struct {
+		kind, field string
+		values      []string
+	}{
+		{"class", "id", classIDs},
+		{"class", "name", classNames},
+		{"subject", "id", subjectIDs},
+		{"subject", "name", subjectNames},
+		{"teacher", "id", teacherIDs},
+		{"teacher", "email", teacherEmails},
+		{"student", "id", studentIDs},
+		{"student", "email", studentEmails},
+		{"enrollment", "id", enrollmentIDs},
+		{"active enrollment", "student", activeStudents},
+		{"assignment", "id", assignmentIDs},
+		{"assignment", "subject class term", assignmentKeys},
+		{"exec", "id", execIDs},
+		{"exec", "email", execEmails},
+		{"exec", "username", usernames},
+	} {
+		if err := unique(check.kind, check.field, check.values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,309 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/password"
+)
+
+func TestLoadFixture(t *testing.T) {
+	store, err := LoadFixture("testdata/school.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	students, total, err := store.Students.GetAllStudents(ctx, map[string]string{"class": "1A"}, nil, 1, 10, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 || students[0].FirstName != "Dan" {
+		t.Fatalf("Expected the 2 students of 1A, got %d %+v", total, students)
+	}
+	inClass, err := store.Teachers.GetStudentsByTeacherID(ctx, 101)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inClass) != 1 || inClass[0].Email != "fay@school.test" {
+		t.Fatalf("Expected Fay in the class of Bob, got %+v", inClass)
+	}
+	teachers, err := store.Classes.GetTeachersByClassID(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(teachers) != 1 || teachers[0].ID != 100 {
+		t.Fatalf("Expected Ann the teacher of 1A, got %+v", teachers)
+	}
+
+	subjects, total, err := store.Assignments.GetAllAssignments(ctx, map[string]string{"class_id": "2"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 || subjects[0].SubjectName != "Art" || subjects[1].TeacherName != "Ann Lee" {
+		t.Fatalf("Expected Art and Math taught in 2B, got %+v", subjects)
+	}
+
+	exec, err := store.Execs.GetLoginDetailsForUsername(ctx, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if match, _, err := password.Verify("Local-Admin-1", exec.Password, password.DefaultParams); err != nil || !match {
+		t.Fatalf("Expected the plain text password of the fixture hashed, got %v %v", match, err)
+	}
+}
+
+func TestFixtureRejected(t *testing.T) {
+	classes := []models.Class{{ID: 1, Name: "1A"}, {ID: 2, Name: "2B"}}
+	for name, f := range map[string]Fixture{
+		"duplicate teacher email": {Classes: classes, Teachers: []models.Teacher{
+			{Email: "a@school.test", ClassID: 1},
+			{Email: "a@school.test", ClassID: 2},
+		}},
+		"duplicate class name":     {Classes: []models.Class{{ID: 1, Name: "1A"}, {ID: 2, Name: "1A"}}},
+		"class without id":         {Classes: []models.Class{{Name: "1A"}}},
+		"unknown class":            {Classes: classes, Students: []models.Student{{Email: "s@school.test", ClassID: 9}}},
+		"unknown homeroom teacher": {Classes: []models.Class{{ID: 1, Name: "1A", HomeroomTeacherID: 100}}},
+		"duplicate assignment": {Classes: classes, Subjects: []models.Subject{{ID: 1, Name: "Art"}},
+			Teachers: []models.Teacher{{ID: 100, Email: "a@school.test", ClassID: 1}},
+			Assignments: []models.Assignment{
+				{TeacherID: 100, SubjectID: 1, ClassID: 1},
+				{TeacherID: 100, SubjectID: 1, ClassID: 1},
+			},
+		},
+		"unknown assignment subject": {Classes: classes,
+			Teachers:    []models.Teacher{{ID: 100, Email: "a@school.test", ClassID: 1}},
+			Assignments: []models.Assignment{{TeacherID: 100, SubjectID: 1, ClassID: 1}},
+		},
+		"unknown enrollment student": {Classes: classes,
+			Enrollments: []models.Enrollment{{StudentID: 100, ClassID: 1, Status: models.EnrollmentActive}},
+		},
+		"active enrollment in another class": {Classes: classes,
+			Students:    []models.Student{{ID: 100, Email: "s@school.test", ClassID: 1}},
+			Enrollments: []models.Enrollment{{StudentID: 100, ClassID: 2, Status: models.EnrollmentActive}},
+		},
+		"duplicate username": {Execs: []models.Exec{
+			{Email: "a@school.test", Username: "admin", Password: "x"},
+			{Email: "b@school.test", Username: "admin", Password: "x"},
+		}},
+	} {
+		if _, err := NewStoreFromFixture(f); err == nil {
+			t.Fatalf("Expected the fixture with %s rejected", name)
+		}
+	}
+}
+
+func TestClassForeignKey(t *testing.T) {
+	store := NewStore()
+	ctx := context.Background()
+
+	if _, err := store.Students.InsertStudents(ctx, &models.Student{Email: "s@school.test", ClassID: 1}); err == nil {
+		t.Fatal("Expected the student of the missing class rejected")
+	}
+	classID, err := store.Classes.InsertClass(ctx, &models.Class{Name: "1A", AcademicYear: "2025/2026"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Classes.InsertClass(ctx, &models.Class{Name: "1A", AcademicYear: "2025/2026"}); err == nil {
+		t.Fatal("Expected the duplicate class of the academic year rejected")
+	}
+	if _, err := store.Classes.InsertClass(ctx, &models.Class{Name: "2B", HomeroomTeacherID: 100}); err == nil {
+		t.Fatal("Expected the class with missing homeroom teacher rejected")
+	}
+	teacherID, err := store.Teachers.InsertTeachers(ctx, &models.Teacher{Email: "t@school.test", ClassID: int(classID)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	studentID, err := store.Students.InsertStudents(ctx, &models.Student{Email: "s@school.test", ClassID: int(classID)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Students.PatchiStudent(ctx, int(studentID), models.Student{ClassID: 9}); err == nil {
+		t.Fatal("Expected the move to the missing class rejected")
+	}
+	if _, err := store.Classes.PatchClass(ctx, int(classID), models.Class{HomeroomTeacherID: int(teacherID)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Classes.DeleteClass(ctx, int(classID)); err == nil || !strings.Contains(err.Error(), "still has") {
+		t.Fatalf("Expected the class with members kept, got %v", err)
+	}
+
+	// the soft deleted members keep the class until they are purged
+	if err := store.Teachers.DeleteTeacher(ctx, int(teacherID)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Students.DeleteStudent(ctx, int(studentID)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Classes.DeleteClass(ctx, int(classID)); err == nil {
+		t.Fatal("Expected the class of the deleted members kept")
+	}
+	before := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	if _, err := store.Students.PurgeDeleted(ctx, before); err != nil {
+		t.Fatal(err)
+	}
+	if purged, err := store.Teachers.PurgeDeleted(ctx, before); err != nil || purged != 1 {
+		t.Fatalf("Expected the teacher purged, got %d %v", purged, err)
+	}
+	if class, err := store.Classes.GetClassByID(ctx, int(classID)); err != nil || class.HomeroomTeacherID != 0 {
+		t.Fatalf("Expected the purged teacher removed as homeroom teacher, got %+v %v", class, err)
+	}
+	if err := store.Classes.DeleteClass(ctx, int(classID)); err != nil {
+		t.Fatalf("Expected the empty class deleted, got %v", err)
+	}
+}
+
+func TestAssignments(t *testing.T) {
+	store := NewStore()
+	ctx := context.Background()
+	classID, err := store.Classes.InsertClass(ctx, &models.Class{Name: "1A"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	subjectID, err := store.Subjects.InsertSubject(ctx, &models.Subject{Name: "Art"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	teacherID, err := store.Teachers.InsertTeachers(ctx, &models.Teacher{Email: "t@school.test", ClassID: int(classID)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assignment := models.Assignment{TeacherID: int(teacherID), SubjectID: int(subjectID), ClassID: int(classID), Term: "T1"}
+	if _, err := store.Assignments.InsertAssignment(ctx, &assignment); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Assignments.InsertAssignment(ctx, &assignment); err == nil {
+		t.Fatal("Expected the subject assigned twice in the class in the term rejected")
+	}
+	if _, err := store.Assignments.InsertAssignment(ctx, &models.Assignment{
+		TeacherID: int(teacherID), SubjectID: 9, ClassID: int(classID),
+	}); err == nil {
+		t.Fatal("Expected the assignment of the missing subject rejected")
+	}
+	if err := store.Subjects.DeleteSubject(ctx, int(subjectID)); err == nil || !strings.Contains(err.Error(), "still has") {
+		t.Fatalf("Expected the assigned subject kept, got %v", err)
+	}
+
+	if err := store.Teachers.DeleteTeacher(ctx, int(teacherID)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Teachers.PurgeDeleted(ctx, time.Now().Add(time.Hour).UTC().Format(time.RFC3339)); err != nil {
+		t.Fatal(err)
+	}
+	if _, total, _ := store.Assignments.GetAllAssignments(ctx, nil, nil); total != 0 {
+		t.Fatalf("Expected the assignments of the purged teacher removed, got %d", total)
+	}
+	if err := store.Classes.DeleteClass(ctx, int(classID)); err != nil {
+		t.Fatalf("Expected the class without assignments deleted, got %v", err)
+	}
+}
+
+func TestEnrollments(t *testing.T) {
+	store, err := NewStoreFromFixture(Fixture{
+		Classes: []models.Class{
+			{ID: 1, Name: "10B", AcademicYear: "2025/2026"},
+			{ID: 2, Name: "11B", AcademicYear: "2026/2027"},
+		},
+		Students: []models.Student{
+			{ID: 100, Email: "a@school.test", ClassID: 1},
+			{ID: 101, Email: "b@school.test", ClassID: 1},
+		},
+		Enrollments: []models.Enrollment{
+			{StudentID: 100, ClassID: 1, StartDate: "2025-09-01", Status: models.EnrollmentActive},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if history, _ := store.Enrollments.GetEnrollmentsByStudentID(ctx, 101); len(history) != 1 ||
+		history[0].ClassName != "10B" || history[0].StartDate != today() {
+		t.Fatalf("Expected the student without enrollment enrolled in 10B on load, got %+v", history)
+	}
+	if _, err := store.Enrollments.TransferStudent(ctx, 100, 2, "2025-01-01"); err == nil ||
+		!strings.Contains(err.Error(), "before the start") {
+		t.Fatalf("Expected the transfer before the enrollment rejected, got %v", err)
+	}
+	if _, err := store.Enrollments.EnrollStudent(ctx, 100, 2, "2026-09-01"); err == nil ||
+		!strings.Contains(err.Error(), "already enrolled") {
+		t.Fatalf("Expected the enrolled student rejected, got %v", err)
+	}
+	transferred, err := store.Enrollments.TransferStudent(ctx, 100, 2, "2026-09-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transferred.ClassName != "11B" || transferred.AcademicYear != "2026/2027" {
+		t.Fatalf("Expected the enrollment in 11B of 2026/2027, got %+v", transferred)
+	}
+	in10B, _, _ := store.Students.GetAllStudents(ctx, map[string]string{"class": "10B"}, nil, 1, 10, false)
+	if len(in10B) != 1 || in10B[0].ID != 101 {
+		t.Fatalf("Expected only the student 101 in 10B, got %+v", in10B)
+	}
+
+	if _, err := store.Enrollments.GraduateStudent(ctx, 100, "2027-06-30"); err != nil {
+		t.Fatal(err)
+	}
+	in11B, _, _ := store.Students.GetAllStudents(ctx, map[string]string{"class_id": "2"}, nil, 1, 10, false)
+	if len(in11B) != 0 {
+		t.Fatalf("Expected the graduated student in no class, got %+v", in11B)
+	}
+	if student, _ := store.Students.GetStudentByID(ctx, 100); student.ClassID != 2 {
+		t.Fatalf("Expected the graduated student to keep the last class, got %+v", student)
+	}
+	history, _ := store.Enrollments.GetEnrollmentsByStudentID(ctx, 100)
+	if len(history) != 2 || history[0].Status != models.EnrollmentTransferred || history[0].EndDate != "2026-09-01" ||
+		history[1].Status != models.EnrollmentGraduated {
+		t.Fatalf("Expected 10B transferred and 11B graduated, got %+v", history)
+	}
+
+	if _, err := store.Students.PatchiStudent(ctx, 101, models.Student{ClassID: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if in11B, _ = store.Classes.GetStudentsByClassID(ctx, 2); len(in11B) != 1 || in11B[0].ID != 101 {
+		t.Fatalf("Expected the student moved by the patch in 11B, got %+v", in11B)
+	}
+	if err := store.Classes.DeleteClass(ctx, 1); err == nil || !strings.Contains(err.Error(), "still has") {
+		t.Fatalf("Expected the class with enrollments kept, got %v", err)
+	}
+}
+
+// TestConcurrentWrites - the emails stay unique when the same records are written at once
+func TestConcurrentWrites(t *testing.T) {
+	store := NewStore()
+	ctx := context.Background()
+	classID, err := store.Classes.InsertClass(ctx, &models.Class{Name: "1A"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Teachers.InsertTeachers(ctx, &models.Teacher{Email: "t@school.test", ClassID: int(classID)}); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, _ = store.Students.InsertStudents(ctx, &models.Student{Email: fmt.Sprintf("s%d@school.test", i%10), ClassID: int(classID)})
+		}()
+		go func() {
+			defer wg.Done()
+			_, _ = store.Teachers.PatchTeacher(ctx, 100, models.Teacher{Subject: fmt.Sprint("Subject ", i)})
+		}()
+	}
+	wg.Wait()
+
+	students, total, err := store.Students.GetAllStudents(ctx, nil, []string{"email:asc"}, 1, 100, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 10 || !strings.HasPrefix(students[0].Email, "s0@") {
+		t.Fatalf("Expected the 10 unique students, got %d %+v", total, students)
+	}
+}
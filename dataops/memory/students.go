@@ -0,0 +1,374 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"strconv"
+	"sync"
+
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+)
+
+var _ dataops.StudentInf = (*Students)(nil)
+
+var studentSortColumns = map[string]bool{
+	"first_name": true,
+	"last_name":  true,
+	"email":      true,
+	"class_id":   true,
+}
+
+// Students - in-memory dataops.StudentInf, the emails are unique like in the table
+type Students struct {
+	// Classes - the class of the students has to exist like the foreign key of the table,
+	// without it any class is accepted
+	Classes *Classes
+	// Enrollments - the new student and the student moved to another class are enrolled
+	// in the class, the class filters use the active enrollments. Without it the class of
+	// the student is the class
+	Enrollments *Enrollments
+	// History - the revisions of the changed students, without it the changes are not recorded
+	History dataops.ChangeHistoryInf
+
+	mu       sync.Mutex
+	nextID   int
+	students map[int]models.Student
+}
+
+// NewStudents - the repository with the students, the ones without id get the next free id
+func NewStudents(students ...models.Student) *Students {
+	s := &Students{nextID: firstID, students: map[int]models.Student{}}
+	for _, student := range students {
+		if student.ID >= s.nextID {
+			s.nextID = student.ID + 1
+		}
+	}
+	for _, student := range students {
+		if student.ID == 0 {
+			student.ID = s.nextID
+			s.nextID++
+		}
+		s.students[student.ID] = student
+	}
+	return s
+}
+
+func studentColumn(s models.Student, column string) (string, bool) {
+	switch column {
+	case "first_name":
+		return s.FirstName, true
+	case "last_name":
+		return s.LastName, true
+	case "email":
+		return s.Email, true
+	case "class_id":
+		return strconv.Itoa(s.ClassID), true
+	}
+	return "", false
+}
+
+func studentID(s models.Student) int { return s.ID }
+
+func (s *Students) emailTaken(email string, exceptID int) bool {
+	for id, student := range s.students {
+		if id != exceptID && student.Email == email {
+			return true
+		}
+	}
+	return false
+}
+
+// lock - the classes are locked before the students like the classes do when they look
+// at the students, so the class can't go while the student is written
+func (s *Students) lock() func() {
+	if s.Classes != nil {
+		s.Classes.mu.Lock()
+	}
+	s.mu.Lock()
+	return func() {
+		s.mu.Unlock()
+		if s.Classes != nil {
+			s.Classes.mu.Unlock()
+		}
+	}
+}
+
+func (s *Students) classExists(id int) bool {
+	return s.Classes == nil || s.Classes.exists(id)
+}
+
+// inClass - any student is in the class, the deleted ones too
+func (s *Students) inClass(classID int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, student := range s.students {
+		if student.ClassID == classID {
+			return true
+		}
+	}
+	return false
+}
+
+// enroll - the student is enrolled in the class from today, the caller holds the lock
+func (s *Students) enroll(studentID, classID int) {
+	if s.Enrollments == nil {
+		return
+	}
+	s.Enrollments.mu.Lock()
+	defer s.Enrollments.mu.Unlock()
+	s.Enrollments.start(studentID, classID, today())
+}
+
+// classOf - the class of the active enrollment of the student, without the enrollments
+// the class of the student. The caller holds the lock
+func (s *Students) classOf() func(models.Student) (int, bool) {
+	if s.Enrollments == nil {
+		return func(student models.Student) (int, bool) { return student.ClassID, true }
+	}
+	classes := s.Enrollments.activeClasses()
+	return func(student models.Student) (int, bool) {
+		classID, ok := classes[student.ID]
+		return classID, ok
+	}
+}
+
+func (s *Students) active(id int) (models.Student, bool) {
+	student, ok := s.students[id]
+	return student, ok && student.DeletedAt == ""
+}
+
+// byClass - the not deleted students enrolled in the class ordered by id
+func (s *Students) byClass(classID int) []models.Student {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	classOf := s.classOf()
+	students := make([]models.Student, 0)
+	for _, student := range s.students {
+		if current, ok := classOf(student); student.DeletedAt == "" && ok && current == classID {
+			students = append(students, student)
+		}
+	}
+	sortRecords(students, nil, nil, studentColumn, studentID)
+	return students
+}
+
+func (s *Students) InsertStudents(ctx context.Context, student *models.Student) (int64, error) {
+	defer s.lock()()
+
+	if s.emailTaken(student.Email, 0) {
+		return 0, fmt.Errorf("duplicate email %s", student.Email)
+	}
+	if !s.classExists(student.ClassID) {
+		return 0, fmt.Errorf("class %d not found", student.ClassID)
+	}
+	stored := *student
+	stored.ID = s.nextID
+	stored.DeletedAt = ""
+	if err := record(s.History, ctx, models.EntityStudent, stored.ID, models.ChangeCreate, nil, stored); err != nil {
+		return 0, err
+	}
+	s.nextID++
+	s.students[stored.ID] = stored
+	s.enroll(stored.ID, stored.ClassID)
+	return int64(stored.ID), nil
+}
+
+func (s *Students) GetStudentByID(_ context.Context, id int) (models.Student, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	student, ok := s.active(id)
+	if !ok {
+		return models.Student{}, errors.New("student not found")
+	}
+	return student, nil
+}
+
+// GetAllStudents - one page of the matching students ordered by first name by default
+// and the count of all matching students, the class param is the name of the class. The
+// class and class_id params match the class of the active enrollment
+func (s *Students) GetAllStudents(
+	_ context.Context,
+	params map[string]string,
+	sortBy []string,
+	pageNumber, limit int,
+	includeDeleted bool,
+) ([]models.Student, int, error) {
+	params, classes := classParam(params, s.Classes)
+	classIDParam := params["class_id"]
+	if classIDParam != "" {
+		params = maps.Clone(params)
+		delete(params, "class_id")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	classOf := s.classOf()
+	students := make([]models.Student, 0)
+	for _, student := range s.students {
+		if student.DeletedAt != "" && !includeDeleted {
+			continue
+		}
+		current, enrolled := classOf(student)
+		if (classes != nil || classIDParam != "") && !enrolled {
+			continue
+		}
+		if classes != nil && !classes[current] {
+			continue
+		}
+		if classIDParam != "" && strconv.Itoa(current) != classIDParam {
+			continue
+		}
+		if matches(student, params, studentColumn) {
+			students = append(students, student)
+		}
+	}
+	if len(sortBy) == 0 {
+		sortBy = []string{"first_name:asc"}
+	}
+	sortRecords(students, sortBy, studentSortColumns, studentColumn, studentID)
+	return page(students, pageNumber, limit), len(students), nil
+}
+
+// UpdateStudent - the change of the class is a transfer of the student to the class from
+// today
+func (s *Students) UpdateStudent(ctx context.Context, id int, updated models.Student) (models.Student, error) {
+	defer s.lock()()
+
+	existing, ok := s.active(id)
+	if !ok {
+		return models.Student{}, errors.New("sql error")
+	}
+	if s.emailTaken(updated.Email, id) || !s.classExists(updated.ClassID) {
+		return models.Student{}, errors.New("error student database error")
+	}
+	updated.ID = id
+	updated.DeletedAt = ""
+	if err := record(s.History, ctx, models.EntityStudent, id, models.ChangeUpdate, existing, updated); err != nil {
+		return models.Student{}, err
+	}
+	s.students[id] = updated
+	if updated.ClassID != existing.ClassID {
+		s.enroll(id, updated.ClassID)
+	}
+	return updated, nil
+}
+
+func (s *Students) PatchiStudent(ctx context.Context, id int, updated models.Student) (models.Student, error) {
+	defer s.lock()()
+
+	before, ok := s.active(id)
+	student := before
+	if !ok {
+		return models.Student{}, errors.New("unable to retreive data")
+	}
+	if updated.Email != "" && s.emailTaken(updated.Email, id) {
+		return models.Student{}, errors.New("Error updating student")
+	}
+	if updated.ClassID != 0 && !s.classExists(updated.ClassID) {
+		return models.Student{}, errors.New("Error updating student")
+	}
+	if updated.FirstName != "" {
+		student.FirstName = updated.FirstName
+	}
+	if updated.LastName != "" {
+		student.LastName = updated.LastName
+	}
+	if updated.Email != "" {
+		student.Email = updated.Email
+	}
+	if updated.ClassID != 0 {
+		student.ClassID = updated.ClassID
+	}
+	if err := record(s.History, ctx, models.EntityStudent, id, models.ChangeUpdate, before, student); err != nil {
+		return models.Student{}, err
+	}
+	if student.ClassID != before.ClassID {
+		s.enroll(id, student.ClassID)
+	}
+	s.students[id] = student
+	return student, nil
+}
+
+func (s *Students) DeleteStudent(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	student, ok := s.active(id)
+	if !ok {
+		return errors.New("Student not found")
+	}
+	if err := record(s.History, ctx, models.EntityStudent, id, models.ChangeDelete, student, nil); err != nil {
+		return err
+	}
+	student.DeletedAt = now()
+	s.students[id] = student
+	return nil
+}
+
+// DeleteBulkStudents - none of the students is deleted when any id does not exist
+func (s *Students) DeleteBulkStudents(ctx context.Context, ids []int) ([]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range ids {
+		if _, ok := s.active(id); !ok {
+			return nil, errors.New("ID does not exists,  doing rollback...")
+		}
+	}
+	if len(ids) == 0 {
+		return nil, errors.New("none of the id exists")
+	}
+	for _, id := range ids {
+		if err := record(s.History, ctx, models.EntityStudent, id, models.ChangeDelete, s.students[id], nil); err != nil {
+			return nil, err
+		}
+	}
+	deletedAt := now()
+	for _, id := range ids {
+		student := s.students[id]
+		student.DeletedAt = deletedAt
+		s.students[id] = student
+	}
+	return ids, nil
+}
+
+func (s *Students) RestoreStudent(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	student, ok := s.students[id]
+	if !ok || student.DeletedAt == "" {
+		return errors.New("Deleted student not found")
+	}
+	student.DeletedAt = ""
+	if err := record(s.History, ctx, models.EntityStudent, id, models.ChangeUndelete, nil, student); err != nil {
+		return err
+	}
+	s.students[id] = student
+	return nil
+}
+
+// PurgeDeleted - the enrollments of the purged students go with them
+func (s *Students) PurgeDeleted(_ context.Context, before string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := map[int]bool{}
+	for id, student := range s.students {
+		if student.DeletedAt != "" && student.DeletedAt < before {
+			delete(s.students, id)
+			purged[id] = true
+		}
+	}
+	if s.Enrollments != nil {
+		s.Enrollments.removeStudents(purged)
+	}
+	return int64(len(purged)), nil
+}
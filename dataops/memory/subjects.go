@@ -0,0 +1,169 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+)
+
+var _ dataops.SubjectsInf = (*Subjects)(nil)
+
+var subjectSortColumns = map[string]bool{
+	"name": true,
+	"code": true,
+}
+
+// Subjects - in-memory dataops.SubjectsInf, the names are unique like in the table. The
+// subjects are locked after the classes and before the teachers
+type Subjects struct {
+	// Assignments - a subject with teaching assignments can't be deleted
+	Assignments *Assignments
+
+	mu       sync.Mutex
+	nextID   int
+	subjects map[int]models.Subject
+}
+
+// NewSubjects - the repository with the subjects, the ones without id get the next free id
+func NewSubjects(subjects ...models.Subject) *Subjects {
+	s := &Subjects{nextID: 1, subjects: map[int]models.Subject{}}
+	for _, subject := range subjects {
+		if subject.ID >= s.nextID {
+			s.nextID = subject.ID + 1
+		}
+	}
+	for _, subject := range subjects {
+		if subject.ID == 0 {
+			subject.ID = s.nextID
+			s.nextID++
+		}
+		s.subjects[subject.ID] = subject
+	}
+	return s
+}
+
+func subjectColumn(s models.Subject, column string) (string, bool) {
+	switch column {
+	case "name":
+		return s.Name, true
+	case "code":
+		return s.Code, true
+	}
+	return "", false
+}
+
+func subjectID(s models.Subject) int { return s.ID }
+
+func (s *Subjects) nameTaken(name string, exceptID int) bool {
+	for id, subject := range s.subjects {
+		if id != exceptID && subject.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Subjects) InsertSubject(_ context.Context, subject *models.Subject) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.nameTaken(subject.Name, 0) {
+		return 0, fmt.Errorf("duplicate subject %s", subject.Name)
+	}
+	stored := *subject
+	stored.ID = s.nextID
+	s.nextID++
+	s.subjects[stored.ID] = stored
+	return int64(stored.ID), nil
+}
+
+func (s *Subjects) GetSubjectByID(_ context.Context, id int) (models.Subject, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subject, ok := s.subjects[id]
+	if !ok {
+		return models.Subject{}, errors.New("subject not found")
+	}
+	return subject, nil
+}
+
+// GetAllSubjects - the matching subjects ordered by name by default and their count
+func (s *Subjects) GetAllSubjects(
+	_ context.Context,
+	params map[string]string,
+	sortBy []string,
+) ([]models.Subject, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subjects := make([]models.Subject, 0)
+	for _, subject := range s.subjects {
+		if matches(subject, params, subjectColumn) {
+			subjects = append(subjects, subject)
+		}
+	}
+	if len(sortBy) == 0 {
+		sortBy = []string{"name:asc"}
+	}
+	sortRecords(subjects, sortBy, subjectSortColumns, subjectColumn, subjectID)
+	return subjects, len(subjects), nil
+}
+
+func (s *Subjects) UpdateSubject(_ context.Context, id int, updated models.Subject) (models.Subject, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.subjects[id]; !ok {
+		return models.Subject{}, errors.New("subject not found")
+	}
+	if s.nameTaken(updated.Name, id) {
+		return models.Subject{}, errors.New("error subject database error")
+	}
+	updated.ID = id
+	s.subjects[id] = updated
+	return updated, nil
+}
+
+func (s *Subjects) PatchSubject(_ context.Context, id int, updated models.Subject) (models.Subject, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subject, ok := s.subjects[id]
+	if !ok {
+		return models.Subject{}, errors.New("subject not found")
+	}
+	if updated.Name != "" && s.nameTaken(updated.Name, id) {
+		return models.Subject{}, errors.New("error subject database error")
+	}
+	if updated.Name != "" {
+		subject.Name = updated.Name
+	}
+	if updated.Code != "" {
+		subject.Code = updated.Code
+	}
+	if updated.Description != "" {
+		subject.Description = updated.Description
+	}
+	s.subjects[id] = subject
+	return subject, nil
+}
+
+// DeleteSubject - the subject with teaching assignments is kept
+func (s *Subjects) DeleteSubject(_ context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.subjects[id]; !ok {
+		return errors.New("Subject not found")
+	}
+	if s.Assignments != nil && s.Assignments.references(func(a models.Assignment) bool { return a.SubjectID == id }) {
+		return errors.New("Subject still has teaching assignments")
+	}
+	delete(s.subjects, id)
+	return nil
+}
@@ -0,0 +1,353 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+)
+
+var _ dataops.TeachersInf = (*Teachers)(nil)
+
+var teacherSortColumns = map[string]bool{
+	"first_name": true,
+	"last_name":  true,
+	"email":      true,
+	"class_id":   true,
+	"subject":    true,
+}
+
+// Teachers - in-memory dataops.TeachersInf, the emails are unique like in the table
+type Teachers struct {
+	// Classes - the class of the teachers has to exist like the foreign key of the table,
+	// without it any class is accepted
+	Classes *Classes
+	// Students - used by GetStudentsByTeacherID, without it the teachers have no students
+	Students *Students
+	// Assignments - the teaching assignments of the purged teachers are removed
+	Assignments *Assignments
+	// History - the revisions of the changed teachers, without it the changes are not recorded
+	History dataops.ChangeHistoryInf
+
+	mu       sync.Mutex
+	nextID   int
+	teachers map[int]models.Teacher
+}
+
+// NewTeachers - the repository with the teachers, the ones without id get the next free id
+func NewTeachers(teachers ...models.Teacher) *Teachers {
+	t := &Teachers{nextID: firstID, teachers: map[int]models.Teacher{}}
+	for _, teacher := range teachers {
+		if teacher.ID >= t.nextID {
+			t.nextID = teacher.ID + 1
+		}
+	}
+	for _, teacher := range teachers {
+		if teacher.ID == 0 {
+			teacher.ID = t.nextID
+			t.nextID++
+		}
+		t.teachers[teacher.ID] = teacher
+	}
+	return t
+}
+
+func teacherColumn(t models.Teacher, column string) (string, bool) {
+	switch column {
+	case "first_name":
+		return t.FirstName, true
+	case "last_name":
+		return t.LastName, true
+	case "email":
+		return t.Email, true
+	case "class_id":
+		return strconv.Itoa(t.ClassID), true
+	case "subject":
+		return t.Subject, true
+	}
+	return "", false
+}
+
+func teacherID(t models.Teacher) int { return t.ID }
+
+// emailTaken - the deleted teachers keep their email until they are purged
+func (t *Teachers) emailTaken(email string, exceptID int) bool {
+	for id, teacher := range t.teachers {
+		if id != exceptID && teacher.Email == email {
+			return true
+		}
+	}
+	return false
+}
+
+// lock - the classes are locked before the teachers like the classes do when they look
+// at the teachers, so the class can't go while the teacher is written
+func (t *Teachers) lock() func() {
+	if t.Classes != nil {
+		t.Classes.mu.Lock()
+	}
+	t.mu.Lock()
+	return func() {
+		t.mu.Unlock()
+		if t.Classes != nil {
+			t.Classes.mu.Unlock()
+		}
+	}
+}
+
+func (t *Teachers) classExists(id int) bool {
+	return t.Classes == nil || t.Classes.exists(id)
+}
+
+// exists - the teacher is in the table, the deleted ones too
+func (t *Teachers) exists(id int) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, ok := t.teachers[id]
+	return ok
+}
+
+// inClass - any teacher is in the class, the deleted ones too
+func (t *Teachers) inClass(classID int) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, teacher := range t.teachers {
+		if teacher.ClassID == classID {
+			return true
+		}
+	}
+	return false
+}
+
+// byClass - the not deleted teachers of the class ordered by id
+func (t *Teachers) byClass(classID int) []models.Teacher {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	teachers := make([]models.Teacher, 0)
+	for _, teacher := range t.teachers {
+		if teacher.DeletedAt == "" && teacher.ClassID == classID {
+			teachers = append(teachers, teacher)
+		}
+	}
+	sortRecords(teachers, nil, nil, teacherColumn, teacherID)
+	return teachers
+}
+
+func (t *Teachers) active(id int) (models.Teacher, bool) {
+	teacher, ok := t.teachers[id]
+	return teacher, ok && teacher.DeletedAt == ""
+}
+
+func (t *Teachers) InsertTeachers(ctx context.Context, teacher *models.Teacher) (int64, error) {
+	defer t.lock()()
+
+	if t.emailTaken(teacher.Email, 0) {
+		return 0, fmt.Errorf("duplicate email %s", teacher.Email)
+	}
+	if !t.classExists(teacher.ClassID) {
+		return 0, fmt.Errorf("class %d not found", teacher.ClassID)
+	}
+	stored := *teacher
+	stored.ID = t.nextID
+	stored.DeletedAt = ""
+	if err := record(t.History, ctx, models.EntityTeacher, stored.ID, models.ChangeCreate, nil, stored); err != nil {
+		return 0, err
+	}
+	t.nextID++
+	t.teachers[stored.ID] = stored
+	return int64(stored.ID), nil
+}
+
+func (t *Teachers) GetTeacherByID(_ context.Context, id int) (models.Teacher, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	teacher, ok := t.active(id)
+	if !ok {
+		return models.Teacher{}, errors.New("teacher not found")
+	}
+	return teacher, nil
+}
+
+// GetAllTeachers - the matching teachers and their count, the class param is the name of
+// the class
+func (t *Teachers) GetAllTeachers(
+	_ context.Context,
+	params map[string]string,
+	sortBy []string,
+	includeDeleted bool,
+) ([]models.Teacher, int, error) {
+	params, classes := classParam(params, t.Classes)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	teachers := make([]models.Teacher, 0)
+	for _, teacher := range t.teachers {
+		if teacher.DeletedAt != "" && !includeDeleted {
+			continue
+		}
+		if classes != nil && !classes[teacher.ClassID] {
+			continue
+		}
+		if matches(teacher, params, teacherColumn) {
+			teachers = append(teachers, teacher)
+		}
+	}
+	sortRecords(teachers, sortBy, teacherSortColumns, teacherColumn, teacherID)
+	return teachers, len(teachers), nil
+}
+
+func (t *Teachers) UpdateTeacher(ctx context.Context, id int, updated models.Teacher) (models.Teacher, error) {
+	defer t.lock()()
+
+	teacher, ok := t.active(id)
+	if !ok {
+		return models.Teacher{}, errors.New("sql error")
+	}
+	if t.emailTaken(updated.Email, id) || !t.classExists(updated.ClassID) {
+		return models.Teacher{}, errors.New("error teacher database error")
+	}
+	updated.ID = id
+	updated.DeletedAt = ""
+	if err := record(t.History, ctx, models.EntityTeacher, id, models.ChangeUpdate, teacher, updated); err != nil {
+		return models.Teacher{}, err
+	}
+	t.teachers[id] = updated
+	return updated, nil
+}
+
+func (t *Teachers) PatchTeacher(ctx context.Context, id int, updated models.Teacher) (models.Teacher, error) {
+	defer t.lock()()
+
+	before, ok := t.active(id)
+	teacher := before
+	if !ok {
+		return models.Teacher{}, errors.New("unable to retreive data")
+	}
+	if updated.Email != "" && t.emailTaken(updated.Email, id) {
+		return models.Teacher{}, errors.New("Error updating teacher")
+	}
+	if updated.ClassID != 0 && !t.classExists(updated.ClassID) {
+		return models.Teacher{}, errors.New("Error updating teacher")
+	}
+	if updated.FirstName != "" {
+		teacher.FirstName = updated.FirstName
+	}
+	if updated.LastName != "" {
+		teacher.LastName = updated.LastName
+	}
+	if updated.Email != "" {
+		teacher.Email = updated.Email
+	}
+	if updated.ClassID != 0 {
+		teacher.ClassID = updated.ClassID
+	}
+	if updated.Subject != "" {
+		teacher.Subject = updated.Subject
+	}
+	if err := record(t.History, ctx, models.EntityTeacher, id, models.ChangeUpdate, before, teacher); err != nil {
+		return models.Teacher{}, err
+	}
+	t.teachers[id] = teacher
+	return teacher, nil
+}
+
+func (t *Teachers) DeleteTeacher(ctx context.Context, id int) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	teacher, ok := t.active(id)
+	if !ok {
+		return errors.New("Teacher not found")
+	}
+	if err := record(t.History, ctx, models.EntityTeacher, id, models.ChangeDelete, teacher, nil); err != nil {
+		return err
+	}
+	teacher.DeletedAt = now()
+	t.teachers[id] = teacher
+	return nil
+}
+
+// DeleteBulkTeachers - none of the teachers is deleted when any id does not exist
+func (t *Teachers) DeleteBulkTeachers(ctx context.Context, ids []int) ([]int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, id := range ids {
+		if _, ok := t.active(id); !ok {
+			return nil, errors.New("ID does not exists,  doing rollback...")
+		}
+	}
+	if len(ids) == 0 {
+		return nil, errors.New("none of the id exists")
+	}
+	for _, id := range ids {
+		if err := record(t.History, ctx, models.EntityTeacher, id, models.ChangeDelete, t.teachers[id], nil); err != nil {
+			return nil, err
+		}
+	}
+	deletedAt := now()
+	for _, id := range ids {
+		teacher := t.teachers[id]
+		teacher.DeletedAt = deletedAt
+		t.teachers[id] = teacher
+	}
+	return ids, nil
+}
+
+func (t *Teachers) GetStudentsByTeacherID(_ context.Context, id int) ([]models.Student, error) {
+	t.mu.Lock()
+	teacher, ok := t.active(id)
+	t.mu.Unlock()
+	if !ok || t.Students == nil {
+		return nil, nil
+	}
+	return t.Students.byClass(teacher.ClassID), nil
+}
+
+func (t *Teachers) RestoreTeacher(ctx context.Context, id int) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	teacher, ok := t.teachers[id]
+	if !ok || teacher.DeletedAt == "" {
+		return errors.New("Deleted teacher not found")
+	}
+	teacher.DeletedAt = ""
+	if err := record(t.History, ctx, models.EntityTeacher, id, models.ChangeUndelete, nil, teacher); err != nil {
+		return err
+	}
+	t.teachers[id] = teacher
+	return nil
+}
+
+// PurgeDeleted - the purged teachers are removed as homeroom teachers of their classes
+// and their teaching assignments are removed
+func (t *Teachers) PurgeDeleted(_ context.Context, before string) (int64, error) {
+	defer t.lock()()
+
+	purge := map[int]bool{}
+	for id, teacher := range t.teachers {
+		if teacher.DeletedAt != "" && teacher.DeletedAt < before {
+			purge[id] = true
+		}
+	}
+	for id := range purge {
+		delete(t.teachers, id)
+	}
+	if t.Classes != nil {
+		t.Classes.clearHomeroom(purge)
+	}
+	if t.Assignments != nil {
+		t.Assignments.removeTeachers(purge)
+	}
+	return int64(len(purge)), nil
+}
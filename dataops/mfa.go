@@ -0,0 +1,173 @@
+package dataops
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/repository/sqlconnect"
+)
+
+type MFA struct {
+	db     *sqlconnect.DB
+	logger *logging.Logger
+}
+
+func NewMFADB(db *sqlconnect.DB, logger *logging.Logger) *MFA {
+	return &MFA{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// GetMFA - returns not enabled mfa with the exec id when the exec has no enrollment
+func (m *MFA) GetMFA(execID int) (models.ExecMFA, error) {
+	mfa := models.ExecMFA{ExecID: execID}
+	err := m.db.QueryRow("SELECT secret, enabled, last_used_step FROM exec_mfa WHERE exec_id = ?", execID).
+		Scan(&mfa.Secret, &mfa.Enabled, &mfa.LastUsedStep)
+	if err == sql.ErrNoRows {
+		return mfa, nil
+	} else if err != nil {
+		m.logger.Logging.Debugf("error quering the mfa %v", err)
+		return models.ExecMFA{}, m.logger.ErrorMessage("database error")
+	}
+	return mfa, nil
+}
+
+// SaveMFASecret - stores new not yet confirmed secret, it replaces the old enrollment
+func (m *MFA) SaveMFASecret(execID int, secret string) error {
+	_, err := m.db.Exec(
+		"INSERT INTO exec_mfa (exec_id, secret, enabled, last_used_step) VALUES (?,?,FALSE,0) "+
+			m.db.Dialect.Upsert("exec_id", "secret", "enabled", "last_used_step"),
+		execID,
+		secret,
+	)
+	if err != nil {
+		m.logger.Logging.Debugf("error saving the mfa secret %v", err)
+		return m.logger.ErrorMessage("database error")
+	}
+	return nil
+}
+
+// EnableMFA - enables the confirmed secret and replaces the recovery codes
+func (m *MFA) EnableMFA(execID int, step int64, recoveryCodeHashes []string) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return m.logger.ErrorLogger(err, "Error starting Transaction")
+	}
+	_, err = tx.Exec(
+		"UPDATE exec_mfa SET enabled = TRUE, last_used_step = ? WHERE exec_id = ?",
+		step,
+		execID,
+	)
+	if err != nil {
+		_ = tx.Rollback()
+		m.logger.Logging.Debugf("error enabling mfa %v", err)
+		return m.logger.ErrorMessage("database error")
+	}
+	if err := replaceRecoveryCodes(tx, execID, recoveryCodeHashes); err != nil {
+		_ = tx.Rollback()
+		m.logger.Logging.Debugf("error storing recovery codes %v", err)
+		return m.logger.ErrorMessage("database error")
+	}
+	if err := tx.Commit(); err != nil {
+		m.logger.Logging.Debugf("error commiting the transaction %v", err)
+		return m.logger.ErrorMessage("database error")
+	}
+	return nil
+}
+
+func (m *MFA) DisableMFA(execID int) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return m.logger.ErrorLogger(err, "Error starting Transaction")
+	}
+	if _, err := tx.Exec("DELETE FROM exec_mfa_recovery_codes WHERE exec_id = ?", execID); err != nil {
+		_ = tx.Rollback()
+		m.logger.Logging.Debugf("error deleting recovery codes %v", err)
+		return m.logger.ErrorMessage("database error")
+	}
+	if _, err := tx.Exec("DELETE FROM exec_mfa WHERE exec_id = ?", execID); err != nil {
+		_ = tx.Rollback()
+		m.logger.Logging.Debugf("error deleting mfa %v", err)
+		return m.logger.ErrorMessage("database error")
+	}
+	if err := tx.Commit(); err != nil {
+		m.logger.Logging.Debugf("error commiting the transaction %v", err)
+		return m.logger.ErrorMessage("database error")
+	}
+	return nil
+}
+
+// UseMFAStep - marks the time step as used, false when it or a later step was already used
+func (m *MFA) UseMFAStep(execID int, step int64) (bool, error) {
+	result, err := m.db.Exec(
+		"UPDATE exec_mfa SET last_used_step = ? WHERE exec_id = ? AND last_used_step < ?",
+		step,
+		execID,
+		step,
+	)
+	if err != nil {
+		m.logger.Logging.Debugf("error updating the mfa step %v", err)
+		return false, m.logger.ErrorMessage("database error")
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, m.logger.ErrorMessage("database error")
+	}
+	return rowsAffected > 0, nil
+}
+
+func (m *MFA) ReplaceRecoveryCodes(execID int, recoveryCodeHashes []string) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return m.logger.ErrorLogger(err, "Error starting Transaction")
+	}
+	if err := replaceRecoveryCodes(tx, execID, recoveryCodeHashes); err != nil {
+		_ = tx.Rollback()
+		m.logger.Logging.Debugf("error storing recovery codes %v", err)
+		return m.logger.ErrorMessage("database error")
+	}
+	if err := tx.Commit(); err != nil {
+		m.logger.Logging.Debugf("error commiting the transaction %v", err)
+		return m.logger.ErrorMessage("database error")
+	}
+	return nil
+}
+
+// UseRecoveryCode - marks the recovery code as used, false when it does not exists or is used
+func (m *MFA) UseRecoveryCode(execID int, hashedCode string) (bool, error) {
+	result, err := m.db.Exec(
+		"UPDATE exec_mfa_recovery_codes SET used_at = ? WHERE exec_id = ? AND code_hash = ? AND used_at IS NULL",
+		time.Now().Format(time.RFC3339),
+		execID,
+		hashedCode,
+	)
+	if err != nil {
+		m.logger.Logging.Debugf("error using the recovery code %v", err)
+		return false, m.logger.ErrorMessage("database error")
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, m.logger.ErrorMessage("database error")
+	}
+	return rowsAffected > 0, nil
+}
+
+func replaceRecoveryCodes(tx *sqlconnect.Tx, execID int, recoveryCodeHashes []string) error {
+	if _, err := tx.Exec("DELETE FROM exec_mfa_recovery_codes WHERE exec_id = ?", execID); err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare("INSERT INTO exec_mfa_recovery_codes (exec_id, code_hash) VALUES (?,?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, hash := range recoveryCodeHashes {
+		if _, err := stmt.Exec(execID, hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
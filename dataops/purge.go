@@ -0,0 +1,87 @@
+package dataops
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
+)
+
+// DeletedPurger - store which can remove for good its soft deleted records
+type DeletedPurger interface {
+	PurgeDeleted(context.Context, string) (int64, error)
+}
+
+type purgeStore struct {
+	name  string
+	store DeletedPurger
+}
+
+// Purge - removes the records soft deleted longer than the retention, the stores are
+// purged in the order they are added
+type Purge struct {
+	retention time.Duration
+	logger    *logging.Logger
+	stores    []purgeStore
+	stop      chan struct{}
+	stopOnce  sync.Once
+	wg        sync.WaitGroup
+}
+
+func NewPurge(retention time.Duration, logger *logging.Logger) *Purge {
+	return &Purge{
+		retention: retention,
+		logger:    logger,
+		stop:      make(chan struct{}),
+	}
+}
+
+// Add - adds the store to the purge
+func (p *Purge) Add(name string, store DeletedPurger) *Purge {
+	p.stores = append(p.stores, purgeStore{name: name, store: store})
+	return p
+}
+
+// Run - purges all stores once, the failed store does not stop the others
+func (p *Purge) Run(now time.Time) int64 {
+	before := now.Add(-p.retention).UTC().Format(time.RFC3339)
+	var total int64
+	for _, s := range p.stores {
+		n, err := s.store.PurgeDeleted(context.Background(), before)
+		if err != nil {
+			p.logger.Logging.Errorf("failed to purge the deleted %s %v", s.name, err)
+			continue
+		}
+		if n > 0 {
+			p.logger.Logging.Infof("purged %d deleted %s", n, s.name)
+		}
+		total += n
+	}
+	return total
+}
+
+// Start - runs the purge now and then every interval until Stop
+func (p *Purge) Start(interval time.Duration) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		p.Run(time.Now())
+		for {
+			select {
+			case <-ticker.C:
+				p.Run(time.Now())
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop - stops the scheduled purge and waits for the running one
+func (p *Purge) Stop() {
+	p.stopOnce.Do(func() { close(p.stop) })
+	p.wg.Wait()
+}
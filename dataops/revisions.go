@@ -0,0 +1,155 @@
+package dataops
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+)
+
+type (
+	actorKey    struct{}
+	restoredKey struct{}
+)
+
+// WithActor - the context of the changes made by the exec, the revisions written with it
+// name the exec. The changes without actor are made by schoolctl or the server itself
+func WithActor(ctx context.Context, execID int) context.Context {
+	return context.WithValue(ctx, actorKey{}, execID)
+}
+
+// WithRestoredRevision - the context of the update which sets the record back to its
+// state after the revision, the update is written as the restore of the revision
+func WithRestoredRevision(ctx context.Context, revisionID int) context.Context {
+	return context.WithValue(ctx, restoredKey{}, revisionID)
+}
+
+// ExecRevision - the fields of the exec kept in the change history, the password and the
+// tokens are never written there, only the time the password was changed
+type ExecRevision struct {
+	ID                int    `json:"id"`
+	FirstName         string `json:"first_name"`
+	LastName          string `json:"last_name"`
+	Email             string `json:"email"`
+	Username          string `json:"username"`
+	InactiveStatus    bool   `json:"inactive_status"`
+	Role              string `json:"role"`
+	PasswordChangedAt string `json:"password_changed_at,omitempty"`
+}
+
+func NewExecRevision(exec models.Exec) ExecRevision {
+	return ExecRevision{
+		ID:                exec.ID,
+		FirstName:         exec.FirstName,
+		LastName:          exec.LastName,
+		Email:             exec.Email,
+		Username:          exec.Username,
+		InactiveStatus:    exec.InactiveStatus,
+		Role:              exec.Role,
+		PasswordChangedAt: exec.PasswordChangedAt.String,
+	}
+}
+
+// NewRevision - the revision of the change of the record made with the context, before is
+// nil for the created record and after for the deleted one. The update made with
+// WithRestoredRevision is the restore of the revision
+func NewRevision(ctx context.Context, entity string, entityID int, action string, before, after any) models.Revision {
+	rev := models.Revision{
+		Entity:    entity,
+		EntityID:  entityID,
+		Action:    action,
+		Before:    marshalState(before),
+		After:     marshalState(after),
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	rev.Changes = diffStates(rev.Before, rev.After)
+	if actorID, ok := ctx.Value(actorKey{}).(int); ok {
+		rev.ActorID = actorID
+	}
+	if restored, ok := ctx.Value(restoredKey{}).(int); ok && action == models.ChangeUpdate {
+		rev.Action = models.ChangeRestore
+		rev.RestoredFrom = restored
+	}
+	return rev
+}
+
+// diffStates - the fields which differ between the two json states, the missing
+// state or field counts as null
+func diffStates(before, after json.RawMessage) []models.FieldChange {
+	from, to := map[string]any{}, map[string]any{}
+	if len(before) > 0 {
+		_ = json.Unmarshal(before, &from)
+	}
+	if len(after) > 0 {
+		_ = json.Unmarshal(after, &to)
+	}
+	fields := map[string]bool{}
+	for field := range from {
+		fields[field] = true
+	}
+	for field := range to {
+		fields[field] = true
+	}
+	names := make([]string, 0, len(fields))
+	for field := range fields {
+		if field != "id" {
+			names = append(names, field)
+		}
+	}
+	sort.Strings(names)
+
+	changes := make([]models.FieldChange, 0)
+	for _, field := range names {
+		if !reflect.DeepEqual(from[field], to[field]) {
+			changes = append(changes, models.FieldChange{Field: field, From: from[field], To: to[field]})
+		}
+	}
+	return changes
+}
+
+func marshalState(state any) json.RawMessage {
+	if state == nil {
+		return nil
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// execer - the database or the transaction the revision is written with
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func insertRevision(ctx context.Context, db execer, rev models.Revision) error {
+	changes, err := json.Marshal(rev.Changes)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx,
+		`INSERT INTO change_history (entity, entity_id, action, actor_id, before_data, after_data, changes, restored_from, created_at)
+		VALUES (?,?,?,?,?,?,?,?,?)`,
+		rev.Entity,
+		rev.EntityID,
+		rev.Action,
+		nullID(rev.ActorID),
+		nullJSON(rev.Before),
+		nullJSON(rev.After),
+		string(changes),
+		nullID(rev.RestoredFrom),
+		rev.CreatedAt,
+	)
+	return err
+}
+
+// writeRevision - writes the revision of the change in the transaction of the change, so
+// the change is not there without its revision
+func writeRevision(ctx context.Context, db execer, entity string, entityID int, action string, before, after any) error {
+	return insertRevision(ctx, db, NewRevision(ctx, entity, entityID, action, before, after))
+}
@@ -0,0 +1,153 @@
+package dataops
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/repository/sqlconnect"
+)
+
+type Sessions struct {
+	db     *sqlconnect.DB
+	logger *logging.Logger
+}
+
+func NewSessionsDB(db *sqlconnect.DB, logger *logging.Logger) *Sessions {
+	return &Sessions{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (s *Sessions) CreateSession(session *models.ExecSession) (int64, error) {
+	lastID, err := s.db.InsertContext(
+		context.Background(),
+		"INSERT INTO exec_sessions (exec_id, refresh_token_hash, user_agent, ip_address, expires_at) VALUES (?,?,?,?,?)",
+		session.ExecID,
+		session.RefreshTokenHash,
+		session.UserAgent,
+		session.IPAddress,
+		session.ExpiresAt,
+	)
+	if err != nil {
+		s.logger.Logging.Debugf("error insert session to the database %v", err)
+		return 0, s.logger.ErrorMessage("sql database error")
+	}
+	return lastID, nil
+}
+
+func (s *Sessions) GetSessionByTokenHash(hashedToken string) (models.ExecSession, error) {
+	var session models.ExecSession
+	err := s.db.QueryRow("SELECT id, exec_id, refresh_token_hash, user_agent, ip_address, expires_at, revoked_at, created_at FROM exec_sessions WHERE refresh_token_hash = ?", hashedToken).
+		Scan(
+			&session.ID,
+			&session.ExecID,
+			&session.RefreshTokenHash,
+			&session.UserAgent,
+			&session.IPAddress,
+			&session.ExpiresAt,
+			&session.RevokedAt,
+			&session.CreatedAt,
+		)
+	if err == sql.ErrNoRows {
+		s.logger.Logging.Debugf("session not found %v", err)
+		return models.ExecSession{}, s.logger.ErrorMessage("session not found")
+	} else if err != nil {
+		s.logger.Logging.Debugf("error quering the database %v", err)
+		return models.ExecSession{}, s.logger.ErrorMessage("sql session error")
+	}
+	return session, nil
+}
+
+func (s *Sessions) IsSessionActive(id int) (bool, error) {
+	var count int
+	err := s.db.QueryRow(
+		"SELECT COUNT(*) FROM exec_sessions WHERE id = ? AND revoked_at IS NULL AND expires_at > ?",
+		id,
+		time.Now().UTC().Format(time.RFC3339),
+	).Scan(&count)
+	if err != nil {
+		s.logger.Logging.Debugf("error quering the session %v", err)
+		return false, s.logger.ErrorMessage("sql session error")
+	}
+	return count > 0, nil
+}
+
+// RevokeSession - false when the session was already revoked, the refresh which rotates
+// the session relies on it as the revoke is the only write of the rotation
+func (s *Sessions) RevokeSession(id int) (bool, error) {
+	result, err := s.db.Exec(
+		"UPDATE exec_sessions SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL",
+		time.Now().UTC().Format(time.RFC3339),
+		id,
+	)
+	if err != nil {
+		s.logger.Logging.Debugf("error revoking session %v", err)
+		return false, s.logger.ErrorMessage("database error")
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		s.logger.Logging.Debugf("error retreiving revoke result %v", err)
+		return false, s.logger.ErrorMessage("database error")
+	}
+	return rowsAffected == 1, nil
+}
+
+func (s *Sessions) RevokeAllSessions(execID int) (int64, error) {
+	result, err := s.db.Exec(
+		"UPDATE exec_sessions SET revoked_at = ? WHERE exec_id = ? AND revoked_at IS NULL",
+		time.Now().UTC().Format(time.RFC3339),
+		execID,
+	)
+	if err != nil {
+		s.logger.Logging.Debugf("error revoking sessions of exec %d %v", execID, err)
+		return 0, s.logger.ErrorMessage("database error")
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		s.logger.Logging.Debugf("error retreiving revoke result %v", err)
+		return 0, s.logger.ErrorMessage("database error")
+	}
+	return rowsAffected, nil
+}
+
+// ListActiveSessions - the not revoked and not expired sessions of the exec, the newest first
+func (s *Sessions) ListActiveSessions(execID int) ([]models.ExecSession, error) {
+	rows, err := s.db.Query(
+		"SELECT id, exec_id, user_agent, ip_address, expires_at, revoked_at, created_at FROM exec_sessions WHERE exec_id = ? AND revoked_at IS NULL AND expires_at > ? ORDER BY id DESC",
+		execID,
+		time.Now().UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		s.logger.Logging.Debugf("error quering the sessions %v", err)
+		return nil, s.logger.ErrorMessage("sql session error")
+	}
+	defer rows.Close()
+
+	sessions := make([]models.ExecSession, 0)
+	for rows.Next() {
+		var session models.ExecSession
+		err := rows.Scan(
+			&session.ID,
+			&session.ExecID,
+			&session.UserAgent,
+			&session.IPAddress,
+			&session.ExpiresAt,
+			&session.RevokedAt,
+			&session.CreatedAt,
+		)
+		if err != nil {
+			s.logger.Logging.Debugf("error scanning the session %v", err)
+			return nil, s.logger.ErrorMessage("sql session error")
+		}
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		s.logger.Logging.Debugf("error reading the sessions %v", err)
+		return nil, s.logger.ErrorMessage("sql session error")
+	}
+	return sessions, nil
+}
@@ -0,0 +1,85 @@
+package dataops
+
+import (
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/repository/sqlconnect"
+)
+
+type Stats struct {
+	db     *sqlconnect.DB
+	logger *logging.Logger
+}
+
+func NewStatsDB(db *sqlconnect.DB, logger *logging.Logger) *Stats {
+	return &Stats{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (s *Stats) GetStats() (models.SchoolStats, error) {
+	stats := models.SchoolStats{
+		ExecsByRole:     map[string]int{},
+		StudentsByClass: map[string]int{},
+	}
+	counts := []struct {
+		query string
+		dest  *int
+	}{
+		{"SELECT COUNT(*) FROM teachers WHERE deleted_at IS NULL", &stats.Teachers},
+		{"SELECT COUNT(*) FROM teachers WHERE deleted_at IS NOT NULL", &stats.DeletedTeachers},
+		{"SELECT COUNT(*) FROM classes", &stats.Classes},
+		{"SELECT COUNT(*) FROM students WHERE deleted_at IS NULL", &stats.Students},
+		{"SELECT COUNT(*) FROM students WHERE deleted_at IS NOT NULL", &stats.DeletedStudents},
+		{"SELECT COUNT(*) FROM execs WHERE deleted_at IS NULL", &stats.Execs},
+		{"SELECT COUNT(*) FROM execs WHERE deleted_at IS NULL AND inactive_status", &stats.InactiveExecs},
+		{"SELECT COUNT(*) FROM execs WHERE deleted_at IS NOT NULL", &stats.DeletedExecs},
+	}
+	for _, c := range counts {
+		if err := s.db.QueryRow(c.query).Scan(c.dest); err != nil {
+			s.logger.Logging.Debugf("error counting %q %v", c.query, err)
+			return models.SchoolStats{}, s.logger.ErrorMessage("error retrieving the stats")
+		}
+	}
+
+	groups := []struct {
+		query string
+		dest  map[string]int
+	}{
+		{"SELECT role, COUNT(*) FROM execs WHERE deleted_at IS NULL GROUP BY role", stats.ExecsByRole},
+		{
+			`SELECT c.name, COUNT(*) FROM students s
+			JOIN enrollments e ON e.student_id = s.id AND e.status = 'active'
+			JOIN classes c ON c.id = e.class_id
+			WHERE s.deleted_at IS NULL GROUP BY c.name`,
+			stats.StudentsByClass,
+		},
+	}
+	for _, g := range groups {
+		if err := s.groupCounts(g.query, g.dest); err != nil {
+			s.logger.Logging.Debugf("error counting %q %v", g.query, err)
+			return models.SchoolStats{}, s.logger.ErrorMessage("error retrieving the stats")
+		}
+	}
+	return stats, nil
+}
+
+func (s *Stats) groupCounts(query string, dest map[string]int) error {
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			key   string
+			count int
+		)
+		if err := rows.Scan(&key, &count); err != nil {
+			return err
+		}
+		dest[key] = count
+	}
+	return rows.Err()
+}
@@ -1,80 +1,135 @@
 package dataops
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"log"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
 	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
 	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/utils"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/repository/sqlconnect"
 )
 
 type Students struct {
-	db     *sql.DB
-	logger *logging.Logger
+	db      *sqlconnect.DB
+	logger  *logging.Logger
+	timeout time.Duration
 }
 
-func NewStudentsDB(db *sql.DB, logger *logging.Logger) *Students {
+func NewStudentsDB(db *sqlconnect.DB, logger *logging.Logger, timeout time.Duration) *Students {
 	return &Students{
-		db:     db,
-		logger: logger,
+		db:      db,
+		logger:  logger,
+		timeout: timeout,
 	}
 }
 
-func (t *Students) InsertStudents(st *models.Student) (int64, error) {
-	stmt, err := t.db.Prepare(utils.GenereateInsertQuery(models.Student{}, "students"))
+// InsertStudents - the new student is enrolled in the class from today
+func (t *Students) InsertStudents(ctx context.Context, st *models.Student) (int64, error) {
+	ctx, cancel := queryContext(ctx, t.timeout)
+	defer cancel()
+
+	tx, err := t.db.BeginTx(ctx, nil)
 	if err != nil {
-		t.logger.Logging.Debugf("error prepare insert statement %v", err)
-		return 0, t.logger.ErrorMessage("sql database insert student error ")
+		t.logger.Logging.Debugf("Error starting transaction %v", err)
+		return 0, dbError(ctx, t.logger, err, "sql database error")
 	}
-	defer stmt.Close()
 	values := utils.GetStructValues(st)
-
-	sqlResp, err := stmt.Exec(values...)
+	lastID, err := tx.InsertContext(ctx, utils.GenereateInsertQuery(models.Student{}, "students"), values...)
 	if err != nil {
-
+		_ = tx.Rollback()
 		t.logger.Logging.Debugf("error insert student to the database %v", err)
-		return 0, t.logger.ErrorMessage("sql database error")
+		return 0, dbError(ctx, t.logger, err, "sql database error")
 	}
+	if _, err := startEnrollment(ctx, tx, int(lastID), st.ClassID, today()); err != nil {
+		_ = tx.Rollback()
+		t.logger.Logging.Debugf("error enrolling the student %v", err)
+		return 0, dbError(ctx, t.logger, err, "sql database error")
+	}
+	created := *st
+	created.ID = int(lastID)
+	if err := t.commitChange(ctx, tx, created.ID, models.ChangeCreate, nil, created); err != nil {
+		return 0, err
+	}
+	return lastID, nil
+}
 
-	lastID, err := sqlResp.LastInsertId()
-	if err != nil {
+// lockStudent - the not deleted student read in the transaction of its change, the row
+// stays locked until the end of the transaction so the revision has the state the change
+// is made on
+func lockStudent(ctx context.Context, tx *sqlconnect.Tx, id int) (models.Student, error) {
+	var student models.Student
+	err := tx.QueryRowContext(ctx,
+		"SELECT id, first_name, last_name, email, class_id FROM students WHERE id = ? AND deleted_at IS NULL"+
+			tx.Dialect().ForUpdate(),
+		id,
+	).Scan(
+		&student.ID,
+		&student.FirstName,
+		&student.LastName,
+		&student.Email,
+		&student.ClassID,
+	)
+	return student, err
+}
 
-		t.logger.Logging.Debugf("eror get last insert teacher %v", err)
-		return 0, t.logger.ErrorMessage("sql database error")
+// commitChange - writes the revision of the change of the student and commits its
+// transaction, before is nil for the created student and after for the deleted one
+func (t *Students) commitChange(ctx context.Context, tx *sqlconnect.Tx, id int, action string, before, after any) error {
+	if err := writeRevision(ctx, tx, models.EntityStudent, id, action, before, after); err != nil {
+		t.logger.Logging.Debugf("error writing the student revision %v", err)
+		return dbError(ctx, t.logger, err, "sql database error")
 	}
-	return lastID, nil
+	if err := tx.Commit(); err != nil {
+		t.logger.Logging.Debugf("error commiting the transaction %v", err)
+		return dbError(ctx, t.logger, err, "sql database error")
+	}
+	return nil
 }
 
-func (t *Students) GetStudentByID(id int) (models.Student, error) {
+func (t *Students) GetStudentByID(ctx context.Context, id int) (models.Student, error) {
+	ctx, cancel := queryContext(ctx, t.timeout)
+	defer cancel()
+
 	var student models.Student
 
-	err := t.db.QueryRow("SELECT id, first_name, last_name ,email, class FROM students WHERE id = ?", id).
+	err := t.db.QueryRowContext(ctx, "SELECT id, first_name, last_name ,email, class_id FROM students WHERE id = ? AND deleted_at IS NULL", id).
 		Scan(
 			&student.ID,
 			&student.FirstName,
 			&student.LastName,
 			&student.Email,
-			&student.Class,
+			&student.ClassID,
 		)
 	if err == sql.ErrNoRows {
 		t.logger.Logging.Debugf("error student not found %v", err)
-		return models.Student{}, t.logger.ErrorMessage("sql student error")
+		return models.Student{}, dbError(ctx, t.logger, err, "sql student error")
 	} else if err != nil {
 		t.logger.Logging.Debugf("error quring the database %v", err)
-		return models.Student{}, t.logger.ErrorMessage("sql student error")
+		return models.Student{}, dbError(ctx, t.logger, err, "sql student error")
 	}
 	return student, nil
 }
 
+// GetAllStudents - one page of the students matching the params and the count of all
+// matching students, the soft deleted students are returned only with includeDeleted
 func (t *Students) GetAllStudents(
+	ctx context.Context,
 	params map[string]string,
 	sortBy []string, page, limit int,
-) (*sql.Rows, int, error) {
-	query := "SELECT id, first_name,last_name,email,class FROM students WHERE 1=1"
+	includeDeleted bool,
+) ([]models.Student, int, error) {
+	ctx, cancel := queryContext(ctx, t.timeout)
+	defer cancel()
+
+	where := " WHERE 1=1"
+	if !includeDeleted {
+		where += " AND deleted_at IS NULL"
+	}
 	var args []any
 	var orderByParts []string
 
@@ -83,16 +138,22 @@ func (t *Students) GetAllStudents(
 		"first_name": true,
 		"last_name":  true,
 		"email":      true,
-		"class":      true,
+		"class_id":   true,
 	}
 	// filtering by map of params
 	for param, dbField := range params {
 		if dbField != "" {
-			query += " AND " + param + " = ?"
+			where += studentFilterCondition(param)
 			args = append(args, dbField)
 		}
 	}
 
+	var totalStudents int
+	if err := t.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM students"+where, args...).Scan(&totalStudents); err != nil {
+		t.logger.Logging.Debugf("error counting the students %v", err)
+		return nil, 0, dbError(ctx, t.logger, err, "error retrtreiving data")
+	}
+
 	for _, criteria := range sortBy {
 		parts := strings.Split(criteria, ":")
 		if len(parts) == 2 {
@@ -109,6 +170,7 @@ func (t *Students) GetAllStudents(
 		orderByParts = append(orderByParts, "first_name ASC") // default sort
 	}
 
+	query := "SELECT id, first_name,last_name,email,class_id, COALESCE(deleted_at, '') FROM students" + where
 	query += " ORDER BY " + strings.Join(orderByParts, ", ")
 
 	if page < 1 {
@@ -121,84 +183,114 @@ func (t *Students) GetAllStudents(
 	query += " LIMIT ? OFFSET ?"
 	args = append(args, limit, offset)
 
-	rows, err := t.db.Query(query, args...)
+	rows, err := t.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		t.logger.Logging.Debugf("error retreiving the data %v", err)
-		return nil, 0, t.logger.ErrorMessage("error retrtreiving data")
+		return nil, 0, dbError(ctx, t.logger, err, "error retrtreiving data")
 	}
+	defer rows.Close()
 
-	var TotalStudents int
-
-	_ = t.db.QueryRow("SELECT COUNT(*) FROM students").Scan(&TotalStudents)
-
-	return rows, TotalStudents, nil
+	students := make([]models.Student, 0)
+	for rows.Next() {
+		var student models.Student
+		err := rows.Scan(
+			&student.ID,
+			&student.FirstName,
+			&student.LastName,
+			&student.Email,
+			&student.ClassID,
+			&student.DeletedAt,
+		)
+		if err != nil {
+			t.logger.Logging.Debugf("error scanning the student %v", err)
+			return nil, 0, dbError(ctx, t.logger, err, "error scanning database results")
+		}
+		students = append(students, student)
+	}
+	if err := rows.Err(); err != nil {
+		t.logger.Logging.Debugf("error reading the students %v", err)
+		return nil, 0, dbError(ctx, t.logger, err, "error retrtreiving data")
+	}
+	return students, totalStudents, nil
 }
 
-func (t *Students) UpdateStudent(id int, updatedStudent models.Student) (models.Student, error) {
-	var existingStudent models.Student
+func (t *Students) UpdateStudent(ctx context.Context, id int, updatedStudent models.Student) (models.Student, error) {
+	ctx, cancel := queryContext(ctx, t.timeout)
+	defer cancel()
 
-	row := t.db.QueryRow(
-		"SELECT id ,first_name,last_name,email,class from students WHERE id = ?",
-		id,
-	)
-	err := row.Scan(
-		&existingStudent.ID,
-		&existingStudent.FirstName,
-		&existingStudent.LastName,
-		&existingStudent.Email,
-		&existingStudent.Class,
-	)
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		t.logger.Logging.Debugf("Error starting transaction %v", err)
+		return models.Student{}, dbError(ctx, t.logger, err, "database error")
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	existingStudent, err := lockStudent(ctx, tx, id)
 	if err != nil {
 		if err != sql.ErrNoRows {
 			t.logger.Logging.Debugf("student not found %v", err)
-			return models.Student{}, t.logger.ErrorMessage("database retreive data error")
+			return models.Student{}, dbError(ctx, t.logger, err, "database retreive data error")
 		} else {
 			t.logger.Logging.Debugf("unable to retreive the data %v", err)
-			return models.Student{}, t.logger.ErrorMessage("sql error")
+			return models.Student{}, dbError(ctx, t.logger, err, "sql error")
 		}
 	}
 
 	updatedStudent.ID = existingStudent.ID
-	switch {
-	}
+	updatedStudent.DeletedAt = ""
 
-	_, err = t.db.Exec(
-		"UPDATE students SET first_name = ?, last_name = ? ,email = ? , class = ? WHERE id = ?  ",
-		&updatedStudent.FirstName,
-		&updatedStudent.LastName,
-		&updatedStudent.Email,
-		&updatedStudent.Class,
-		&updatedStudent.ID,
-	)
-	if err != nil {
+	if err := save(ctx, tx, updatedStudent, existingStudent.ClassID); err != nil {
 		t.logger.Logging.Debugf("error updating the student database %v", err)
-		return models.Student{}, t.logger.ErrorMessage("database error")
+		return models.Student{}, dbError(ctx, t.logger, err, "database error")
+	}
+	if err := t.commitChange(ctx, tx, id, models.ChangeUpdate, existingStudent, updatedStudent); err != nil {
+		return models.Student{}, err
 	}
 
 	return updatedStudent, nil
 }
 
-func (t *Students) PatchiStudent(id int, updatedStudent models.Student) (models.Student, error) {
-	var existingStudent models.Student
-
-	row := t.db.QueryRow(
-		"SELECT id ,first_name,last_name,email,class from students WHERE id = ?",
-		id,
-	)
-	err := row.Scan(
-		&existingStudent.ID,
-		&existingStudent.FirstName,
-		&existingStudent.LastName,
-		&existingStudent.Email,
-		&existingStudent.Class,
+// save - updates the student in the transaction, the change of the class is a transfer
+// of the student to the class from today
+func save(ctx context.Context, tx *sqlconnect.Tx, student models.Student, classID int) error {
+	_, err := tx.ExecContext(ctx,
+		"UPDATE students SET first_name = ?, last_name = ? ,email = ? , class_id = ? WHERE id = ? AND deleted_at IS NULL",
+		student.FirstName,
+		student.LastName,
+		student.Email,
+		student.ClassID,
+		student.ID,
 	)
+	if err != nil {
+		return err
+	}
+	if student.ClassID != classID {
+		if _, err := startEnrollment(ctx, tx, student.ID, student.ClassID, today()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *Students) PatchiStudent(ctx context.Context, id int, updatedStudent models.Student) (models.Student, error) {
+	ctx, cancel := queryContext(ctx, t.timeout)
+	defer cancel()
+
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		t.logger.Logging.Debugf("Error starting transaction %v", err)
+		return models.Student{}, dbError(ctx, t.logger, err, "database error")
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	existingStudent, err := lockStudent(ctx, tx, id)
 	if err != nil {
 		if err != sql.ErrNoRows {
 			t.logger.Logging.Debugf("Student not found %v", err)
-			return models.Student{}, t.logger.ErrorMessage("database error")
+			return models.Student{}, dbError(ctx, t.logger, err, "database error")
 		} else {
 			t.logger.Logging.Debugf("unable to retreive data %v", err)
-			return models.Student{}, t.logger.ErrorMessage("database error")
+			return models.Student{}, dbError(ctx, t.logger, err, "database error")
 		}
 	}
 
@@ -212,8 +304,8 @@ func (t *Students) PatchiStudent(id int, updatedStudent models.Student) (models.
 	// if updatedTeacher.Email != "" {
 	// 	existingTeacher.Email = updatedTeacher.Email
 	// }
-	// if updatedTeacher.Class != "" {
-	// 	existingTeacher.Class = updatedTeacher.Class
+	// if updatedTeacher.ClassID != 0 {
+	// 	existingTeacher.ClassID = updatedTeacher.ClassID
 	// }
 	// if updatedTeacher.Subject != ""  {
 	// 	existingTeacher.Subject = updatedTeacher.Subject
@@ -221,6 +313,7 @@ func (t *Students) PatchiStudent(id int, updatedStudent models.Student) (models.
 
 	// apply updates using reflect package
 
+	before := existingStudent
 	studentVal := reflect.ValueOf(&existingStudent).Elem()
 
 	updatedVal := reflect.ValueOf(updatedStudent)
@@ -243,89 +336,102 @@ func (t *Students) PatchiStudent(id int, updatedStudent models.Student) (models.
 				}
 			}
 		}
+		// the not zero ints like the class id are set too, the id of the record stays
+		if updatedField.Kind() == reflect.Int && updatedField.Int() != 0 && fieldName != "ID" {
+			existingField := studentVal.FieldByName(fieldName)
+			if existingField.IsValid() && existingField.CanSet() && existingField.Kind() == reflect.Int {
+				existingField.SetInt(updatedField.Int())
+			}
+		}
 	}
 
-	_, err = t.db.Exec(
-		"UPDATE students SET first_name = ?, last_name = ? ,email = ? , class = ? WHERE id = ?  ",
-		existingStudent.FirstName,
-		existingStudent.LastName,
-		existingStudent.Email,
-		existingStudent.Class,
-		existingStudent.ID,
-	)
-	if err != nil {
+	if err := save(ctx, tx, existingStudent, before.ClassID); err != nil {
 		t.logger.Logging.Debugf("error updating student %v", err)
-		return models.Student{}, t.logger.ErrorMessage("database error")
+		return models.Student{}, dbError(ctx, t.logger, err, "database error")
+	}
+	if err := t.commitChange(ctx, tx, id, models.ChangeUpdate, before, existingStudent); err != nil {
+		return models.Student{}, err
 	}
 
 	return existingStudent, nil
 }
 
-func (t *Students) DeleteStudent(id int) error {
-	result, err := t.db.Exec("DELETE from students WHERE id = ?", id)
+// DeleteStudent - soft deletes the student, it is removed for good by PurgeDeleted
+func (t *Students) DeleteStudent(ctx context.Context, id int) error {
+	ctx, cancel := queryContext(ctx, t.timeout)
+	defer cancel()
+
+	tx, err := t.db.BeginTx(ctx, nil)
 	if err != nil {
-		t.logger.Logging.Debugf("error deleting student %v", err)
-		return t.logger.ErrorMessage("database delete error")
+		t.logger.Logging.Debugf("Error starting transaction %v", err)
+		return dbError(ctx, t.logger, err, "database delete error")
 	}
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		t.logger.Logging.Debugf("error retreiving delete result %v", err)
-		return t.logger.ErrorMessage("error database delete operation")
+	defer func() { _ = tx.Rollback() }()
+
+	if err := t.softDelete(ctx, tx, id, time.Now().UTC().Format(time.RFC3339)); err != nil {
+		return err
 	}
+	if err := tx.Commit(); err != nil {
+		t.logger.Logging.Debugf("error commiting the transaction %v", err)
+		return dbError(ctx, t.logger, err, "database delete error")
+	}
+	return nil
+}
 
-	if rowsAffected == 0 {
-		t.logger.Logging.Debugf("student not found %v", err)
+// softDelete - soft deletes the student in the transaction and writes the revision of the
+// delete
+func (t *Students) softDelete(ctx context.Context, tx *sqlconnect.Tx, id int, deletedAt string) error {
+	existingStudent, err := lockStudent(ctx, tx, id)
+	if err == sql.ErrNoRows {
+		t.logger.Logging.Debugf("student %d not found", id)
 		return t.logger.ErrorMessage("student not found")
+	} else if err != nil {
+		t.logger.Logging.Debugf("error retreiving the student %v", err)
+		return dbError(ctx, t.logger, err, "database delete error")
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE students SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL", deletedAt, id)
+	if err != nil {
+		t.logger.Logging.Debugf("error deleting student %v", err)
+		return dbError(ctx, t.logger, err, "database delete error")
+	}
+	if err := writeRevision(ctx, tx, models.EntityStudent, id, models.ChangeDelete, existingStudent, nil); err != nil {
+		t.logger.Logging.Debugf("error writing the student revision %v", err)
+		return dbError(ctx, t.logger, err, "database delete error")
 	}
-
 	return nil
 }
 
-func (t *Students) DeleteBulkStudents(idn []int) ([]int, error) {
-	tx, err := t.db.Begin()
+// DeleteBulkStudents - soft deletes all the students or none of them when any of them is
+// not there
+func (t *Students) DeleteBulkStudents(ctx context.Context, idn []int) ([]int, error) {
+	ctx, cancel := queryContext(ctx, t.timeout)
+	defer cancel()
+
+	tx, err := t.db.BeginTx(ctx, nil)
 	if err != nil {
 		t.logger.Logging.Debugf("Error starting transaction %v", err)
-		return nil, t.logger.ErrorMessage("database error")
-	}
-	stmt, err := tx.Prepare("DELETE from students WHERE id = ?")
-	if err != nil {
-		t.logger.Logging.Debugf("delete error and preparing delete statement %v", err)
-		tx.Rollback()
-		return nil, t.logger.ErrorMessage("error deleting")
+		return nil, dbError(ctx, t.logger, err, "database error")
 	}
-	defer stmt.Close()
+	defer func() { _ = tx.Rollback() }()
 
 	var deletedIds []int
+	deletedAt := time.Now().UTC().Format(time.RFC3339)
 
 	for _, id := range idn {
-		res, err := stmt.Exec(id)
-		if err != nil {
-			_ = tx.Rollback()
-			t.logger.Logging.Debugf("error deleting student %v", err)
-			return nil, t.logger.ErrorMessage("error database deleting student")
-		}
-		rowsAffected, err := res.RowsAffected()
-		if err != nil {
-			_ = tx.Rollback()
-			t.logger.Logging.Debugf("error retreive delete result from the database %v", err)
-			return nil, t.logger.ErrorMessage("database retreive delete result")
-		}
-		// if teacher was deleted then add ID to the deletedIDs slice
-		if rowsAffected > 0 {
-			deletedIds = append(deletedIds, id)
-		}
-		if rowsAffected < 1 {
-			_ = tx.Rollback()
-			t.logger.Logging.Debugf("ID %d does not exists, doing rollback...", id)
-			return nil, t.logger.ErrorMessage("database error")
+		if err := t.softDelete(ctx, tx, id, deletedAt); err != nil {
+			if strings.Contains(err.Error(), "not found") {
+				t.logger.Logging.Debugf("ID %d does not exists, doing rollback...", id)
+				return nil, t.logger.ErrorMessage("database error")
+			}
+			return nil, err
 		}
+		deletedIds = append(deletedIds, id)
 	}
 	// commit changes
 	err = tx.Commit()
 	if err != nil {
-		log.Println(err)
 		t.logger.Logging.Debugf("error commiting the transaction %v", err)
-		return nil, t.logger.ErrorMessage("database error")
+		return nil, dbError(ctx, t.logger, err, "database error")
 	}
 	if len(deletedIds) < 1 {
 		t.logger.Logging.Debugf("none of the id exists %v", err)
@@ -334,3 +440,49 @@ func (t *Students) DeleteBulkStudents(idn []int) ([]int, error) {
 
 	return deletedIds, err
 }
+
+// RestoreStudent - brings back the soft deleted student
+func (t *Students) RestoreStudent(ctx context.Context, id int) error {
+	ctx, cancel := queryContext(ctx, t.timeout)
+	defer cancel()
+
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		t.logger.Logging.Debugf("Error starting transaction %v", err)
+		return dbError(ctx, t.logger, err, "database error")
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.ExecContext(ctx, "UPDATE students SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL", id)
+	if err != nil {
+		t.logger.Logging.Debugf("error restoring student %v", err)
+		return dbError(ctx, t.logger, err, "database error")
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		t.logger.Logging.Debugf("error retreiving restore result %v", err)
+		return dbError(ctx, t.logger, err, "database error")
+	}
+	if rowsAffected == 0 {
+		return t.logger.ErrorMessage("deleted student not found")
+	}
+	restoredStudent, err := lockStudent(ctx, tx, id)
+	if err != nil {
+		t.logger.Logging.Debugf("error retreiving restored student %v", err)
+		return dbError(ctx, t.logger, err, "database error")
+	}
+	return t.commitChange(ctx, tx, id, models.ChangeUndelete, nil, restoredStudent)
+}
+
+// PurgeDeleted - removes for good the students soft deleted before the RFC3339 time
+func (t *Students) PurgeDeleted(ctx context.Context, before string) (int64, error) {
+	ctx, cancel := queryContext(ctx, t.timeout)
+	defer cancel()
+
+	result, err := t.db.ExecContext(ctx, "DELETE FROM students WHERE deleted_at IS NOT NULL AND deleted_at < ?", before)
+	if err != nil {
+		t.logger.Logging.Debugf("error purging deleted students %v", err)
+		return 0, dbError(ctx, t.logger, err, "database error")
+	}
+	return result.RowsAffected()
+}
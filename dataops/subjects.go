@@ -0,0 +1,212 @@
+package dataops
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/repository/sqlconnect"
+)
+
+// Subjects - the catalog of the subjects, a subject is deleted for good and only when
+// no teaching assignment references it
+type Subjects struct {
+	db      *sqlconnect.DB
+	logger  *logging.Logger
+	timeout time.Duration
+}
+
+func NewSubjectsDB(db *sqlconnect.DB, logger *logging.Logger, timeout time.Duration) *Subjects {
+	return &Subjects{
+		db:      db,
+		logger:  logger,
+		timeout: timeout,
+	}
+}
+
+const subjectColumns = "id, name, code, description"
+
+func scanSubject(scan func(...any) error) (models.Subject, error) {
+	var subject models.Subject
+	err := scan(&subject.ID, &subject.Name, &subject.Code, &subject.Description)
+	return subject, err
+}
+
+func (s *Subjects) InsertSubject(ctx context.Context, subject *models.Subject) (int64, error) {
+	ctx, cancel := queryContext(ctx, s.timeout)
+	defer cancel()
+
+	id, err := s.db.InsertContext(ctx,
+		"INSERT INTO subjects (name, code, description) VALUES (?,?,?)",
+		subject.Name,
+		subject.Code,
+		subject.Description,
+	)
+	if err != nil {
+		s.logger.Logging.Debugf("error insert subject to the database %v", err)
+		return 0, dbError(ctx, s.logger, err, "error database subject insert")
+	}
+	return id, nil
+}
+
+func (s *Subjects) GetSubjectByID(ctx context.Context, id int) (models.Subject, error) {
+	ctx, cancel := queryContext(ctx, s.timeout)
+	defer cancel()
+
+	subject, err := scanSubject(s.db.QueryRowContext(ctx, "SELECT "+subjectColumns+" FROM subjects WHERE id = ?", id).Scan)
+	if err == sql.ErrNoRows {
+		s.logger.Logging.Debugf("subject not found %v", err)
+		return models.Subject{}, dbError(ctx, s.logger, err, "subject not found")
+	} else if err != nil {
+		s.logger.Logging.Debugf("error quering the database %v", err)
+		return models.Subject{}, dbError(ctx, s.logger, err, "error quering the database error")
+	}
+	return subject, nil
+}
+
+// GetAllSubjects - the subjects matching the params and their count, ordered by name by
+// default
+func (s *Subjects) GetAllSubjects(
+	ctx context.Context,
+	params map[string]string,
+	sortBy []string,
+) ([]models.Subject, int, error) {
+	ctx, cancel := queryContext(ctx, s.timeout)
+	defer cancel()
+
+	query := "SELECT " + subjectColumns + " FROM subjects WHERE 1=1"
+	var args []any
+	for param, value := range params {
+		if value != "" {
+			query += " AND " + param + " = ?"
+			args = append(args, value)
+		}
+	}
+
+	allowedColumns := map[string]bool{
+		"name": true,
+		"code": true,
+	}
+	var orderByParts []string
+	for _, criteria := range sortBy {
+		parts := strings.Split(criteria, ":")
+		if len(parts) == 2 {
+			sortColumn := parts[0]
+			sortOrder := strings.ToUpper(parts[1])
+
+			if allowedColumns[sortColumn] && (sortOrder == "ASC" || sortOrder == "DESC") {
+				orderByParts = append(orderByParts, fmt.Sprintf("%s %s", sortColumn, sortOrder))
+			}
+		}
+	}
+	if len(orderByParts) == 0 {
+		orderByParts = append(orderByParts, "name ASC")
+	}
+	query += " ORDER BY " + strings.Join(orderByParts, ", ") + ", id ASC"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		s.logger.Logging.Debugf("error retreiving the subjects %v", err)
+		return nil, 0, dbError(ctx, s.logger, err, "error retreiving data")
+	}
+	defer rows.Close()
+
+	subjects := make([]models.Subject, 0)
+	for rows.Next() {
+		subject, err := scanSubject(rows.Scan)
+		if err != nil {
+			s.logger.Logging.Debugf("error scanning the subject %v", err)
+			return nil, 0, dbError(ctx, s.logger, err, "error scanning database results")
+		}
+		subjects = append(subjects, subject)
+	}
+	if err := rows.Err(); err != nil {
+		s.logger.Logging.Debugf("error reading the subjects %v", err)
+		return nil, 0, dbError(ctx, s.logger, err, "error retreiving data")
+	}
+	return subjects, len(subjects), nil
+}
+
+func (s *Subjects) UpdateSubject(ctx context.Context, id int, updated models.Subject) (models.Subject, error) {
+	ctx, cancel := queryContext(ctx, s.timeout)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx,
+		"UPDATE subjects SET name = ?, code = ?, description = ? WHERE id = ?",
+		updated.Name,
+		updated.Code,
+		updated.Description,
+		id,
+	)
+	if err != nil {
+		s.logger.Logging.Debugf("error updating the subject %v", err)
+		return models.Subject{}, dbError(ctx, s.logger, err, "error subject database error")
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		s.logger.Logging.Debugf("error retreiving updated subject %v", err)
+		return models.Subject{}, dbError(ctx, s.logger, err, "error subject database error")
+	}
+	// mariadb counts only the changed rows, the subject which is the same is still found
+	if rowsAffected == 0 {
+		if _, err := s.GetSubjectByID(ctx, id); err != nil {
+			return models.Subject{}, err
+		}
+	}
+	updated.ID = id
+	return updated, nil
+}
+
+// PatchSubject - sets the not empty fields of the subject
+func (s *Subjects) PatchSubject(ctx context.Context, id int, updated models.Subject) (models.Subject, error) {
+	existing, err := s.GetSubjectByID(ctx, id)
+	if err != nil {
+		return models.Subject{}, err
+	}
+	if updated.Name != "" {
+		existing.Name = updated.Name
+	}
+	if updated.Code != "" {
+		existing.Code = updated.Code
+	}
+	if updated.Description != "" {
+		existing.Description = updated.Description
+	}
+	return s.UpdateSubject(ctx, id, existing)
+}
+
+// DeleteSubject - removes the subject which no teaching assignment references
+func (s *Subjects) DeleteSubject(ctx context.Context, id int) error {
+	ctx, cancel := queryContext(ctx, s.timeout)
+	defer cancel()
+
+	var assignments int
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM teaching_assignments WHERE subject_id = ?", id).
+		Scan(&assignments)
+	if err != nil {
+		s.logger.Logging.Debugf("error counting the assignments of the subject %v", err)
+		return dbError(ctx, s.logger, err, "Error deleting subject")
+	}
+	if assignments > 0 {
+		return s.logger.ErrorMessage("Subject still has teaching assignments")
+	}
+
+	result, err := s.db.ExecContext(ctx, "DELETE FROM subjects WHERE id = ?", id)
+	if err != nil {
+		s.logger.Logging.Debugf("error deleting subject %v", err)
+		return dbError(ctx, s.logger, err, "Error deleting subject")
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		s.logger.Logging.Debugf("error retreiving deleted subject %v", err)
+		return dbError(ctx, s.logger, err, "Error deleting subject")
+	}
+	if rowsAffected == 0 {
+		return s.logger.ErrorMessage("Subject not found")
+	}
+	return nil
+}
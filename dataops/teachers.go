@@ -2,74 +2,117 @@
 package dataops
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"log"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
 	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
 	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/utils"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/repository/sqlconnect"
 )
 
 type Teachers struct {
-	db     *sql.DB
-	logger *logging.Logger
+	db      *sqlconnect.DB
+	logger  *logging.Logger
+	timeout time.Duration
 }
 
-func NewTeachersDB(db *sql.DB, logger *logging.Logger) *Teachers {
+func NewTeachersDB(db *sqlconnect.DB, logger *logging.Logger, timeout time.Duration) *Teachers {
 	return &Teachers{
-		db:     db,
-		logger: logger,
+		db:      db,
+		logger:  logger,
+		timeout: timeout,
 	}
 }
 
-func (t *Teachers) InsertTeachers(tm *models.Teacher) (int64, error) {
-	stmt, err := t.db.Prepare(utils.GenereateInsertQuery(models.Teacher{}, "teachers"))
+func (t *Teachers) InsertTeachers(ctx context.Context, tm *models.Teacher) (int64, error) {
+	ctx, cancel := queryContext(ctx, t.timeout)
+	defer cancel()
+
+	tx, err := t.db.BeginTx(ctx, nil)
 	if err != nil {
-		t.logger.Logging.Debugf("error prepare insert statement %v", err)
-		return 0, t.logger.ErrorMessage("error database insert statement")
+		t.logger.Logging.Errorf("Error starting transaction %v", err)
+		return 0, dbError(ctx, t.logger, err, "error database teacher insert")
 	}
-	defer stmt.Close()
+	defer func() { _ = tx.Rollback() }()
 
 	values := utils.GetStructValues(tm)
-	sqlResp, err := stmt.Exec(values...)
+	lastID, err := tx.InsertContext(ctx, utils.GenereateInsertQuery(models.Teacher{}, "teachers"), values...)
 	if err != nil {
 		t.logger.Logging.Errorf("error insert teacher to the database %v", err)
-		return 0, t.logger.ErrorMessage("error database teacher insert")
+		return 0, dbError(ctx, t.logger, err, "error database teacher insert")
 	}
-	lastID, err := sqlResp.LastInsertId()
-	if err != nil {
-		t.logger.Logging.Errorf("eror get last insert teacher %v", err)
-		return 0, t.logger.ErrorMessage("error database")
+	created := *tm
+	created.ID = int(lastID)
+	if err := t.commitChange(ctx, tx, created.ID, models.ChangeCreate, nil, created); err != nil {
+		return 0, err
 	}
 	return lastID, nil
 }
 
-func (t *Teachers) GetTeacherByID(id int) (models.Teacher, error) {
+// lockTeacher - the not deleted teacher read in the transaction of its change, the row
+// stays locked until the end of the transaction so the revision has the state the change
+// is made on
+func lockTeacher(ctx context.Context, tx *sqlconnect.Tx, id int) (models.Teacher, error) {
 	var teacher models.Teacher
-	err := t.db.QueryRow("SELECT id, first_name, last_name ,email, class, subject FROM teachers WHERE id = ?", id).
+	err := tx.QueryRowContext(ctx,
+		"SELECT id, first_name, last_name, email, class_id, subject FROM teachers WHERE id = ? AND deleted_at IS NULL"+
+			tx.Dialect().ForUpdate(),
+		id,
+	).Scan(
+		&teacher.ID,
+		&teacher.FirstName,
+		&teacher.LastName,
+		&teacher.Email,
+		&teacher.ClassID,
+		&teacher.Subject,
+	)
+	return teacher, err
+}
+
+func (t *Teachers) GetTeacherByID(ctx context.Context, id int) (models.Teacher, error) {
+	ctx, cancel := queryContext(ctx, t.timeout)
+	defer cancel()
+
+	var teacher models.Teacher
+	err := t.db.QueryRowContext(ctx, "SELECT id, first_name, last_name ,email, class_id, subject FROM teachers WHERE id = ? AND deleted_at IS NULL", id).
 		Scan(
 			&teacher.ID,
 			&teacher.FirstName,
 			&teacher.LastName,
 			&teacher.Email,
-			&teacher.Class,
+			&teacher.ClassID,
 			&teacher.Subject,
 		)
 	if err == sql.ErrNoRows {
 		t.logger.Logging.Debugf("teacher not found %v", err)
-		return models.Teacher{}, t.logger.ErrorMessage("teacher not found")
+		return models.Teacher{}, dbError(ctx, t.logger, err, "teacher not found")
 	} else if err != nil {
 		t.logger.Logging.Debugf("error quering the database %v", err)
-		return models.Teacher{}, t.logger.ErrorMessage("error quering the database error")
+		return models.Teacher{}, dbError(ctx, t.logger, err, "error quering the database error")
 	}
 	return teacher, nil
 }
 
-func (t *Teachers) GetAllTeachers(params map[string]string, sortBy []string) (*sql.Rows, error) {
-	query := "SELECT id, first_name,last_name,email,class,subject FROM teachers WHERE 1=1"
+// GetAllTeachers - the teachers matching the params and their count, the soft deleted
+// teachers are returned only with includeDeleted
+func (t *Teachers) GetAllTeachers(
+	ctx context.Context,
+	params map[string]string,
+	sortBy []string,
+	includeDeleted bool,
+) ([]models.Teacher, int, error) {
+	ctx, cancel := queryContext(ctx, t.timeout)
+	defer cancel()
+
+	query := "SELECT id, first_name,last_name,email,class_id,subject, COALESCE(deleted_at, '') FROM teachers WHERE 1=1"
+	if !includeDeleted {
+		query += " AND deleted_at IS NULL"
+	}
 	var args []any
 	var orderByParts []string
 
@@ -78,13 +121,13 @@ func (t *Teachers) GetAllTeachers(params map[string]string, sortBy []string) (*s
 		"first_name": true,
 		"last_name":  true,
 		"email":      true,
-		"class":      true,
+		"class_id":   true,
 		"subject":    true,
 	}
 	// filtering by map of params
 	for param, dbField := range params {
 		if dbField != "" {
-			query += " AND " + param + " = ?"
+			query += filterCondition(param)
 			args = append(args, dbField)
 		}
 	}
@@ -105,84 +148,119 @@ func (t *Teachers) GetAllTeachers(params map[string]string, sortBy []string) (*s
 		query += " ORDER BY " + strings.Join(orderByParts, ", ")
 	}
 
-	rows, err := t.db.Query(query, args...)
+	rows, err := t.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		t.logger.Logging.Debugf("error retreiving data %v", err)
-		return nil, t.logger.ErrorMessage("error retreiving data")
+		return nil, 0, dbError(ctx, t.logger, err, "error retreiving data")
 	}
-	return rows, nil
+	defer rows.Close()
+
+	teachers := make([]models.Teacher, 0)
+	for rows.Next() {
+		var teacher models.Teacher
+		err := rows.Scan(
+			&teacher.ID,
+			&teacher.FirstName,
+			&teacher.LastName,
+			&teacher.Email,
+			&teacher.ClassID,
+			&teacher.Subject,
+			&teacher.DeletedAt,
+		)
+		if err != nil {
+			t.logger.Logging.Debugf("error scanning the teacher %v", err)
+			return nil, 0, dbError(ctx, t.logger, err, "error scanning database results")
+		}
+		teachers = append(teachers, teacher)
+	}
+	if err := rows.Err(); err != nil {
+		t.logger.Logging.Debugf("error reading the teachers %v", err)
+		return nil, 0, dbError(ctx, t.logger, err, "error retreiving data")
+	}
+	return teachers, len(teachers), nil
 }
 
-func (t *Teachers) UpdateTeacher(id int, updatedTeacher models.Teacher) (models.Teacher, error) {
-	var existingTeacher models.Teacher
+func (t *Teachers) UpdateTeacher(ctx context.Context, id int, updatedTeacher models.Teacher) (models.Teacher, error) {
+	ctx, cancel := queryContext(ctx, t.timeout)
+	defer cancel()
 
-	row := t.db.QueryRow(
-		"SELECT id ,first_name,last_name,email,class,subject from teachers WHERE id = ?",
-		id,
-	)
-	err := row.Scan(
-		&existingTeacher.ID,
-		&existingTeacher.FirstName,
-		&existingTeacher.LastName,
-		&existingTeacher.Email,
-		&existingTeacher.Class,
-		&existingTeacher.Subject,
-	)
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		t.logger.Logging.Debugf("Error starting transaction %v", err)
+		return models.Teacher{}, dbError(ctx, t.logger, err, "sql error")
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	existingTeacher, err := lockTeacher(ctx, tx, id)
 	if err != nil {
 		if err != sql.ErrNoRows {
 			t.logger.Logging.Debugf("techer not found %v", err)
-			return models.Teacher{}, t.logger.ErrorMessage("sql error")
+			return models.Teacher{}, dbError(ctx, t.logger, err, "sql error")
 		} else {
 			t.logger.Logging.Debugf("unable to retreive the data %v", err)
-			return models.Teacher{}, t.logger.ErrorMessage("sql error")
+			return models.Teacher{}, dbError(ctx, t.logger, err, "sql error")
 		}
 	}
 
 	updatedTeacher.ID = existingTeacher.ID
-	switch {
-	}
+	updatedTeacher.DeletedAt = ""
 
-	_, err = t.db.Exec(
-		"UPDATE teachers SET first_name = ?, last_name = ? ,email = ? , class = ?,subject = ? WHERE id = ?  ",
+	_, err = tx.ExecContext(ctx,
+		"UPDATE teachers SET first_name = ?, last_name = ? ,email = ? , class_id = ?,subject = ? WHERE id = ? AND deleted_at IS NULL",
 		&updatedTeacher.FirstName,
 		&updatedTeacher.LastName,
 		&updatedTeacher.Email,
-		&updatedTeacher.Class,
+		&updatedTeacher.ClassID,
 		&updatedTeacher.Subject,
 		&updatedTeacher.ID,
 	)
 	if err != nil {
 		t.logger.Logging.Debugf("errr updating the teacher database %v", err)
-		return models.Teacher{}, t.logger.ErrorMessage("error teacher database error")
+		return models.Teacher{}, dbError(ctx, t.logger, err, "error teacher database error")
+	}
+	if err := t.commitChange(ctx, tx, id, models.ChangeUpdate, existingTeacher, updatedTeacher); err != nil {
+		return models.Teacher{}, err
 	}
 
 	return updatedTeacher, nil
 }
 
-func (t *Teachers) PatchTeacher(id int, updatedTeacher models.Teacher) (models.Teacher, error) {
-	var existingTeacher models.Teacher
+// commitChange - writes the revision of the change of the teacher and commits its
+// transaction, before is nil for the created teacher and after for the deleted one
+func (t *Teachers) commitChange(ctx context.Context, tx *sqlconnect.Tx, id int, action string, before, after any) error {
+	if err := writeRevision(ctx, tx, models.EntityTeacher, id, action, before, after); err != nil {
+		t.logger.Logging.Debugf("error writing the teacher revision %v", err)
+		return dbError(ctx, t.logger, err, "error teacher database error")
+	}
+	if err := tx.Commit(); err != nil {
+		t.logger.Logging.Debugf("error commiting the transaction %v", err)
+		return dbError(ctx, t.logger, err, "error teacher database error")
+	}
+	return nil
+}
+
+func (t *Teachers) PatchTeacher(ctx context.Context, id int, updatedTeacher models.Teacher) (models.Teacher, error) {
+	ctx, cancel := queryContext(ctx, t.timeout)
+	defer cancel()
 
-	row := t.db.QueryRow(
-		"SELECT id ,first_name,last_name,email,class,subject from teachers WHERE id = ?",
-		id,
-	)
-	err := row.Scan(
-		&existingTeacher.ID,
-		&existingTeacher.FirstName,
-		&existingTeacher.LastName,
-		&existingTeacher.Email,
-		&existingTeacher.Class,
-		&existingTeacher.Subject,
-	)
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		t.logger.Logging.Debugf("Error starting transaction %v", err)
+		return models.Teacher{}, dbError(ctx, t.logger, err, "unable to retreive data")
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	existingTeacher, err := lockTeacher(ctx, tx, id)
 	if err != nil {
 		if err != sql.ErrNoRows {
 			t.logger.Logging.Warnf("teacher not found %v", err)
-			return models.Teacher{}, t.logger.ErrorMessage("Teacher not found")
+			return models.Teacher{}, dbError(ctx, t.logger, err, "Teacher not found")
 		} else {
 			t.logger.Logging.Debugf("unable to retreive data %v", err)
-			return models.Teacher{}, t.logger.ErrorMessage("unable to retreive data")
+			return models.Teacher{}, dbError(ctx, t.logger, err, "unable to retreive data")
 		}
 	}
+	before := existingTeacher
 
 	// if updatedTeacher.FirstName != "" {
 	// 	existingTeacher.FirstName = updatedTeacher.FirstName
@@ -194,8 +272,8 @@ func (t *Teachers) PatchTeacher(id int, updatedTeacher models.Teacher) (models.T
 	// if updatedTeacher.Email != "" {
 	// 	existingTeacher.Email = updatedTeacher.Email
 	// }
-	// if updatedTeacher.Class != "" {
-	// 	existingTeacher.Class = updatedTeacher.Class
+	// if updatedTeacher.ClassID != 0 {
+	// 	existingTeacher.ClassID = updatedTeacher.ClassID
 	// }
 	// if updatedTeacher.Subject != ""  {
 	// 	existingTeacher.Subject = updatedTeacher.Subject
@@ -225,90 +303,111 @@ func (t *Teachers) PatchTeacher(id int, updatedTeacher models.Teacher) (models.T
 				}
 			}
 		}
+		// the not zero ints like the class id are set too, the id of the record stays
+		if updatedField.Kind() == reflect.Int && updatedField.Int() != 0 && fieldName != "ID" {
+			existingField := teacherVal.FieldByName(fieldName)
+			if existingField.IsValid() && existingField.CanSet() && existingField.Kind() == reflect.Int {
+				existingField.SetInt(updatedField.Int())
+			}
+		}
 	}
 
-	_, err = t.db.Exec(
-		"UPDATE teachers SET first_name = ?, last_name = ? ,email = ? , class = ?,subject = ? WHERE id = ?  ",
+	_, err = tx.ExecContext(ctx,
+		"UPDATE teachers SET first_name = ?, last_name = ? ,email = ? , class_id = ?,subject = ? WHERE id = ? AND deleted_at IS NULL",
 		existingTeacher.FirstName,
 		existingTeacher.LastName,
 		existingTeacher.Email,
-		existingTeacher.Class,
+		existingTeacher.ClassID,
 		existingTeacher.Subject,
 		existingTeacher.ID,
 	)
 	if err != nil {
 		t.logger.Logging.Debugf("Error updating teacher %v", err)
-		return models.Teacher{}, t.logger.ErrorMessage("Error updating teacher")
+		return models.Teacher{}, dbError(ctx, t.logger, err, "Error updating teacher")
+	}
+	if err := t.commitChange(ctx, tx, id, models.ChangeUpdate, before, existingTeacher); err != nil {
+		return models.Teacher{}, err
 	}
 
 	return existingTeacher, nil
 }
 
-func (t *Teachers) DeleteTeacher(id int) error {
-	result, err := t.db.Exec("DELETE from teachers WHERE id = ?", id)
+// DeleteTeacher - soft deletes the teacher, it is removed for good by PurgeDeleted
+func (t *Teachers) DeleteTeacher(ctx context.Context, id int) error {
+	ctx, cancel := queryContext(ctx, t.timeout)
+	defer cancel()
+
+	tx, err := t.db.BeginTx(ctx, nil)
 	if err != nil {
-		t.logger.Logging.Debugf("error deleting teacher -  %v", err)
-		return t.logger.ErrorMessage("Error deleting teacher")
+		t.logger.Logging.Debugf("Error starting transaction %v", err)
+		return dbError(ctx, t.logger, err, "Error deleting teacher")
 	}
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		t.logger.Logging.Debugf("error retreiving deleted teacher %v", err)
-		return t.logger.ErrorMessage("Error retreiving deleted teacher")
+	defer func() { _ = tx.Rollback() }()
+
+	if err := t.softDelete(ctx, tx, id, time.Now().UTC().Format(time.RFC3339)); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		t.logger.Logging.Debugf("error commiting the transaction %v", err)
+		return dbError(ctx, t.logger, err, "Error deleting teacher")
 	}
+	return nil
+}
 
-	if rowsAffected == 0 {
-		t.logger.Logging.Debugf("teacher not found %v", err)
+// softDelete - soft deletes the teacher in the transaction and writes the revision of the
+// delete
+func (t *Teachers) softDelete(ctx context.Context, tx *sqlconnect.Tx, id int, deletedAt string) error {
+	existingTeacher, err := lockTeacher(ctx, tx, id)
+	if err == sql.ErrNoRows {
+		t.logger.Logging.Debugf("teacher %d not found", id)
 		return t.logger.ErrorMessage("Teacher not found")
+	} else if err != nil {
+		t.logger.Logging.Debugf("error retreiving the teacher %v", err)
+		return dbError(ctx, t.logger, err, "Error deleting teacher")
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE teachers SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL", deletedAt, id)
+	if err != nil {
+		t.logger.Logging.Debugf("error deleting teacher -  %v", err)
+		return dbError(ctx, t.logger, err, "Error deleting teacher")
+	}
+	if err := writeRevision(ctx, tx, models.EntityTeacher, id, models.ChangeDelete, existingTeacher, nil); err != nil {
+		t.logger.Logging.Debugf("error writing the teacher revision %v", err)
+		return dbError(ctx, t.logger, err, "Error deleting teacher")
 	}
-
 	return nil
 }
 
-func (t *Teachers) DeleteBulkTeachers(idn []int) ([]int, error) {
-	tx, err := t.db.Begin()
+// DeleteBulkTeachers - soft deletes all the teachers or none of them when any of them is
+// not there
+func (t *Teachers) DeleteBulkTeachers(ctx context.Context, idn []int) ([]int, error) {
+	ctx, cancel := queryContext(ctx, t.timeout)
+	defer cancel()
+
+	tx, err := t.db.BeginTx(ctx, nil)
 	if err != nil {
 		t.logger.Logging.Errorf("Error starting transaction %v", err)
-		return nil, t.logger.ErrorLogger(err, "Error starting Transaction")
+		return nil, dbError(ctx, t.logger, err, "Error starting Transaction")
 	}
-	stmt, err := tx.Prepare("DELETE from teachers WHERE id = ?")
-	if err != nil {
-		t.logger.Logging.Debugf("error preparing delete statement %v", err)
-		tx.Rollback()
-		return nil, t.logger.ErrorMessage("error preparing delete statement")
-	}
-	defer stmt.Close()
+	defer func() { _ = tx.Rollback() }()
 
 	var deletedIds []int
+	deletedAt := time.Now().UTC().Format(time.RFC3339)
 
 	for _, id := range idn {
-		res, err := stmt.Exec(id)
-		if err != nil {
-			tx.Rollback()
-			t.logger.Logging.Errorf("error deleting teacher %v", err)
-			return nil, t.logger.ErrorMessage("error deleting teacher")
-		}
-		rowsAffected, err := res.RowsAffected()
-		if err != nil {
-			t.logger.Logging.Debugf("error retreiving delete result %v", err)
-			log.Println(err)
-			return nil, t.logger.ErrorMessage("error retreiving delete result")
-		}
-		// if teacher was deleted then add ID to the deletedIDs slice
-		if rowsAffected > 0 {
-			deletedIds = append(deletedIds, id)
-		}
-		if rowsAffected < 1 {
-			t.logger.Logging.Debugf("ID %d does not exists", id)
-			tx.Rollback()
-			return nil, t.logger.ErrorMessage("ID does not exists,  doing rollback...")
-
+		if err := t.softDelete(ctx, tx, id, deletedAt); err != nil {
+			if strings.Contains(err.Error(), "not found") {
+				t.logger.Logging.Debugf("ID %d does not exists", id)
+				return nil, t.logger.ErrorMessage("ID does not exists,  doing rollback...")
+			}
+			return nil, err
 		}
+		deletedIds = append(deletedIds, id)
 	}
 	// commit changes
 	err = tx.Commit()
 	if err != nil {
 		t.logger.Logging.Debugf("error commiting the transaction %v", err)
-		return nil, t.logger.ErrorMessage("error commiting the transaction")
+		return nil, dbError(ctx, t.logger, err, "error commiting the transaction")
 	}
 	if len(deletedIds) < 1 {
 		return nil, t.logger.ErrorMessage("none of the id exists")
@@ -317,14 +416,20 @@ func (t *Teachers) DeleteBulkTeachers(idn []int) ([]int, error) {
 	return deletedIds, err
 }
 
-func (t *Teachers) GetStudentsByTeacherID(id int) ([]models.Student, error) {
-	query := `SELECT id,first_name,last_name,email,class FROM students where class=(SELECT class from teachers WHERE id = ?)`
+func (t *Teachers) GetStudentsByTeacherID(ctx context.Context, id int) ([]models.Student, error) {
+	ctx, cancel := queryContext(ctx, t.timeout)
+	defer cancel()
+
+	query := `SELECT s.id,s.first_name,s.last_name,s.email,s.class_id FROM students s
+	JOIN enrollments e ON e.student_id = s.id AND e.status = 'active'
+	JOIN teachers t ON t.class_id = e.class_id
+	WHERE t.id = ? AND t.deleted_at IS NULL AND s.deleted_at IS NULL ORDER BY s.id`
 
 	var students []models.Student
-	rows, err := t.db.Query(query, id)
+	rows, err := t.db.QueryContext(ctx, query, id)
 	if err != nil {
 		t.logger.Logging.Debugf("error while execute query %v", err)
-		return nil, t.logger.ErrorMessage("error execute query")
+		return nil, dbError(ctx, t.logger, err, "error execute query")
 	}
 
 	defer rows.Close()
@@ -335,17 +440,63 @@ func (t *Teachers) GetStudentsByTeacherID(id int) ([]models.Student, error) {
 			&student.FirstName,
 			&student.LastName,
 			&student.Email,
-			&student.Class,
+			&student.ClassID,
 		)
 		if err != nil {
-			return nil, t.logger.ErrorMessage("error fetching the database")
+			return nil, dbError(ctx, t.logger, err, "error fetching the database")
 		}
 		students = append(students, student)
 
 	}
 	err = rows.Err()
 	if err != nil {
-		return nil, t.logger.ErrorLogger(err, "rows error")
+		return nil, dbError(ctx, t.logger, err, "rows error")
 	}
 	return students, nil
 }
+
+// RestoreTeacher - brings back the soft deleted teacher
+func (t *Teachers) RestoreTeacher(ctx context.Context, id int) error {
+	ctx, cancel := queryContext(ctx, t.timeout)
+	defer cancel()
+
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		t.logger.Logging.Debugf("Error starting transaction %v", err)
+		return dbError(ctx, t.logger, err, "Error restoring teacher")
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.ExecContext(ctx, "UPDATE teachers SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL", id)
+	if err != nil {
+		t.logger.Logging.Debugf("error restoring teacher %v", err)
+		return dbError(ctx, t.logger, err, "Error restoring teacher")
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		t.logger.Logging.Debugf("error retreiving restored teacher %v", err)
+		return dbError(ctx, t.logger, err, "Error restoring teacher")
+	}
+	if rowsAffected == 0 {
+		return t.logger.ErrorMessage("Deleted teacher not found")
+	}
+	restoredTeacher, err := lockTeacher(ctx, tx, id)
+	if err != nil {
+		t.logger.Logging.Debugf("error retreiving restored teacher %v", err)
+		return dbError(ctx, t.logger, err, "Error restoring teacher")
+	}
+	return t.commitChange(ctx, tx, id, models.ChangeUndelete, nil, restoredTeacher)
+}
+
+// PurgeDeleted - removes for good the teachers soft deleted before the RFC3339 time
+func (t *Teachers) PurgeDeleted(ctx context.Context, before string) (int64, error) {
+	ctx, cancel := queryContext(ctx, t.timeout)
+	defer cancel()
+
+	result, err := t.db.ExecContext(ctx, "DELETE FROM teachers WHERE deleted_at IS NOT NULL AND deleted_at < ?", before)
+	if err != nil {
+		t.logger.Logging.Debugf("error purging deleted teachers %v", err)
+		return 0, dbError(ctx, t.logger, err, "Error purging deleted teachers")
+	}
+	return result.RowsAffected()
+}
@@ -5,33 +5,28 @@ go 1.25.4
 require (
 	github.com/danielgtaylor/huma/v2 v2.34.1
 	github.com/go-sql-driver/mysql v1.9.3
+	github.com/golang-jwt/jwt/v5 v5.3.0
+	github.com/jackc/pgx/v5 v5.11.0
 	github.com/sirupsen/logrus v1.9.3
 	golang.org/x/crypto v0.44.0
+	modernc.org/sqlite v1.46.1
 )
 
 require (
 	filippo.io/edwards25519 v1.1.0 // indirect
-	github.com/golang-jwt/jwt/v5 v5.3.0 // indirect
-	github.com/gorilla/context v1.1.2 // indirect
-	github.com/gorilla/mux v1.8.1 // indirect
-	github.com/gorilla/pat v1.0.2 // indirect
-	github.com/gorilla/websocket v1.5.3 // indirect
-	github.com/ian-kent/envconf v0.0.0-20141026121121-c19809918c02 // indirect
-	github.com/ian-kent/go-log v0.0.0-20160113211217-5731446c36ab // indirect
-	github.com/ian-kent/goose v0.0.0-20141221090059-c3541ea826ad // indirect
-	github.com/ian-kent/linkio v0.0.0-20170807205755-97566b872887 // indirect
-	github.com/mailhog/MailHog v1.0.1 // indirect
-	github.com/mailhog/MailHog-Server v1.0.1 // indirect
-	github.com/mailhog/MailHog-UI v1.0.1 // indirect
-	github.com/mailhog/data v1.0.1 // indirect
-	github.com/mailhog/http v1.0.1 // indirect
-	github.com/mailhog/mhsendmail v0.2.0 // indirect
-	github.com/mailhog/smtp v1.0.1 // indirect
-	github.com/mailhog/storage v1.0.1 // indirect
-	github.com/ogier/pflag v0.0.1 // indirect
-	github.com/philhofer/fwd v1.2.0 // indirect
-	github.com/t-k/fluent-logger-golang v1.0.0 // indirect
-	github.com/tinylib/msgp v1.5.0 // indirect
+	github.com/dustin/go-humanize v1.0.1 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/jackc/pgpassfile v1.0.0 // indirect
+	github.com/jackc/pgservicefile v0.0.0-20240606120523-5a60cdf6a761 // indirect
+	github.com/jackc/puddle/v2 v2.2.2 // indirect
+	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/ncruces/go-strftime v1.0.0 // indirect
+	github.com/remyoudompheng/bigfft v0.0.0-20230129092748-24d4a6f8daec // indirect
+	golang.org/x/exp v0.0.0-20251023183803-a4bb9ffd2546 // indirect
+	golang.org/x/sync v0.18.0 // indirect
 	golang.org/x/sys v0.38.0 // indirect
-	gopkg.in/mgo.v2 v2.0.0-20190816093944-a6b53ec6cb22 // indirect
+	golang.org/x/text v0.31.0 // indirect
+	modernc.org/libc v1.67.6 // indirect
+	modernc.org/mathutil v1.7.1 // indirect
+	modernc.org/memory v1.11.0 // indirect
 )
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/middleware"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/utils"
+)
+
+// apiKeyPrefixLen - the start of the key which is stored in clear to recognize the key in the list
+const apiKeyPrefixLen = 8
+
+type APIKeysHandlers struct {
+	apiKeysDB dataops.APIKeysInf
+	logger    *logging.Logger
+}
+
+func NewAPIKeysHandler(adb dataops.APIKeysInf, logger *logging.Logger) *APIKeysHandlers {
+	return &APIKeysHandlers{
+		apiKeysDB: adb,
+		logger:    logger,
+	}
+}
+
+// CreateAPIKeyHandler - the key gets only the scopes which the role of the exec has
+func (h *APIKeysHandlers) CreateAPIKeyHandler(
+	ctx context.Context,
+	input *APIKeyCreateInput,
+) (*APIKeyCreateOutput, error) {
+	execID, err := currentExecID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	role, _ := ctx.Value(middleware.ContextKey("role")).(string)
+	scopes := make([]string, 0, len(input.Body.Scopes))
+	for _, s := range input.Body.Scopes {
+		if !middleware.HasPermission(role, middleware.Permission(s)) {
+			return nil, huma.Error400BadRequest(
+				"Invalid scope",
+				fmt.Errorf("scope %s is not granted to the role %s", s, role),
+			)
+		}
+		if !slices.Contains(scopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+	if input.Body.ExpiresAt != "" {
+		expiresAt, err := time.Parse(time.RFC3339, input.Body.ExpiresAt)
+		if err != nil || !expiresAt.After(time.Now()) {
+			return nil, huma.Error400BadRequest("expires_at must be in the future")
+		}
+		input.Body.ExpiresAt = expiresAt.UTC().Format(time.RFC3339)
+	}
+
+	key, hashedKey, err := utils.GenerateToken()
+	if err != nil {
+		h.logger.Logging.Errorf("failed to generate api key %v", err)
+		return nil, huma.Error500InternalServerError("Could not generate the api key")
+	}
+	apiKey := models.APIKey{
+		ExecID:    execID,
+		Name:      input.Body.Name,
+		Prefix:    key[:apiKeyPrefixLen],
+		KeyHash:   hashedKey,
+		Scopes:    scopes,
+		ExpiresAt: input.Body.ExpiresAt,
+	}
+	id, err := h.apiKeysDB.CreateAPIKey(&apiKey)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Error adding to the database", err)
+	}
+	apiKey.ID = int(id)
+
+	out := &APIKeyCreateOutput{}
+	out.Body.Key = key
+	out.Body.Data = apiKey
+	return out, nil
+}
+
+func (h *APIKeysHandlers) listAPIKeys(execID int) (*APIKeysOutput, error) {
+	keys, err := h.apiKeysDB.ListAPIKeys(execID)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Error quering database", err)
+	}
+
+	out := &APIKeysOutput{}
+	out.Body.Status = "Success"
+	out.Body.Count = len(keys)
+	out.Body.Data = keys
+	return out, nil
+}
+
+func (h *APIKeysHandlers) revokeAPIKey(execID, keyID int) (*APIKeyRevokeOutput, error) {
+	revoked, err := h.apiKeysDB.RevokeAPIKey(execID, keyID)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Could not revoke the api key", err)
+	}
+	if !revoked {
+		return nil, huma.Error404NotFound("api key not found or already revoked")
+	}
+
+	out := &APIKeyRevokeOutput{}
+	out.Body.Status = "API key revoked"
+	out.Body.ID = keyID
+	return out, nil
+}
+
+func (h *APIKeysHandlers) ListMyAPIKeysHandler(
+	ctx context.Context,
+	_ *struct{},
+) (*APIKeysOutput, error) {
+	execID, err := currentExecID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return h.listAPIKeys(execID)
+}
+
+func (h *APIKeysHandlers) RevokeMyAPIKeyHandler(
+	ctx context.Context,
+	input *struct {
+		KeyID int `path:"keyid"`
+	},
+) (*APIKeyRevokeOutput, error) {
+	execID, err := currentExecID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return h.revokeAPIKey(execID, input.KeyID)
+}
+
+func (h *APIKeysHandlers) ListAPIKeysHandler(
+	ctx context.Context,
+	input *struct {
+		ID int `path:"id"`
+	},
+) (*APIKeysOutput, error) {
+	return h.listAPIKeys(input.ID)
+}
+
+func (h *APIKeysHandlers) RevokeAPIKeyHandler(
+	ctx context.Context,
+	input *struct {
+		ID    int `path:"id"`
+		KeyID int `path:"keyid"`
+	},
+) (*APIKeyRevokeOutput, error) {
+	return h.revokeAPIKey(input.ID, input.KeyID)
+}
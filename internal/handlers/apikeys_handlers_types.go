@@ -0,0 +1,29 @@
+package handlers
+
+import "github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+
+type APIKeyCreateInput struct {
+	Body models.APIKeyInput
+}
+
+type APIKeyCreateOutput struct {
+	Body struct {
+		Key  string        `json:"key"  doc:"The api key, shown only once"`
+		Data models.APIKey `json:"data"`
+	}
+}
+
+type APIKeysOutput struct {
+	Body struct {
+		Status string          `json:"status"`
+		Count  int             `json:"count"`
+		Data   []models.APIKey `json:"data"`
+	}
+}
+
+type APIKeyRevokeOutput struct {
+	Body struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}
+}
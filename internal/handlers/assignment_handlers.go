@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
+)
+
+type AssignmentHandlers struct {
+	mutex         sync.Mutex
+	assignmentsDB dataops.AssignmentsInf
+	teachersDB    dataops.TeachersInf
+	subjectsDB    dataops.SubjectsInf
+	classesDB     dataops.ClassesInf
+	logger        *logging.Logger
+}
+
+func NewAssignmentsHandler(
+	adb dataops.AssignmentsInf,
+	tdb dataops.TeachersInf,
+	sdb dataops.SubjectsInf,
+	cdb dataops.ClassesInf,
+	logger *logging.Logger,
+) *AssignmentHandlers {
+	return &AssignmentHandlers{
+		assignmentsDB: adb,
+		teachersDB:    tdb,
+		subjectsDB:    sdb,
+		classesDB:     cdb,
+		logger:        logger,
+	}
+}
+
+// assignmentError - 404 for the missing assignment, otherwise the fallback
+func assignmentError(err error, fallback error) error {
+	switch {
+	case queryFailed(err):
+		return dbError(err, fallback)
+	case strings.Contains(err.Error(), "not found"):
+		return huma.Error404NotFound("assignment not found", err)
+	}
+	return fallback
+}
+
+// checkAssignment - the teacher, subject and class exist and nobody else teaches the
+// subject in the class in the term, the database would only fail on them
+func (h *AssignmentHandlers) checkAssignment(ctx context.Context, assignment models.Assignment, exceptID int) error {
+	missing := func(err error, what string, id int) error {
+		if queryFailed(err) {
+			return dbError(err, err)
+		}
+		return huma.Error422UnprocessableEntity(what + " " + strconv.Itoa(id) + " not found")
+	}
+	if _, err := h.teachersDB.GetTeacherByID(ctx, assignment.TeacherID); err != nil {
+		return missing(err, "teacher", assignment.TeacherID)
+	}
+	subject, err := h.subjectsDB.GetSubjectByID(ctx, assignment.SubjectID)
+	if err != nil {
+		return missing(err, "subject", assignment.SubjectID)
+	}
+	class, err := h.classesDB.GetClassByID(ctx, assignment.ClassID)
+	if err != nil {
+		return missing(err, "class", assignment.ClassID)
+	}
+
+	same, _, err := h.assignmentsDB.GetAllAssignments(ctx, map[string]string{
+		"subject_id": strconv.Itoa(assignment.SubjectID),
+		"class_id":   strconv.Itoa(assignment.ClassID),
+		"term":       assignment.Term,
+	}, nil)
+	if err != nil {
+		return dbError(err, huma.Error500InternalServerError("Error quering database", err))
+	}
+	for _, other := range same {
+		// the empty term is not a filter so it is compared here
+		if other.ID != exceptID && other.Term == assignment.Term {
+			return huma.Error409Conflict(
+				"subject " + subject.Name + " in class " + class.Name + " is already assigned to teacher " +
+					strconv.Itoa(other.TeacherID) + " in the term",
+			)
+		}
+	}
+	return nil
+}
+
+func (h *AssignmentHandlers) AssignmentGet(ctx context.Context, input *AssignmentIDInput) (*AssignmentOutput, error) {
+	assignment, err := h.assignmentsDB.GetAssignmentByID(ctx, input.ID)
+	if err != nil {
+		return nil, assignmentError(err, huma.Error500InternalServerError("Error quering database", err))
+	}
+	resp := &AssignmentOutput{}
+	resp.Body.Status = "Success"
+	resp.Body.Data = assignment
+	return resp, nil
+}
+
+func (h *AssignmentHandlers) AssignmentsGet(
+	ctx context.Context,
+	input *models.AssignmentsQueryInput,
+) (*AssignmentsOutput, error) {
+	params := map[string]string{"term": input.Term}
+	if input.TeacherID > 0 {
+		params["teacher_id"] = strconv.Itoa(input.TeacherID)
+	}
+	if input.SubjectID > 0 {
+		params["subject_id"] = strconv.Itoa(input.SubjectID)
+	}
+	if input.ClassID > 0 {
+		params["class_id"] = strconv.Itoa(input.ClassID)
+	}
+	return h.assignments(ctx, params, input.SortBy)
+}
+
+func (h *AssignmentHandlers) assignments(
+	ctx context.Context,
+	params map[string]string,
+	sortBy []string,
+) (*AssignmentsOutput, error) {
+	assignments, count, err := h.assignmentsDB.GetAllAssignments(ctx, params, sortBy)
+	if err != nil {
+		return nil, dbError(err, huma.Error500InternalServerError("Error quering database", err))
+	}
+	resp := &AssignmentsOutput{}
+	resp.Body.Status = "Success"
+	resp.Body.Count = count
+	resp.Body.Data = assignments
+	return resp, nil
+}
+
+func (h *AssignmentHandlers) AssignmentsAdd(ctx context.Context, input *AssignmentsInput) (*AssignmentsOutput, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	addedAssignments := make([]models.Assignment, len(input.Body.Assignments))
+	for i, newAssignment := range input.Body.Assignments {
+		assignment := models.Assignment{
+			TeacherID: newAssignment.TeacherID,
+			SubjectID: newAssignment.SubjectID,
+			ClassID:   newAssignment.ClassID,
+			Term:      newAssignment.Term,
+		}
+		if err := h.checkAssignment(ctx, assignment, 0); err != nil {
+			return nil, err
+		}
+		id, err := h.assignmentsDB.InsertAssignment(ctx, &assignment)
+		if err != nil {
+			return nil, dbError(err, huma.Error500InternalServerError("Error adding to the database", err))
+		}
+		added, err := h.assignmentsDB.GetAssignmentByID(ctx, int(id))
+		if err != nil {
+			return nil, dbError(err, huma.Error500InternalServerError("Error quering database", err))
+		}
+		addedAssignments[i] = added
+	}
+
+	resp := &AssignmentsOutput{}
+	resp.Body.Status = "Success"
+	resp.Body.Count = len(addedAssignments)
+	resp.Body.Data = addedAssignments
+	return resp, nil
+}
+
+// UpdateAssignmentHandler - sets all fields of the assignment, the subject of the class
+// is handed over to another teacher by changing only the teacher
+func (h *AssignmentHandlers) UpdateAssignmentHandler(
+	ctx context.Context,
+	input *AssignmentUpdateInput,
+) (*AssignmentOutput, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	assignment := models.Assignment{
+		ID:        input.ID,
+		TeacherID: input.Body.Assignment.TeacherID,
+		SubjectID: input.Body.Assignment.SubjectID,
+		ClassID:   input.Body.Assignment.ClassID,
+		Term:      input.Body.Assignment.Term,
+	}
+	if _, err := h.assignmentsDB.GetAssignmentByID(ctx, input.ID); err != nil {
+		return nil, assignmentError(err, huma.Error500InternalServerError("error update database", err))
+	}
+	if err := h.checkAssignment(ctx, assignment, input.ID); err != nil {
+		return nil, err
+	}
+	updatedAssignment, err := h.assignmentsDB.UpdateAssignment(ctx, input.ID, assignment)
+	if err != nil {
+		return nil, assignmentError(err, huma.Error500InternalServerError("error update database", err))
+	}
+	resp := &AssignmentOutput{}
+	resp.Body.Status = "Success"
+	resp.Body.Data = updatedAssignment
+	return resp, nil
+}
+
+func (h *AssignmentHandlers) DeleteAssignmentHandler(
+	ctx context.Context,
+	input *AssignmentIDInput,
+) (*DeleteAssignmentOutput, error) {
+	if err := h.assignmentsDB.DeleteAssignment(ctx, input.ID); err != nil {
+		return nil, assignmentError(err, huma.Error500InternalServerError("error deleting assignment", err))
+	}
+	resp := &DeleteAssignmentOutput{}
+	resp.Body.Status = "Assignment deleted sucessfully"
+	resp.Body.ID = input.ID
+	return resp, nil
+}
+
+// TeacherAssignmentsHandler - the load of the teacher, the subjects the teacher teaches
+// in which classes
+func (h *AssignmentHandlers) TeacherAssignmentsHandler(
+	ctx context.Context,
+	input *TermAssignmentsInput,
+) (*AssignmentsOutput, error) {
+	if _, err := h.teachersDB.GetTeacherByID(ctx, input.ID); err != nil {
+		if queryFailed(err) {
+			return nil, dbError(err, err)
+		}
+		return nil, huma.Error404NotFound("teacher not found", err)
+	}
+	return h.assignments(ctx, map[string]string{
+		"teacher_id": strconv.Itoa(input.ID),
+		"term":       input.Term,
+	}, nil)
+}
+
+// ClassSubjectsHandler - the subjects of the class and the teachers who teach them
+func (h *AssignmentHandlers) ClassSubjectsHandler(
+	ctx context.Context,
+	input *TermAssignmentsInput,
+) (*AssignmentsOutput, error) {
+	if _, err := h.classesDB.GetClassByID(ctx, input.ID); err != nil {
+		return nil, classError(err, huma.Error500InternalServerError("Error quering database", err))
+	}
+	return h.assignments(ctx, map[string]string{
+		"class_id": strconv.Itoa(input.ID),
+		"term":     input.Term,
+	}, nil)
+}
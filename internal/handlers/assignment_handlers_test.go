@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/humatest"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops/memory"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
+)
+
+func TestAssignmentHandlers(t *testing.T) {
+	_, api := humatest.New(t)
+	store, err := memory.NewStoreFromFixture(memory.Fixture{
+		Classes:  []models.Class{{ID: 1, Name: "10B"}, {ID: 2, Name: "9A"}},
+		Subjects: []models.Subject{{ID: 1, Name: "History"}, {ID: 2, Name: "Math"}},
+		Teachers: []models.Teacher{
+			{ID: 100, FirstName: "Jane", LastName: "Small", Email: "jane@example.com", ClassID: 1},
+			{ID: 101, FirstName: "Adam", LastName: "Brown", Email: "adam@example.com", ClassID: 2},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewAssignmentsHandler(store.Assignments, store.Teachers, store.Subjects, store.Classes, logging.Init(false))
+	huma.Register(api, huma.Operation{
+		OperationID: "post-assignments",
+		Method:      http.MethodPost,
+		Path:        "/assignments",
+	}, h.AssignmentsAdd)
+	huma.Register(api, huma.Operation{
+		OperationID: "assignments-by-teacher-id",
+		Method:      http.MethodGet,
+		Path:        "/teachers/{id}/assignments",
+	}, h.TeacherAssignmentsHandler)
+	huma.Register(api, huma.Operation{
+		OperationID: "subjects-by-class-id",
+		Method:      http.MethodGet,
+		Path:        "/classes/{id}/subjects",
+	}, h.ClassSubjectsHandler)
+
+	resp := api.Post("/assignments", map[string]any{
+		"assignments": []map[string]any{
+			{"teacher_id": 100, "subject_id": 1, "class_id": 1, "term": "T1"},
+			{"teacher_id": 100, "subject_id": 1, "class_id": 2, "term": "T1"},
+			{"teacher_id": 101, "subject_id": 2, "class_id": 1, "term": "T1"},
+		},
+	})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from add, got %d %s", resp.Code, resp.Body.String())
+	}
+	if code := api.Post("/assignments", map[string]any{
+		"assignments": []map[string]any{{"teacher_id": 101, "subject_id": 1, "class_id": 1, "term": "T1"}},
+	}).Code; code != http.StatusConflict {
+		t.Fatalf("Expected 409 for History in 10B assigned twice, got %d", code)
+	}
+	if code := api.Post("/assignments", map[string]any{
+		"assignments": []map[string]any{{"teacher_id": 101, "subject_id": 9, "class_id": 1}},
+	}).Code; code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected 422 for the missing subject, got %d", code)
+	}
+
+	var body struct {
+		Count int                 `json:"count"`
+		Data  []models.Assignment `json:"data"`
+	}
+	resp = api.Get("/teachers/100/assignments?term=T1")
+	if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Count != 2 || body.Data[0].ClassName != "10B" || body.Data[1].ClassName != "9A" {
+		t.Fatalf("Expected History in 10B and 9A for Jane, got %s", resp.Body.String())
+	}
+	resp = api.Get("/classes/1/subjects")
+	if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Count != 2 || body.Data[0].SubjectName != "History" || body.Data[1].TeacherName != "Adam Brown" {
+		t.Fatalf("Expected History and Math teachers of 10B, got %s", resp.Body.String())
+	}
+	if code := api.Get("/teachers/7/assignments").Code; code != http.StatusNotFound {
+		t.Fatalf("Expected 404 for the missing teacher, got %d", code)
+	}
+	if code := api.Get("/classes/7/subjects").Code; code != http.StatusNotFound {
+		t.Fatalf("Expected 404 for the missing class, got %d", code)
+	}
+}
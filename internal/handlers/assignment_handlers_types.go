@@ -0,0 +1,49 @@
+package handlers
+
+import "github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+
+type AssignmentsInput struct {
+	Body struct {
+		Assignments []models.AssignmentInput `json:"assignments" doc:"Teaching assignments"`
+	}
+}
+
+type AssignmentsOutput struct {
+	Body struct {
+		Status string              `json:"status"`
+		Count  int                 `json:"count"`
+		Data   []models.Assignment `json:"data"`
+	}
+}
+
+type AssignmentIDInput struct {
+	ID int `path:"id"`
+}
+
+type AssignmentOutput struct {
+	Body struct {
+		Status string            `json:"status"`
+		Data   models.Assignment `json:"data"`
+	}
+}
+
+type AssignmentUpdateInput struct {
+	ID   int `path:"id"`
+	Body struct {
+		Assignment models.AssignmentInput `json:"assignment" doc:"Teaching assignment"`
+	}
+}
+
+type DeleteAssignmentOutput struct {
+	Body struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}
+}
+
+// TermAssignmentsInput - the assignments of the teacher or class of the id, in the term
+// when it is set
+type TermAssignmentsInput struct {
+	ID   int    `path:"id"`
+	Term string `query:"term" doc:"Only the assignments of the term"`
+}
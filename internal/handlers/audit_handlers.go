@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
+)
+
+type AuditHandlers struct {
+	auditDB dataops.AuthAuditInf
+	logger  *logging.Logger
+}
+
+func NewAuditHandler(adb dataops.AuthAuditInf, logger *logging.Logger) *AuditHandlers {
+	return &AuditHandlers{
+		auditDB: adb,
+		logger:  logger,
+	}
+}
+
+// auditTime - parses the RFC3339 time of the query to the UTC form the events are stored in
+func auditTime(location, value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return "", huma.Error400BadRequest(
+			"Invalid time format",
+			&huma.ErrorDetail{Location: location, Message: "must be RFC3339 time", Value: value},
+		)
+	}
+	return t.UTC().Format(time.RFC3339), nil
+}
+
+func (h *AuditHandlers) ListAuthEventsHandler(
+	ctx context.Context,
+	input *AuthEventsInput,
+) (*AuthEventsOutput, error) {
+	from, err := auditTime("query.from", input.From)
+	if err != nil {
+		return nil, err
+	}
+	to, err := auditTime("query.to", input.To)
+	if err != nil {
+		return nil, err
+	}
+
+	events, total, err := h.auditDB.ListAuthEvents(models.AuthEventFilter{
+		ExecID:    input.ExecID,
+		EventType: input.EventType,
+		Outcome:   input.Outcome,
+		From:      from,
+		To:        to,
+		Page:      input.Page,
+		Limit:     input.Limit,
+	})
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Error quering database", err)
+	}
+
+	out := &AuthEventsOutput{}
+	out.Body.Status = "Success"
+	out.Body.Count = total
+	out.Body.Page = input.Page
+	out.Body.PageSize = input.Limit
+	out.Body.Data = events
+	return out, nil
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/humatest"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/config"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/password"
+)
+
+type mockAuditDB struct {
+	events []models.AuthEvent
+	filter models.AuthEventFilter
+}
+
+func (m *mockAuditDB) RecordAuthEvent(event models.AuthEvent) error {
+	m.events = append(m.events, event)
+	return nil
+}
+
+func (m *mockAuditDB) ListAuthEvents(filter models.AuthEventFilter) ([]models.AuthEvent, int, error) {
+	m.filter = filter
+	return m.events, len(m.events), nil
+}
+
+func TestLoginFailureIsAudited(t *testing.T) {
+	_, api := humatest.New(t)
+	hash, err := password.Hash("secret", password.DefaultParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auditDB := &mockAuditDB{}
+	h := NewExecsHandler(ExecsDeps{
+		Execs:         &mockLoginExecsDB{username: "admin", password: hash},
+		LoginAttempts: &mockLoginAttemptsDB{attempts: map[string]models.LoginAttempt{}},
+		MFA:           &mockMFADB{},
+		Audit:         auditDB,
+		Config:        config.Config{LoginMaxAttempts: 5, LoginMaxAttemptsIP: 10},
+	})
+	huma.Register(api, huma.Operation{
+		OperationID: "login-exec",
+		Method:      http.MethodPost,
+		Path:        "/execs/login",
+	}, h.ExecLoginHandler)
+
+	api.Post("/execs/login", map[string]any{
+		"execs": map[string]any{"username": "admin", "password": "wrong"},
+	})
+	if len(auditDB.events) != 1 {
+		t.Fatalf("Expected one audit event, got %+v", auditDB.events)
+	}
+	event := auditDB.events[0]
+	if event.EventType != models.AuthEventLogin || event.Outcome != models.AuthOutcomeFailure ||
+		event.Username != "admin" || event.CreatedAt == "" {
+		t.Fatalf("Expected failed login of admin, got %+v", event)
+	}
+}
+
+func TestListAuthEvents(t *testing.T) {
+	_, api := humatest.New(t)
+	auditDB := &mockAuditDB{events: []models.AuthEvent{{ID: 1, EventType: models.AuthEventLogin}}}
+	h := NewAuditHandler(auditDB, logging.Init(false))
+	huma.Register(api, huma.Operation{
+		OperationID: "list-auth-events",
+		Method:      http.MethodGet,
+		Path:        "/audit/auth",
+	}, h.ListAuthEventsHandler)
+
+	resp := api.Get("/audit/auth?exec_id=2&event_type=login&outcome=failure&from=2025-01-01T02:00:00%2B02:00&page=2&limit=5")
+	if resp.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d %s", resp.Code, resp.Body.String())
+	}
+	want := models.AuthEventFilter{
+		ExecID:    2,
+		EventType: models.AuthEventLogin,
+		Outcome:   models.AuthOutcomeFailure,
+		From:      "2025-01-01T00:00:00Z",
+		Page:      2,
+		Limit:     5,
+	}
+	if auditDB.filter != want {
+		t.Fatalf("Expected filter %+v, got %+v", want, auditDB.filter)
+	}
+
+	if code := api.Get("/audit/auth?to=yesterday").Code; code != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for invalid time, got %d", code)
+	}
+	if code := api.Get("/audit/auth?outcome=maybe").Code; code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected 422 for unknown outcome, got %d", code)
+	}
+}
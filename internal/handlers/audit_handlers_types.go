@@ -0,0 +1,22 @@
+package handlers
+
+import "github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+
+type AuthEventsInput struct {
+	ExecID    int    `query:"exec_id"    doc:"Only the events about this exec"`
+	EventType string `query:"event_type" doc:"Only the events of this type" enum:"login,login_mfa,login_oidc,logout,logout_all,refresh,password_change,password_set,password_reset_request,password_reset,account_activation,exec_deactivate,exec_reactivate,exec_lock,exec_unlock,sessions_revoke,mfa_enable,mfa_disable"`
+	Outcome   string `query:"outcome"    doc:"Only the successful or the failed events" enum:"success,failure"`
+	From      string `query:"from"       doc:"Events at or after this time, RFC3339" example:"2025-01-01T00:00:00Z"`
+	To        string `query:"to"         doc:"Events before this time, RFC3339" example:"2025-02-01T00:00:00Z"`
+	PaginationParams
+}
+
+type AuthEventsOutput struct {
+	Body struct {
+		Status   string             `json:"status"`
+		Count    int                `json:"count"`
+		Page     int                `json:"page"`
+		PageSize int                `json:"page_size"`
+		Data     []models.AuthEvent `json:"data"`
+	}
+}
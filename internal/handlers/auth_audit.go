@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/middleware"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+)
+
+// auditAuth - writes the authentication event to the audit log with the client of the request,
+// the errors are only logged so a broken audit log does not stop the logins
+func (h *ExecsHandlers) auditAuth(
+	ctx context.Context,
+	eventType string,
+	exec models.Exec,
+	outcome, detail string,
+) {
+	if h.auditDB == nil {
+		return
+	}
+	event := models.AuthEvent{
+		EventType: eventType,
+		ExecID:    exec.ID,
+		Username:  exec.Username,
+		IPAddress: middleware.ClientIP(ctx),
+		UserAgent: middleware.ClientUserAgent(ctx),
+		Outcome:   outcome,
+		Detail:    detail,
+		CreatedAt: h.now().UTC().Format(time.RFC3339),
+	}
+	if actorID, err := execIDFromContext(ctx); err == nil && actorID != exec.ID {
+		event.ActorID = actorID
+	}
+	if len(event.UserAgent) > 255 {
+		event.UserAgent = event.UserAgent[:255]
+	}
+	if len(event.Username) > 255 {
+		event.Username = event.Username[:255]
+	}
+	if err := h.auditDB.RecordAuthEvent(event); err != nil {
+		h.logger.Logging.Errorf("failed to write the audit event %s %v", eventType, err)
+	}
+}
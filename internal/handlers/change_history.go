@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"encoding/json"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
+)
+
+// changeRecorder - reads the revisions of the records and their states for the restores,
+// shared by the teachers, students and execs handlers. The revisions are written by dataops
+// together with the change
+type changeRecorder struct {
+	historyDB dataops.ChangeHistoryInf
+	logger    *logging.Logger
+}
+
+func (c changeRecorder) listRevisions(entity string, input *RevisionsInput) (*RevisionsOutput, error) {
+	revisions, total, err := c.historyDB.ListRevisions(entity, input.ID, input.Page, input.Limit)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Error quering database", err)
+	}
+
+	out := &RevisionsOutput{}
+	out.Body.Status = "Success"
+	out.Body.Count = total
+	out.Body.Page = input.Page
+	out.Body.PageSize = input.Limit
+	out.Body.Data = revisions
+	return out, nil
+}
+
+// revisionState - decodes the state of the record after the revision into state
+func (c changeRecorder) revisionState(
+	entity string,
+	input *RevisionRestoreInput,
+	state any,
+) (models.Revision, error) {
+	rev, err := c.historyDB.GetRevision(entity, input.ID, input.Revision)
+	if err != nil {
+		return models.Revision{}, huma.Error404NotFound("revision not found", err)
+	}
+	if len(rev.After) == 0 {
+		return models.Revision{}, huma.Error409Conflict("the revision deleted the record, there is nothing to restore")
+	}
+	if err := json.Unmarshal(rev.After, state); err != nil {
+		c.logger.Logging.Errorf("invalid state of the revision %d %v", rev.ID, err)
+		return models.Revision{}, huma.Error500InternalServerError("invalid revision")
+	}
+	return rev, nil
+}
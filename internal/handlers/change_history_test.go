@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/humatest"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops/memory"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
+)
+
+type mockHistoryDB struct {
+	revisions []models.Revision
+}
+
+func (m *mockHistoryDB) RecordRevision(rev models.Revision) error {
+	rev.ID = len(m.revisions) + 1
+	m.revisions = append(m.revisions, rev)
+	return nil
+}
+
+func (m *mockHistoryDB) ListRevisions(entity string, id, page, limit int) ([]models.Revision, int, error) {
+	var revisions []models.Revision
+	for _, rev := range m.revisions {
+		if rev.Entity == entity && rev.EntityID == id {
+			revisions = append(revisions, rev)
+		}
+	}
+	return revisions, len(revisions), nil
+}
+
+func (m *mockHistoryDB) GetRevision(entity string, entityID, id int) (models.Revision, error) {
+	for _, rev := range m.revisions {
+		if rev.ID == id && rev.Entity == entity && rev.EntityID == entityID {
+			return rev, nil
+		}
+	}
+	return models.Revision{}, errors.New("revision not found")
+}
+
+func TestTeacherChangeHistory(t *testing.T) {
+	_, api := humatest.New(t)
+	mockDB := memory.NewTeachers(models.Teacher{
+		ID:        42,
+		FirstName: "Jane",
+		LastName:  "Small",
+		Email:     "janesmall@example.com",
+		ClassID:   12,
+		Subject:   "History",
+	})
+	historyDB := &mockHistoryDB{}
+	mockDB.History = historyDB
+	h := NewTeachersHandler(mockDB, historyDB, logging.Init(false))
+	huma.Register(api, huma.Operation{
+		OperationID: "update-teacher",
+		Method:      http.MethodPut,
+		Path:        "/teachers/{id}",
+	}, h.UpdateTeacherHandler)
+	huma.Register(api, huma.Operation{
+		OperationID: "teacher-history",
+		Method:      http.MethodGet,
+		Path:        "/teachers/{id}/history",
+	}, h.TeacherHistoryHandler)
+	huma.Register(api, huma.Operation{
+		OperationID: "restore-teacher-revision",
+		Method:      http.MethodPost,
+		Path:        "/teachers/{id}/history/{revision}/restore",
+	}, h.RestoreTeacherRevisionHandler)
+
+	ctx := dataops.WithActor(context.Background(), 7)
+	resp := api.PutCtx(ctx, "/teachers/42", map[string]any{
+		"teacher": map[string]any{
+			"id":         42,
+			"first_name": "Jane",
+			"last_name":  "Small",
+			"email":      "janesmall@example.com",
+			"class_id":   12,
+			"subject":    "Math",
+		},
+	})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from update, got %d %s", resp.Code, resp.Body.String())
+	}
+	if len(historyDB.revisions) != 1 {
+		t.Fatalf("Expected one revision, got %+v", historyDB.revisions)
+	}
+	rev := historyDB.revisions[0]
+	if rev.Action != models.ChangeUpdate || rev.ActorID != 7 || len(rev.Changes) != 1 ||
+		rev.Changes[0] != (models.FieldChange{Field: "subject", From: "History", To: "Math"}) {
+		t.Fatalf("Expected subject change by exec 7, got %+v", rev)
+	}
+
+	resp = api.Get("/teachers/42/history")
+	var history struct {
+		Count int               `json:"count"`
+		Data  []models.Revision `json:"data"`
+	}
+	if err := json.Unmarshal(resp.Body.Bytes(), &history); err != nil {
+		t.Fatal(err)
+	}
+	if history.Count != 1 || history.Data[0].ID != rev.ID {
+		t.Fatalf("Expected the revision in the history, got %s", resp.Body.String())
+	}
+
+	// the state after the first revision of the teacher
+	historyDB.revisions[0].After = json.RawMessage(`{"id":42,"first_name":"Janet","last_name":"Small","class_id":11,"subject":"Art","email":"janet@example.com"}`)
+	resp = api.PostCtx(ctx, "/teachers/42/history/1/restore")
+	if resp.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from restore, got %d %s", resp.Code, resp.Body.String())
+	}
+	restored := historyDB.revisions[1]
+	if restored.Action != models.ChangeRestore || restored.RestoredFrom != 1 || len(restored.Changes) != 4 {
+		t.Fatalf("Expected restore revision of revision 1, got %+v", restored)
+	}
+
+	historyDB.revisions[0].After = nil
+	if code := api.PostCtx(ctx, "/teachers/42/history/1/restore").Code; code != http.StatusConflict {
+		t.Fatalf("Expected 409 for restoring a delete, got %d", code)
+	}
+	if code := api.PostCtx(ctx, "/teachers/42/history/9/restore").Code; code != http.StatusNotFound {
+		t.Fatalf("Expected 404 for unknown revision, got %d", code)
+	}
+}
@@ -0,0 +1,23 @@
+package handlers
+
+import "github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+
+type RevisionsInput struct {
+	ID int `path:"id"`
+	PaginationParams
+}
+
+type RevisionsOutput struct {
+	Body struct {
+		Status   string            `json:"status"`
+		Count    int               `json:"count"`
+		Page     int               `json:"page"`
+		PageSize int               `json:"page_size"`
+		Data     []models.Revision `json:"data"`
+	}
+}
+
+type RevisionRestoreInput struct {
+	ID       int `path:"id"`
+	Revision int `path:"revision" doc:"ID of the revision which state is restored"`
+}
@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
+)
+
+type ClassHandlers struct {
+	mutex      sync.Mutex
+	classesDB  dataops.ClassesInf
+	teachersDB dataops.TeachersInf
+	logger     *logging.Logger
+}
+
+func NewClassesHandler(
+	cdb dataops.ClassesInf,
+	tdb dataops.TeachersInf,
+	logger *logging.Logger,
+) *ClassHandlers {
+	return &ClassHandlers{
+		classesDB:  cdb,
+		teachersDB: tdb,
+		logger:     logger,
+	}
+}
+
+// classError - 404 for the missing class and 409 for the class which is still referenced,
+// otherwise the fallback
+func classError(err error, fallback error) error {
+	switch {
+	case queryFailed(err):
+		return dbError(err, fallback)
+	case strings.Contains(err.Error(), "not found"):
+		return huma.Error404NotFound("class not found", err)
+	case strings.Contains(err.Error(), "still has"):
+		return huma.Error409Conflict("the class still has teachers, students, teaching assignments or enrollments", err)
+	}
+	return fallback
+}
+
+// checkClass - the name is free in the academic year and the homeroom teacher exists,
+// the database would only fail on them
+func (h *ClassHandlers) checkClass(ctx context.Context, class models.Class, exceptID int) error {
+	same, _, err := h.classesDB.GetAllClasses(ctx, map[string]string{
+		"name":          class.Name,
+		"academic_year": class.AcademicYear,
+	}, nil)
+	if err != nil {
+		return dbError(err, huma.Error500InternalServerError("Error quering database", err))
+	}
+	for _, other := range same {
+		// the empty academic year is not a filter so it is compared here
+		if other.ID != exceptID && other.AcademicYear == class.AcademicYear {
+			return huma.Error409Conflict("class " + class.Name + " already exists in the academic year")
+		}
+	}
+	if class.HomeroomTeacherID != 0 {
+		if _, err := h.teachersDB.GetTeacherByID(ctx, class.HomeroomTeacherID); err != nil {
+			if queryFailed(err) {
+				return dbError(err, err)
+			}
+			return huma.Error422UnprocessableEntity(
+				"homeroom teacher " + strconv.Itoa(class.HomeroomTeacherID) + " not found",
+			)
+		}
+	}
+	return nil
+}
+
+func (h *ClassHandlers) ClassGet(ctx context.Context, input *ClassIDInput) (*ClassOutput, error) {
+	class, err := h.classesDB.GetClassByID(ctx, input.ID)
+	if err != nil {
+		return nil, classError(err, huma.Error500InternalServerError("Error quering database", err))
+	}
+	resp := &ClassOutput{}
+	resp.Body.Status = "Success"
+	resp.Body.Data = class
+	return resp, nil
+}
+
+func (h *ClassHandlers) ClassesGet(
+	ctx context.Context,
+	input *models.ClassesQueryInput,
+) (*ClassesOutput, error) {
+	params := map[string]string{
+		"name":          input.Name,
+		"grade_level":   input.GradeLevel,
+		"academic_year": input.AcademicYear,
+	}
+	classes, count, err := h.classesDB.GetAllClasses(ctx, params, input.SortBy)
+	if err != nil {
+		return nil, dbError(err, huma.Error500InternalServerError("Error quering database", err))
+	}
+	resp := &ClassesOutput{}
+	resp.Body.Status = "Success"
+	resp.Body.Count = count
+	resp.Body.Data = classes
+	return resp, nil
+}
+
+func (h *ClassHandlers) ClassesAdd(ctx context.Context, input *ClassesInput) (*ClassesOutput, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	addedClasses := make([]models.Class, len(input.Body.Classes))
+	for i, newClass := range input.Body.Classes {
+		class := models.Class{
+			Name:              newClass.Name,
+			GradeLevel:        newClass.GradeLevel,
+			AcademicYear:      newClass.AcademicYear,
+			HomeroomTeacherID: newClass.HomeroomTeacherID,
+			Room:              newClass.Room,
+			Capacity:          newClass.Capacity,
+		}
+		if err := h.checkClass(ctx, class, 0); err != nil {
+			return nil, err
+		}
+		id, err := h.classesDB.InsertClass(ctx, &class)
+		if err != nil {
+			return nil, dbError(err, huma.Error500InternalServerError("Error adding to the database", err))
+		}
+		class.ID = int(id)
+		addedClasses[i] = class
+	}
+
+	resp := &ClassesOutput{}
+	resp.Body.Status = "Success"
+	resp.Body.Count = len(addedClasses)
+	resp.Body.Data = addedClasses
+	return resp, nil
+}
+
+func (h *ClassHandlers) UpdateClassHandler(ctx context.Context, input *ClassUpdateInput) (*ClassOutput, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	class := models.Class{
+		ID:                input.ID,
+		Name:              input.Body.Class.Name,
+		GradeLevel:        input.Body.Class.GradeLevel,
+		AcademicYear:      input.Body.Class.AcademicYear,
+		HomeroomTeacherID: input.Body.Class.HomeroomTeacherID,
+		Room:              input.Body.Class.Room,
+		Capacity:          input.Body.Class.Capacity,
+	}
+	if _, err := h.classesDB.GetClassByID(ctx, input.ID); err != nil {
+		return nil, classError(err, huma.Error500InternalServerError("error update database", err))
+	}
+	if err := h.checkClass(ctx, class, input.ID); err != nil {
+		return nil, err
+	}
+	updatedClass, err := h.classesDB.UpdateClass(ctx, input.ID, class)
+	if err != nil {
+		return nil, classError(err, huma.Error500InternalServerError("error update database", err))
+	}
+	resp := &ClassOutput{}
+	resp.Body.Status = "Success"
+	resp.Body.Data = updatedClass
+	return resp, nil
+}
+
+func (h *ClassHandlers) PatchClassHandler(ctx context.Context, input *ClassPatchInput) (*ClassOutput, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	existingClass, err := h.classesDB.GetClassByID(ctx, input.ID)
+	if err != nil {
+		return nil, classError(err, huma.Error500InternalServerError("error update database", err))
+	}
+	patch := models.Class{
+		Name:              input.Body.Class.Name,
+		GradeLevel:        input.Body.Class.GradeLevel,
+		AcademicYear:      input.Body.Class.AcademicYear,
+		HomeroomTeacherID: input.Body.Class.HomeroomTeacherID,
+		Room:              input.Body.Class.Room,
+		Capacity:          input.Body.Class.Capacity,
+	}
+	// the name is checked with the values after the patch and the homeroom teacher only
+	// when it is set by the patch
+	patched := existingClass
+	if patch.Name != "" {
+		patched.Name = patch.Name
+	}
+	if patch.AcademicYear != "" {
+		patched.AcademicYear = patch.AcademicYear
+	}
+	patched.HomeroomTeacherID = patch.HomeroomTeacherID
+	if err := h.checkClass(ctx, patched, input.ID); err != nil {
+		return nil, err
+	}
+	updatedClass, err := h.classesDB.PatchClass(ctx, input.ID, patch)
+	if err != nil {
+		return nil, classError(err, huma.Error500InternalServerError("error update database", err))
+	}
+	resp := &ClassOutput{}
+	resp.Body.Status = "Success"
+	resp.Body.Data = updatedClass
+	return resp, nil
+}
+
+// DeleteClassHandler - removes the class for good, the class with teachers, students,
+// teaching assignments or enrollments is kept
+func (h *ClassHandlers) DeleteClassHandler(ctx context.Context, input *ClassIDInput) (*DeleteClassOutput, error) {
+	if err := h.classesDB.DeleteClass(ctx, input.ID); err != nil {
+		return nil, classError(err, huma.Error500InternalServerError("error deleting class", err))
+	}
+	resp := &DeleteClassOutput{}
+	resp.Body.Status = "Class deleted sucessfully"
+	resp.Body.ID = input.ID
+	return resp, nil
+}
+
+func (h *ClassHandlers) ClassStudentsHandler(ctx context.Context, input *ClassIDInput) (*ClassStudentsOutput, error) {
+	if _, err := h.classesDB.GetClassByID(ctx, input.ID); err != nil {
+		return nil, classError(err, huma.Error500InternalServerError("Error quering database", err))
+	}
+	students, err := h.classesDB.GetStudentsByClassID(ctx, input.ID)
+	if err != nil {
+		return nil, dbError(err, huma.Error500InternalServerError("Error quering database", err))
+	}
+	resp := &ClassStudentsOutput{}
+	resp.Body.Status = "Success"
+	resp.Body.Count = len(students)
+	resp.Body.Data = students
+	return resp, nil
+}
+
+func (h *ClassHandlers) ClassTeachersHandler(ctx context.Context, input *ClassIDInput) (*ClassTeachersOutput, error) {
+	if _, err := h.classesDB.GetClassByID(ctx, input.ID); err != nil {
+		return nil, classError(err, huma.Error500InternalServerError("Error quering database", err))
+	}
+	teachers, err := h.classesDB.GetTeachersByClassID(ctx, input.ID)
+	if err != nil {
+		return nil, dbError(err, huma.Error500InternalServerError("Error quering database", err))
+	}
+	resp := &ClassTeachersOutput{}
+	resp.Body.Status = "Success"
+	resp.Body.Count = len(teachers)
+	resp.Body.Data = teachers
+	return resp, nil
+}
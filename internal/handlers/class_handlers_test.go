@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/humatest"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops/memory"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
+)
+
+func TestClassHandlers(t *testing.T) {
+	_, api := humatest.New(t)
+	store := memory.NewStore()
+	h := NewClassesHandler(store.Classes, store.Teachers, logging.Init(false))
+	huma.Register(api, huma.Operation{
+		OperationID: "post-classes",
+		Method:      http.MethodPost,
+		Path:        "/classes",
+	}, h.ClassesAdd)
+	huma.Register(api, huma.Operation{
+		OperationID: "patch-class",
+		Method:      http.MethodPatch,
+		Path:        "/classes/{id}",
+	}, h.PatchClassHandler)
+	huma.Register(api, huma.Operation{
+		OperationID: "delete-class",
+		Method:      http.MethodDelete,
+		Path:        "/classes/{id}",
+	}, h.DeleteClassHandler)
+	huma.Register(api, huma.Operation{
+		OperationID: "get-class-teachers",
+		Method:      http.MethodGet,
+		Path:        "/classes/{id}/teachers",
+	}, h.ClassTeachersHandler)
+
+	resp := api.Post("/classes", map[string]any{
+		"classes": []map[string]any{{"name": "10B", "academic_year": "2025/2026"}},
+	})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from add, got %d %s", resp.Code, resp.Body.String())
+	}
+	if code := api.Post("/classes", map[string]any{
+		"classes": []map[string]any{{"name": "10B", "academic_year": "2025/2026"}},
+	}).Code; code != http.StatusConflict {
+		t.Fatalf("Expected 409 for the duplicate class, got %d", code)
+	}
+	if code := api.Patch("/classes/1", map[string]any{
+		"class": map[string]any{"homeroom_teacher_id": 100},
+	}).Code; code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected 422 for the missing homeroom teacher, got %d", code)
+	}
+
+	if _, err := store.Teachers.InsertTeachers(context.Background(), &models.Teacher{
+		FirstName: "Jane", Email: "jane@example.com", ClassID: 1,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if resp := api.Get("/classes/1/teachers"); resp.Code != http.StatusOK {
+		t.Fatalf("Expected 200 for the teachers of the class, got %d %s", resp.Code, resp.Body.String())
+	}
+	if code := api.Get("/classes/9/teachers").Code; code != http.StatusNotFound {
+		t.Fatalf("Expected 404 for the teachers of the missing class, got %d", code)
+	}
+	if code := api.Delete("/classes/1").Code; code != http.StatusConflict {
+		t.Fatalf("Expected 409 deleting the class with a teacher, got %d", code)
+	}
+	if code := api.Delete("/classes/9").Code; code != http.StatusNotFound {
+		t.Fatalf("Expected 404 deleting the missing class, got %d", code)
+	}
+}
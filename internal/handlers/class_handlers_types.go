@@ -0,0 +1,65 @@
+package handlers
+
+import "github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+
+type ClassesInput struct {
+	Body struct {
+		Classes []models.ClassInput `json:"classes" doc:"Classes"`
+	}
+}
+
+type ClassesOutput struct {
+	Body struct {
+		Status string         `json:"status"`
+		Count  int            `json:"count"`
+		Data   []models.Class `json:"data"`
+	}
+}
+
+type ClassIDInput struct {
+	ID int `path:"id"`
+}
+
+type ClassOutput struct {
+	Body struct {
+		Status string       `json:"status"`
+		Data   models.Class `json:"data"`
+	}
+}
+
+type ClassUpdateInput struct {
+	ID   int `path:"id"`
+	Body struct {
+		Class models.ClassUpdateBody `json:"class" doc:"Class"`
+	}
+}
+
+type ClassPatchInput struct {
+	ID   int `path:"id"`
+	Body struct {
+		Class models.ClassPatchBody `json:"class" doc:"Class"`
+	}
+}
+
+type DeleteClassOutput struct {
+	Body struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}
+}
+
+type ClassStudentsOutput struct {
+	Body struct {
+		Status string           `json:"status"`
+		Count  int              `json:"count"`
+		Data   []models.Student `json:"data"`
+	}
+}
+
+type ClassTeachersOutput struct {
+	Body struct {
+		Status string           `json:"status"`
+		Count  int              `json:"count"`
+		Data   []models.Teacher `json:"data"`
+	}
+}
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops"
+)
+
+// queryFailed - the query timed out or was canceled, the error says nothing about the record
+func queryFailed(err error) bool {
+	return errors.Is(err, dataops.ErrQueryTimeout) || errors.Is(err, dataops.ErrQueryCanceled)
+}
+
+// dbError - 504 when the query timed out and 503 when it was canceled, otherwise the
+// error the handler returns for the failed query
+func dbError(err error, fallback error) error {
+	switch {
+	case errors.Is(err, dataops.ErrQueryTimeout):
+		return huma.Error504GatewayTimeout("database query timed out")
+	case errors.Is(err, dataops.ErrQueryCanceled):
+		return huma.Error503ServiceUnavailable("database query canceled")
+	}
+	return fallback
+}
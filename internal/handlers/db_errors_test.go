@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/humatest"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops/memory"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
+)
+
+// mockSlowTeachersDB - the lookup by id times out and the listing is canceled
+type mockSlowTeachersDB struct {
+	*memory.Teachers
+}
+
+func (m *mockSlowTeachersDB) GetTeacherByID(context.Context, int) (models.Teacher, error) {
+	return models.Teacher{}, fmt.Errorf("error quering the database error: %w", dataops.ErrQueryTimeout)
+}
+
+func (m *mockSlowTeachersDB) GetAllTeachers(
+	context.Context,
+	map[string]string,
+	[]string,
+	bool,
+) ([]models.Teacher, int, error) {
+	return nil, 0, fmt.Errorf("error retreiving data: %w", dataops.ErrQueryCanceled)
+}
+
+func TestQueryTimeoutStatus(t *testing.T) {
+	_, api := humatest.New(t)
+	h := NewTeachersHandler(&mockSlowTeachersDB{memory.NewTeachers()}, nil, logging.Init(false))
+	huma.Get(api, "/teachers/{id}", h.TeacherGet)
+	huma.Get(api, "/teachers", h.TeachersGet)
+
+	if code := api.Get("/teachers/42").Code; code != http.StatusGatewayTimeout {
+		t.Fatalf("Expected status 504 for the timed out query, got %v", code)
+	}
+	if code := api.Get("/teachers").Code; code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status 503 for the canceled query, got %v", code)
+	}
+}
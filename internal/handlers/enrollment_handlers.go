@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
+)
+
+type EnrollmentHandlers struct {
+	mutex         sync.Mutex
+	enrollmentsDB dataops.EnrollmentsInf
+	studentsDB    dataops.StudentInf
+	classesDB     dataops.ClassesInf
+	logger        *logging.Logger
+}
+
+func NewEnrollmentsHandler(
+	edb dataops.EnrollmentsInf,
+	sdb dataops.StudentInf,
+	cdb dataops.ClassesInf,
+	logger *logging.Logger,
+) *EnrollmentHandlers {
+	return &EnrollmentHandlers{
+		enrollmentsDB: edb,
+		studentsDB:    sdb,
+		classesDB:     cdb,
+		logger:        logger,
+	}
+}
+
+// enrollmentError - 404 for the missing student, 409 when the enrollment of the student
+// does not allow the change and 422 for the date before the start of the enrollment or
+// the missing class, otherwise the fallback
+func enrollmentError(err error, fallback error) error {
+	switch {
+	case queryFailed(err):
+		return dbError(err, fallback)
+	case strings.Contains(err.Error(), "student not found"):
+		return huma.Error404NotFound("student not found", err)
+	case strings.Contains(err.Error(), "already enrolled"), strings.Contains(err.Error(), "not enrolled"):
+		return huma.Error409Conflict(err.Error())
+	case strings.Contains(err.Error(), "before the start"), strings.Contains(err.Error(), "class not found"):
+		return huma.Error422UnprocessableEntity(err.Error())
+	}
+	return fallback
+}
+
+// checkClass - the class of the enrollment exists, the database would only fail on it
+func (h *EnrollmentHandlers) checkClass(ctx context.Context, classID int) error {
+	if _, err := h.classesDB.GetClassByID(ctx, classID); err != nil {
+		if queryFailed(err) {
+			return dbError(err, err)
+		}
+		return huma.Error422UnprocessableEntity("class " + strconv.Itoa(classID) + " not found")
+	}
+	return nil
+}
+
+// enrollmentDate - the date of the enrollment, today when it is not set
+func enrollmentDate(d string) string {
+	if d == "" {
+		return time.Now().UTC().Format(time.DateOnly)
+	}
+	return d
+}
+
+// StudentEnrollmentsHandler - the classes of the student over the academic years
+func (h *EnrollmentHandlers) StudentEnrollmentsHandler(
+	ctx context.Context,
+	input *StudentEnrollmentsInput,
+) (*EnrollmentsOutput, error) {
+	if _, err := h.studentsDB.GetStudentByID(ctx, input.ID); err != nil {
+		if queryFailed(err) {
+			return nil, dbError(err, err)
+		}
+		return nil, huma.Error404NotFound("student not found", err)
+	}
+	enrollments, err := h.enrollmentsDB.GetEnrollmentsByStudentID(ctx, input.ID)
+	if err != nil {
+		return nil, dbError(err, huma.Error500InternalServerError("Error quering database", err))
+	}
+	resp := &EnrollmentsOutput{}
+	resp.Body.Status = "Success"
+	resp.Body.Count = len(enrollments)
+	resp.Body.Data = enrollments
+	return resp, nil
+}
+
+// EnrollStudentHandler - the student without a class, new or graduated, is enrolled in
+// the class
+func (h *EnrollmentHandlers) EnrollStudentHandler(
+	ctx context.Context,
+	input *EnrollStudentInput,
+) (*EnrollmentOutput, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if err := h.checkClass(ctx, input.Body.ClassID); err != nil {
+		return nil, err
+	}
+	enrollment, err := h.enrollmentsDB.EnrollStudent(ctx, input.ID, input.Body.ClassID, enrollmentDate(input.Body.Date))
+	if err != nil {
+		return nil, enrollmentError(err, huma.Error500InternalServerError("error enrolling student", err))
+	}
+	return enrollmentOutput(enrollment), nil
+}
+
+// TransferStudentHandler - the student leaves the class of the active enrollment for the
+// other class
+func (h *EnrollmentHandlers) TransferStudentHandler(
+	ctx context.Context,
+	input *EnrollStudentInput,
+) (*EnrollmentOutput, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if err := h.checkClass(ctx, input.Body.ClassID); err != nil {
+		return nil, err
+	}
+	enrollment, err := h.enrollmentsDB.TransferStudent(ctx, input.ID, input.Body.ClassID, enrollmentDate(input.Body.Date))
+	if err != nil {
+		return nil, enrollmentError(err, huma.Error500InternalServerError("error transferring student", err))
+	}
+	return enrollmentOutput(enrollment), nil
+}
+
+// GraduateStudentHandler - the active enrollment of the student ends as graduated
+func (h *EnrollmentHandlers) GraduateStudentHandler(
+	ctx context.Context,
+	input *GraduateStudentInput,
+) (*EnrollmentOutput, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	enrollment, err := h.enrollmentsDB.GraduateStudent(ctx, input.ID, enrollmentDate(input.Body.Date))
+	if err != nil {
+		return nil, enrollmentError(err, huma.Error500InternalServerError("error graduating student", err))
+	}
+	return enrollmentOutput(enrollment), nil
+}
+
+func enrollmentOutput(enrollment models.Enrollment) *EnrollmentOutput {
+	resp := &EnrollmentOutput{}
+	resp.Body.Status = "Success"
+	resp.Body.Data = enrollment
+	return resp
+}
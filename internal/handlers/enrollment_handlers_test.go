@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/humatest"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops/memory"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
+)
+
+func TestEnrollmentHandlers(t *testing.T) {
+	_, api := humatest.New(t)
+	store, err := memory.NewStoreFromFixture(memory.Fixture{
+		Classes: []models.Class{{ID: 1, Name: "10B"}, {ID: 2, Name: "11B"}},
+		Students: []models.Student{
+			{ID: 100, FirstName: "Dan", Email: "dan@example.com", ClassID: 1},
+		},
+		Enrollments: []models.Enrollment{
+			{StudentID: 100, ClassID: 1, StartDate: "2025-09-01", Status: models.EnrollmentActive},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewEnrollmentsHandler(store.Enrollments, store.Students, store.Classes, logging.Init(false))
+	huma.Register(api, huma.Operation{
+		OperationID: "enrollments-by-student-id",
+		Method:      http.MethodGet,
+		Path:        "/students/{id}/enrollments",
+	}, h.StudentEnrollmentsHandler)
+	huma.Register(api, huma.Operation{
+		OperationID: "enroll-student",
+		Method:      http.MethodPost,
+		Path:        "/students/{id}/enroll",
+	}, h.EnrollStudentHandler)
+	huma.Register(api, huma.Operation{
+		OperationID: "transfer-student",
+		Method:      http.MethodPost,
+		Path:        "/students/{id}/transfer",
+	}, h.TransferStudentHandler)
+	huma.Register(api, huma.Operation{
+		OperationID: "graduate-student",
+		Method:      http.MethodPost,
+		Path:        "/students/{id}/graduate",
+	}, h.GraduateStudentHandler)
+
+	if code := api.Post("/students/100/enroll", map[string]any{"class_id": 2}).Code; code != http.StatusConflict {
+		t.Fatalf("Expected 409 enrolling the enrolled student, got %d", code)
+	}
+	if code := api.Post("/students/100/transfer", map[string]any{"class_id": 9}).Code; code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected 422 for the missing class, got %d", code)
+	}
+	if code := api.Post("/students/100/transfer", map[string]any{
+		"class_id": 2, "date": "2025-01-01",
+	}).Code; code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected 422 for the transfer before the enrollment, got %d", code)
+	}
+	if code := api.Post("/students/7/graduate", map[string]any{}).Code; code != http.StatusNotFound {
+		t.Fatalf("Expected 404 for the missing student, got %d", code)
+	}
+	if resp := api.Post("/students/100/transfer", map[string]any{
+		"class_id": 2, "date": "2026-09-01",
+	}); resp.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from transfer, got %d %s", resp.Code, resp.Body.String())
+	}
+	if resp := api.Post("/students/100/graduate", map[string]any{"date": "2027-06-30"}); resp.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from graduate, got %d %s", resp.Code, resp.Body.String())
+	}
+	if code := api.Post("/students/100/transfer", map[string]any{"class_id": 1}).Code; code != http.StatusConflict {
+		t.Fatalf("Expected 409 transferring the graduated student, got %d", code)
+	}
+
+	var body struct {
+		Count int                 `json:"count"`
+		Data  []models.Enrollment `json:"data"`
+	}
+	resp := api.Get("/students/100/enrollments")
+	if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Count != 2 || body.Data[0].ClassName != "10B" || body.Data[1].Status != models.EnrollmentGraduated {
+		t.Fatalf("Expected 10B and 11B graduated, got %s", resp.Body.String())
+	}
+	if code := api.Get("/students/7/enrollments").Code; code != http.StatusNotFound {
+		t.Fatalf("Expected 404 for the enrollments of the missing student, got %d", code)
+	}
+}
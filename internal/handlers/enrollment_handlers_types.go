@@ -0,0 +1,33 @@
+package handlers
+
+import "github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+
+type StudentEnrollmentsInput struct {
+	ID int `path:"id"`
+}
+
+type EnrollmentsOutput struct {
+	Body struct {
+		Status string              `json:"status"`
+		Count  int                 `json:"count"`
+		Data   []models.Enrollment `json:"data"`
+	}
+}
+
+type EnrollmentOutput struct {
+	Body struct {
+		Status string            `json:"status"`
+		Data   models.Enrollment `json:"data"`
+	}
+}
+
+// EnrollStudentInput - the student of the id is enrolled or transferred to the class
+type EnrollStudentInput struct {
+	ID   int `path:"id"`
+	Body models.EnrollInput
+}
+
+type GraduateStudentInput struct {
+	ID   int `path:"id"`
+	Body models.GraduateInput
+}
@@ -3,8 +3,6 @@ package handlers
 import (
 	"context"
 	"crypto/sha256"
-	"crypto/subtle"
-	"encoding/base64"
 	"encoding/hex"
 	"fmt"
 	"math/rand"
@@ -17,28 +15,79 @@ import (
 	"github.com/danielgtaylor/huma/v2"
 	"github.com/dkr290/go-advanced-projects/rest-api-school-management/config"
 	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/middleware"
 	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
 	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/mailer"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/oidc"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/password"
 	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/utils"
-	"golang.org/x/crypto/argon2"
 )
 
 type ExecsHandlers struct {
-	mutex   sync.Mutex
-	execsDB dataops.ExecsInf
-	logger  *logging.Logger
-	conf    config.Config
+	mutex           sync.Mutex
+	execsDB         dataops.ExecsInf
+	sessionsDB      dataops.SessionsInf
+	loginAttemptsDB dataops.LoginAttemptsInf
+	mfaDB           dataops.MFAInf
+	invitationsDB   dataops.ExecInvitationsInf
+	auditDB         dataops.AuthAuditInf
+	changeRecorder
+	identitiesDB   dataops.ExecIdentitiesInf
+	oidcProvider   *oidc.Provider
+	authCache      middleware.AuthInvalidator
+	logger         *logging.Logger
+	conf           config.Config
+	passwordPolicy password.Policy
+	mail           *mailer.Sender
+	// now - the clock for the totp codes and the mfa challenge, replaced in the tests
+	now func() time.Time
 }
 
-func NewExecsHandler(
-	tdb dataops.ExecsInf,
-	logger *logging.Logger,
-	conf config.Config,
-) *ExecsHandlers {
+// ExecsDeps - the stores and services of the execs handlers, the ones which are not
+// used can be left out, the logger and the auth cache have defaults
+type ExecsDeps struct {
+	Execs          dataops.ExecsInf
+	Sessions       dataops.SessionsInf
+	LoginAttempts  dataops.LoginAttemptsInf
+	MFA            dataops.MFAInf
+	Invitations    dataops.ExecInvitationsInf
+	Audit          dataops.AuthAuditInf
+	History        dataops.ChangeHistoryInf
+	AuthCache      middleware.AuthInvalidator
+	Logger         *logging.Logger
+	Config         config.Config
+	PasswordPolicy password.Policy
+	Mail           *mailer.Sender
+}
+
+// noAuthCache - the auth cache of the handlers without one, nothing is cached
+type noAuthCache struct{}
+
+func (noAuthCache) InvalidateSession(int) {}
+func (noAuthCache) InvalidateExec(int)    {}
+
+func NewExecsHandler(deps ExecsDeps) *ExecsHandlers {
+	if deps.Logger == nil {
+		deps.Logger = logging.Init(false)
+	}
+	if deps.AuthCache == nil {
+		deps.AuthCache = noAuthCache{}
+	}
 	return &ExecsHandlers{
-		execsDB: tdb,
-		logger:  logger,
-		conf:    conf,
+		execsDB:         deps.Execs,
+		sessionsDB:      deps.Sessions,
+		loginAttemptsDB: deps.LoginAttempts,
+		mfaDB:           deps.MFA,
+		invitationsDB:   deps.Invitations,
+		auditDB:         deps.Audit,
+		changeRecorder:  changeRecorder{historyDB: deps.History, logger: deps.Logger},
+		authCache:       deps.AuthCache,
+		logger:          deps.Logger,
+		conf:            deps.Config,
+		passwordPolicy:  deps.PasswordPolicy,
+		mail:            deps.Mail,
+		now:             time.Now,
 	}
 }
 
@@ -48,9 +97,9 @@ func (h *ExecsHandlers) ExecGetHandler(ctx context.Context, input *struct {
 ) (*ExecIDResponse, error) {
 	resp := ExecIDResponse{}
 
-	exec, err := h.execsDB.GetExecsByID(input.ID)
+	exec, err := h.execsDB.GetExecsByID(ctx, input.ID)
 	if err != nil {
-		return nil, huma.Error500InternalServerError("Error quering database", err)
+		return nil, dbError(err, huma.Error500InternalServerError("Error quering database", err))
 	}
 
 	resp.Body.Data = exec
@@ -66,6 +115,18 @@ func (h *ExecsHandlers) ExecAddHandler(
 
 	addedExecs := make([]models.Exec, len(input.Body.Execs))
 
+	// check all passwords first so that no exec is added when any of them is rejected
+	for i, newExec := range input.Body.Execs {
+		err := h.checkPassword(ctx,
+			fmt.Sprintf("body.execs[%d].password", i),
+			newExec.Password,
+			models.Exec{Username: newExec.Username, Email: newExec.Email},
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	for i, newExec := range input.Body.Execs {
 
 		err := utils.EmailCheck(newExec.Email)
@@ -75,7 +136,7 @@ func (h *ExecsHandlers) ExecAddHandler(
 				fmt.Errorf("invalid email: %s", newExec.Email),
 			)
 		}
-		encodedPass, err := utils.PasswordHash(newExec.Password)
+		encodedPass, err := h.hashPassword(newExec.Password)
 		if err != nil {
 			h.logger.Logging.Errorf("failed to generate salt %v", err)
 			return nil, huma.Error400BadRequest("error adding data")
@@ -89,14 +150,15 @@ func (h *ExecsHandlers) ExecAddHandler(
 			Password:  encodedPass,
 			Role:      newExec.Role,
 		}
-		id, err := h.execsDB.InsertExecs(&exec)
+		id, err := h.execsDB.InsertExecs(ctx, &exec)
 		if err != nil {
-			return nil, huma.Error500InternalServerError(
+			return nil, dbError(err, huma.Error500InternalServerError(
 				"Error adding to the database",
 				err,
-			)
+			))
 		}
 		exec.ID = int(id)
+		h.recordPasswordHistory(ctx, exec.ID, encodedPass)
 		addedExecs[i] = exec
 	}
 
@@ -121,36 +183,19 @@ func (e *ExecsHandlers) ExecsGetHandler(
 		"role":       input.Role,
 	}
 
+	if input.IncludeDeleted && !middleware.Allowed(ctx, middleware.PermDeletedManage) {
+		return nil, huma.Error403Forbidden("missing permission " + string(middleware.PermDeletedManage))
+	}
+
 	sortBy := input.SortBy
 	// filtering by params basically with query parameters anf filtering
-	rows, err := e.execsDB.GetAllExecs(params, sortBy)
+	execsList, count, err := e.execsDB.GetAllExecs(ctx, params, sortBy, input.IncludeDeleted)
 	if err != nil {
-		return nil, huma.Error500InternalServerError("Error quering database", err)
-	}
-
-	execsList := make([]models.Exec, 0)
-
-	for rows.Next() {
-		var exec models.Exec
-		err = rows.Scan(
-			&exec.ID,
-			&exec.FirstName,
-			&exec.LastName,
-			&exec.Email,
-			&exec.Username,
-			&exec.UserCreatedAt,
-			&exec.InactiveStatus,
-			&exec.Role,
-		)
-		if err != nil {
-			return nil, huma.Error500InternalServerError("Error scanning database results", err)
-		}
-		execsList = append(execsList, exec)
+		return nil, dbError(err, huma.Error500InternalServerError("Error quering database", err))
 	}
-	defer rows.Close()
 
 	response.Body.Status = "Sucess"
-	response.Body.Count = len(execsList)
+	response.Body.Count = count
 	response.Body.Data = execsList
 	return &response, nil
 }
@@ -182,9 +227,9 @@ func (h *ExecsHandlers) PatchExecsHandler(
 		Username:  input.Body.Exec.Username,
 	}
 
-	updatedExec, err := h.execsDB.PatchExec(input.Body.Exec.ID, exec)
+	updatedExec, err := h.execsDB.PatchExec(ctx, input.Body.Exec.ID, exec)
 	if err != nil {
-		return nil, err
+		return nil, dbError(err, err)
 	}
 	resp := ExecPatchOutput{}
 	resp.Body.Status = "Success"
@@ -204,10 +249,14 @@ func (h *ExecsHandlers) ExecDeleteByIDHandler(
 	}
 }, error,
 ) {
-	err := h.execsDB.DeleteExec(input.ID)
+	err := h.execsDB.DeleteExec(ctx, input.ID)
 	if err != nil {
-		return nil, err
+		return nil, dbError(err, err)
+	}
+	if _, err := h.sessionsDB.RevokeAllSessions(input.ID); err != nil {
+		h.logger.Logging.Errorf("failed to revoke sessions of deleted exec %v", err)
 	}
+	h.authCache.InvalidateExec(input.ID)
 	output := &struct {
 		Body struct {
 			Status string `json:"status"`
@@ -241,97 +290,108 @@ func (h *ExecsHandlers) ExecLoginHandler(
 	exec := input.Body.Exec
 	if exec.Username == "" || exec.Password == "" {
 		h.logger.Logging.Debugf(
-			"Invalid or blank username or password for username=%s and password=%s",
+			"Invalid or blank username or password for username=%s",
 			exec.Username,
-			exec.Password,
 		)
 		return nil, huma.Error400BadRequest("Invalid or blank username or password")
 	}
 
-	// Search for the user if the user actually exists
-	exists, err, passFromDB := h.execsDB.SearchUsername(exec.Username)
+	// throttle the failed logins per username and per ip address
+	loginKeys := h.loginKeys(ctx, exec.Username)
+	wait, err := h.loginBlockedFor(loginKeys)
 	if err != nil {
-		return nil, huma.Error404NotFound("database error:", err)
+		return nil, huma.Error500InternalServerError("database error", err)
 	}
-	if !exists {
-		return nil, huma.Error404NotFound("user not found")
-	}
-	inactive, err := h.execsDB.IsInactiveUser(exec.Username)
-	if err != nil {
-		return nil, err
+	if wait > 0 {
+		h.auditAuth(ctx, models.AuthEventLogin, models.Exec{Username: exec.Username}, models.AuthOutcomeFailure, "locked")
+		return nil, tooManyLoginAttempts(wait)
 	}
 
-	if inactive {
-		return nil, huma.Error403Forbidden("user inactive")
+	// Search for the user if the user actually exists
+	exists, err, passFromDB := h.execsDB.SearchUsername(ctx, exec.Username)
+	if queryFailed(err) {
+		return nil, dbError(err, err)
+	}
+	// the invited exec has no password until the invitation is activated, the login fails
+	// like for an unknown username so the invited usernames are not revealed
+	if err != nil || !exists || passFromDB == "" {
+		// spend the same time as for the password check of existing user
+		_, _ = password.Hash(exec.Password, h.hashParams())
+		h.recordLoginFailure(loginKeys)
+		detail := "unknown username"
+		if err == nil && exists {
+			detail = "no password"
+		}
+		h.auditAuth(ctx, models.AuthEventLogin, models.Exec{Username: exec.Username}, models.AuthOutcomeFailure, detail)
+		return nil, invalidCredentials()
 	}
 
 	// verify password
-	ps := strings.Split(passFromDB, ".")
-	if len(ps) != 2 {
-		h.logger.Logging.Error("invalid encoded hash format")
-		return nil, huma.Error400BadRequest("invalid encoded hash format")
-	}
-
-	saltBase := ps[0]
-	hashedPasswordBase64 := ps[1]
-
-	salt, err := base64.StdEncoding.DecodeString(saltBase)
+	match, rehash, err := password.Verify(exec.Password, passFromDB, h.hashParams())
 	if err != nil {
-		h.logger.Logging.Error("failed to decode the salt")
-		return nil, huma.Error400BadRequest("invalid encoded hash format")
-
+		h.logger.Logging.Errorf("invalid password hash for username=%s %v", exec.Username, err)
+		return nil, huma.Error500InternalServerError("invalid encoded hash format")
 	}
-	hashedDBPassword, err := base64.StdEncoding.DecodeString(hashedPasswordBase64)
-	if err != nil {
-		h.logger.Logging.Error("failed to decode the hashed password")
-		return nil, huma.Error400BadRequest("invalid encoded hash format for password")
-
+	if !match {
+		h.logger.Logging.Debugf("incorrect password for username=%s", exec.Username)
+		h.recordLoginFailure(loginKeys)
+		h.auditAuth(ctx, models.AuthEventLogin, models.Exec{Username: exec.Username}, models.AuthOutcomeFailure, "invalid password")
+		return nil, invalidCredentials()
 	}
 
-	hashInputPassword := argon2.IDKey([]byte(exec.Password), salt, 1, 64*1024, 4, 32)
-
-	if len(hashedDBPassword) != len(hashInputPassword) {
-		h.logger.Logging.Error("incorrect password")
-		return nil, huma.Error403Forbidden("incorrect password")
+	if err := h.loginAttemptsDB.ResetLoginAttempts(usernameAttemptKey(exec.Username)); err != nil {
+		h.logger.Logging.Errorf("failed to reset login attempts %v", err)
 	}
 
-	if subtle.ConstantTimeCompare(hashedDBPassword, hashInputPassword) == 1 {
-	} else {
-		h.logger.Logging.Error("incorrect password")
-		return nil, huma.Error403Forbidden("incorrect password")
-
+	inactive, err := h.execsDB.IsInactiveUser(ctx, exec.Username)
+	if inactive {
+		h.auditAuth(ctx, models.AuthEventLogin, models.Exec{Username: exec.Username}, models.AuthOutcomeFailure, "inactive")
+		return nil, huma.Error403Forbidden("user inactive")
+	}
+	if err != nil {
+		return nil, dbError(err, huma.Error500InternalServerError("database error", err))
 	}
+
 	// generate token
-	user, err := h.execsDB.GetLoginDetailsForUsername(exec.Username)
+	user, err := h.execsDB.GetLoginDetailsForUsername(ctx, exec.Username)
 	if err != nil {
 		h.logger.Logging.Errorf("error on get user details %v", err)
-		return nil, huma.Error403Forbidden("incorrect user get from db")
+		return nil, dbError(err, huma.Error403Forbidden("incorrect user get from db"))
 
 	}
-	tokenString, err := utils.SighnToken(
-		fmt.Sprintf("%v", user.ID),
-		user.Username,
-		user.Role,
-		h.conf,
-	)
+	if rehash {
+		h.rehashPassword(ctx, user.ID, passFromDB, exec.Password)
+	}
+
+	// with two factor enabled the session is issued only after the totp code on /execs/login/mfa
+	mfa, err := h.mfaDB.GetMFA(user.ID)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("database error", err)
+	}
+	if mfa.Enabled {
+		mfaToken, err := utils.SignMFAChallenge(strconv.Itoa(user.ID), h.conf, h.now())
+		if err != nil {
+			h.logger.Logging.Errorf("Could not create mfa challenge %v", err)
+			return nil, huma.Error500InternalServerError("Could not create login token", err)
+		}
+		out := &ExecsLoginOutput{}
+		out.Body.MFARequired = true
+		out.Body.MFAToken = mfaToken
+		return out, nil
+	}
+
+	tokenString, refreshToken, err := h.newSession(ctx, user, input.UserAgent)
 	if err != nil {
-		h.logger.Logging.Errorf("Could not create login token %v", err)
 		return nil, huma.Error500InternalServerError("Could not create login token", err)
 	}
+	h.auditAuth(ctx, models.AuthEventLogin, user, models.AuthOutcomeSuccess, "")
 
-	// Send token as responce or as a cookie
-	// how to make it as cookie in huma
+	// Send token as responce and as a cookie together with the refresh token
 	out := &ExecsLoginOutput{}
 	out.Body.Token = tokenString
-	out.SetCookie = http.Cookie{
-		Name:     "Bearer",
-		Value:    tokenString,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   true,
-		Expires:  time.Now().Add(24 * time.Hour),
-		SameSite: http.SameSiteStrictMode,
-	}
+	out.Body.RefreshToken = refreshToken
+	out.Body.ExpiresIn = int(h.conf.JWTExpiresIn.Seconds())
+	out.SetCookie = h.sessionCookies(tokenString, refreshToken)
 
 	return out, nil
 }
@@ -340,28 +400,32 @@ func (h *ExecsHandlers) LogoutExecsHandler(
 	ctx context.Context,
 	_ *struct{},
 ) (*ExecLogoutOutput, error) {
-	out := &ExecLogoutOutput{}
-	out.SetCookie = http.Cookie{
-		Name:     "Bearer",
-		Value:    "",
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   true,
-		Expires:  time.Unix(0, 0),
-		SameSite: http.SameSiteStrictMode,
+	if sid, ok := ctx.Value(middleware.ContextKey("sid")).(int); ok {
+		if _, err := h.sessionsDB.RevokeSession(sid); err != nil {
+			return nil, huma.Error500InternalServerError("Could not revoke the session", err)
+		}
+		h.authCache.InvalidateSession(sid)
+	}
+	if id, err := execIDFromContext(ctx); err == nil {
+		username, _ := ctx.Value(middleware.ContextKey("username")).(string)
+		h.auditAuth(ctx, models.AuthEventLogout, models.Exec{ID: id, Username: username}, models.AuthOutcomeSuccess, "")
 	}
+
+	out := &ExecLogoutOutput{}
+	out.SetCookie = h.clearedSessionCookies()
 	out.Body.Status = "Logged out sucessfully"
 
 	return out, nil
 }
 
+// UpdatePasswordHandler - the logged in exec changes its own password with the current one
 func (h *ExecsHandlers) UpdatePasswordHandler(
 	ctx context.Context,
 	input *ExecUpdatePasswordInput,
 ) (*ExecUpdatePasswordOutput, error) {
-	id := input.Body.ID
-	if id <= 0 {
-		return nil, huma.NewError(http.StatusBadRequest, "invalid exec id", nil)
+	id, err := currentExecID(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	if input.Body.CurrentPassword == "" || input.Body.NewPassword == "" {
@@ -369,70 +433,89 @@ func (h *ExecsHandlers) UpdatePasswordHandler(
 	}
 
 	// Search for the user if the user actually exists
-	userFromDB, passFromDB, role, err := h.execsDB.GetUserPasswordFromId(input.Body.ID)
+	userFromDB, passFromDB, role, err := h.execsDB.GetUserPasswordFromId(ctx, id)
 	if err != nil {
-		return nil, huma.Error404NotFound("database error:", err)
+		return nil, dbError(err, huma.Error404NotFound("database error:", err))
 	}
 
 	// verify password
-	ps := strings.Split(passFromDB, ".")
-	if len(ps) != 2 {
-		h.logger.Logging.Error("invalid encoded hash format")
-		return nil, huma.Error400BadRequest("invalid encoded hash format")
-	}
-	saltBase := ps[0]
-	hashedPasswordBase64 := ps[1]
-
-	salt, err := base64.StdEncoding.DecodeString(saltBase)
+	match, _, err := password.Verify(input.Body.CurrentPassword, passFromDB, h.hashParams())
 	if err != nil {
-		h.logger.Logging.Error("failed to decode the salt")
+		h.logger.Logging.Errorf("invalid password hash for exec %d %v", id, err)
 		return nil, huma.Error400BadRequest("invalid encoded hash format")
-
 	}
-	hashedDBPassword, err := base64.StdEncoding.DecodeString(hashedPasswordBase64)
-	if err != nil {
-		h.logger.Logging.Error("failed to decode the hashed password")
-		return nil, huma.Error400BadRequest("invalid encoded hash format for password")
-
+	if !match {
+		h.auditAuth(ctx, models.AuthEventPasswordChange, models.Exec{ID: id, Username: userFromDB}, models.AuthOutcomeFailure, "invalid current password")
+		return nil, huma.Error400BadRequest("current password does not match")
 	}
 
-	hashInputPassword := argon2.IDKey([]byte(input.Body.CurrentPassword), salt, 1, 64*1024, 4, 32)
-
-	if len(hashedDBPassword) != len(hashInputPassword) ||
-		subtle.ConstantTimeCompare(hashedDBPassword, hashInputPassword) != 1 {
-		return nil, huma.Error400BadRequest("current password does not match")
+	current, err := h.execsDB.GetExecsByID(ctx, id)
+	if err != nil {
+		return nil, dbError(err, huma.Error404NotFound("database error:", err))
+	}
+	if err := h.checkPassword(ctx, "body.new_password", input.Body.NewPassword, current); err != nil {
+		return nil, err
 	}
 
-	encodedPass, err := utils.PasswordHash(input.Body.NewPassword)
+	encodedPass, err := h.hashPassword(input.Body.NewPassword)
 	if err != nil {
 		h.logger.Logging.Debugf("failed to generate salt %v", err)
 		return nil, huma.Error400BadRequest("error hashing password")
 	}
-	err = h.execsDB.UpdatePasswordChange(id, encodedPass)
+	err = h.execsDB.UpdatePasswordChange(ctx, id, encodedPass)
 	if err != nil {
 		h.logger.Logging.Debugf("update error: %v", err)
-		return nil, huma.Error400BadRequest("failed to update password")
-	}
-	token, err := utils.SighnToken(strconv.Itoa(id), userFromDB, role, h.conf)
+		return nil, dbError(err, huma.Error400BadRequest("failed to update password"))
+	}
+	h.recordPasswordHistory(ctx, id, encodedPass)
+	h.revokeAfterPasswordChange(id)
+	h.auditAuth(ctx, models.AuthEventPasswordChange, models.Exec{ID: id, Username: userFromDB}, models.AuthOutcomeSuccess, "")
+	token, refreshToken, err := h.newSession(
+		ctx,
+		models.Exec{ID: id, Username: userFromDB, Role: role},
+		input.UserAgent,
+	)
 	if err != nil {
 		h.logger.Logging.Debugf("password is updated but token error: %v", err)
 		return nil, huma.Error400BadRequest("password updated, failed to create token")
 	}
 	out := &ExecUpdatePasswordOutput{}
-	out.SetCookie = http.Cookie{
-		Name:     "Bearer",
-		Value:    token,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   true,
-		Expires:  time.Now().Add(24 * time.Hour),
-		SameSite: http.SameSiteStrictMode,
-	}
+	out.SetCookie = h.sessionCookies(token, refreshToken)
 	out.Body.PasswordUpdated = "password updated sucessfully"
 
 	return out, nil
 }
 
+// SetExecPasswordHandler - admin sets new password of exec by id without the current one,
+// all sessions of the exec are revoked
+func (h *ExecsHandlers) SetExecPasswordHandler(
+	ctx context.Context,
+	input *ExecSetPasswordInput,
+) (*ExecUpdatePasswordOutput, error) {
+	current, err := h.execsDB.GetExecsByID(ctx, input.ID)
+	if err != nil {
+		return nil, dbError(err, huma.Error404NotFound("exec not found", err))
+	}
+	if err := h.checkPassword(ctx, "body.new_password", input.Body.NewPassword, current); err != nil {
+		return nil, err
+	}
+	encodedPass, err := h.hashPassword(input.Body.NewPassword)
+	if err != nil {
+		h.logger.Logging.Errorf("failed to hash the password %v", err)
+		return nil, huma.Error500InternalServerError("error hashing password")
+	}
+	if err := h.execsDB.UpdatePasswordChange(ctx, current.ID, encodedPass); err != nil {
+		return nil, dbError(err, huma.Error500InternalServerError("failed to update password", err))
+	}
+	h.recordPasswordHistory(ctx, current.ID, encodedPass)
+	h.revokeAfterPasswordChange(current.ID)
+	h.auditAuth(ctx, models.AuthEventPasswordSet, current, models.AuthOutcomeSuccess, "")
+
+	out := &ExecUpdatePasswordOutput{}
+	out.Body.PasswordUpdated = "password updated sucessfully"
+	return out, nil
+}
+
 func (h *ExecsHandlers) ForgotpasswordExecsHandler(
 	ctx context.Context,
 	input *ExecsForgotPasswordInput,
@@ -447,9 +530,13 @@ func (h *ExecsHandlers) ForgotpasswordExecsHandler(
 		return nil, huma.Error400BadRequest("Invalid email format")
 	}
 	// Get user by email
-	exec, err := h.execsDB.GetIdFromEmail(input.Body.Email)
+	exec, err := h.execsDB.GetIdFromEmail(ctx, input.Body.Email)
+	if queryFailed(err) {
+		return nil, dbError(err, err)
+	}
 	if err != nil {
 		h.logger.Logging.Debugf("Error getting eec fom email: %v", err)
+		h.auditAuth(ctx, models.AuthEventPasswordResetRequest, models.Exec{}, models.AuthOutcomeFailure, "unknown email")
 		return nil, huma.Error400BadRequest("database error")
 
 	}
@@ -470,22 +557,21 @@ func (h *ExecsHandlers) ForgotpasswordExecsHandler(
 	hashedTokenString := hex.EncodeToString(hashedToken[:])
 
 	// Store reset token in database
-	err = h.execsDB.StoreResetToken(exec.ID, hashedTokenString, expiry)
+	err = h.execsDB.StoreResetToken(ctx, exec.ID, hashedTokenString, expiry)
 	if err != nil {
 		h.logger.Logging.Errorf("Failed to store reset token: %v", err)
-		return nil, huma.Error500InternalServerError("Failed to process request")
+		return nil, dbError(err, huma.Error500InternalServerError("Failed to process request"))
 	}
-	resetLink := fmt.Sprintf("http://localhost:8082/execs/resetpassword/reset/%s", token)
-	messg := fmt.Sprintf(
-		"Forgot your password? Reset your password using the following link: \n%s\nIf you didn't equest a password reset please ignore this email. This link is only valid for %s time",
-		resetLink,
-		h.conf.ResetTokenExpDuration,
-	)
-
-	err = utils.SendResetEmail(input.Body.Email, messg, "localhost", "1025")
+	resetLink := fmt.Sprintf("%s/execs/resetpassword/reset/%s", h.conf.PublicBaseURL, token)
+	err = h.mail.SendTemplate(ctx, "password_reset", input.Body.Email, map[string]any{
+		"ResetLink": resetLink,
+		"ExpiresIn": h.conf.ResetTokenExpDuration.String(),
+	})
 	if err != nil {
 		h.logger.Logging.Errorf("Email send failed: %v", err)
+		return nil, huma.Error503ServiceUnavailable("Could not send the email, try again later")
 	}
+	h.auditAuth(ctx, models.AuthEventPasswordResetRequest, exec, models.AuthOutcomeSuccess, "")
 
 	out := &struct {
 		Body struct {
@@ -507,7 +593,13 @@ func (h *ExecsHandlers) PasswordresetExecsHandler(
 	input *ExecsPasswordResetInput,
 ) (*PasswordresetOutput, error) {
 	if input.Body.NewPassword != input.Body.ConfirmPassword {
-		return nil, huma.Error500InternalServerError("Passwords did not match")
+		return nil, huma.Error422UnprocessableEntity(
+			"Passwords did not match",
+			&huma.ErrorDetail{
+				Location: "body.confirm_password",
+				Message:  "must be the same as the new password",
+			},
+		)
 	}
 
 	bytes, err := hex.DecodeString(input.ResetCode)
@@ -518,25 +610,105 @@ func (h *ExecsHandlers) PasswordresetExecsHandler(
 
 	hashedToken := sha256.Sum256(bytes)
 	hashedTokenString := hex.EncodeToString(hashedToken[:])
-	exec, err := h.execsDB.GetEmailFromToken(hashedTokenString)
+	exec, err := h.execsDB.GetEmailFromToken(ctx, hashedTokenString)
+	if queryFailed(err) {
+		return nil, dbError(err, err)
+	}
 	if err != nil {
 		h.logger.Logging.Errorf("error: %v", err)
+		h.auditAuth(ctx, models.AuthEventPasswordReset, models.Exec{}, models.AuthOutcomeFailure, "invalid or expired reset code")
 		return nil, huma.Error500InternalServerError("internal error", err)
 	}
-	hashedPassword, err := utils.PasswordHash(input.Body.NewPassword)
+	current, err := h.execsDB.GetExecsByID(ctx, exec.ID)
+	if err != nil {
+		return nil, dbError(err, huma.Error500InternalServerError("internal error", err))
+	}
+	if err := h.checkPassword(ctx, "body.new_password", input.Body.NewPassword, current); err != nil {
+		return nil, err
+	}
+	hashedPassword, err := h.hashPassword(input.Body.NewPassword)
 	if err != nil {
 		h.logger.Logging.Errorf("Internal error %v", err)
 		return nil, huma.Error500InternalServerError("Internal error")
 	}
 
-	err = h.execsDB.UpdateResetedPassword(hashedPassword, exec.ID)
+	err = h.execsDB.UpdateResetedPassword(ctx, hashedPassword, exec.ID)
 	if err != nil {
 		h.logger.Logging.Errorf("Internal database error %v", err)
-		return nil, huma.Error500InternalServerError("Internal database error")
+		return nil, dbError(err, huma.Error500InternalServerError("Internal database error"))
 	}
+	h.recordPasswordHistory(ctx, exec.ID, hashedPassword)
+	h.revokeAfterPasswordChange(exec.ID)
+	h.auditAuth(ctx, models.AuthEventPasswordReset, current, models.AuthOutcomeSuccess, "")
 
 	out := &PasswordresetOutput{}
 	out.Body.Data = "Password reset sucessfully"
 
 	return out, nil
 }
+
+func (h *ExecsHandlers) ExecHistoryHandler(
+	ctx context.Context,
+	input *RevisionsInput,
+) (*RevisionsOutput, error) {
+	return h.listRevisions(models.EntityExec, input)
+}
+
+// RestoreExecRevisionHandler - sets the names, email and username of the exec back to
+// their state after the revision, the role and the status have their own endpoints
+func (h *ExecsHandlers) RestoreExecRevisionHandler(
+	ctx context.Context,
+	input *RevisionRestoreInput,
+) (*ExecPatchOutput, error) {
+	if _, err := h.execsDB.GetExecsByID(ctx, input.ID); err != nil {
+		return nil, dbError(err, huma.Error404NotFound("exec not found", err))
+	}
+	var state dataops.ExecRevision
+	rev, err := h.revisionState(models.EntityExec, input, &state)
+	if err != nil {
+		return nil, err
+	}
+	if other, err := h.execsDB.GetIdFromEmail(ctx, state.Email); err == nil && other.ID != input.ID {
+		return nil, huma.Error409Conflict("exec with this email already exists")
+	}
+	restoredExec, err := h.execsDB.PatchExec(dataops.WithRestoredRevision(ctx, rev.ID), input.ID, models.Exec{
+		FirstName: state.FirstName,
+		LastName:  state.LastName,
+		Email:     state.Email,
+		Username:  state.Username,
+	})
+	if err != nil {
+		return nil, dbError(err, huma.Error500InternalServerError("Could not restore the exec", err))
+	}
+
+	resp := &ExecPatchOutput{}
+	resp.Body.Status = "Success"
+	resp.Body.Data = restoredExec
+	return resp, nil
+}
+
+// RestoreExecHandler - brings back the soft deleted exec before it is purged, the sessions
+// revoked by the delete stay revoked
+func (h *ExecsHandlers) RestoreExecHandler(
+	ctx context.Context,
+	input *struct {
+		ID int `path:"id"`
+	},
+) (*ExecPatchOutput, error) {
+	if err := h.execsDB.RestoreExec(ctx, input.ID); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return nil, huma.Error404NotFound("deleted exec not found", err)
+		}
+		return nil, dbError(err, huma.Error500InternalServerError("Could not restore the exec", err))
+	}
+	exec, err := h.execsDB.GetExecsByID(ctx, input.ID)
+	if err != nil {
+		return nil, dbError(err, huma.Error500InternalServerError("Error quering database", err))
+	}
+	h.authCache.InvalidateExec(exec.ID)
+
+	resp := &ExecPatchOutput{}
+	resp.Body.Status = "Exec restored"
+	resp.Body.Data = exec
+	return resp, nil
+}
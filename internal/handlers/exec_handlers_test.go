@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/humatest"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops/memory"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/middleware"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+)
+
+func TestExecsGet(t *testing.T) {
+	_, api := humatest.New(t)
+	execsDB := memory.NewExecs(
+		models.Exec{Username: "admin", Email: "admin@school.test", Password: "secret-hash", Role: middleware.RoleAdmin},
+		models.Exec{Username: "staff", Email: "staff@school.test", Password: "secret-hash", Role: middleware.RoleStaff},
+	)
+	if err := execsDB.DeleteExec(context.Background(), 2); err != nil {
+		t.Fatal(err)
+	}
+	h := NewExecsHandler(ExecsDeps{
+		Execs: execsDB,
+	})
+	huma.Register(api, huma.Operation{
+		OperationID: "get-execs",
+		Method:      http.MethodGet,
+		Path:        "/execs",
+	}, h.ExecsGetHandler)
+
+	var body struct {
+		Count int           `json:"count"`
+		Data  []models.Exec `json:"data"`
+	}
+	resp := api.Get("/execs")
+	if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Count != 1 || body.Data[0].Username != "admin" {
+		t.Fatalf("Expected only the not deleted exec, got %s", resp.Body.String())
+	}
+	if strings.Contains(resp.Body.String(), "secret-hash") {
+		t.Fatalf("Expected no password hashes in the response")
+	}
+
+	admin := context.WithValue(context.Background(), middleware.ContextKey("role"), middleware.RoleAdmin)
+	resp = api.GetCtx(admin, "/execs?include_deleted=true&sort_by=username:desc")
+	if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Count != 2 || body.Data[0].Username != "staff" || body.Data[0].DeletedAt == "" {
+		t.Fatalf("Expected the deleted exec first, got %s", resp.Body.String())
+	}
+}
@@ -63,37 +63,65 @@ type DeleteExecsOutput struct {
 }
 
 type ExecsLoginInput struct {
-	Body struct {
+	UserAgent string `header:"User-Agent"`
+	Body      struct {
 		Exec models.ExecLoginInput `json:"execs" doc:"Execs"`
 	}
 }
 
 type ExecsLoginOutput struct {
 	Body struct {
-		Token string `                    json:"token"`
+		Token        string `json:"token,omitempty"`
+		RefreshToken string `json:"refresh_token,omitempty"`
+		ExpiresIn    int    `json:"expires_in,omitempty"   doc:"Access token lifetime in seconds"`
+		MFARequired  bool   `json:"mfa_required,omitempty" doc:"The login must be completed on /execs/login/mfa"`
+		MFAToken     string `json:"mfa_token,omitempty"    doc:"Challenge token for /execs/login/mfa"`
 	}
-	SetCookie http.Cookie `header:"Set-Cookie"`
+	SetCookie []http.Cookie `header:"Set-Cookie"`
 }
 
 type ExecLogoutOutput struct {
 	Body struct {
 		Status string `json:"status"`
 	}
-	SetCookie http.Cookie `header:"Set-Cookie"`
+	SetCookie []http.Cookie `header:"Set-Cookie"`
 }
 
-type ExecUpdatePasswordInput struct {
+type ExecRefreshInput struct {
+	UserAgent     string `header:"User-Agent"`
+	RefreshCookie string `cookie:"refresh_token"`
+	Body          *struct {
+		RefreshToken string `json:"refresh_token,omitempty" doc:"Refresh token when it is not send as cookie"`
+	}
+}
+
+type ExecSessionsRevokeOutput struct {
 	Body struct {
-		ID              int    `json:"id"`
+		Status  string `json:"status"`
+		Revoked int64  `json:"revoked"`
+	}
+	SetCookie []http.Cookie `header:"Set-Cookie"`
+}
+
+type ExecUpdatePasswordInput struct {
+	UserAgent string `header:"User-Agent"`
+	Body      struct {
 		CurrentPassword string `json:"current_password"`
 		NewPassword     string `json:"new_password"`
 	}
 }
+
+type ExecSetPasswordInput struct {
+	ID   int `path:"id"`
+	Body struct {
+		NewPassword string `json:"new_password" required:"true" minLength:"2" maxLength:"255" doc:"New password of the exec"`
+	}
+}
 type ExecUpdatePasswordOutput struct {
 	Body struct {
 		PasswordUpdated string `json:"password_updated"`
 	}
-	SetCookie http.Cookie `header:"Set-Cookie"`
+	SetCookie []http.Cookie `header:"Set-Cookie"`
 }
 
 type ExecsForgotPasswordInput struct {
@@ -115,3 +143,103 @@ type PasswordresetOutput struct {
 		Data string `json:"data"`
 	}
 }
+
+type ExecLockOutput struct {
+	Body struct {
+		Status      string `json:"status"`
+		ID          int    `json:"id"`
+		LockedUntil string `json:"locked_until,omitempty"`
+	}
+}
+
+type ExecsLoginMFAInput struct {
+	UserAgent string `header:"User-Agent"`
+	Body      models.MFALoginInput
+}
+
+type ExecMFAEnrollOutput struct {
+	Body struct {
+		Secret          string `json:"secret"           doc:"Base32 secret for manual entry"`
+		ProvisioningURI string `json:"provisioning_uri" doc:"otpauth URI to show as QR code"`
+	}
+}
+
+type ExecMFACodeInput struct {
+	Body models.MFACodeInput
+}
+
+type ExecMFARecoveryCodesOutput struct {
+	Body struct {
+		Status        string   `json:"status"`
+		RecoveryCodes []string `json:"recovery_codes" doc:"Shown only once, every code can be used one time"`
+	}
+}
+
+type ExecMFAStatusOutput struct {
+	Body struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}
+}
+
+type ExecOIDCLoginOutput struct {
+	Status    int
+	Location  string      `header:"Location"`
+	SetCookie http.Cookie `header:"Set-Cookie"`
+}
+
+type ExecOIDCCallbackInput struct {
+	UserAgent   string `header:"User-Agent"`
+	Code        string `query:"code"              doc:"Authorization code from the identity provider"`
+	State       string `query:"state"             doc:"State from the login redirect"`
+	Error       string `query:"error"             doc:"Error returned by the identity provider"`
+	StateCookie string `cookie:"oidc_state"`
+}
+
+type ExecInviteInput struct {
+	Body models.ExecInviteInput
+}
+
+type ExecInviteOutput struct {
+	Body struct {
+		Status    string      `json:"status"`
+		Data      models.Exec `json:"data"`
+		ExpiresAt string      `json:"expires_at"`
+	}
+}
+
+type ExecActivateInput struct {
+	ActivationCode string `path:"activationcode" doc:"Activation code from the invitation email"`
+	Body           struct {
+		NewPassword     string `json:"new_password"         required:"true" minLength:"2" maxLength:"255" doc:"Password of the account"`
+		ConfirmPassword string `json:"confirm_password"     required:"true" minLength:"2" maxLength:"255" doc:"Confirm password"`
+		FirstName       string `json:"first_name,omitempty"                               maxLength:"255" doc:"First name if it is not set with the invitation"`
+		LastName        string `json:"last_name,omitempty"                                maxLength:"255" doc:"Last name if it is not set with the invitation"`
+	}
+}
+
+type ExecStatusOutput struct {
+	Body struct {
+		Status         string `json:"status"`
+		ID             int    `json:"id"`
+		InactiveStatus bool   `json:"inactive_status"`
+	}
+}
+
+type ExecMePatchInput struct {
+	Body models.ExecMePatchBody
+}
+
+// ExecSessionView - login session with flag for the session of the current access token
+type ExecSessionView struct {
+	models.ExecSession
+	Current bool `json:"current"`
+}
+
+type ExecSessionsOutput struct {
+	Body struct {
+		Status string            `json:"status"`
+		Count  int               `json:"count"`
+		Data   []ExecSessionView `json:"data"`
+	}
+}
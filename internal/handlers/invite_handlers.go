@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/utils"
+)
+
+// sendInvitation - stores new activation code for the exec and emails the activation link,
+// the code of previous invitation stops working
+func (h *ExecsHandlers) sendInvitation(ctx context.Context, exec models.Exec) (string, error) {
+	token, hashedToken, err := utils.GenerateToken()
+	if err != nil {
+		h.logger.Logging.Errorf("failed to generate activation code %v", err)
+		return "", huma.Error500InternalServerError("Failed to process request")
+	}
+	now := h.now()
+	inv := models.ExecInvitation{
+		ExecID:    exec.ID,
+		TokenHash: hashedToken,
+		ExpiresAt: now.Add(h.conf.InviteTokenExpiresIn).Format(time.RFC3339),
+		CreatedAt: now.Format(time.RFC3339),
+	}
+	if err := h.invitationsDB.SaveInvitation(inv); err != nil {
+		h.logger.Logging.Errorf("Failed to store the invitation: %v", err)
+		return "", huma.Error500InternalServerError("Failed to process request")
+	}
+
+	activationLink := fmt.Sprintf("%s/execs/invitations/%s/activate", h.conf.PublicBaseURL, token)
+	err = h.mail.SendTemplate(ctx, "exec_invite", exec.Email, map[string]any{
+		"FirstName":      exec.FirstName,
+		"Username":       exec.Username,
+		"ActivationLink": activationLink,
+		"ExpiresIn":      h.conf.InviteTokenExpiresIn.String(),
+	})
+	if err != nil {
+		h.logger.Logging.Errorf("Email send failed: %v", err)
+		return "", huma.Error503ServiceUnavailable(
+			"Could not send the invitation email, resend the invitation later",
+		)
+	}
+	return inv.ExpiresAt, nil
+}
+
+func (h *ExecsHandlers) InviteExecHandler(
+	ctx context.Context,
+	input *ExecInviteInput,
+) (*ExecInviteOutput, error) {
+	if err := utils.EmailCheck(input.Body.Email); err != nil {
+		return nil, huma.Error400BadRequest("Invalid email format")
+	}
+	if _, err := h.execsDB.GetIdFromEmail(ctx, input.Body.Email); err == nil {
+		return nil, huma.Error409Conflict("exec with this email already exists")
+	}
+
+	username := input.Body.Username
+	if username == "" {
+		username = input.Body.Email
+	}
+	// without password and inactive until the invitation is accepted
+	exec := models.Exec{
+		FirstName:      input.Body.FirstName,
+		LastName:       input.Body.LastName,
+		Email:          input.Body.Email,
+		Username:       username,
+		InactiveStatus: true,
+		Role:           input.Body.Role,
+	}
+	id, err := h.execsDB.InsertExecs(ctx, &exec)
+	if err != nil {
+		return nil, dbError(err, huma.Error500InternalServerError("Error adding to the database", err))
+	}
+	exec.ID = int(id)
+
+	expiresAt, err := h.sendInvitation(ctx, exec)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &ExecInviteOutput{}
+	out.Body.Status = "Invitation sent"
+	out.Body.Data = exec
+	out.Body.ExpiresAt = expiresAt
+	return out, nil
+}
+
+func (h *ExecsHandlers) ResendInvitationHandler(
+	ctx context.Context,
+	input *struct {
+		ID int `path:"id"`
+	},
+) (*ExecInviteOutput, error) {
+	exec, err := h.execsDB.GetExecsByID(ctx, input.ID)
+	if err != nil {
+		return nil, dbError(err, huma.Error404NotFound("exec not found", err))
+	}
+	pending, err := h.invitationsDB.HasInvitation(exec.ID)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("internal error", err)
+	}
+	if !pending {
+		return nil, huma.Error409Conflict("exec has already activated the account")
+	}
+
+	expiresAt, err := h.sendInvitation(ctx, exec)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &ExecInviteOutput{}
+	out.Body.Status = "Invitation sent"
+	out.Body.Data = exec
+	out.Body.ExpiresAt = expiresAt
+	return out, nil
+}
+
+func (h *ExecsHandlers) ActivateExecHandler(
+	ctx context.Context,
+	input *ExecActivateInput,
+) (*PasswordresetOutput, error) {
+	if input.Body.NewPassword != input.Body.ConfirmPassword {
+		return nil, huma.Error422UnprocessableEntity(
+			"Passwords did not match",
+			&huma.ErrorDetail{
+				Location: "body.confirm_password",
+				Message:  "must be the same as the new password",
+			},
+		)
+	}
+
+	hashedToken, err := utils.HashToken(input.ActivationCode)
+	if err != nil {
+		return nil, huma.Error400BadRequest("invalid or expired activation code")
+	}
+	inv, err := h.invitationsDB.GetInvitationByTokenHash(hashedToken)
+	if err != nil {
+		h.auditAuth(ctx, models.AuthEventActivation, models.Exec{}, models.AuthOutcomeFailure, "invalid or expired activation code")
+		return nil, huma.Error400BadRequest("invalid or expired activation code")
+	}
+	exec, err := h.execsDB.GetExecsByID(ctx, inv.ExecID)
+	if err != nil {
+		return nil, dbError(err, huma.Error500InternalServerError("internal error", err))
+	}
+	if input.Body.FirstName != "" {
+		exec.FirstName = input.Body.FirstName
+	}
+	if input.Body.LastName != "" {
+		exec.LastName = input.Body.LastName
+	}
+	if exec.FirstName == "" || exec.LastName == "" {
+		return nil, huma.Error422UnprocessableEntity(
+			"First and last name are required",
+			&huma.ErrorDetail{
+				Location: "body.first_name",
+				Message:  "first_name and last_name must be set when they are not in the invitation",
+			},
+		)
+	}
+	if err := h.checkPassword(ctx, "body.new_password", input.Body.NewPassword, exec); err != nil {
+		return nil, err
+	}
+	exec.Password, err = h.hashPassword(input.Body.NewPassword)
+	if err != nil {
+		h.logger.Logging.Errorf("Internal error %v", err)
+		return nil, huma.Error500InternalServerError("Internal error")
+	}
+
+	activated, err := h.invitationsDB.ActivateExec(hashedToken, exec)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Internal database error", err)
+	}
+	if !activated {
+		return nil, huma.Error400BadRequest("invalid or expired activation code")
+	}
+	h.recordPasswordHistory(ctx, exec.ID, exec.Password)
+	h.auditAuth(ctx, models.AuthEventActivation, exec, models.AuthOutcomeSuccess, "")
+
+	out := &PasswordresetOutput{}
+	out.Body.Data = "Account activated sucessfully"
+	return out, nil
+}
+
+func (h *ExecsHandlers) DeactivateExecHandler(
+	ctx context.Context,
+	input *struct {
+		ID int `path:"id"`
+	},
+) (*ExecStatusOutput, error) {
+	if uid, err := execIDFromContext(ctx); err == nil && uid == input.ID {
+		return nil, huma.Error409Conflict("you can not deactivate your own account")
+	}
+	exec, err := h.execsDB.GetExecsByID(ctx, input.ID)
+	if err != nil {
+		return nil, dbError(err, huma.Error404NotFound("exec not found", err))
+	}
+	if err := h.execsDB.SetInactiveStatus(ctx, exec.ID, true); err != nil {
+		return nil, dbError(err, huma.Error500InternalServerError("Could not deactivate the exec", err))
+	}
+	if _, err := h.sessionsDB.RevokeAllSessions(exec.ID); err != nil {
+		h.logger.Logging.Errorf("failed to revoke sessions of deactivated exec %v", err)
+	}
+	h.authCache.InvalidateExec(exec.ID)
+	h.auditAuth(ctx, models.AuthEventDeactivate, exec, models.AuthOutcomeSuccess, "")
+
+	out := &ExecStatusOutput{}
+	out.Body.Status = "Exec deactivated"
+	out.Body.ID = exec.ID
+	out.Body.InactiveStatus = true
+	return out, nil
+}
+
+func (h *ExecsHandlers) ReactivateExecHandler(
+	ctx context.Context,
+	input *struct {
+		ID int `path:"id"`
+	},
+) (*ExecStatusOutput, error) {
+	exec, err := h.execsDB.GetExecsByID(ctx, input.ID)
+	if err != nil {
+		return nil, dbError(err, huma.Error404NotFound("exec not found", err))
+	}
+	pending, err := h.invitationsDB.HasInvitation(exec.ID)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("internal error", err)
+	}
+	if pending {
+		return nil, huma.Error409Conflict("exec has not activated the account yet")
+	}
+	if err := h.execsDB.SetInactiveStatus(ctx, exec.ID, false); err != nil {
+		return nil, dbError(err, huma.Error500InternalServerError("Could not reactivate the exec", err))
+	}
+	h.authCache.InvalidateExec(exec.ID)
+	h.auditAuth(ctx, models.AuthEventReactivate, exec, models.AuthOutcomeSuccess, "")
+
+	out := &ExecStatusOutput{}
+	out.Body.Status = "Exec reactivated"
+	out.Body.ID = exec.ID
+	return out, nil
+}
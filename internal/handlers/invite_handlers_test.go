@@ -0,0 +1,310 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/humatest"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/config"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops/memory"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/middleware"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/mailer"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/password"
+)
+
+type mockInviteExecsDB struct {
+	dataops.ExecsInf
+	execs map[int]models.Exec
+}
+
+func (m *mockInviteExecsDB) InsertExecs(_ context.Context, ex *models.Exec) (int64, error) {
+	id := len(m.execs) + 1
+	exec := *ex
+	exec.ID = id
+	m.execs[id] = exec
+	return int64(id), nil
+}
+
+func (m *mockInviteExecsDB) GetIdFromEmail(_ context.Context, email string) (models.Exec, error) {
+	for _, exec := range m.execs {
+		if exec.Email == email {
+			return exec, nil
+		}
+	}
+	return models.Exec{}, errors.New("user not found")
+}
+
+func (m *mockInviteExecsDB) GetExecsByID(_ context.Context, id int) (models.Exec, error) {
+	exec, ok := m.execs[id]
+	if !ok {
+		return models.Exec{}, errors.New("sql exec error")
+	}
+	return exec, nil
+}
+
+func (m *mockInviteExecsDB) SetInactiveStatus(_ context.Context, id int, inactive bool) error {
+	exec := m.execs[id]
+	exec.InactiveStatus = inactive
+	m.execs[id] = exec
+	return nil
+}
+
+type mockInvitationsDB struct {
+	execs       *mockInviteExecsDB
+	invitations map[int]models.ExecInvitation
+}
+
+func (m *mockInvitationsDB) SaveInvitation(inv models.ExecInvitation) error {
+	m.invitations[inv.ExecID] = inv
+	return nil
+}
+
+func (m *mockInvitationsDB) GetInvitationByTokenHash(hash string) (models.ExecInvitation, error) {
+	for _, inv := range m.invitations {
+		if inv.TokenHash == hash {
+			return inv, nil
+		}
+	}
+	return models.ExecInvitation{}, errors.New("invalid or expired activation code")
+}
+
+func (m *mockInvitationsDB) HasInvitation(execID int) (bool, error) {
+	_, ok := m.invitations[execID]
+	return ok, nil
+}
+
+func (m *mockInvitationsDB) ActivateExec(hash string, exec models.Exec) (bool, error) {
+	if inv, ok := m.invitations[exec.ID]; !ok || inv.TokenHash != hash {
+		return false, nil
+	}
+	delete(m.invitations, exec.ID)
+	exec.InactiveStatus = false
+	m.execs.execs[exec.ID] = exec
+	return true, nil
+}
+
+type mockAuthInvalidator struct {
+	execs []int
+}
+
+func (m *mockAuthInvalidator) InvalidateSession(int) {}
+
+func (m *mockAuthInvalidator) InvalidateExec(id int) {
+	m.execs = append(m.execs, id)
+}
+
+var activationLinkRe = regexp.MustCompile(`/execs/invitations/([0-9a-f]+)/activate`)
+
+func TestInviteAndActivateExec(t *testing.T) {
+	_, api := humatest.New(t)
+	templates, err := mailer.LoadTemplates("../../templates/email")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mail := mailer.NewMemoryMailer()
+	execsDB := &mockInviteExecsDB{execs: map[int]models.Exec{}}
+	invitationsDB := &mockInvitationsDB{execs: execsDB, invitations: map[int]models.ExecInvitation{}}
+	cache := &mockAuthInvalidator{}
+	h := NewExecsHandler(ExecsDeps{
+		Execs:          execsDB,
+		Sessions:       &mockSessionsDB{},
+		Invitations:    invitationsDB,
+		AuthCache:      cache,
+		Config:         config.Config{InviteTokenExpiresIn: time.Hour, PublicBaseURL: "https://school.test"},
+		PasswordPolicy: password.Policy{MinLength: 10},
+		Mail:           mailer.NewSender(mail, templates),
+	})
+	huma.Register(api, huma.Operation{
+		OperationID: "invite-exec",
+		Method:      http.MethodPost,
+		Path:        "/execs/invite",
+	}, h.InviteExecHandler)
+	huma.Register(api, huma.Operation{
+		OperationID: "activate-exec",
+		Method:      http.MethodPost,
+		Path:        "/execs/invitations/{activationcode}/activate",
+	}, h.ActivateExecHandler)
+	huma.Register(api, huma.Operation{
+		OperationID: "reactivate-exec",
+		Method:      http.MethodPost,
+		Path:        "/execs/{id}/reactivate",
+	}, h.ReactivateExecHandler)
+
+	resp := api.Post("/execs/invite", map[string]any{"email": "new@school.test", "role": "staff"})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from invite, got %d %s", resp.Code, resp.Body.String())
+	}
+	invited := execsDB.execs[1]
+	if !invited.InactiveStatus || invited.Password != "" || invited.Username != "new@school.test" {
+		t.Fatalf("Expected inactive exec without password, got %+v", invited)
+	}
+	if code := api.Post("/execs/invite", map[string]any{"email": "new@school.test", "role": "staff"}).Code; code != http.StatusConflict {
+		t.Fatalf("Expected 409 for invited email, got %d", code)
+	}
+	if code := api.Post("/execs/1/reactivate").Code; code != http.StatusConflict {
+		t.Fatalf("Expected 409 for reactivating not activated exec, got %d", code)
+	}
+
+	messages := mail.Messages()
+	if len(messages) != 1 || messages[0].To[0] != "new@school.test" {
+		t.Fatalf("Expected one invitation email, got %+v", messages)
+	}
+	match := activationLinkRe.FindStringSubmatch(messages[0].Text)
+	if match == nil {
+		t.Fatalf("Expected activation link in the email, got %s", messages[0].Text)
+	}
+	activate := "/execs/invitations/" + match[1] + "/activate"
+
+	body := map[string]any{
+		"new_password":     "short",
+		"confirm_password": "short",
+		"first_name":       "New",
+		"last_name":        "Exec",
+	}
+	if code := api.Post(activate, body).Code; code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected 422 for weak password, got %d", code)
+	}
+	body["new_password"], body["confirm_password"] = "long enough password", "long enough password"
+	if resp := api.Post(activate, body); resp.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from activation, got %d %s", resp.Code, resp.Body.String())
+	}
+	activated := execsDB.execs[1]
+	if activated.InactiveStatus || activated.FirstName != "New" {
+		t.Fatalf("Expected active exec with the name, got %+v", activated)
+	}
+	if ok, _, _ := password.Verify("long enough password", activated.Password, password.DefaultParams); !ok {
+		t.Fatalf("Expected the password to be set on activation")
+	}
+	if code := api.Post(activate, body).Code; code != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for used activation code, got %d", code)
+	}
+}
+
+// the memory repository activates the exec in memory, the activated exec can log in
+func TestInviteActivateLoginMemory(t *testing.T) {
+	_, api := humatest.New(t)
+	templates, err := mailer.LoadTemplates("../../templates/email")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mail := mailer.NewMemoryMailer()
+	store := memory.NewStore()
+	h := NewExecsHandler(ExecsDeps{
+		Execs:         store.Execs,
+		Sessions:      &mockSessionsDB{},
+		LoginAttempts: &mockLoginAttemptsDB{attempts: map[string]models.LoginAttempt{}},
+		MFA:           &mockMFADB{},
+		Invitations:   store.Invitations,
+		Config: config.Config{
+			InviteTokenExpiresIn: time.Hour,
+			PublicBaseURL:        "https://school.test",
+			JWTSecret:            "test",
+			JWTExpiresIn:         time.Minute,
+			LoginMaxAttempts:     5,
+			LoginMaxAttemptsIP:   10,
+			LoginLockDuration:    time.Minute,
+		},
+		PasswordPolicy: password.Policy{MinLength: 10},
+		Mail:           mailer.NewSender(mail, templates),
+	})
+	huma.Register(api, huma.Operation{
+		OperationID: "invite-exec",
+		Method:      http.MethodPost,
+		Path:        "/execs/invite",
+	}, h.InviteExecHandler)
+	huma.Register(api, huma.Operation{
+		OperationID: "activate-exec",
+		Method:      http.MethodPost,
+		Path:        "/execs/invitations/{activationcode}/activate",
+	}, h.ActivateExecHandler)
+	huma.Register(api, huma.Operation{
+		OperationID: "login-exec",
+		Method:      http.MethodPost,
+		Path:        "/execs/login",
+	}, h.ExecLoginHandler)
+
+	login := func() int {
+		return api.Post("/execs/login", map[string]any{
+			"execs": map[string]any{"username": "new@school.test", "password": "long enough password"},
+		}).Code
+	}
+
+	if resp := api.Post("/execs/invite", map[string]any{
+		"email": "new@school.test", "role": "staff", "first_name": "New", "last_name": "Exec",
+	}); resp.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from invite, got %d %s", resp.Code, resp.Body.String())
+	}
+	if code := login(); code != http.StatusUnauthorized && code != http.StatusForbidden {
+		t.Fatalf("Expected the invited exec unable to log in, got %d", code)
+	}
+	match := activationLinkRe.FindStringSubmatch(mail.Messages()[0].Text)
+	if match == nil {
+		t.Fatalf("Expected activation link in the email, got %s", mail.Messages()[0].Text)
+	}
+	activate := "/execs/invitations/" + match[1] + "/activate"
+	body := map[string]any{
+		"new_password":     "long enough password",
+		"confirm_password": "long enough password",
+	}
+	if resp := api.Post(activate, body); resp.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from activation, got %d %s", resp.Code, resp.Body.String())
+	}
+	if code := login(); code != http.StatusOK {
+		t.Fatalf("Expected the activated exec to log in, got %d", code)
+	}
+	if code := api.Post(activate, body).Code; code != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for used activation code, got %d", code)
+	}
+}
+
+func TestDeactivateExec(t *testing.T) {
+	_, api := humatest.New(t)
+	execsDB := &mockInviteExecsDB{execs: map[int]models.Exec{
+		1: {ID: 1, Username: "admin", Role: middleware.RoleAdmin},
+		2: {ID: 2, Username: "staff", Role: middleware.RoleStaff},
+	}}
+	cache := &mockAuthInvalidator{}
+	h := NewExecsHandler(ExecsDeps{
+		Execs:       execsDB,
+		Sessions:    &mockSessionsDB{},
+		Invitations: &mockInvitationsDB{execs: execsDB, invitations: map[int]models.ExecInvitation{}},
+		AuthCache:   cache,
+	})
+	huma.Register(api, huma.Operation{
+		OperationID: "deactivate-exec",
+		Method:      http.MethodPost,
+		Path:        "/execs/{id}/deactivate",
+	}, h.DeactivateExecHandler)
+	huma.Register(api, huma.Operation{
+		OperationID: "reactivate-exec",
+		Method:      http.MethodPost,
+		Path:        "/execs/{id}/reactivate",
+	}, h.ReactivateExecHandler)
+
+	ctx := context.WithValue(context.Background(), middleware.ContextKey("uid"), "1")
+	if code := api.PostCtx(ctx, "/execs/1/deactivate").Code; code != http.StatusConflict {
+		t.Fatalf("Expected 409 for deactivating own account, got %d", code)
+	}
+	if code := api.PostCtx(ctx, "/execs/3/deactivate").Code; code != http.StatusNotFound {
+		t.Fatalf("Expected 404 for unknown exec, got %d", code)
+	}
+	if resp := api.PostCtx(ctx, "/execs/2/deactivate"); resp.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from deactivate, got %d %s", resp.Code, resp.Body.String())
+	}
+	if !execsDB.execs[2].InactiveStatus || len(cache.execs) != 1 || cache.execs[0] != 2 {
+		t.Fatalf("Expected exec 2 inactive and removed from the auth cache")
+	}
+	if code := api.PostCtx(ctx, "/execs/2/reactivate").Code; code != http.StatusOK {
+		t.Fatalf("Expected 200 from reactivate, got %d", code)
+	}
+	if execsDB.execs[2].InactiveStatus {
+		t.Fatalf("Expected exec 2 active again")
+	}
+}
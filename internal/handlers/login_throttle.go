@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/middleware"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+)
+
+// invalidCredentials - same answer for unknown username and wrong password so the usernames can not be guessed
+func invalidCredentials() error {
+	return huma.Error401Unauthorized("invalid username or password")
+}
+
+func usernameAttemptKey(username string) string {
+	return "user:" + strings.ToLower(username)
+}
+
+func ipAttemptKey(ip string) string {
+	return "ip:" + ip
+}
+
+// loginKeys - the throttle keys of the login with the max failures allowed for each
+func (h *ExecsHandlers) loginKeys(ctx context.Context, username string) map[string]int {
+	keys := map[string]int{
+		usernameAttemptKey(username): h.conf.LoginMaxAttempts,
+	}
+	if ip := middleware.ClientIP(ctx); ip != "" {
+		keys[ipAttemptKey(ip)] = h.conf.LoginMaxAttemptsIP
+	}
+	return keys
+}
+
+// loginBlockedFor - how long the client has to wait before the next login attempt
+func (h *ExecsHandlers) loginBlockedFor(keys map[string]int) (time.Duration, error) {
+	var wait time.Duration
+	now := time.Now()
+	for key := range keys {
+		attempt, err := h.loginAttemptsDB.GetLoginAttempt(key)
+		if err != nil {
+			return 0, err
+		}
+		if attempt.LockedUntil == "" {
+			continue
+		}
+		lockedUntil, err := time.Parse(time.RFC3339, attempt.LockedUntil)
+		if err != nil {
+			continue
+		}
+		if d := lockedUntil.Sub(now); d > wait {
+			wait = d
+		}
+	}
+	return wait, nil
+}
+
+// loginBackoff - delay after n failures, doubled every time until the account is locked
+func (h *ExecsHandlers) loginBackoff(failures, maxAttempts int) time.Duration {
+	if failures >= maxAttempts {
+		return h.conf.LoginLockDuration
+	}
+	backoff := time.Duration(float64(h.conf.LoginBackoffBase) * math.Pow(2, float64(failures-1)))
+	return min(backoff, h.conf.LoginLockDuration)
+}
+
+// recordLoginFailure - the failures are counted by the database, so the parallel requests
+// can not get more attempts than allowed, and the lock is computed from the returned count
+func (h *ExecsHandlers) recordLoginFailure(keys map[string]int) {
+	now := time.Now()
+	for key, maxAttempts := range keys {
+		// failures older than the lock duration are forgotten
+		failures, err := h.loginAttemptsDB.AddLoginFailure(key, now, now.Add(-h.conf.LoginLockDuration))
+		if err != nil {
+			h.logger.Logging.Errorf("failed to save login attempt %v", err)
+			continue
+		}
+		if failures >= maxAttempts {
+			h.logger.Logging.Warnf("login locked for %s after %d failures", key, failures)
+		}
+		if err := h.loginAttemptsDB.LockLogin(key, failures, now.Add(h.loginBackoff(failures, maxAttempts))); err != nil {
+			h.logger.Logging.Errorf("failed to lock the login %v", err)
+		}
+	}
+}
+
+func tooManyLoginAttempts(wait time.Duration) error {
+	seconds := int(math.Ceil(wait.Seconds()))
+	return huma.ErrorWithHeaders(
+		huma.Error429TooManyRequests("too many failed login attempts, try again later"),
+		http.Header{"Retry-After": []string{strconv.Itoa(seconds)}},
+	)
+}
+
+func (h *ExecsHandlers) LockExecHandler(
+	ctx context.Context,
+	input *struct {
+		ID   int `path:"id"`
+		Body *models.ExecLockInput
+	},
+) (*ExecLockOutput, error) {
+	duration := h.conf.LoginLockDuration
+	if input.Body != nil && input.Body.Duration != "" {
+		d, err := time.ParseDuration(input.Body.Duration)
+		if err != nil || d <= 0 {
+			return nil, huma.Error400BadRequest("invalid lock duration", err)
+		}
+		duration = d
+	}
+
+	exec, err := h.execsDB.GetExecsByID(ctx, input.ID)
+	if err != nil {
+		return nil, dbError(err, huma.Error404NotFound("exec not found", err))
+	}
+
+	now := time.Now()
+	attempt := models.LoginAttempt{
+		Key:            usernameAttemptKey(exec.Username),
+		FailedAttempts: h.conf.LoginMaxAttempts,
+		LastFailedAt:   now.Format(time.RFC3339),
+		LockedUntil:    now.Add(duration).Format(time.RFC3339),
+	}
+	if err := h.loginAttemptsDB.SaveLoginAttempt(attempt); err != nil {
+		return nil, huma.Error500InternalServerError("Could not lock the exec", err)
+	}
+	h.auditAuth(ctx, models.AuthEventLock, exec, models.AuthOutcomeSuccess, "locked until "+attempt.LockedUntil)
+
+	out := &ExecLockOutput{}
+	out.Body.Status = "Exec locked"
+	out.Body.ID = exec.ID
+	out.Body.LockedUntil = attempt.LockedUntil
+	return out, nil
+}
+
+func (h *ExecsHandlers) UnlockExecHandler(
+	ctx context.Context,
+	input *struct {
+		ID int `path:"id"`
+	},
+) (*ExecLockOutput, error) {
+	exec, err := h.execsDB.GetExecsByID(ctx, input.ID)
+	if err != nil {
+		return nil, dbError(err, huma.Error404NotFound("exec not found", err))
+	}
+	if err := h.loginAttemptsDB.ResetLoginAttempts(usernameAttemptKey(exec.Username)); err != nil {
+		return nil, huma.Error500InternalServerError("Could not unlock the exec", err)
+	}
+	h.auditAuth(ctx, models.AuthEventUnlock, exec, models.AuthOutcomeSuccess, "")
+
+	out := &ExecLockOutput{}
+	out.Body.Status = "Exec unlocked"
+	out.Body.ID = exec.ID
+	return out, nil
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/humatest"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/config"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/password"
+)
+
+// mockLoginExecsDB - only the methods used by the login, the rest panics through the nil interface
+type mockLoginExecsDB struct {
+	dataops.ExecsInf
+	username string
+	password string
+}
+
+func (m *mockLoginExecsDB) SearchUsername(_ context.Context, username string) (bool, error, string) {
+	if username != m.username {
+		return false, nil, ""
+	}
+	return true, nil, m.password
+}
+
+type mockLoginAttemptsDB struct {
+	attempts map[string]models.LoginAttempt
+}
+
+func (m *mockLoginAttemptsDB) GetLoginAttempt(key string) (models.LoginAttempt, error) {
+	if attempt, ok := m.attempts[key]; ok {
+		return attempt, nil
+	}
+	return models.LoginAttempt{Key: key}, nil
+}
+
+func (m *mockLoginAttemptsDB) SaveLoginAttempt(attempt models.LoginAttempt) error {
+	m.attempts[attempt.Key] = attempt
+	return nil
+}
+
+func (m *mockLoginAttemptsDB) AddLoginFailure(key string, failedAt, resetBefore time.Time) (int, error) {
+	attempt, _ := m.GetLoginAttempt(key)
+	if lastFailed, err := time.Parse(time.RFC3339, attempt.LastFailedAt); err == nil && lastFailed.Before(resetBefore) {
+		attempt.FailedAttempts = 0
+	}
+	attempt.FailedAttempts++
+	attempt.LastFailedAt = failedAt.UTC().Format(time.RFC3339)
+	m.attempts[key] = attempt
+	return attempt.FailedAttempts, nil
+}
+
+func (m *mockLoginAttemptsDB) LockLogin(key string, failures int, lockedUntil time.Time) error {
+	if attempt := m.attempts[key]; attempt.FailedAttempts == failures {
+		attempt.LockedUntil = lockedUntil.UTC().Format(time.RFC3339)
+		m.attempts[key] = attempt
+	}
+	return nil
+}
+
+func (m *mockLoginAttemptsDB) ResetLoginAttempts(key string) error {
+	delete(m.attempts, key)
+	return nil
+}
+
+// mockMFADB - in memory two factor of a single exec
+type mockMFADB struct {
+	mfa           models.ExecMFA
+	recoveryCodes map[string]bool
+}
+
+func (m *mockMFADB) GetMFA(execID int) (models.ExecMFA, error) {
+	if m.mfa.ExecID != execID {
+		return models.ExecMFA{ExecID: execID}, nil
+	}
+	return m.mfa, nil
+}
+
+func (m *mockMFADB) SaveMFASecret(execID int, secret string) error {
+	m.mfa = models.ExecMFA{ExecID: execID, Secret: secret}
+	return nil
+}
+
+func (m *mockMFADB) EnableMFA(execID int, step int64, hashes []string) error {
+	m.mfa.Enabled = true
+	m.mfa.LastUsedStep = step
+	return m.ReplaceRecoveryCodes(execID, hashes)
+}
+
+func (m *mockMFADB) DisableMFA(execID int) error {
+	m.mfa = models.ExecMFA{}
+	m.recoveryCodes = nil
+	return nil
+}
+
+func (m *mockMFADB) UseMFAStep(execID int, step int64) (bool, error) {
+	if m.mfa.LastUsedStep >= step {
+		return false, nil
+	}
+	m.mfa.LastUsedStep = step
+	return true, nil
+}
+
+func (m *mockMFADB) ReplaceRecoveryCodes(execID int, hashes []string) error {
+	m.recoveryCodes = map[string]bool{}
+	for _, hash := range hashes {
+		m.recoveryCodes[hash] = false
+	}
+	return nil
+}
+
+func (m *mockMFADB) UseRecoveryCode(execID int, hash string) (bool, error) {
+	used, ok := m.recoveryCodes[hash]
+	if !ok || used {
+		return false, nil
+	}
+	m.recoveryCodes[hash] = true
+	return true, nil
+}
+
+func TestExecLoginThrottle(t *testing.T) {
+	_, api := humatest.New(t)
+	hash, err := password.Hash("secret", password.DefaultParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attemptsDB := &mockLoginAttemptsDB{attempts: map[string]models.LoginAttempt{}}
+	conf := config.Config{
+		LoginMaxAttempts:   2,
+		LoginMaxAttemptsIP: 10,
+		LoginLockDuration:  time.Minute,
+		LoginBackoffBase:   0,
+	}
+	h := NewExecsHandler(ExecsDeps{
+		Execs:         &mockLoginExecsDB{username: "admin", password: hash},
+		LoginAttempts: attemptsDB,
+		MFA:           &mockMFADB{},
+		Config:        conf,
+	})
+	huma.Register(api, huma.Operation{
+		OperationID: "login-exec",
+		Method:      http.MethodPost,
+		Path:        "/execs/login",
+	}, h.ExecLoginHandler)
+
+	login := func(username, password string) int {
+		return api.Post("/execs/login", map[string]any{
+			"execs": map[string]any{"username": username, "password": password},
+		}).Code
+	}
+
+	unknown := login("nobody", "secret")
+	wrong := login("admin", "wrong")
+	if unknown != http.StatusUnauthorized || wrong != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 for unknown user and wrong password, got %d and %d", unknown, wrong)
+	}
+
+	if code := login("admin", "wrong"); code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401, got %d", code)
+	}
+	if code := login("admin", "secret"); code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 after too many failures, got %d", code)
+	}
+	if attemptsDB.attempts["user:admin"].FailedAttempts != 2 {
+		t.Fatalf("Expected 2 failed attempts, got %+v", attemptsDB.attempts["user:admin"])
+	}
+}
+
+// TestExecLoginWithoutPassword - the invited exec without password fails like an unknown
+// username and counts as a failed attempt
+func TestExecLoginWithoutPassword(t *testing.T) {
+	_, api := humatest.New(t)
+	attemptsDB := &mockLoginAttemptsDB{attempts: map[string]models.LoginAttempt{}}
+	h := NewExecsHandler(ExecsDeps{
+		Execs:         &mockLoginExecsDB{username: "invited"},
+		LoginAttempts: attemptsDB,
+		MFA:           &mockMFADB{},
+		Config:        config.Config{LoginMaxAttempts: 5, LoginMaxAttemptsIP: 10, LoginLockDuration: time.Minute},
+	})
+	huma.Register(api, huma.Operation{
+		OperationID: "login-exec",
+		Method:      http.MethodPost,
+		Path:        "/execs/login",
+	}, h.ExecLoginHandler)
+
+	resp := api.Post("/execs/login", map[string]any{
+		"execs": map[string]any{"username": "invited", "password": "anything"},
+	})
+	if resp.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 for the exec without password, got %d %s", resp.Code, resp.Body.String())
+	}
+	if attemptsDB.attempts["user:invited"].FailedAttempts != 1 {
+		t.Fatalf("Expected the failed attempt recorded, got %+v", attemptsDB.attempts["user:invited"])
+	}
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/middleware"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/utils"
+)
+
+func (h *ExecsHandlers) GetMeHandler(
+	ctx context.Context,
+	_ *struct{},
+) (*ExecIDResponse, error) {
+	id, err := currentExecID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	exec, err := h.execsDB.GetExecsByID(ctx, id)
+	if err != nil {
+		return nil, dbError(err, huma.Error404NotFound("exec not found", err))
+	}
+
+	resp := &ExecIDResponse{}
+	resp.Body.Data = exec
+	return resp, nil
+}
+
+// PatchMeHandler - the exec can change only its names and email, the username and the role
+// are changed by the admins
+func (h *ExecsHandlers) PatchMeHandler(
+	ctx context.Context,
+	input *ExecMePatchInput,
+) (*ExecPatchOutput, error) {
+	id, err := currentExecID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if email := input.Body.Email; email != "" {
+		if err := utils.EmailCheck(email); err != nil {
+			return nil, huma.Error400BadRequest(
+				"Invalid mail format",
+				fmt.Errorf("invalid email: %s", email),
+			)
+		}
+		if other, err := h.execsDB.GetIdFromEmail(ctx, email); err == nil && other.ID != id {
+			return nil, huma.Error409Conflict("exec with this email already exists")
+		}
+	}
+
+	updatedExec, err := h.execsDB.PatchExec(ctx, id, models.Exec{
+		FirstName: input.Body.FirstName,
+		LastName:  input.Body.LastName,
+		Email:     input.Body.Email,
+	})
+	if err != nil {
+		return nil, dbError(err, huma.Error500InternalServerError("Could not update the profile", err))
+	}
+
+	resp := &ExecPatchOutput{}
+	resp.Body.Status = "Success"
+	resp.Body.Data = updatedExec
+	return resp, nil
+}
+
+func (h *ExecsHandlers) ListMySessionsHandler(
+	ctx context.Context,
+	_ *struct{},
+) (*ExecSessionsOutput, error) {
+	id, err := currentExecID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := h.sessionsDB.ListActiveSessions(id)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Error quering database", err)
+	}
+
+	sid, _ := ctx.Value(middleware.ContextKey("sid")).(int)
+	views := make([]ExecSessionView, 0, len(sessions))
+	for _, session := range sessions {
+		views = append(views, ExecSessionView{ExecSession: session, Current: session.ID == sid})
+	}
+
+	out := &ExecSessionsOutput{}
+	out.Body.Status = "Success"
+	out.Body.Count = len(views)
+	out.Body.Data = views
+	return out, nil
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/humatest"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/middleware"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+)
+
+type mockMeExecsDB struct {
+	mockInviteExecsDB
+}
+
+func (m *mockMeExecsDB) PatchExec(_ context.Context, id int, updated models.Exec) (models.Exec, error) {
+	exec := m.execs[id]
+	if updated.FirstName != "" {
+		exec.FirstName = updated.FirstName
+	}
+	if updated.Email != "" {
+		exec.Email = updated.Email
+	}
+	m.execs[id] = exec
+	return exec, nil
+}
+
+type mockMeSessionsDB struct {
+	mockSessionsDB
+	sessions map[int][]models.ExecSession
+}
+
+func (m *mockMeSessionsDB) ListActiveSessions(execID int) ([]models.ExecSession, error) {
+	return m.sessions[execID], nil
+}
+
+func TestMeEndpoints(t *testing.T) {
+	_, api := humatest.New(t)
+	execsDB := &mockMeExecsDB{mockInviteExecsDB{execs: map[int]models.Exec{
+		1: {ID: 1, Username: "admin", Email: "admin@school.test", Role: middleware.RoleAdmin},
+		2: {ID: 2, Username: "staff", Email: "staff@school.test", Role: middleware.RoleStaff},
+	}}}
+	sessionsDB := &mockMeSessionsDB{sessions: map[int][]models.ExecSession{
+		2: {{ID: 7, ExecID: 2}, {ID: 5, ExecID: 2}},
+	}}
+	h := NewExecsHandler(ExecsDeps{
+		Execs:    execsDB,
+		Sessions: sessionsDB,
+	})
+	huma.Register(api, huma.Operation{
+		OperationID: "get-me",
+		Method:      http.MethodGet,
+		Path:        "/me",
+	}, h.GetMeHandler)
+	huma.Register(api, huma.Operation{
+		OperationID: "patch-me",
+		Method:      http.MethodPatch,
+		Path:        "/me",
+	}, h.PatchMeHandler)
+	huma.Register(api, huma.Operation{
+		OperationID: "list-my-sessions",
+		Method:      http.MethodGet,
+		Path:        "/me/sessions",
+	}, h.ListMySessionsHandler)
+
+	if code := api.Get("/me").Code; code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 without token, got %d", code)
+	}
+
+	ctx := context.WithValue(context.Background(), middleware.ContextKey("uid"), "2")
+	ctx = context.WithValue(ctx, middleware.ContextKey("sid"), 5)
+	resp := api.GetCtx(ctx, "/me")
+	var me struct {
+		Data models.Exec `json:"data"`
+	}
+	if err := json.Unmarshal(resp.Body.Bytes(), &me); err != nil {
+		t.Fatal(err)
+	}
+	if me.Data.Username != "staff" {
+		t.Fatalf("Expected the exec of the token, got %s", resp.Body.String())
+	}
+
+	if code := api.PatchCtx(ctx, "/me", map[string]any{"email": "admin@school.test"}).Code; code != http.StatusConflict {
+		t.Fatalf("Expected 409 for email of other exec, got %d", code)
+	}
+	if code := api.PatchCtx(ctx, "/me", map[string]any{"first_name": "Sam"}).Code; code != http.StatusOK {
+		t.Fatalf("Expected 200 from patch, got %d", code)
+	}
+	if execsDB.execs[2].FirstName != "Sam" || execsDB.execs[1].FirstName != "" {
+		t.Fatalf("Expected only the exec of the token to be updated")
+	}
+
+	resp = api.GetCtx(ctx, "/me/sessions")
+	var sessions struct {
+		Count int `json:"count"`
+		Data  []struct {
+			ID      int  `json:"id"`
+			Current bool `json:"current"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(resp.Body.Bytes(), &sessions); err != nil {
+		t.Fatal(err)
+	}
+	if sessions.Count != 2 || sessions.Data[0].Current || !sessions.Data[1].Current {
+		t.Fatalf("Expected session 5 marked as current, got %s", resp.Body.String())
+	}
+}
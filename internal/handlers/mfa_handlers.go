@@ -0,0 +1,315 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/totp"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/utils"
+)
+
+const recoveryCodesCount = 10
+
+// generateRecoveryCodes - one time codes for login without the authenticator, only the hashes are stored
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodesCount)
+	hashes := make([]string, recoveryCodesCount)
+	for i := range codes {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		code := hex.EncodeToString(b)
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = hashRecoveryCode(code)
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode - the dash and the case of the entered code does not matter
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+func (h *ExecsHandlers) mfaSecret(mfa models.ExecMFA) (string, error) {
+	secret, err := utils.DecryptSecret(mfa.Secret, h.conf.MFASecretKey)
+	if err != nil {
+		h.logger.Logging.Errorf("failed to decrypt the totp secret of exec %d %v", mfa.ExecID, err)
+		return "", huma.Error500InternalServerError("invalid totp secret")
+	}
+	return secret, nil
+}
+
+// verifyMFACode - checks the code and marks its time step as used so it can not be replayed
+func (h *ExecsHandlers) verifyMFACode(mfa models.ExecMFA, code string) (bool, error) {
+	secret, err := h.mfaSecret(mfa)
+	if err != nil {
+		return false, err
+	}
+	step, ok := totp.Validate(secret, code, h.now())
+	if !ok || step <= mfa.LastUsedStep {
+		return false, nil
+	}
+	used, err := h.mfaDB.UseMFAStep(mfa.ExecID, step)
+	if err != nil {
+		return false, huma.Error500InternalServerError("database error", err)
+	}
+	return used, nil
+}
+
+func (h *ExecsHandlers) EnrollMFAHandler(
+	ctx context.Context,
+	_ *struct{},
+) (*ExecMFAEnrollOutput, error) {
+	id, err := currentExecID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	exec, err := h.execsDB.GetExecsByID(ctx, id)
+	if err != nil {
+		return nil, dbError(err, huma.Error404NotFound("exec not found", err))
+	}
+	mfa, err := h.mfaDB.GetMFA(id)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("database error", err)
+	}
+	if mfa.Enabled {
+		return nil, huma.Error409Conflict("two factor already enabled, disable it first")
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Could not generate the secret", err)
+	}
+	encrypted, err := utils.EncryptSecret(secret, h.conf.MFASecretKey)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Could not encrypt the secret", err)
+	}
+	if err := h.mfaDB.SaveMFASecret(id, encrypted); err != nil {
+		return nil, huma.Error500InternalServerError("Could not save the secret", err)
+	}
+
+	out := &ExecMFAEnrollOutput{}
+	out.Body.Secret = secret
+	out.Body.ProvisioningURI = totp.ProvisioningURI(h.conf.MFAIssuer, exec.Username, secret)
+	return out, nil
+}
+
+func (h *ExecsHandlers) ConfirmMFAHandler(
+	ctx context.Context,
+	input *ExecMFACodeInput,
+) (*ExecMFARecoveryCodesOutput, error) {
+	id, err := currentExecID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	mfa, err := h.mfaDB.GetMFA(id)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("database error", err)
+	}
+	if mfa.Secret == "" {
+		return nil, huma.Error400BadRequest("two factor is not enrolled")
+	}
+	if mfa.Enabled {
+		return nil, huma.Error409Conflict("two factor already enabled")
+	}
+
+	secret, err := h.mfaSecret(mfa)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := totp.Validate(secret, input.Body.Code, h.now())
+	if !ok {
+		return nil, huma.Error400BadRequest("invalid code")
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Could not generate recovery codes", err)
+	}
+	if err := h.mfaDB.EnableMFA(id, step, hashes); err != nil {
+		return nil, huma.Error500InternalServerError("Could not enable two factor", err)
+	}
+	h.auditAuth(ctx, models.AuthEventMFAEnable, models.Exec{ID: id}, models.AuthOutcomeSuccess, "")
+
+	out := &ExecMFARecoveryCodesOutput{}
+	out.Body.Status = "Two factor enabled"
+	out.Body.RecoveryCodes = codes
+	return out, nil
+}
+
+func (h *ExecsHandlers) RegenerateRecoveryCodesHandler(
+	ctx context.Context,
+	input *ExecMFACodeInput,
+) (*ExecMFARecoveryCodesOutput, error) {
+	id, err := currentExecID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	mfa, err := h.mfaDB.GetMFA(id)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("database error", err)
+	}
+	if !mfa.Enabled {
+		return nil, huma.Error400BadRequest("two factor is not enabled")
+	}
+	ok, err := h.verifyMFACode(mfa, input.Body.Code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, huma.Error400BadRequest("invalid code")
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Could not generate recovery codes", err)
+	}
+	if err := h.mfaDB.ReplaceRecoveryCodes(id, hashes); err != nil {
+		return nil, huma.Error500InternalServerError("Could not save recovery codes", err)
+	}
+
+	out := &ExecMFARecoveryCodesOutput{}
+	out.Body.Status = "Recovery codes regenerated"
+	out.Body.RecoveryCodes = codes
+	return out, nil
+}
+
+// DisableMFAHandler - for exec which lost the authenticator and the recovery codes
+func (h *ExecsHandlers) DisableMFAHandler(
+	ctx context.Context,
+	input *struct {
+		ID int `path:"id"`
+	},
+) (*ExecMFAStatusOutput, error) {
+	if err := h.mfaDB.DisableMFA(input.ID); err != nil {
+		return nil, huma.Error500InternalServerError("Could not disable two factor", err)
+	}
+	h.auditAuth(ctx, models.AuthEventMFADisable, models.Exec{ID: input.ID}, models.AuthOutcomeSuccess, "")
+
+	out := &ExecMFAStatusOutput{}
+	out.Body.Status = "Two factor disabled"
+	out.Body.ID = input.ID
+	return out, nil
+}
+
+// DisableMyMFAHandler - the exec turns off its own two factor with a current code
+func (h *ExecsHandlers) DisableMyMFAHandler(
+	ctx context.Context,
+	input *ExecMFACodeInput,
+) (*ExecMFAStatusOutput, error) {
+	id, err := currentExecID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	mfa, err := h.mfaDB.GetMFA(id)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("database error", err)
+	}
+	if !mfa.Enabled {
+		return nil, huma.Error400BadRequest("two factor is not enabled")
+	}
+	ok, err := h.verifyMFACode(mfa, input.Body.Code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, huma.Error400BadRequest("invalid code")
+	}
+	if err := h.mfaDB.DisableMFA(id); err != nil {
+		return nil, huma.Error500InternalServerError("Could not disable two factor", err)
+	}
+	h.auditAuth(ctx, models.AuthEventMFADisable, models.Exec{ID: id}, models.AuthOutcomeSuccess, "")
+
+	out := &ExecMFAStatusOutput{}
+	out.Body.Status = "Two factor disabled"
+	out.Body.ID = id
+	return out, nil
+}
+
+// ExecLoginMFAHandler - second step of the login with the challenge token and totp or recovery code
+func (h *ExecsHandlers) ExecLoginMFAHandler(
+	ctx context.Context,
+	input *ExecsLoginMFAInput,
+) (*ExecsLoginOutput, error) {
+	if input.Body.Code == "" && input.Body.RecoveryCode == "" {
+		return nil, huma.Error400BadRequest("code or recovery_code is required")
+	}
+	uid, err := utils.ParseMFAChallenge(input.Body.MFAToken, h.conf, h.now())
+	if err != nil {
+		h.logger.Logging.Debugf("invalid mfa challenge %v", err)
+		return nil, huma.Error401Unauthorized("invalid or expired mfa token")
+	}
+	id, err := strconv.Atoi(uid)
+	if err != nil {
+		return nil, huma.Error401Unauthorized("invalid or expired mfa token")
+	}
+	exec, err := h.execsDB.GetExecsByID(ctx, id)
+	if err != nil {
+		return nil, dbError(err, huma.Error401Unauthorized("invalid or expired mfa token"))
+	}
+
+	loginKeys := h.loginKeys(ctx, exec.Username)
+	wait, err := h.loginBlockedFor(loginKeys)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("database error", err)
+	}
+	if wait > 0 {
+		h.auditAuth(ctx, models.AuthEventLoginMFA, exec, models.AuthOutcomeFailure, "locked")
+		return nil, tooManyLoginAttempts(wait)
+	}
+	if exec.InactiveStatus {
+		h.auditAuth(ctx, models.AuthEventLoginMFA, exec, models.AuthOutcomeFailure, "inactive")
+		return nil, huma.Error403Forbidden("user inactive")
+	}
+
+	mfa, err := h.mfaDB.GetMFA(id)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("database error", err)
+	}
+	if !mfa.Enabled {
+		return nil, huma.Error401Unauthorized("invalid or expired mfa token")
+	}
+
+	var ok bool
+	if input.Body.RecoveryCode != "" {
+		ok, err = h.mfaDB.UseRecoveryCode(id, hashRecoveryCode(input.Body.RecoveryCode))
+		if err != nil {
+			return nil, huma.Error500InternalServerError("database error", err)
+		}
+	} else {
+		ok, err = h.verifyMFACode(mfa, input.Body.Code)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !ok {
+		h.logger.Logging.Debugf("invalid mfa code for username=%s", exec.Username)
+		h.recordLoginFailure(loginKeys)
+		h.auditAuth(ctx, models.AuthEventLoginMFA, exec, models.AuthOutcomeFailure, "invalid code")
+		return nil, huma.Error401Unauthorized("invalid code")
+	}
+	if err := h.loginAttemptsDB.ResetLoginAttempts(usernameAttemptKey(exec.Username)); err != nil {
+		h.logger.Logging.Errorf("failed to reset login attempts %v", err)
+	}
+
+	tokenString, refreshToken, err := h.newSession(ctx, exec, input.UserAgent)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Could not create login token", err)
+	}
+	h.auditAuth(ctx, models.AuthEventLoginMFA, exec, models.AuthOutcomeSuccess, "")
+
+	out := &ExecsLoginOutput{}
+	out.Body.Token = tokenString
+	out.Body.RefreshToken = refreshToken
+	out.Body.ExpiresIn = int(h.conf.JWTExpiresIn.Seconds())
+	out.SetCookie = h.sessionCookies(tokenString, refreshToken)
+	return out, nil
+}
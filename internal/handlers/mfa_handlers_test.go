@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/humatest"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/config"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/middleware"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/password"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/totp"
+)
+
+func (m *mockLoginExecsDB) IsInactiveUser(_ context.Context, username string) (bool, error) {
+	return false, nil
+}
+
+func (m *mockLoginExecsDB) GetLoginDetailsForUsername(_ context.Context, username string) (models.Exec, error) {
+	return models.Exec{ID: 1, Username: m.username, Role: middleware.RoleAdmin}, nil
+}
+
+func (m *mockLoginExecsDB) GetExecsByID(_ context.Context, id int) (models.Exec, error) {
+	return models.Exec{ID: 1, Username: m.username, Role: middleware.RoleAdmin}, nil
+}
+
+type mockSessionsDB struct {
+	dataops.SessionsInf
+	created int64
+	revoked int
+}
+
+func (m *mockSessionsDB) CreateSession(session *models.ExecSession) (int64, error) {
+	m.created++
+	return m.created, nil
+}
+
+func (m *mockSessionsDB) RevokeAllSessions(execID int) (int64, error) {
+	m.revoked++
+	return 1, nil
+}
+
+func TestExecLoginMFA(t *testing.T) {
+	_, api := humatest.New(t)
+	hash, err := password.Hash("secret", password.DefaultParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := config.Config{
+		JWTSecret:             "test",
+		JWTExpiresIn:          time.Minute,
+		MFASecretKey:          "test",
+		MFAIssuer:             "School API",
+		MFAChallengeExpiresIn: 5 * time.Minute,
+		LoginMaxAttempts:      5,
+		LoginMaxAttemptsIP:    10,
+		LoginLockDuration:     time.Minute,
+	}
+	sessionsDB := &mockSessionsDB{}
+	h := NewExecsHandler(ExecsDeps{
+		Execs:         &mockLoginExecsDB{username: "admin", password: hash},
+		Sessions:      sessionsDB,
+		LoginAttempts: &mockLoginAttemptsDB{attempts: map[string]models.LoginAttempt{}},
+		MFA:           &mockMFADB{},
+		Config:        conf,
+	})
+	clock := time.Unix(1_700_000_000, 0)
+	h.now = func() time.Time { return clock }
+
+	huma.Register(api, huma.Operation{
+		OperationID: "login-exec",
+		Method:      http.MethodPost,
+		Path:        "/execs/login",
+	}, h.ExecLoginHandler)
+	huma.Register(api, huma.Operation{
+		OperationID: "login-mfa-exec",
+		Method:      http.MethodPost,
+		Path:        "/execs/login/mfa",
+	}, h.ExecLoginMFAHandler)
+	huma.Register(api, huma.Operation{
+		OperationID: "enroll-my-mfa",
+		Method:      http.MethodPost,
+		Path:        "/me/mfa/enroll",
+	}, h.EnrollMFAHandler)
+	huma.Register(api, huma.Operation{
+		OperationID: "confirm-my-mfa",
+		Method:      http.MethodPost,
+		Path:        "/me/mfa/confirm",
+	}, h.ConfirmMFAHandler)
+
+	if code := api.Post("/me/mfa/enroll").Code; code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 for enrolling without token, got %d", code)
+	}
+
+	ctx := context.WithValue(context.Background(), middleware.ContextKey("uid"), "1")
+	resp := api.PostCtx(ctx, "/me/mfa/enroll")
+	if resp.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from enroll, got %d %s", resp.Code, resp.Body.String())
+	}
+	var enrolled struct {
+		Secret string `json:"secret"`
+	}
+	if err := json.Unmarshal(resp.Body.Bytes(), &enrolled); err != nil {
+		t.Fatal(err)
+	}
+
+	code, _ := totp.Code(enrolled.Secret, clock)
+	resp = api.PostCtx(ctx, "/me/mfa/confirm", map[string]any{"code": code})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from confirm, got %d %s", resp.Code, resp.Body.String())
+	}
+	var confirmed struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	if err := json.Unmarshal(resp.Body.Bytes(), &confirmed); err != nil {
+		t.Fatal(err)
+	}
+	if len(confirmed.RecoveryCodes) != recoveryCodesCount {
+		t.Fatalf("Expected %d recovery codes, got %d", recoveryCodesCount, len(confirmed.RecoveryCodes))
+	}
+
+	login := func() string {
+		resp := api.Post("/execs/login", map[string]any{
+			"execs": map[string]any{"username": "admin", "password": "secret"},
+		})
+		var out struct {
+			Token       string `json:"token"`
+			MFARequired bool   `json:"mfa_required"`
+			MFAToken    string `json:"mfa_token"`
+		}
+		if err := json.Unmarshal(resp.Body.Bytes(), &out); err != nil {
+			t.Fatal(err)
+		}
+		if !out.MFARequired || out.Token != "" {
+			t.Fatalf("Expected only mfa challenge from login, got %s", resp.Body.String())
+		}
+		return out.MFAToken
+	}
+	loginMFA := func(body map[string]any) int {
+		return api.Post("/execs/login/mfa", body).Code
+	}
+
+	mfaToken := login()
+	if status := loginMFA(map[string]any{"mfa_token": mfaToken, "code": code}); status != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 for replayed code, got %d", status)
+	}
+
+	clock = clock.Add(totp.Period)
+	code, _ = totp.Code(enrolled.Secret, clock)
+	if status := loginMFA(map[string]any{"mfa_token": mfaToken, "code": code}); status != http.StatusOK {
+		t.Fatalf("Expected 200 for valid code, got %d", status)
+	}
+	if sessionsDB.created != 1 {
+		t.Fatalf("Expected session after the second step, got %d", sessionsDB.created)
+	}
+
+	recovery := map[string]any{"mfa_token": login(), "recovery_code": confirmed.RecoveryCodes[0]}
+	if status := loginMFA(recovery); status != http.StatusOK {
+		t.Fatalf("Expected 200 for recovery code, got %d", status)
+	}
+	if status := loginMFA(recovery); status != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 for used recovery code, got %d", status)
+	}
+
+	mfaToken = login()
+	clock = clock.Add(10 * time.Minute)
+	code, _ = totp.Code(enrolled.Secret, clock)
+	if status := loginMFA(map[string]any{"mfa_token": mfaToken, "code": code}); status != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 for expired mfa token, got %d", status)
+	}
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/oidc"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/utils"
+)
+
+const (
+	oidcStateCookieName = "oidc_state"
+	oidcCookiePath      = "/execs/oidc"
+)
+
+// EnableOIDC - turns on the login with the external identity provider
+func (h *ExecsHandlers) EnableOIDC(provider *oidc.Provider, idb dataops.ExecIdentitiesInf) {
+	h.oidcProvider = provider
+	h.identitiesDB = idb
+}
+
+// oidcStateCookie - the callback is cross site navigation from the provider, so the cookie
+// has to be SameSite lax or the browser does not send it back
+func (h *ExecsHandlers) oidcStateCookie(value string, expires time.Time) http.Cookie {
+	cookie := h.authCookie(oidcStateCookieName, value, oidcCookiePath, expires)
+	cookie.SameSite = http.SameSiteLaxMode
+	return cookie
+}
+
+func (h *ExecsHandlers) OIDCLoginHandler(
+	ctx context.Context,
+	_ *struct{},
+) (*ExecOIDCLoginOutput, error) {
+	if h.oidcProvider == nil {
+		return nil, huma.Error404NotFound("oidc login is not configured")
+	}
+
+	var values [3]string
+	for i := range values {
+		v, err := oidc.RandomString()
+		if err != nil {
+			return nil, huma.Error500InternalServerError("Could not start the login", err)
+		}
+		values[i] = v
+	}
+	state, nonce, verifier := values[0], values[1], values[2]
+
+	authURL, err := h.oidcProvider.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		h.logger.Logging.Errorf("oidc provider not available %v", err)
+		return nil, huma.Error502BadGateway("identity provider not available")
+	}
+	now := h.now()
+	stateToken, err := utils.SignOIDCState(state, nonce, verifier, h.conf, now)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Could not start the login", err)
+	}
+
+	out := &ExecOIDCLoginOutput{}
+	out.Status = http.StatusFound
+	out.Location = authURL
+	out.SetCookie = h.oidcStateCookie(stateToken, now.Add(10*time.Minute))
+	return out, nil
+}
+
+// OIDCCallbackHandler - finishes the login at the provider and issues the same session as the
+// password login. The provider is trusted with the second factor so the totp is not asked here
+func (h *ExecsHandlers) OIDCCallbackHandler(
+	ctx context.Context,
+	input *ExecOIDCCallbackInput,
+) (*ExecsLoginOutput, error) {
+	if h.oidcProvider == nil {
+		return nil, huma.Error404NotFound("oidc login is not configured")
+	}
+	if input.Error != "" {
+		return nil, huma.Error401Unauthorized("login at the identity provider failed: " + input.Error)
+	}
+	if input.Code == "" || input.StateCookie == "" {
+		return nil, huma.Error400BadRequest("missing code or login state")
+	}
+
+	now := h.now()
+	state, nonce, verifier, err := utils.ParseOIDCState(input.StateCookie, h.conf, now)
+	if err != nil || subtle.ConstantTimeCompare([]byte(state), []byte(input.State)) != 1 {
+		h.logger.Logging.Debugf("invalid oidc state %v", err)
+		return nil, huma.Error400BadRequest("invalid or expired login state")
+	}
+
+	idToken, err := h.oidcProvider.Exchange(ctx, input.Code, verifier)
+	if err != nil {
+		h.logger.Logging.Errorf("oidc code exchange failed %v", err)
+		return nil, huma.Error401Unauthorized("login at the identity provider failed")
+	}
+	claims, err := h.oidcProvider.VerifyIDToken(ctx, idToken, nonce, now)
+	if err != nil {
+		h.logger.Logging.Errorf("oidc id token rejected %v", err)
+		return nil, huma.Error401Unauthorized("login at the identity provider failed")
+	}
+
+	exec, err := h.execForIdentity(ctx, claims)
+	if err != nil {
+		h.auditAuth(ctx, models.AuthEventLoginOIDC, models.Exec{Username: claims.Email}, models.AuthOutcomeFailure, "no exec for the identity")
+		return nil, err
+	}
+	if exec.InactiveStatus {
+		h.auditAuth(ctx, models.AuthEventLoginOIDC, exec, models.AuthOutcomeFailure, "inactive")
+		return nil, huma.Error403Forbidden("user inactive")
+	}
+
+	tokenString, refreshToken, err := h.newSession(ctx, exec, input.UserAgent)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Could not create login token", err)
+	}
+	h.auditAuth(ctx, models.AuthEventLoginOIDC, exec, models.AuthOutcomeSuccess, "")
+
+	out := &ExecsLoginOutput{}
+	out.Body.Token = tokenString
+	out.Body.RefreshToken = refreshToken
+	out.Body.ExpiresIn = int(h.conf.JWTExpiresIn.Seconds())
+	out.SetCookie = append(
+		h.sessionCookies(tokenString, refreshToken),
+		h.oidcStateCookie("", time.Unix(0, 0)),
+	)
+	return out, nil
+}
+
+// execForIdentity - the exec linked to the subject, on the first login the exec is found by
+// the verified email and the subject is linked to it
+func (h *ExecsHandlers) execForIdentity(ctx context.Context, claims *oidc.Claims) (models.Exec, error) {
+	execID, err := h.identitiesDB.GetExecIDBySubject(claims.Issuer, claims.Subject)
+	if err != nil {
+		return models.Exec{}, huma.Error500InternalServerError("database error", err)
+	}
+	if execID == 0 {
+		if claims.Email == "" || !claims.EmailVerified {
+			return models.Exec{}, huma.Error403Forbidden("no exec for this identity")
+		}
+		byEmail, err := h.execsDB.GetIdFromEmail(ctx, strings.TrimSpace(claims.Email))
+		if err != nil {
+			return models.Exec{}, dbError(err, huma.Error403Forbidden("no exec for this identity"))
+		}
+		if err := h.identitiesDB.LinkIdentity(claims.Issuer, claims.Subject, byEmail.ID); err != nil {
+			return models.Exec{}, huma.Error500InternalServerError("database error", err)
+		}
+		h.logger.Logging.Infof("linked oidc subject %s to exec %d", claims.Subject, byEmail.ID)
+		execID = byEmail.ID
+	}
+
+	exec, err := h.execsDB.GetExecsByID(ctx, execID)
+	if err != nil {
+		return models.Exec{}, dbError(err, huma.Error403Forbidden("no exec for this identity"))
+	}
+	return exec, nil
+}
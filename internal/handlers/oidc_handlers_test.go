@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/humatest"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/config"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/oidc"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/oidc/oidctest"
+)
+
+func (m *mockLoginExecsDB) GetIdFromEmail(_ context.Context, email string) (models.Exec, error) {
+	if email != "admin@example.com" {
+		return models.Exec{}, errors.New("user not found")
+	}
+	return models.Exec{ID: 1}, nil
+}
+
+type mockIdentitiesDB struct {
+	links map[string]int
+}
+
+func (m *mockIdentitiesDB) GetExecIDBySubject(issuer, subject string) (int, error) {
+	return m.links[issuer+"|"+subject], nil
+}
+
+func (m *mockIdentitiesDB) LinkIdentity(issuer, subject string, execID int) error {
+	m.links[issuer+"|"+subject] = execID
+	return nil
+}
+
+func TestExecOIDCLogin(t *testing.T) {
+	idp := oidctest.NewServer("school-api", "secret")
+	defer idp.Close()
+
+	_, api := humatest.New(t)
+	sessionsDB := &mockSessionsDB{}
+	h := NewExecsHandler(ExecsDeps{
+		Execs:         &mockLoginExecsDB{username: "admin"},
+		Sessions:      sessionsDB,
+		LoginAttempts: &mockLoginAttemptsDB{attempts: map[string]models.LoginAttempt{}},
+		MFA:           &mockMFADB{},
+		Config:        config.Config{JWTSecret: "test", JWTExpiresIn: time.Minute, CookieName: "Bearer"},
+	})
+	identities := &mockIdentitiesDB{links: map[string]int{}}
+	h.EnableOIDC(oidc.NewProvider(oidc.Config{
+		Issuer:       idp.Issuer(),
+		ClientID:     "school-api",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost:8082/execs/oidc/callback",
+	}, idp.Client()), identities)
+
+	huma.Register(api, huma.Operation{
+		OperationID: "oidc-login-exec",
+		Method:      http.MethodGet,
+		Path:        "/execs/oidc/login",
+	}, h.OIDCLoginHandler)
+	huma.Register(api, huma.Operation{
+		OperationID: "oidc-callback-exec",
+		Method:      http.MethodGet,
+		Path:        "/execs/oidc/callback",
+	}, h.OIDCCallbackHandler)
+
+	// login returns the redirect to the provider and the state cookie
+	login := func() (string, string) {
+		resp := api.Get("/execs/oidc/login")
+		if resp.Code != http.StatusFound {
+			t.Fatalf("Expected redirect to the provider, got %d %s", resp.Code, resp.Body.String())
+		}
+		cookies := resp.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].Name != oidcStateCookieName {
+			t.Fatalf("Expected the state cookie, got %v", cookies)
+		}
+		client := idp.Client()
+		client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+		idpResp, err := client.Get(resp.Header().Get("Location"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		idpResp.Body.Close()
+		callback, err := url.Parse(idpResp.Header.Get("Location"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "/execs/oidc/callback?" + callback.RawQuery, oidcStateCookieName + "=" + cookies[0].Value
+	}
+
+	callback, _ := login()
+	if code := api.Get(callback).Code; code != http.StatusBadRequest {
+		t.Fatalf("Expected 400 without the state cookie, got %d", code)
+	}
+
+	callback, cookie := login()
+	resp := api.Get(callback, "Cookie: "+cookie)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from the callback, got %d %s", resp.Code, resp.Body.String())
+	}
+	if sessionsDB.created != 1 {
+		t.Fatalf("Expected session to be created, got %d", sessionsDB.created)
+	}
+	if identities.links[idp.Issuer()+"|"+idp.Subject] != 1 {
+		t.Fatalf("Expected the subject linked to the exec by email, got %v", identities.links)
+	}
+
+	// the linked subject logs in also after the email is changed at the provider
+	idp.Email = "changed@example.com"
+	callback, cookie = login()
+	if code := api.Get(callback, "Cookie: "+cookie).Code; code != http.StatusOK {
+		t.Fatalf("Expected 200 for linked subject, got %d", code)
+	}
+
+	idp.Subject = "unknown-subject"
+	callback, cookie = login()
+	if code := api.Get(callback, "Cookie: "+cookie).Code; code != http.StatusForbidden {
+		t.Fatalf("Expected 403 for identity without exec, got %d", code)
+	}
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/password"
+)
+
+// checkPassword - the password policy, the breached passwords and the password history of the
+// exec in one place, the violations are returned as 422 with the location of the password field
+func (h *ExecsHandlers) checkPassword(ctx context.Context, location, pw string, exec models.Exec) error {
+	violations := h.passwordPolicy.Check(pw, exec.Username, exec.Email)
+
+	if exec.ID > 0 && h.passwordPolicy.HistorySize > 0 {
+		hashes, err := h.execsDB.GetPasswordHistory(ctx, exec.ID, h.passwordPolicy.HistorySize)
+		if err != nil {
+			return dbError(err, huma.Error500InternalServerError("database error", err))
+		}
+		violations = append(violations, h.passwordPolicy.CheckHistory(pw, hashes, h.hashParams())...)
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+	details := make([]error, 0, len(violations))
+	for _, v := range violations {
+		details = append(details, &huma.ErrorDetail{Location: location, Message: v})
+	}
+	return huma.Error422UnprocessableEntity("password does not meet the password policy", details...)
+}
+
+// recordPasswordHistory - remembers the new password so it can not be reused later
+func (h *ExecsHandlers) recordPasswordHistory(ctx context.Context, id int, hash string) {
+	if h.passwordPolicy.HistorySize <= 0 {
+		return
+	}
+	if err := h.execsDB.AddPasswordHistory(ctx, id, hash, h.passwordPolicy.HistorySize); err != nil {
+		h.logger.Logging.Errorf("failed to store the password history %v", err)
+	}
+}
+
+// hashParams - the argon2id parameters from the config, the defaults when they are not set
+func (h *ExecsHandlers) hashParams() password.Params {
+	return password.ParamsFromConfig(h.conf)
+}
+
+func (h *ExecsHandlers) hashPassword(pw string) (string, error) {
+	return password.Hash(pw, h.hashParams())
+}
+
+// rehashPassword - upgrades the stored hash after successful login, failure only means
+// the old hash stays until the next login
+func (h *ExecsHandlers) rehashPassword(ctx context.Context, id int, oldHash, pw string) {
+	newHash, err := h.hashPassword(pw)
+	if err != nil {
+		h.logger.Logging.Errorf("failed to rehash the password %v", err)
+		return
+	}
+	if err := h.execsDB.RehashPassword(ctx, id, oldHash, newHash); err != nil {
+		h.logger.Logging.Errorf("failed to store the rehashed password %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/humatest"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/config"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/password"
+	"golang.org/x/crypto/argon2"
+)
+
+type mockPasswordHistoryDB struct {
+	mockLoginExecsDB
+	history []string
+}
+
+func (m *mockPasswordHistoryDB) GetPasswordHistory(_ context.Context, id int, n int) ([]string, error) {
+	return m.history, nil
+}
+
+func TestCheckPassword(t *testing.T) {
+	oldHash, err := password.Hash("Old-Password-1", password.DefaultParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewExecsHandler(ExecsDeps{
+		Execs:          &mockPasswordHistoryDB{history: []string{oldHash}},
+		PasswordPolicy: password.Policy{MinLength: 10, RequireDigit: true, DisallowIdentity: true, HistorySize: 3},
+	})
+	exec := models.Exec{ID: 1, Username: "jdoe", Email: "jdoe@example.com"}
+
+	if err := h.checkPassword(context.Background(), "body.new_password", "Fresh-Password-2", exec); err != nil {
+		t.Fatalf("Expected password to be accepted, got %v", err)
+	}
+
+	tests := []struct {
+		password string
+		want     string
+	}{
+		{"short-1", "must be at least 10 characters long"},
+		{"jdoe-password-1", "must not contain the username or the email"},
+		{"Old-Password-1", "must not be one of the last 3 passwords"},
+	}
+	for _, tt := range tests {
+		err := h.checkPassword(context.Background(), "body.new_password", tt.password, exec)
+		var model *huma.ErrorModel
+		if !errors.As(err, &model) || model.Status != http.StatusUnprocessableEntity {
+			t.Fatalf("%q: expected 422, got %v", tt.password, err)
+		}
+		if len(model.Errors) != 1 || model.Errors[0].Location != "body.new_password" ||
+			model.Errors[0].Message != tt.want {
+			t.Fatalf("%q: expected %q for body.new_password, got %+v", tt.password, tt.want, model.Errors)
+		}
+	}
+}
+
+type mockRehashExecsDB struct {
+	mockLoginExecsDB
+	rehashed string
+}
+
+func (m *mockRehashExecsDB) RehashPassword(_ context.Context, id int, oldHash, newHash string) error {
+	if oldHash == m.password {
+		m.password = newHash
+		m.rehashed = newHash
+	}
+	return nil
+}
+
+func TestExecLoginRehashesLegacyPassword(t *testing.T) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		t.Fatal(err)
+	}
+	key := argon2.IDKey([]byte("secret"), salt, 1, 64*1024, 4, 32)
+	legacy := base64.StdEncoding.EncodeToString(salt) + "." + base64.StdEncoding.EncodeToString(key)
+
+	execsDB := &mockRehashExecsDB{mockLoginExecsDB: mockLoginExecsDB{username: "admin", password: legacy}}
+	_, api := humatest.New(t)
+	h := NewExecsHandler(ExecsDeps{
+		Execs:         execsDB,
+		Sessions:      &mockSessionsDB{},
+		LoginAttempts: &mockLoginAttemptsDB{attempts: map[string]models.LoginAttempt{}},
+		MFA:           &mockMFADB{},
+		Config:        config.Config{JWTSecret: "test", JWTExpiresIn: time.Minute, LoginMaxAttempts: 5},
+	})
+	huma.Register(api, huma.Operation{
+		OperationID: "login-exec",
+		Method:      http.MethodPost,
+		Path:        "/execs/login",
+	}, h.ExecLoginHandler)
+
+	login := func() int {
+		return api.Post("/execs/login", map[string]any{
+			"execs": map[string]any{"username": "admin", "password": "secret"},
+		}).Code
+	}
+	if code := login(); code != http.StatusOK {
+		t.Fatalf("Expected login with legacy hash, got %d", code)
+	}
+	if !strings.HasPrefix(execsDB.rehashed, "$argon2id$") {
+		t.Fatalf("Expected legacy hash to be replaced with PHC format, got %q", execsDB.rehashed)
+	}
+
+	execsDB.rehashed = ""
+	if code := login(); code != http.StatusOK {
+		t.Fatalf("Expected login with the new hash, got %d", code)
+	}
+	if execsDB.rehashed != "" {
+		t.Fatalf("Expected no rehash with the current parameters")
+	}
+}
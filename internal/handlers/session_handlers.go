@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/middleware"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/utils"
+)
+
+const refreshCookieName = "refresh_token"
+
+// newSession - creates a login session with rotating refresh token and signs the access token for it
+func (h *ExecsHandlers) newSession(
+	ctx context.Context,
+	exec models.Exec,
+	userAgent string,
+) (string, string, error) {
+	refreshToken, hashedToken, err := utils.GenerateToken()
+	if err != nil {
+		h.logger.Logging.Errorf("failed to generate refresh token %v", err)
+		return "", "", err
+	}
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+
+	session := models.ExecSession{
+		ExecID:           exec.ID,
+		RefreshTokenHash: hashedToken,
+		UserAgent:        userAgent,
+		IPAddress:        middleware.ClientIP(ctx),
+		ExpiresAt:        time.Now().UTC().Add(h.conf.RefreshTokenExpiresIn).Format(time.RFC3339),
+	}
+	sid, err := h.sessionsDB.CreateSession(&session)
+	if err != nil {
+		return "", "", err
+	}
+
+	token, err := utils.SighnToken(
+		strconv.Itoa(exec.ID),
+		exec.Username,
+		exec.Role,
+		strconv.FormatInt(sid, 10),
+		h.conf,
+	)
+	if err != nil {
+		h.logger.Logging.Errorf("Could not create login token %v", err)
+		return "", "", err
+	}
+	return token, refreshToken, nil
+}
+
+// authCookie - cookie with the name and flags from the config
+func (h *ExecsHandlers) authCookie(name, value, path string, expires time.Time) http.Cookie {
+	return http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   h.conf.CookieDomain,
+		HttpOnly: true,
+		Secure:   h.conf.CookieSecure,
+		Expires:  expires,
+		SameSite: h.conf.CookieSameSite,
+	}
+}
+
+func (h *ExecsHandlers) sessionCookies(token, refreshToken string) []http.Cookie {
+	return []http.Cookie{
+		h.authCookie(h.conf.CookieName, token, "/", time.Now().Add(h.conf.JWTExpiresIn)),
+		h.authCookie(
+			refreshCookieName,
+			refreshToken,
+			"/execs",
+			time.Now().Add(h.conf.RefreshTokenExpiresIn),
+		),
+	}
+}
+
+func (h *ExecsHandlers) clearedSessionCookies() []http.Cookie {
+	return []http.Cookie{
+		h.authCookie(h.conf.CookieName, "", "/", time.Unix(0, 0)),
+		h.authCookie(refreshCookieName, "", "/execs", time.Unix(0, 0)),
+	}
+}
+
+// revokeAfterPasswordChange - the refresh tokens of the old password must not be
+// able to issue new access tokens after the password is changed
+func (h *ExecsHandlers) revokeAfterPasswordChange(id int) {
+	if _, err := h.sessionsDB.RevokeAllSessions(id); err != nil {
+		h.logger.Logging.Errorf("failed to revoke sessions after password change %v", err)
+	}
+	h.authCache.InvalidateExec(id)
+}
+
+// execIDFromContext - the exec id from the uid claim put in the context by the jwt middleware
+func execIDFromContext(ctx context.Context) (int, error) {
+	uid, _ := ctx.Value(middleware.ContextKey("uid")).(string)
+	return strconv.Atoi(uid)
+}
+
+// currentExecID - the exec of the access token, the /me endpoints never take the exec id
+// from the request so nobody can act on other account with them
+func currentExecID(ctx context.Context) (int, error) {
+	id, err := execIDFromContext(ctx)
+	if err != nil {
+		return 0, huma.Error401Unauthorized("invalid token")
+	}
+	return id, nil
+}
+
+// refreshTokenReused - a rotated token is used again so it is probably stolen, every
+// session of the exec is revoked
+func (h *ExecsHandlers) refreshTokenReused(ctx context.Context, execID int) error {
+	h.logger.Logging.Warnf("reuse of revoked refresh token for exec %d", execID)
+	if _, err := h.sessionsDB.RevokeAllSessions(execID); err != nil {
+		h.logger.Logging.Errorf("failed to revoke sessions %v", err)
+	}
+	h.authCache.InvalidateExec(execID)
+	h.auditAuth(ctx, models.AuthEventRefresh, models.Exec{ID: execID}, models.AuthOutcomeFailure, "reuse of revoked refresh token")
+	return huma.Error401Unauthorized("invalid refresh token")
+}
+
+func (h *ExecsHandlers) RefreshExecsHandler(
+	ctx context.Context,
+	input *ExecRefreshInput,
+) (*ExecsLoginOutput, error) {
+	refreshToken := input.RefreshCookie
+	if input.Body != nil && input.Body.RefreshToken != "" {
+		refreshToken = input.Body.RefreshToken
+	}
+	if refreshToken == "" {
+		return nil, huma.Error401Unauthorized("refresh token missing")
+	}
+
+	hashedToken, err := utils.HashToken(refreshToken)
+	if err != nil {
+		h.logger.Logging.Debugf("invalid refresh token format %v", err)
+		return nil, huma.Error401Unauthorized("invalid refresh token")
+	}
+	session, err := h.sessionsDB.GetSessionByTokenHash(hashedToken)
+	if err != nil {
+		return nil, huma.Error401Unauthorized("invalid refresh token")
+	}
+
+	if session.RevokedAt.Valid {
+		return nil, h.refreshTokenReused(ctx, session.ExecID)
+	}
+	if session.ExpiresAt <= time.Now().UTC().Format(time.RFC3339) {
+		return nil, huma.Error401Unauthorized("refresh token expired")
+	}
+
+	exec, err := h.execsDB.GetExecsByID(ctx, session.ExecID)
+	if err != nil {
+		return nil, dbError(err, huma.Error401Unauthorized("invalid refresh token"))
+	}
+	if exec.InactiveStatus {
+		return nil, huma.Error403Forbidden("user inactive")
+	}
+
+	// only one refresh rotates the session, the other one with the same token at the same
+	// time is a reuse as well
+	revoked, err := h.sessionsDB.RevokeSession(session.ID)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Could not rotate the session", err)
+	}
+	h.authCache.InvalidateSession(session.ID)
+	if !revoked {
+		return nil, h.refreshTokenReused(ctx, session.ExecID)
+	}
+	token, newRefreshToken, err := h.newSession(ctx, exec, input.UserAgent)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Could not create login token", err)
+	}
+
+	out := &ExecsLoginOutput{}
+	out.Body.Token = token
+	out.Body.RefreshToken = newRefreshToken
+	out.Body.ExpiresIn = int(h.conf.JWTExpiresIn.Seconds())
+	out.SetCookie = h.sessionCookies(token, newRefreshToken)
+	return out, nil
+}
+
+func (h *ExecsHandlers) LogoutAllExecsHandler(
+	ctx context.Context,
+	_ *struct{},
+) (*ExecSessionsRevokeOutput, error) {
+	id, err := currentExecID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	revoked, err := h.sessionsDB.RevokeAllSessions(id)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Could not revoke sessions", err)
+	}
+	h.authCache.InvalidateExec(id)
+	username, _ := ctx.Value(middleware.ContextKey("username")).(string)
+	h.auditAuth(ctx, models.AuthEventLogoutAll, models.Exec{ID: id, Username: username}, models.AuthOutcomeSuccess, "")
+
+	out := &ExecSessionsRevokeOutput{}
+	out.Body.Status = "Logged out from all sessions"
+	out.Body.Revoked = revoked
+	out.SetCookie = h.clearedSessionCookies()
+	return out, nil
+}
+
+func (h *ExecsHandlers) RevokeExecSessionsHandler(
+	ctx context.Context,
+	input *struct {
+		ID int `path:"id"`
+	},
+) (*ExecSessionsRevokeOutput, error) {
+	revoked, err := h.sessionsDB.RevokeAllSessions(input.ID)
+	if err != nil {
+		return nil, huma.Error500InternalServerError("Could not revoke sessions", err)
+	}
+	h.authCache.InvalidateExec(input.ID)
+	h.auditAuth(ctx, models.AuthEventSessionsRevoke, models.Exec{ID: input.ID}, models.AuthOutcomeSuccess, "")
+
+	out := &ExecSessionsRevokeOutput{}
+	out.Body.Status = "Sessions revoked"
+	out.Body.Revoked = revoked
+	return out, nil
+}
@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/humatest"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/config"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/middleware"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/utils"
+)
+
+// mockRefreshSessionsDB - the sessions by id, beforeRevoke runs before a session is
+// revoked so a test can revoke it first like a concurrent refresh
+type mockRefreshSessionsDB struct {
+	dataops.SessionsInf
+	sessions     map[int]models.ExecSession
+	beforeRevoke func()
+}
+
+func (m *mockRefreshSessionsDB) CreateSession(session *models.ExecSession) (int64, error) {
+	id := len(m.sessions) + 1
+	session.ID = id
+	m.sessions[id] = *session
+	return int64(id), nil
+}
+
+func (m *mockRefreshSessionsDB) GetSessionByTokenHash(hash string) (models.ExecSession, error) {
+	for _, session := range m.sessions {
+		if session.RefreshTokenHash == hash {
+			return session, nil
+		}
+	}
+	return models.ExecSession{}, errors.New("session not found")
+}
+
+func (m *mockRefreshSessionsDB) RevokeSession(id int) (bool, error) {
+	if m.beforeRevoke != nil {
+		m.beforeRevoke()
+		m.beforeRevoke = nil
+	}
+	session := m.sessions[id]
+	if session.RevokedAt.Valid {
+		return false, nil
+	}
+	session.RevokedAt = sql.NullString{String: time.Now().Format(time.RFC3339), Valid: true}
+	m.sessions[id] = session
+	return true, nil
+}
+
+func (m *mockRefreshSessionsDB) RevokeAllSessions(execID int) (int64, error) {
+	var revoked int64
+	for id, session := range m.sessions {
+		if session.ExecID == execID && !session.RevokedAt.Valid {
+			session.RevokedAt = sql.NullString{String: time.Now().Format(time.RFC3339), Valid: true}
+			m.sessions[id] = session
+			revoked++
+		}
+	}
+	return revoked, nil
+}
+
+// active - the not revoked sessions of the exec
+func (m *mockRefreshSessionsDB) active(execID int) int {
+	count := 0
+	for _, session := range m.sessions {
+		if session.ExecID == execID && !session.RevokedAt.Valid {
+			count++
+		}
+	}
+	return count
+}
+
+func TestRefreshExecSession(t *testing.T) {
+	_, api := humatest.New(t)
+	execsDB := &mockInviteExecsDB{execs: map[int]models.Exec{
+		1: {ID: 1, Username: "admin", Role: middleware.RoleAdmin},
+		2: {ID: 2, Username: "staff", Role: middleware.RoleStaff, InactiveStatus: true},
+	}}
+	sessionsDB := &mockRefreshSessionsDB{sessions: map[int]models.ExecSession{}}
+	cache := &mockAuthInvalidator{}
+	h := NewExecsHandler(ExecsDeps{
+		Execs:     execsDB,
+		Sessions:  sessionsDB,
+		AuthCache: cache,
+		Config: config.Config{
+			JWTSecret:             "test",
+			JWTExpiresIn:          time.Minute,
+			RefreshTokenExpiresIn: time.Hour,
+			CookieName:            "Bearer",
+		},
+	})
+	huma.Register(api, huma.Operation{
+		OperationID: "refresh-exec",
+		Method:      http.MethodPost,
+		Path:        "/execs/refresh",
+	}, h.RefreshExecsHandler)
+
+	// session - the refresh token of a new session of the exec
+	session := func(execID int, expiresAt time.Time) string {
+		token, hash, err := utils.GenerateToken()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := sessionsDB.CreateSession(&models.ExecSession{
+			ExecID: execID, RefreshTokenHash: hash, ExpiresAt: expiresAt.Format(time.RFC3339),
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	refresh := func(token string) (int, string) {
+		resp := api.Post("/execs/refresh", map[string]any{"refresh_token": token})
+		var body struct {
+			RefreshToken string `json:"refresh_token"`
+		}
+		_ = json.Unmarshal(resp.Body.Bytes(), &body)
+		return resp.Code, body.RefreshToken
+	}
+
+	first := session(1, time.Now().Add(time.Hour))
+	code, rotated := refresh(first)
+	if code != http.StatusOK || rotated == "" || rotated == first {
+		t.Fatalf("Expected 200 with a new refresh token, got %d", code)
+	}
+	if sessionsDB.active(1) != 1 {
+		t.Fatalf("Expected only the rotated session active, got %d", sessionsDB.active(1))
+	}
+
+	if code, _ := refresh(first); code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 for the reused refresh token, got %d", code)
+	}
+	if sessionsDB.active(1) != 0 || len(cache.execs) == 0 {
+		t.Fatalf("Expected all sessions of the exec revoked after the reuse, got %d active", sessionsDB.active(1))
+	}
+
+	// the other refresh with the same token rotates the session between the read and the
+	// revoke of this one
+	raced := session(1, time.Now().Add(time.Hour))
+	other := session(1, time.Now().Add(time.Hour))
+	sessionsDB.beforeRevoke = func() {
+		for id, s := range sessionsDB.sessions {
+			if hash, _ := utils.HashToken(raced); s.RefreshTokenHash == hash {
+				s.RevokedAt = sql.NullString{String: time.Now().Format(time.RFC3339), Valid: true}
+				sessionsDB.sessions[id] = s
+			}
+		}
+	}
+	if code, _ := refresh(raced); code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 for the refresh which lost the rotation, got %d", code)
+	}
+	if code, _ := refresh(other); code != http.StatusUnauthorized || sessionsDB.active(1) != 0 {
+		t.Fatalf("Expected all sessions revoked after the concurrent refresh, got %d", code)
+	}
+
+	if code, _ := refresh(session(1, time.Now().Add(-time.Minute))); code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 for the expired refresh token, got %d", code)
+	}
+	if code, _ := refresh(session(2, time.Now().Add(time.Hour))); code != http.StatusForbidden {
+		t.Fatalf("Expected 403 for the inactive exec, got %d", code)
+	}
+	if code, _ := refresh("not-a-token"); code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 for the unknown refresh token, got %d", code)
+	}
+}
+
+func TestRevokeExecSessions(t *testing.T) {
+	_, api := humatest.New(t)
+	sessionsDB := &mockRefreshSessionsDB{sessions: map[int]models.ExecSession{
+		1: {ID: 1, ExecID: 1},
+		2: {ID: 2, ExecID: 1},
+		3: {ID: 3, ExecID: 2},
+	}}
+	cache := &mockAuthInvalidator{}
+	h := NewExecsHandler(ExecsDeps{
+		Sessions:  sessionsDB,
+		AuthCache: cache,
+		Config:    config.Config{CookieName: "Bearer"},
+	})
+	huma.Register(api, huma.Operation{
+		OperationID: "logout-all-exec",
+		Method:      http.MethodPost,
+		Path:        "/execs/logout-all",
+	}, h.LogoutAllExecsHandler)
+	huma.Register(api, huma.Operation{
+		OperationID: "revoke-exec-sessions",
+		Method:      http.MethodPost,
+		Path:        "/execs/{id}/sessions/revoke",
+	}, h.RevokeExecSessionsHandler)
+
+	if code := api.Post("/execs/logout-all").Code; code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 without token, got %d", code)
+	}
+	ctx := context.WithValue(context.Background(), middleware.ContextKey("uid"), "1")
+	resp := api.PostCtx(ctx, "/execs/logout-all")
+	var body struct {
+		Revoked int64 `json:"revoked"`
+	}
+	if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Code != http.StatusOK || body.Revoked != 2 || sessionsDB.active(2) != 1 {
+		t.Fatalf("Expected only the 2 sessions of the exec revoked, got %d %s", resp.Code, resp.Body.String())
+	}
+	if cookies := resp.Result().Cookies(); len(cookies) != 2 || cookies[0].Value != "" {
+		t.Fatalf("Expected the session cookies cleared, got %v", cookies)
+	}
+
+	resp = api.Post("/execs/2/sessions/revoke")
+	if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Code != http.StatusOK || body.Revoked != 1 || sessionsDB.active(2) != 0 {
+		t.Fatalf("Expected the session of exec 2 revoked, got %d %s", resp.Code, resp.Body.String())
+	}
+	if cache.execs[len(cache.execs)-1] != 2 {
+		t.Fatalf("Expected the cached status of exec 2 dropped, got %v", cache.execs)
+	}
+}
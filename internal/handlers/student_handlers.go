@@ -4,23 +4,32 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 
 	"github.com/danielgtaylor/huma/v2"
 	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/middleware"
 	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
 	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/utils"
 )
 
 type StudentHandlers struct {
 	mutex      sync.Mutex
 	studentsDB dataops.StudentInf
+	changeRecorder
 }
 
-func NewStudentsHandler(sdb dataops.StudentInf) *StudentHandlers {
+func NewStudentsHandler(
+	sdb dataops.StudentInf,
+	hdb dataops.ChangeHistoryInf,
+	logger *logging.Logger,
+) *StudentHandlers {
 	return &StudentHandlers{
-		studentsDB: sdb,
+		studentsDB:     sdb,
+		changeRecorder: changeRecorder{historyDB: hdb, logger: logger},
 	}
 }
 
@@ -30,9 +39,9 @@ func (h *StudentHandlers) StudentGet(ctx context.Context, input *struct {
 ) (*StudentIDResponse, error) {
 	resp := StudentIDResponse{}
 
-	student, err := h.studentsDB.GetStudentByID(input.ID)
+	student, err := h.studentsDB.GetStudentByID(ctx, input.ID)
 	if err != nil {
-		return nil, huma.Error500InternalServerError("Error quering database", err)
+		return nil, dbError(err, huma.Error500InternalServerError("Error quering database", err))
 	}
 
 	resp.Body.Data = student
@@ -54,31 +63,25 @@ func (h *StudentHandlers) StudentsGet(
 		"email":      input.Email,
 		"class":      input.Class,
 	}
+	if input.ClassID > 0 {
+		params["class_id"] = strconv.Itoa(input.ClassID)
+	}
+
+	if input.IncludeDeleted && !middleware.Allowed(ctx, middleware.PermDeletedManage) {
+		return nil, huma.Error403Forbidden("missing permission " + string(middleware.PermDeletedManage))
+	}
 
 	sortBy := input.SortBy
 	// filtering by params basically with query parameters anf filtering
-	rows, totalStudents, err := h.studentsDB.GetAllStudents(params, sortBy, input.Page, input.Limit)
+	studentsList, totalStudents, err := h.studentsDB.GetAllStudents(ctx, params,
+		sortBy,
+		input.Page,
+		input.Limit,
+		input.IncludeDeleted,
+	)
 	if err != nil {
-		return nil, huma.Error500InternalServerError("Error quering database", err)
-	}
-
-	studentsList := make([]models.Student, 0)
-
-	for rows.Next() {
-		var student models.Student
-		err = rows.Scan(
-			&student.ID,
-			&student.FirstName,
-			&student.LastName,
-			&student.Email,
-			&student.Class,
-		)
-		if err != nil {
-			return nil, huma.Error500InternalServerError("Error scanning database results", err)
-		}
-		studentsList = append(studentsList, student)
+		return nil, dbError(err, huma.Error500InternalServerError("Error quering database", err))
 	}
-	defer rows.Close()
 
 	response.Body.Status = "Sucess"
 	response.Body.Count = totalStudents
@@ -111,14 +114,14 @@ func (h *StudentHandlers) StudentsAdd(
 			FirstName: newStudent.FirstName,
 			LastName:  newStudent.LastName,
 			Email:     newStudent.Email,
-			Class:     newStudent.Class,
+			ClassID:   newStudent.ClassID,
 		}
-		id, err := h.studentsDB.InsertStudents(&student)
+		id, err := h.studentsDB.InsertStudents(ctx, &student)
 		if err != nil {
-			return nil, huma.Error500InternalServerError(
+			return nil, dbError(err, huma.Error500InternalServerError(
 				"Error adding to the database",
 				err,
-			)
+			))
 		}
 		student.ID = int(id)
 		addedStudents[i] = student
@@ -153,15 +156,15 @@ func (h *StudentHandlers) UpdateStudentHandler(
 		FirstName: input.Body.Student.FirstName,
 		LastName:  input.Body.Student.LastName,
 		Email:     input.Body.Student.Email,
-		Class:     input.Body.Student.Class,
+		ClassID:   input.Body.Student.ClassID,
 	}
 
-	updatedStudent, err := h.studentsDB.UpdateStudent(input.Body.Student.ID, student)
+	updatedStudent, err := h.studentsDB.UpdateStudent(ctx, input.Body.Student.ID, student)
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
 			return nil, huma.Error404NotFound("not found", err)
 		}
-		return nil, huma.Error500InternalServerError("error update database", err)
+		return nil, dbError(err, huma.Error500InternalServerError("error update database", err))
 	}
 	resp := StudentsUpdateOutput{}
 	resp.Body.Status = "Sucess"
@@ -193,12 +196,12 @@ func (h *StudentHandlers) PatchStudentHandler(
 		FirstName: input.Body.Student.FirstName,
 		LastName:  input.Body.Student.LastName,
 		Email:     input.Body.Student.Email,
-		Class:     input.Body.Student.Class,
+		ClassID:   input.Body.Student.ClassID,
 	}
 	// TODO: to add better error handling here
-	updatedStudent, err := h.studentsDB.PatchiStudent(input.Body.Student.ID, student)
+	updatedStudent, err := h.studentsDB.PatchiStudent(ctx, input.Body.Student.ID, student)
 	if err != nil {
-		return nil, err
+		return nil, dbError(err, err)
 	}
 	resp := StudentPatchOutput{}
 	resp.Body.Status = "Success"
@@ -216,9 +219,9 @@ func (h *StudentHandlers) DeleteStudentHandler(ctx context.Context, input *struc
 	}
 }, error,
 ) {
-	err := h.studentsDB.DeleteStudent(input.ID)
+	err := h.studentsDB.DeleteStudent(ctx, input.ID)
 	if err != nil {
-		return nil, err
+		return nil, dbError(err, err)
 	}
 
 	output := &struct {
@@ -258,10 +261,16 @@ func (h *StudentHandlers) PatchStudentsHandler(
 			)
 		}
 
-		student := models.Student(newStudent)
-		t, err := h.studentsDB.PatchiStudent(newStudent.ID, student)
+		student := models.Student{
+			ID:        newStudent.ID,
+			FirstName: newStudent.FirstName,
+			LastName:  newStudent.LastName,
+			Email:     newStudent.Email,
+			ClassID:   newStudent.ClassID,
+		}
+		t, err := h.studentsDB.PatchiStudent(ctx, newStudent.ID, student)
 		if err != nil {
-			return nil, err
+			return nil, dbError(err, err)
 		}
 		patchedStudents[i] = t
 	}
@@ -276,9 +285,9 @@ func (h *StudentHandlers) DeleteStudentsHandler(
 	ctx context.Context,
 	input *DeleteStudentsInput,
 ) (*DeleteStudentsOutput, error) {
-	respIDn, err := h.studentsDB.DeleteBulkStudents(input.IDn)
+	respIDn, err := h.studentsDB.DeleteBulkStudents(ctx, input.IDn)
 	if err != nil {
-		return nil, err
+		return nil, dbError(err, err)
 	}
 
 	resp := &DeleteStudentsOutput{}
@@ -287,3 +296,59 @@ func (h *StudentHandlers) DeleteStudentsHandler(
 
 	return resp, nil
 }
+
+func (h *StudentHandlers) StudentHistoryHandler(
+	ctx context.Context,
+	input *RevisionsInput,
+) (*RevisionsOutput, error) {
+	return h.listRevisions(models.EntityStudent, input)
+}
+
+// RestoreStudentRevisionHandler - sets the student back to its state after the revision,
+// the restore is recorded as a new revision
+func (h *StudentHandlers) RestoreStudentRevisionHandler(
+	ctx context.Context,
+	input *RevisionRestoreInput,
+) (*StudentsUpdateOutput, error) {
+	if _, err := h.studentsDB.GetStudentByID(ctx, input.ID); err != nil {
+		return nil, dbError(err, huma.Error404NotFound("student not found", err))
+	}
+	var state models.Student
+	rev, err := h.revisionState(models.EntityStudent, input, &state)
+	if err != nil {
+		return nil, err
+	}
+	restoredStudent, err := h.studentsDB.UpdateStudent(dataops.WithRestoredRevision(ctx, rev.ID), input.ID, state)
+	if err != nil {
+		return nil, dbError(err, huma.Error500InternalServerError("error update database", err))
+	}
+
+	resp := &StudentsUpdateOutput{}
+	resp.Body.Status = "Success"
+	resp.Body.Data = restoredStudent
+	return resp, nil
+}
+
+// RestoreStudentHandler - brings back the soft deleted student before it is purged
+func (h *StudentHandlers) RestoreStudentHandler(
+	ctx context.Context,
+	input *struct {
+		ID int `path:"id"`
+	},
+) (*StudentsUpdateOutput, error) {
+	if err := h.studentsDB.RestoreStudent(ctx, input.ID); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return nil, huma.Error404NotFound("deleted student not found", err)
+		}
+		return nil, dbError(err, huma.Error500InternalServerError("error update database", err))
+	}
+	student, err := h.studentsDB.GetStudentByID(ctx, input.ID)
+	if err != nil {
+		return nil, dbError(err, huma.Error500InternalServerError("Error quering database", err))
+	}
+
+	resp := &StudentsUpdateOutput{}
+	resp.Body.Status = "Student restored"
+	resp.Body.Data = student
+	return resp, nil
+}
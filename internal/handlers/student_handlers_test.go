@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/humatest"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops/memory"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
+)
+
+func TestStudentsGetPaginated(t *testing.T) {
+	_, api := humatest.New(t)
+	mockDB := memory.NewStudents(
+		models.Student{FirstName: "Cid", LastName: "One", Email: "cid@example.com", ClassID: 10},
+		models.Student{FirstName: "Ann", LastName: "Two", Email: "ann@example.com", ClassID: 10},
+		models.Student{FirstName: "Bea", LastName: "Three", Email: "bea@example.com", ClassID: 10},
+		models.Student{FirstName: "Dan", LastName: "Four", Email: "dan@example.com", ClassID: 9},
+	)
+	h := NewStudentsHandler(mockDB, nil, logging.Init(false))
+	huma.Register(api, huma.Operation{
+		OperationID: "get-students",
+		Method:      http.MethodGet,
+		Path:        "/students",
+	}, h.StudentsGet)
+
+	resp := api.Get("/students?class_id=10&limit=2&page=2")
+	if resp.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d %s", resp.Code, resp.Body.String())
+	}
+	var body struct {
+		Count int              `json:"count"`
+		Data  []models.Student `json:"data"`
+	}
+	if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	// the count is of all students of the class, the page has the last one by first name
+	if body.Count != 3 || len(body.Data) != 1 || body.Data[0].FirstName != "Cid" {
+		t.Fatalf("Expected the second page of class 10, got %s", resp.Body.String())
+	}
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
+)
+
+type SubjectHandlers struct {
+	mutex      sync.Mutex
+	subjectsDB dataops.SubjectsInf
+	logger     *logging.Logger
+}
+
+func NewSubjectsHandler(sdb dataops.SubjectsInf, logger *logging.Logger) *SubjectHandlers {
+	return &SubjectHandlers{
+		subjectsDB: sdb,
+		logger:     logger,
+	}
+}
+
+// subjectError - 404 for the missing subject and 409 for the subject which is still
+// assigned, otherwise the fallback
+func subjectError(err error, fallback error) error {
+	switch {
+	case queryFailed(err):
+		return dbError(err, fallback)
+	case strings.Contains(err.Error(), "not found"):
+		return huma.Error404NotFound("subject not found", err)
+	case strings.Contains(err.Error(), "still has"):
+		return huma.Error409Conflict("the subject still has teaching assignments", err)
+	}
+	return fallback
+}
+
+// checkSubject - the name of the subject is free, the database would only fail on it
+func (h *SubjectHandlers) checkSubject(ctx context.Context, name string, exceptID int) error {
+	same, _, err := h.subjectsDB.GetAllSubjects(ctx, map[string]string{"name": name}, nil)
+	if err != nil {
+		return dbError(err, huma.Error500InternalServerError("Error quering database", err))
+	}
+	for _, other := range same {
+		if other.ID != exceptID {
+			return huma.Error409Conflict("subject " + name + " already exists")
+		}
+	}
+	return nil
+}
+
+func (h *SubjectHandlers) SubjectGet(ctx context.Context, input *SubjectIDInput) (*SubjectOutput, error) {
+	subject, err := h.subjectsDB.GetSubjectByID(ctx, input.ID)
+	if err != nil {
+		return nil, subjectError(err, huma.Error500InternalServerError("Error quering database", err))
+	}
+	resp := &SubjectOutput{}
+	resp.Body.Status = "Success"
+	resp.Body.Data = subject
+	return resp, nil
+}
+
+func (h *SubjectHandlers) SubjectsGet(
+	ctx context.Context,
+	input *models.SubjectsQueryInput,
+) (*SubjectsOutput, error) {
+	params := map[string]string{
+		"name": input.Name,
+		"code": input.Code,
+	}
+	subjects, count, err := h.subjectsDB.GetAllSubjects(ctx, params, input.SortBy)
+	if err != nil {
+		return nil, dbError(err, huma.Error500InternalServerError("Error quering database", err))
+	}
+	resp := &SubjectsOutput{}
+	resp.Body.Status = "Success"
+	resp.Body.Count = count
+	resp.Body.Data = subjects
+	return resp, nil
+}
+
+func (h *SubjectHandlers) SubjectsAdd(ctx context.Context, input *SubjectsInput) (*SubjectsOutput, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	addedSubjects := make([]models.Subject, len(input.Body.Subjects))
+	for i, newSubject := range input.Body.Subjects {
+		subject := models.Subject{
+			Name:        newSubject.Name,
+			Code:        newSubject.Code,
+			Description: newSubject.Description,
+		}
+		if err := h.checkSubject(ctx, subject.Name, 0); err != nil {
+			return nil, err
+		}
+		id, err := h.subjectsDB.InsertSubject(ctx, &subject)
+		if err != nil {
+			return nil, dbError(err, huma.Error500InternalServerError("Error adding to the database", err))
+		}
+		subject.ID = int(id)
+		addedSubjects[i] = subject
+	}
+
+	resp := &SubjectsOutput{}
+	resp.Body.Status = "Success"
+	resp.Body.Count = len(addedSubjects)
+	resp.Body.Data = addedSubjects
+	return resp, nil
+}
+
+func (h *SubjectHandlers) UpdateSubjectHandler(ctx context.Context, input *SubjectUpdateInput) (*SubjectOutput, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	subject := models.Subject{
+		ID:          input.ID,
+		Name:        input.Body.Subject.Name,
+		Code:        input.Body.Subject.Code,
+		Description: input.Body.Subject.Description,
+	}
+	if _, err := h.subjectsDB.GetSubjectByID(ctx, input.ID); err != nil {
+		return nil, subjectError(err, huma.Error500InternalServerError("error update database", err))
+	}
+	if err := h.checkSubject(ctx, subject.Name, input.ID); err != nil {
+		return nil, err
+	}
+	updatedSubject, err := h.subjectsDB.UpdateSubject(ctx, input.ID, subject)
+	if err != nil {
+		return nil, subjectError(err, huma.Error500InternalServerError("error update database", err))
+	}
+	resp := &SubjectOutput{}
+	resp.Body.Status = "Success"
+	resp.Body.Data = updatedSubject
+	return resp, nil
+}
+
+func (h *SubjectHandlers) PatchSubjectHandler(ctx context.Context, input *SubjectPatchInput) (*SubjectOutput, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if _, err := h.subjectsDB.GetSubjectByID(ctx, input.ID); err != nil {
+		return nil, subjectError(err, huma.Error500InternalServerError("error update database", err))
+	}
+	if input.Body.Subject.Name != "" {
+		if err := h.checkSubject(ctx, input.Body.Subject.Name, input.ID); err != nil {
+			return nil, err
+		}
+	}
+	updatedSubject, err := h.subjectsDB.PatchSubject(ctx, input.ID, models.Subject{
+		Name:        input.Body.Subject.Name,
+		Code:        input.Body.Subject.Code,
+		Description: input.Body.Subject.Description,
+	})
+	if err != nil {
+		return nil, subjectError(err, huma.Error500InternalServerError("error update database", err))
+	}
+	resp := &SubjectOutput{}
+	resp.Body.Status = "Success"
+	resp.Body.Data = updatedSubject
+	return resp, nil
+}
+
+// DeleteSubjectHandler - removes the subject for good, the subject with teaching
+// assignments is kept
+func (h *SubjectHandlers) DeleteSubjectHandler(ctx context.Context, input *SubjectIDInput) (*DeleteSubjectOutput, error) {
+	if err := h.subjectsDB.DeleteSubject(ctx, input.ID); err != nil {
+		return nil, subjectError(err, huma.Error500InternalServerError("error deleting subject", err))
+	}
+	resp := &DeleteSubjectOutput{}
+	resp.Body.Status = "Subject deleted sucessfully"
+	resp.Body.ID = input.ID
+	return resp, nil
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import "github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+
+type SubjectsInput struct {
+	Body struct {
+		Subjects []models.SubjectInput `json:"subjects" doc:"Subjects"`
+	}
+}
+
+type SubjectsOutput struct {
+	Body struct {
+		Status string           `json:"status"`
+		Count  int              `json:"count"`
+		Data   []models.Subject `json:"data"`
+	}
+}
+
+type SubjectIDInput struct {
+	ID int `path:"id"`
+}
+
+type SubjectOutput struct {
+	Body struct {
+		Status string         `json:"status"`
+		Data   models.Subject `json:"data"`
+	}
+}
+
+type SubjectUpdateInput struct {
+	ID   int `path:"id"`
+	Body struct {
+		Subject models.SubjectUpdateBody `json:"subject" doc:"Subject"`
+	}
+}
+
+type SubjectPatchInput struct {
+	ID   int `path:"id"`
+	Body struct {
+		Subject models.SubjectPatchBody `json:"subject" doc:"Subject"`
+	}
+}
+
+type DeleteSubjectOutput struct {
+	Body struct {
+		Status string `json:"status"`
+		ID     int    `json:"id"`
+	}
+}
@@ -4,23 +4,32 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 
 	"github.com/danielgtaylor/huma/v2"
 	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/middleware"
 	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
 	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/utils"
 )
 
 type TeacherHandlers struct {
 	mutex      sync.Mutex
 	teachersDB dataops.TeachersInf
+	changeRecorder
 }
 
-func NewTeachersHandler(tdb dataops.TeachersInf) *TeacherHandlers {
+func NewTeachersHandler(
+	tdb dataops.TeachersInf,
+	hdb dataops.ChangeHistoryInf,
+	logger *logging.Logger,
+) *TeacherHandlers {
 	return &TeacherHandlers{
-		teachersDB: tdb,
+		teachersDB:     tdb,
+		changeRecorder: changeRecorder{historyDB: hdb, logger: logger},
 	}
 }
 
@@ -30,9 +39,9 @@ func (h *TeacherHandlers) TeacherGet(ctx context.Context, input *struct {
 ) (*TeacherIDResponse, error) {
 	resp := TeacherIDResponse{}
 
-	teacher, err := h.teachersDB.GetTeacherByID(input.ID)
+	teacher, err := h.teachersDB.GetTeacherByID(ctx, input.ID)
 	if err != nil {
-		return nil, huma.Error500InternalServerError("Error quering database", err)
+		return nil, dbError(err, huma.Error500InternalServerError("Error quering database", err))
 	}
 
 	resp.Body.Data = teacher
@@ -52,35 +61,23 @@ func (h *TeacherHandlers) TeachersGet(
 		"class":      input.Class,
 		"subject":    input.Subject,
 	}
+	if input.ClassID > 0 {
+		params["class_id"] = strconv.Itoa(input.ClassID)
+	}
+
+	if input.IncludeDeleted && !middleware.Allowed(ctx, middleware.PermDeletedManage) {
+		return nil, huma.Error403Forbidden("missing permission " + string(middleware.PermDeletedManage))
+	}
 
 	sortBy := input.SortBy
 	// filtering by params basically with query parameters anf filtering
-	rows, err := h.teachersDB.GetAllTeachers(params, sortBy)
+	teachersList, count, err := h.teachersDB.GetAllTeachers(ctx, params, sortBy, input.IncludeDeleted)
 	if err != nil {
-		return nil, huma.Error500InternalServerError("Error quering database", err)
-	}
-
-	teachersList := make([]models.Teacher, 0)
-
-	for rows.Next() {
-		var teacher models.Teacher
-		err = rows.Scan(
-			&teacher.ID,
-			&teacher.FirstName,
-			&teacher.LastName,
-			&teacher.Email,
-			&teacher.Class,
-			&teacher.Subject,
-		)
-		if err != nil {
-			return nil, huma.Error500InternalServerError("Error scanning database results", err)
-		}
-		teachersList = append(teachersList, teacher)
+		return nil, dbError(err, huma.Error500InternalServerError("Error quering database", err))
 	}
-	defer rows.Close()
 
 	response.Body.Status = "Sucess"
-	response.Body.Count = len(teachersList)
+	response.Body.Count = count
 	response.Body.Data = teachersList
 	return &response, nil
 }
@@ -108,15 +105,15 @@ func (h *TeacherHandlers) TeachersAdd(
 			FirstName: newTeacher.FirstName,
 			LastName:  newTeacher.LastName,
 			Email:     newTeacher.Email,
-			Class:     newTeacher.Class,
+			ClassID:   newTeacher.ClassID,
 			Subject:   newTeacher.Subject,
 		}
-		id, err := h.teachersDB.InsertTeachers(&teacher)
+		id, err := h.teachersDB.InsertTeachers(ctx, &teacher)
 		if err != nil {
-			return nil, huma.Error500InternalServerError(
+			return nil, dbError(err, huma.Error500InternalServerError(
 				"Error adding to the database",
 				err,
-			)
+			))
 		}
 		teacher.ID = int(id)
 		addedTeachers[i] = teacher
@@ -151,16 +148,16 @@ func (h *TeacherHandlers) UpdateTeacherHandler(
 		FirstName: input.Body.Teacher.FirstName,
 		LastName:  input.Body.Teacher.LastName,
 		Email:     input.Body.Teacher.Email,
-		Class:     input.Body.Teacher.Class,
+		ClassID:   input.Body.Teacher.ClassID,
 		Subject:   input.Body.Teacher.Subject,
 	}
 
-	updatedTeacher, err := h.teachersDB.UpdateTeacher(input.Body.Teacher.ID, teacher)
+	updatedTeacher, err := h.teachersDB.UpdateTeacher(ctx, input.Body.Teacher.ID, teacher)
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
 			return nil, huma.Error404NotFound("not found", err)
 		}
-		return nil, huma.Error500InternalServerError("error update database", err)
+		return nil, dbError(err, huma.Error500InternalServerError("error update database", err))
 	}
 	resp := TeachersUpdateOutput{}
 	resp.Body.Status = "Sucess"
@@ -192,13 +189,13 @@ func (h *TeacherHandlers) PatchTeacherHandler(
 		FirstName: input.Body.Teacher.FirstName,
 		LastName:  input.Body.Teacher.LastName,
 		Email:     input.Body.Teacher.Email,
-		Class:     input.Body.Teacher.Class,
+		ClassID:   input.Body.Teacher.ClassID,
 		Subject:   input.Body.Teacher.Subject,
 	}
 	// TODO: to add better error handling here
-	updatedTeacher, err := h.teachersDB.PatchTeacher(input.Body.Teacher.ID, teacher)
+	updatedTeacher, err := h.teachersDB.PatchTeacher(ctx, input.Body.Teacher.ID, teacher)
 	if err != nil {
-		return nil, err
+		return nil, dbError(err, err)
 	}
 	resp := TeacherPatchOutput{}
 	resp.Body.Status = "Success"
@@ -216,9 +213,9 @@ func (h *TeacherHandlers) DeleteTeacherHandler(ctx context.Context, input *struc
 	}
 }, error,
 ) {
-	err := h.teachersDB.DeleteTeacher(input.ID)
+	err := h.teachersDB.DeleteTeacher(ctx, input.ID)
 	if err != nil {
-		return nil, err
+		return nil, dbError(err, err)
 	}
 
 	output := &struct {
@@ -258,10 +255,17 @@ func (h *TeacherHandlers) PatchTeachersHandler(
 			)
 		}
 
-		teacher := models.Teacher(newTeacher)
-		t, err := h.teachersDB.PatchTeacher(newTeacher.ID, teacher)
+		teacher := models.Teacher{
+			ID:        newTeacher.ID,
+			FirstName: newTeacher.FirstName,
+			LastName:  newTeacher.LastName,
+			ClassID:   newTeacher.ClassID,
+			Subject:   newTeacher.Subject,
+			Email:     newTeacher.Email,
+		}
+		t, err := h.teachersDB.PatchTeacher(ctx, newTeacher.ID, teacher)
 		if err != nil {
-			return nil, err
+			return nil, dbError(err, err)
 		}
 		patchedTeachers[i] = t
 	}
@@ -276,9 +280,9 @@ func (h *TeacherHandlers) DeleteTeachersHandler(
 	ctx context.Context,
 	input *DeleteTeachersInput,
 ) (*DeleteTeachersOutput, error) {
-	respIDn, err := h.teachersDB.DeleteBulkTeachers(input.IDn)
+	respIDn, err := h.teachersDB.DeleteBulkTeachers(ctx, input.IDn)
 	if err != nil {
-		return nil, err
+		return nil, dbError(err, err)
 	}
 
 	resp := &DeleteTeachersOutput{}
@@ -294,9 +298,9 @@ func (h *TeacherHandlers) GetStudentsByTeacherId(
 ) (*TeacherStatusOutput, error) {
 	teacherID := input.ID
 
-	students, err := h.teachersDB.GetStudentsByTeacherID(teacherID)
+	students, err := h.teachersDB.GetStudentsByTeacherID(ctx, teacherID)
 	if err != nil {
-		return nil, huma.Error500InternalServerError("sql error", err)
+		return nil, dbError(err, huma.Error500InternalServerError("sql error", err))
 	}
 	resp := &TeacherStatusOutput{}
 	resp.Body.Status = "Success"
@@ -305,3 +309,57 @@ func (h *TeacherHandlers) GetStudentsByTeacherId(
 
 	return resp, err
 }
+
+func (h *TeacherHandlers) TeacherHistoryHandler(
+	ctx context.Context,
+	input *RevisionsInput,
+) (*RevisionsOutput, error) {
+	return h.listRevisions(models.EntityTeacher, input)
+}
+
+// RestoreTeacherRevisionHandler - sets the teacher back to its state after the revision,
+// the restore is recorded as a new revision
+func (h *TeacherHandlers) RestoreTeacherRevisionHandler(
+	ctx context.Context,
+	input *RevisionRestoreInput,
+) (*TeachersUpdateOutput, error) {
+	if _, err := h.teachersDB.GetTeacherByID(ctx, input.ID); err != nil {
+		return nil, dbError(err, huma.Error404NotFound("teacher not found", err))
+	}
+	var state models.Teacher
+	rev, err := h.revisionState(models.EntityTeacher, input, &state)
+	if err != nil {
+		return nil, err
+	}
+	restoredTeacher, err := h.teachersDB.UpdateTeacher(dataops.WithRestoredRevision(ctx, rev.ID), input.ID, state)
+	if err != nil {
+		return nil, dbError(err, huma.Error500InternalServerError("error update database", err))
+	}
+
+	resp := &TeachersUpdateOutput{}
+	resp.Body.Status = "Success"
+	resp.Body.Data = restoredTeacher
+	return resp, nil
+}
+
+// RestoreTeacherHandler - brings back the soft deleted teacher before it is purged
+func (h *TeacherHandlers) RestoreTeacherHandler(
+	ctx context.Context,
+	input *TeacherIDInput,
+) (*TeachersUpdateOutput, error) {
+	if err := h.teachersDB.RestoreTeacher(ctx, input.ID); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return nil, huma.Error404NotFound("deleted teacher not found", err)
+		}
+		return nil, dbError(err, huma.Error500InternalServerError("error update database", err))
+	}
+	teacher, err := h.teachersDB.GetTeacherByID(ctx, input.ID)
+	if err != nil {
+		return nil, dbError(err, huma.Error500InternalServerError("Error quering database", err))
+	}
+
+	resp := &TeachersUpdateOutput{}
+	resp.Body.Status = "Teacher restored"
+	resp.Body.Data = teacher
+	return resp, nil
+}
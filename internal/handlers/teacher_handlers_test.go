@@ -1,76 +1,31 @@
 package handlers
 
 import (
-	"database/sql"
+	"context"
+	"encoding/json"
 	"net/http"
 	"strings"
 	"testing"
 
 	"github.com/danielgtaylor/huma/v2"
 	"github.com/danielgtaylor/huma/v2/humatest"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops/memory"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/middleware"
 	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
 )
 
-// Mock implementation of TeachersInf interface
-type mockTeachersDB struct {
-	teachers []models.Teacher
-	teacher  models.Teacher
-	err      error
-}
-
-// Implement all required interface methods
-func (m *mockTeachersDB) InsertTeachers(t *models.Teacher) (int64, error) {
-	return 0, m.err
-}
-
-func (m *mockTeachersDB) GetTeacherByID(id int) (models.Teacher, error) {
-	return m.teacher, m.err
-}
-
-func (m *mockTeachersDB) GetAllTeachers(
-	params map[string]string,
-	sortBy []string,
-) (*sql.Rows, error) {
-	// For testing, you'll need to use a library like sqlmock or restructure to avoid sql.Rows
-	// This is a limitation - sql.Rows cannot be easily mocked
-	// Better approach: return []models.Teacher instead of *sql.Rows from the dataops layer
-	return nil, m.err
-}
-
-func (m *mockTeachersDB) UpdateTeacher(id int, t models.Teacher) (models.Teacher, error) {
-	return t, m.err
-}
-
-func (m *mockTeachersDB) PatchTeacher(id int, t models.Teacher) (models.Teacher, error) {
-	return models.Teacher{}, m.err
-}
-
-func (m *mockTeachersDB) DeleteTeacher(id int) error {
-	return m.err
-}
-
-func (m *mockTeachersDB) DeleteBulkTeachers(ids []int) ([]int, error) {
-	return nil, m.err
-}
-
-func (m *mockTeachersDB) GetStudentsByTeacherID(id int) ([]models.Student, error) {
-	return nil, m.err
-}
-
 func TestTeacherGetById(t *testing.T) {
 	_, api := humatest.New(t)
-	mockDB := &mockTeachersDB{
-		teacher: models.Teacher{
-			ID:        42,
-			FirstName: "Jane",
-			LastName:  "Small",
-			Email:     "janesmall@example.com",
-			Class:     "12C",
-			Subject:   "History",
-		},
-		err: nil,
-	}
-	h := NewTeachersHandler(mockDB)
+	mockDB := memory.NewTeachers(models.Teacher{
+		ID:        42,
+		FirstName: "Jane",
+		LastName:  "Small",
+		Email:     "janesmall@example.com",
+		ClassID:   12,
+		Subject:   "History",
+	})
+	h := NewTeachersHandler(mockDB, nil, logging.Init(false))
 	huma.Register(api, huma.Operation{
 		OperationID: "get-teacher",
 		Method:      http.MethodGet,
@@ -98,18 +53,15 @@ func TestTeacherGetById(t *testing.T) {
 
 func TestUpdateTeacherHandler(t *testing.T) {
 	_, api := humatest.New(t)
-	mockDB := &mockTeachersDB{
-		teacher: models.Teacher{
-			ID:        42,
-			FirstName: "Jane",
-			LastName:  "Small",
-			Email:     "janesmall@example.com",
-			Class:     "12C",
-			Subject:   "History",
-		},
-		err: nil,
-	}
-	h := NewTeachersHandler(mockDB)
+	mockDB := memory.NewTeachers(models.Teacher{
+		ID:        42,
+		FirstName: "Jane",
+		LastName:  "Small",
+		Email:     "janesmall@example.com",
+		ClassID:   12,
+		Subject:   "History",
+	})
+	h := NewTeachersHandler(mockDB, nil, logging.Init(false))
 	huma.Register(api, huma.Operation{
 		OperationID: "update-teacher",
 		Method:      http.MethodPut,
@@ -125,7 +77,7 @@ func TestUpdateTeacherHandler(t *testing.T) {
 			"last_name":  "Small",
 			"id":         42,
 			"email":      "janesmall@example.com",
-			"class":      "12C",
+			"class_id":   12,
 			"subject":    "History",
 		},
 	})
@@ -144,3 +96,69 @@ func TestUpdateTeacherHandler(t *testing.T) {
 		t.Fatalf("Expected response to contain ID '42', got: %s", body)
 	}
 }
+
+func TestRestoreDeletedTeacher(t *testing.T) {
+	_, api := humatest.New(t)
+	mockDB := memory.NewTeachers(models.Teacher{
+		ID:        42,
+		FirstName: "Jane",
+		DeletedAt: "2025-01-01T00:00:00Z",
+	})
+	h := NewTeachersHandler(mockDB, nil, logging.Init(false))
+	huma.Register(api, huma.Operation{
+		OperationID: "get-teachers",
+		Method:      http.MethodGet,
+		Path:        "/teachers",
+	}, h.TeachersGet)
+	huma.Register(api, huma.Operation{
+		OperationID: "restore-teacher",
+		Method:      http.MethodPost,
+		Path:        "/teachers/{id}/restore",
+	}, h.RestoreTeacherHandler)
+
+	manager := context.WithValue(context.Background(), middleware.ContextKey("role"), middleware.RoleManager)
+	if code := api.GetCtx(manager, "/teachers?include_deleted=true").Code; code != http.StatusForbidden {
+		t.Fatalf("Expected 403 for include_deleted without admin, got %d", code)
+	}
+
+	if code := api.Post("/teachers/7/restore").Code; code != http.StatusNotFound {
+		t.Fatalf("Expected 404 for teacher which is not deleted, got %d", code)
+	}
+	resp := api.Post("/teachers/42/restore")
+	if resp.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from restore, got %d %s", resp.Code, resp.Body.String())
+	}
+	if _, err := mockDB.GetTeacherByID(context.Background(), 42); err != nil {
+		t.Fatalf("Expected the teacher to be restored")
+	}
+}
+
+func TestTeachersGet(t *testing.T) {
+	_, api := humatest.New(t)
+	mockDB := memory.NewTeachers(
+		models.Teacher{FirstName: "Jane", LastName: "Small", Email: "jane@example.com", ClassID: 12, Subject: "History"},
+		models.Teacher{FirstName: "Adam", LastName: "Brown", Email: "adam@example.com", ClassID: 12, Subject: "Math"},
+		models.Teacher{FirstName: "Zoe", LastName: "Green", Email: "zoe@example.com", ClassID: 9, Subject: "Art"},
+	)
+	h := NewTeachersHandler(mockDB, nil, logging.Init(false))
+	huma.Register(api, huma.Operation{
+		OperationID: "get-teachers",
+		Method:      http.MethodGet,
+		Path:        "/teachers",
+	}, h.TeachersGet)
+
+	resp := api.Get("/teachers?class_id=12&sort_by=first_name:asc")
+	if resp.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d %s", resp.Code, resp.Body.String())
+	}
+	var body struct {
+		Count int              `json:"count"`
+		Data  []models.Teacher `json:"data"`
+	}
+	if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Count != 2 || len(body.Data) != 2 || body.Data[0].FirstName != "Adam" || body.Data[1].FirstName != "Jane" {
+		t.Fatalf("Expected the teachers of 12C sorted by first name, got %s", resp.Body.String())
+	}
+}
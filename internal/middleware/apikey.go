@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/utils"
+)
+
+// APIKeyHeader - header with the api key of the machine clients
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyChecker - lookup of the hashed api key and recording its usage
+type APIKeyChecker interface {
+	GetAPIKeyByHash(string) (models.APIKey, error)
+	TouchAPIKey(int) error
+}
+
+// ExecLookup - the owner of the api key with its role and status
+type ExecLookup interface {
+	GetExecsByID(context.Context, int) (models.Exec, error)
+}
+
+var errInvalidAPIKey = errors.New("Invalid API key")
+
+// apiKeyContext - authenticates the api key and returns the context with the owner of the key
+// and its scopes, which are checked on top of the role by the Authorize middleware
+func apiKeyContext(
+	ctx context.Context,
+	key string,
+	execs ExecLookup,
+	apiKeys APIKeyChecker,
+	now time.Time,
+) (context.Context, error) {
+	hashedKey, err := utils.HashToken(key)
+	if err != nil {
+		return nil, errInvalidAPIKey
+	}
+	apiKey, err := apiKeys.GetAPIKeyByHash(hashedKey)
+	if err != nil {
+		return nil, errInvalidAPIKey
+	}
+	if apiKey.RevokedAt != "" {
+		return nil, errors.New("API key revoked")
+	}
+	if apiKey.ExpiresAt != "" {
+		expiresAt, err := time.Parse(time.RFC3339, apiKey.ExpiresAt)
+		if err != nil || !now.Before(expiresAt) {
+			return nil, errors.New("API key expired")
+		}
+	}
+	owner, err := execs.GetExecsByID(ctx, apiKey.ExecID)
+	if err != nil {
+		if _, ok := queryErrorStatus(err); ok {
+			return nil, err
+		}
+		return nil, errInvalidAPIKey
+	}
+	if owner.InactiveStatus {
+		return nil, errors.New("User inactive")
+	}
+	// failing to record the usage should not fail the request
+	_ = apiKeys.TouchAPIKey(apiKey.ID)
+
+	scopes := make([]Permission, 0, len(apiKey.Scopes))
+	for _, s := range apiKey.Scopes {
+		scopes = append(scopes, Permission(s))
+	}
+	ctx = context.WithValue(ctx, ContextKey("role"), owner.Role)
+	ctx = context.WithValue(ctx, ContextKey("username"), owner.Username)
+	ctx = context.WithValue(ctx, ContextKey("uid"), strconv.Itoa(apiKey.ExecID))
+	ctx = context.WithValue(ctx, ContextKey("apiKeyID"), apiKey.ID)
+	ctx = context.WithValue(ctx, ContextKey("scopes"), scopes)
+	ctx = dataops.WithActor(ctx, apiKey.ExecID)
+	return ctx, nil
+}
+
+// APIKeyScopes - the scopes of the api key used for the request, false for jwt logins
+func APIKeyScopes(ctx context.Context) ([]Permission, bool) {
+	scopes, ok := ctx.Value(ContextKey("scopes")).([]Permission)
+	return scopes, ok
+}
@@ -0,0 +1,177 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/config"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops/memory"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/internal/models"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/utils"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/repository/migrations"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/repository/sqlconnect"
+)
+
+type mockAPIKeys struct {
+	keys    map[string]models.APIKey
+	touched []int
+}
+
+func (m *mockAPIKeys) GetAPIKeyByHash(hash string) (models.APIKey, error) {
+	key, ok := m.keys[hash]
+	if !ok {
+		return models.APIKey{}, errors.New("api key not found")
+	}
+	return key, nil
+}
+
+func (m *mockAPIKeys) TouchAPIKey(id int) error {
+	m.touched = append(m.touched, id)
+	return nil
+}
+
+type mockExecs struct {
+	exec models.Exec
+	err  error
+}
+
+func (m *mockExecs) GetExecsByID(_ context.Context, id int) (models.Exec, error) {
+	return m.exec, m.err
+}
+
+type mockExecStatus struct {
+	inactive bool
+	err      error
+}
+
+func (m *mockExecStatus) GetAuthStatus(_ context.Context, id int) (string, bool, error) {
+	return "", m.inactive, m.err
+}
+
+func TestAPIKeyContext(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	apiKeys := &mockAPIKeys{keys: map[string]models.APIKey{}}
+	addKey := func(id int, key models.APIKey) string {
+		token, hash, err := utils.GenerateToken()
+		if err != nil {
+			t.Fatal(err)
+		}
+		key.ID = id
+		key.ExecID = 7
+		apiKeys.keys[hash] = key
+		return token
+	}
+	valid := addKey(1, models.APIKey{Scopes: []string{"students:read"}})
+	revoked := addKey(2, models.APIKey{RevokedAt: now.Format(time.RFC3339)})
+	expired := addKey(3, models.APIKey{ExpiresAt: now.Add(-time.Minute).Format(time.RFC3339)})
+	// stored in utc, compared with the local time of the server west of utc
+	local := now.In(time.FixedZone("EST", -5*3600))
+	expiredUTC := addKey(4, models.APIKey{ExpiresAt: now.Add(-time.Minute).UTC().Format(time.RFC3339)})
+
+	owner := &mockExecs{exec: models.Exec{ID: 7, Username: "manager", Role: RoleManager}}
+	ctx, err := apiKeyContext(context.Background(), valid, owner, apiKeys, now)
+	if err != nil {
+		t.Fatalf("Expected valid key, got %v", err)
+	}
+	scopes, ok := APIKeyScopes(ctx)
+	if !ok || len(scopes) != 1 || scopes[0] != PermStudentsRead {
+		t.Fatalf("Expected students:read scope, got %v", scopes)
+	}
+	if ctx.Value(ContextKey("uid")) != "7" || ctx.Value(ContextKey("role")) != RoleManager {
+		t.Fatalf("Expected owner of the key in the context")
+	}
+	if len(apiKeys.touched) != 1 {
+		t.Fatalf("Expected last used to be recorded")
+	}
+
+	for name, key := range map[string]string{
+		"revoked": revoked,
+		"expired": expired,
+		"unknown": "00ff",
+		"not hex": "not-a-key",
+	} {
+		if _, err := apiKeyContext(context.Background(), key, owner, apiKeys, now); err == nil {
+			t.Fatalf("Expected %s key to be rejected", name)
+		}
+	}
+	if _, err := apiKeyContext(context.Background(), expiredUTC, owner, apiKeys, local); err == nil {
+		t.Fatalf("Expected key expired in utc to be rejected in the local time")
+	}
+	if _, err := apiKeyContext(context.Background(), valid, &mockExecs{exec: models.Exec{InactiveStatus: true}}, apiKeys, now); err == nil {
+		t.Fatalf("Expected key of inactive exec to be rejected")
+	}
+	_, err = apiKeyContext(context.Background(), valid, &mockExecs{err: dataops.ErrQueryTimeout}, apiKeys, now)
+	if status, ok := queryErrorStatus(err); !ok || status != http.StatusGatewayTimeout {
+		t.Fatalf("Expected timed out status lookup to answer 504, got %v", err)
+	}
+}
+
+// TestAPIKeyMemoryRepository - in the memory mode the owner of the key is only in the memory
+// store, the execs table of the in-memory database is empty
+func TestAPIKeyMemoryRepository(t *testing.T) {
+	db, err := sqlconnect.Open(sqlconnect.SQLite, ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("PRAGMA foreign_keys = OFF"); err != nil {
+		t.Fatal(err)
+	}
+	logger := logging.Init(false)
+	m, err := migrations.NewMigrator(db, logger, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	store := memory.NewStore()
+	execID, err := store.Execs.InsertExecs(context.Background(), &models.Exec{
+		FirstName: "Mia",
+		LastName:  "Manager",
+		Email:     "mia@school.test",
+		Username:  "mia",
+		Role:      RoleManager,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, hash, err := utils.GenerateToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiKeys := dataops.NewAPIKeysDB(db, logger)
+	if _, err := apiKeys.CreateAPIKey(&models.APIKey{
+		ExecID:  int(execID),
+		Name:    "nightly-sync",
+		Prefix:  token[:8],
+		KeyHash: hash,
+		Scopes:  []string{string(PermStudentsRead)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var role, username any
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		role = r.Context().Value(ContextKey("role"))
+		username = r.Context().Value(ContextKey("username"))
+	})
+	conf := config.Config{JWTSecret: "test-secret", JWTExpiresIn: time.Minute, CookieName: "Bearer"}
+	r := httptest.NewRequest(http.MethodGet, "/students", nil)
+	r.Header.Set(APIKeyHeader, token)
+	w := httptest.NewRecorder()
+	JWTMiddleware(next, conf, *logger, &mockAuth{}, store.Execs, apiKeys).ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected the key to authenticate, got %d %s", w.Code, w.Body.String())
+	}
+	if role != RoleManager || username != "mia" {
+		t.Fatalf("Expected the owner from the memory store, got %v %v", role, username)
+	}
+}
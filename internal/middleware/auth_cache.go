@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ExecStatusChecker - returns password_changed_at and inactive_status of the exec
+type ExecStatusChecker interface {
+	GetAuthStatus(context.Context, int) (string, bool, error)
+}
+
+// AuthChecker - lookups needed by the jwt middleware for every authenticated request
+type AuthChecker interface {
+	SessionChecker
+	ExecStatusChecker
+}
+
+// AuthInvalidator - drops cached entries after the session or the exec is changed
+type AuthInvalidator interface {
+	InvalidateSession(int)
+	InvalidateExec(int)
+}
+
+const maxAuthCacheEntries = 1024
+
+type sessionEntry struct {
+	active  bool
+	expires time.Time
+}
+
+type execStatusEntry struct {
+	passwordChangedAt string
+	inactive          bool
+	expires           time.Time
+}
+
+// AuthCache - short lived cache in front of the session and exec status lookups,
+// so the jwt middleware does not hit the database on every request
+type AuthCache struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	sessions SessionChecker
+	execs    ExecStatusChecker
+	sids     map[int]sessionEntry
+	statuses map[int]execStatusEntry
+}
+
+func NewAuthCache(sessions SessionChecker, execs ExecStatusChecker, ttl time.Duration) *AuthCache {
+	return &AuthCache{
+		ttl:      ttl,
+		sessions: sessions,
+		execs:    execs,
+		sids:     make(map[int]sessionEntry),
+		statuses: make(map[int]execStatusEntry),
+	}
+}
+
+func (c *AuthCache) IsSessionActive(sid int) (bool, error) {
+	c.mu.Lock()
+	entry, ok := c.sids[sid]
+	c.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.active, nil
+	}
+
+	active, err := c.sessions.IsSessionActive(sid)
+	if err != nil {
+		return false, err
+	}
+	c.mu.Lock()
+	c.pruneLocked()
+	c.sids[sid] = sessionEntry{active: active, expires: time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+	return active, nil
+}
+
+func (c *AuthCache) GetAuthStatus(ctx context.Context, id int) (string, bool, error) {
+	c.mu.Lock()
+	entry, ok := c.statuses[id]
+	c.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.passwordChangedAt, entry.inactive, nil
+	}
+
+	passwordChangedAt, inactive, err := c.execs.GetAuthStatus(ctx, id)
+	if err != nil {
+		return "", false, err
+	}
+	c.mu.Lock()
+	c.pruneLocked()
+	c.statuses[id] = execStatusEntry{
+		passwordChangedAt: passwordChangedAt,
+		inactive:          inactive,
+		expires:           time.Now().Add(c.ttl),
+	}
+	c.mu.Unlock()
+	return passwordChangedAt, inactive, nil
+}
+
+func (c *AuthCache) InvalidateSession(sid int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.sids, sid)
+}
+
+// InvalidateExec - drops the exec status and all cached sessions, as revoking
+// every session of an exec does not tell which session ids were affected
+func (c *AuthCache) InvalidateExec(id int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.statuses, id)
+	c.sids = make(map[int]sessionEntry)
+}
+
+// pruneLocked - removes the expired entries once the cache grows, mutex must be held
+func (c *AuthCache) pruneLocked() {
+	if len(c.sids)+len(c.statuses) < maxAuthCacheEntries {
+		return
+	}
+	now := time.Now()
+	for sid, entry := range c.sids {
+		if now.After(entry.expires) {
+			delete(c.sids, sid)
+		}
+	}
+	for id, entry := range c.statuses {
+		if now.After(entry.expires) {
+			delete(c.statuses, id)
+		}
+	}
+}
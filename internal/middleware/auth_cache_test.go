@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type countingAuthStore struct {
+	sessionCalls int
+	statusCalls  int
+	active       bool
+	inactive     bool
+}
+
+func (s *countingAuthStore) IsSessionActive(int) (bool, error) {
+	s.sessionCalls++
+	return s.active, nil
+}
+
+func (s *countingAuthStore) GetAuthStatus(context.Context, int) (string, bool, error) {
+	s.statusCalls++
+	return "2025-01-02T15:04:05Z", s.inactive, nil
+}
+
+func TestAuthCacheServesFromCache(t *testing.T) {
+	store := &countingAuthStore{active: true}
+	cache := NewAuthCache(store, store, time.Minute)
+
+	for range 5 {
+		active, err := cache.IsSessionActive(7)
+		if err != nil || !active {
+			t.Fatalf("Expected active session, got %v %v", active, err)
+		}
+		if _, _, err := cache.GetAuthStatus(context.Background(), 3); err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+	}
+	if store.sessionCalls != 1 || store.statusCalls != 1 {
+		t.Fatalf("Expected one lookup each, got %d sessions and %d statuses",
+			store.sessionCalls, store.statusCalls)
+	}
+}
+
+func TestAuthCacheInvalidate(t *testing.T) {
+	store := &countingAuthStore{active: true}
+	cache := NewAuthCache(store, store, time.Minute)
+
+	_, _ = cache.IsSessionActive(7)
+	store.active = false
+	cache.InvalidateSession(7)
+	if active, _ := cache.IsSessionActive(7); active {
+		t.Fatalf("Expected revoked session after invalidation")
+	}
+
+	_, inactive, _ := cache.GetAuthStatus(context.Background(), 3)
+	if inactive {
+		t.Fatalf("Expected active exec")
+	}
+	store.inactive = true
+	cache.InvalidateExec(3)
+	if _, inactive, _ := cache.GetAuthStatus(context.Background(), 3); !inactive {
+		t.Fatalf("Expected inactive exec after invalidation")
+	}
+}
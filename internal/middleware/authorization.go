@@ -0,0 +1,214 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// Permission - single action which an exec role is allowed to do
+type Permission string
+
+const (
+	PermTeachersRead  Permission = "teachers:read"
+	PermTeachersWrite Permission = "teachers:write"
+	PermStudentsRead  Permission = "students:read"
+	PermStudentsWrite Permission = "students:write"
+	PermClassesRead   Permission = "classes:read"
+	PermClassesWrite  Permission = "classes:write"
+	PermSubjectsRead  Permission = "subjects:read"
+	PermSubjectsWrite Permission = "subjects:write"
+	// PermAssignmentsRead and PermAssignmentsWrite - the teaching assignments of the
+	// teachers to the subjects in the classes
+	PermAssignmentsRead  Permission = "assignments:read"
+	PermAssignmentsWrite Permission = "assignments:write"
+	PermExecsRead        Permission = "execs:read"
+	PermExecsWrite       Permission = "execs:write"
+	PermAuditRead        Permission = "audit:read"
+	// PermDeletedManage - list the soft deleted records and restore them
+	PermDeletedManage Permission = "deleted:manage"
+)
+
+const (
+	RoleAdmin   = "admin"
+	RoleManager = "manager"
+	RoleStaff   = "staff"
+)
+
+// names of the jwt security schemes in the openapi document
+const (
+	SecuritySchemeCookie = "cookieAuth"
+	SecuritySchemeBearer = "bearerAuth"
+	SecuritySchemeAPIKey = "apiKeyAuth"
+)
+
+var rolePermissions = map[string][]Permission{
+	RoleAdmin: {
+		PermTeachersRead,
+		PermTeachersWrite,
+		PermStudentsRead,
+		PermStudentsWrite,
+		PermClassesRead,
+		PermClassesWrite,
+		PermSubjectsRead,
+		PermSubjectsWrite,
+		PermAssignmentsRead,
+		PermAssignmentsWrite,
+		PermExecsRead,
+		PermExecsWrite,
+		PermAuditRead,
+		PermDeletedManage,
+	},
+	RoleManager: {
+		PermTeachersRead,
+		PermTeachersWrite,
+		PermStudentsRead,
+		PermStudentsWrite,
+		PermClassesRead,
+		PermClassesWrite,
+		PermSubjectsRead,
+		PermSubjectsWrite,
+		PermAssignmentsRead,
+		PermAssignmentsWrite,
+		PermExecsRead,
+	},
+	RoleStaff: {
+		PermTeachersRead,
+		PermStudentsRead,
+		PermClassesRead,
+		PermSubjectsRead,
+		PermAssignmentsRead,
+	},
+}
+
+// HasPermission reports whether the role is granted the permission
+func HasPermission(role string, perm Permission) bool {
+	return slices.Contains(rolePermissions[role], perm)
+}
+
+// Allowed reports whether the exec of the request has the permission, with api key
+// the permission must be also in the key scopes
+func Allowed(ctx context.Context, perm Permission) bool {
+	role, _ := ctx.Value(ContextKey("role")).(string)
+	if !HasPermission(role, perm) {
+		return false
+	}
+	if scopes, isAPIKey := APIKeyScopes(ctx); isAPIKey {
+		return slices.Contains(scopes, perm)
+	}
+	return true
+}
+
+// Require - returns the security requirement for huma.Operation with the permissions needed,
+// the token can be send either as cookie or in the Authorization header. Operations with
+// permissions can be called also with api key which has all of them in its scopes
+func Require(perms ...Permission) []map[string][]string {
+	scopes := make([]string, 0, len(perms))
+	for _, p := range perms {
+		scopes = append(scopes, string(p))
+	}
+	security := []map[string][]string{
+		{SecuritySchemeBearer: scopes},
+		{SecuritySchemeCookie: scopes},
+	}
+	if len(perms) > 0 {
+		security = append(security, map[string][]string{SecuritySchemeAPIKey: scopes})
+	}
+	return security
+}
+
+// SecuritySchemes - the jwt security schemes for the huma config components
+func SecuritySchemes(cookieName string) map[string]*huma.SecurityScheme {
+	return map[string]*huma.SecurityScheme{
+		SecuritySchemeBearer: {
+			Type:         "http",
+			Scheme:       "bearer",
+			BearerFormat: "JWT",
+			Description:  "JWT token from /execs/login in the Authorization: Bearer header",
+		},
+		SecuritySchemeCookie: {
+			Type:        "apiKey",
+			In:          "cookie",
+			Name:        cookieName,
+			Description: "JWT token from /execs/login stored in the " + cookieName + " cookie",
+		},
+		SecuritySchemeAPIKey: {
+			Type:        "apiKey",
+			In:          "header",
+			Name:        APIKeyHeader,
+			Description: "API key of exec limited to the scopes it was created with",
+		},
+	}
+}
+
+// requiredPermissions - collect the permissions declared in the operation security
+func requiredPermissions(op *huma.Operation) []Permission {
+	var perms []Permission
+	if op == nil {
+		return perms
+	}
+	for _, req := range op.Security {
+		for _, scopes := range req {
+			for _, s := range scopes {
+				if !slices.Contains(perms, Permission(s)) {
+					perms = append(perms, Permission(s))
+				}
+			}
+		}
+	}
+	return perms
+}
+
+// Authorize - huma middleware which checks the role from the jwt context against the
+// permissions declared by the operation and returns 403 if any is missing. Requests with
+// api key need the permissions also in the key scopes and can not call the secured
+// operations without permissions like logout or password change
+func Authorize(api huma.API) func(ctx huma.Context, next func(huma.Context)) {
+	return func(ctx huma.Context, next func(huma.Context)) {
+		op := ctx.Operation()
+		perms := requiredPermissions(op)
+		scopes, isAPIKey := APIKeyScopes(ctx.Context())
+		if len(perms) == 0 {
+			if isAPIKey && op != nil && len(op.Security) > 0 {
+				huma.WriteErr(api, ctx, http.StatusForbidden, "operation not allowed with API key")
+				return
+			}
+			next(ctx)
+			return
+		}
+
+		role, _ := ctx.Context().Value(ContextKey("role")).(string)
+		for _, p := range perms {
+			if !HasPermission(role, p) || (isAPIKey && !slices.Contains(scopes, p)) {
+				huma.WriteErr(api, ctx, http.StatusForbidden, "missing permission "+string(p))
+				return
+			}
+		}
+		next(ctx)
+	}
+}
+
+// DocumentPermissions - OnAddOperation hook which adds the 401 and 403 responses
+// and the required permissions to the description of the secured operations
+func DocumentPermissions(oapi *huma.OpenAPI, op *huma.Operation) {
+	perms := requiredPermissions(op)
+	if len(perms) == 0 {
+		return
+	}
+	if op.Responses == nil {
+		op.Responses = map[string]*huma.Response{}
+	}
+	for _, code := range []int{http.StatusUnauthorized, http.StatusForbidden} {
+		status := strconv.Itoa(code)
+		if _, ok := op.Responses[status]; !ok {
+			op.Responses[status] = &huma.Response{Description: http.StatusText(code)}
+		}
+	}
+	if op.Extensions == nil {
+		op.Extensions = map[string]any{}
+	}
+	op.Extensions["x-permissions"] = perms
+}
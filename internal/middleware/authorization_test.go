@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/humatest"
+)
+
+func newAuthorizedTestAPI(t *testing.T) humatest.TestAPI {
+	config := huma.DefaultConfig("Test API", "1.0.0")
+	config.OnAddOperation = append(config.OnAddOperation, DocumentPermissions)
+	_, api := humatest.New(t, config)
+	api.UseMiddleware(Authorize(api))
+
+	huma.Register(api, huma.Operation{
+		OperationID: "delete-teacher",
+		Method:      http.MethodDelete,
+		Path:        "/teachers/{id}",
+		Security:    Require(PermTeachersWrite),
+	}, func(ctx context.Context, input *struct {
+		ID int `path:"id"`
+	},
+	) (*struct{}, error) {
+		return nil, nil
+	})
+	return api
+}
+
+func TestAuthorizeRoles(t *testing.T) {
+	api := newAuthorizedTestAPI(t)
+
+	tests := []struct {
+		role string
+		want int
+	}{
+		{RoleAdmin, http.StatusNoContent},
+		{RoleManager, http.StatusNoContent},
+		{RoleStaff, http.StatusForbidden},
+		{"", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		ctx := context.WithValue(context.Background(), ContextKey("role"), tt.role)
+		resp := api.DeleteCtx(ctx, "/teachers/42")
+		if resp.Code != tt.want {
+			t.Fatalf("role %q: expected status %d, got %d", tt.role, tt.want, resp.Code)
+		}
+	}
+}
+
+func TestDocumentPermissions(t *testing.T) {
+	api := newAuthorizedTestAPI(t)
+
+	op := api.OpenAPI().Paths["/teachers/{id}"].Delete
+	if _, ok := op.Responses["403"]; !ok {
+		t.Fatalf("Expected 403 response to be documented")
+	}
+	if len(op.Security) != 3 || op.Security[1][SecuritySchemeCookie][0] != string(PermTeachersWrite) {
+		t.Fatalf("Expected security requirement with %s, got %v", PermTeachersWrite, op.Security)
+	}
+}
+
+func TestAuthorizeAPIKeyScopes(t *testing.T) {
+	api := newAuthorizedTestAPI(t)
+	huma.Register(api, huma.Operation{
+		OperationID: "logout",
+		Method:      http.MethodPost,
+		Path:        "/logout",
+		Security:    Require(),
+	}, func(ctx context.Context, input *struct{}) (*struct{}, error) {
+		return nil, nil
+	})
+
+	withScopes := func(scopes ...Permission) context.Context {
+		ctx := context.WithValue(context.Background(), ContextKey("role"), RoleAdmin)
+		return context.WithValue(ctx, ContextKey("scopes"), scopes)
+	}
+
+	if code := api.DeleteCtx(withScopes(PermTeachersWrite), "/teachers/42").Code; code != http.StatusNoContent {
+		t.Fatalf("Expected 204 for key with the scope, got %d", code)
+	}
+	if code := api.DeleteCtx(withScopes(PermTeachersRead), "/teachers/42").Code; code != http.StatusForbidden {
+		t.Fatalf("Expected 403 for key without the scope, got %d", code)
+	}
+	if code := api.PostCtx(withScopes(PermTeachersWrite), "/logout").Code; code != http.StatusForbidden {
+		t.Fatalf("Expected 403 for operation without permissions, got %d", code)
+	}
+}
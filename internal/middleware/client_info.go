@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+)
+
+// ClientInfo - stores the client ip address and user agent in the request context for the handlers
+func ClientInfo(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := r.RemoteAddr
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+		ctx := context.WithValue(r.Context(), ContextKey("ip"), ip)
+		ctx = context.WithValue(ctx, ContextKey("user_agent"), r.UserAgent())
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// ClientIP - returns the client ip address stored by ClientInfo
+func ClientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(ContextKey("ip")).(string)
+	return ip
+}
+
+// ClientUserAgent - returns the user agent of the client stored by ClientInfo
+func ClientUserAgent(ctx context.Context) string {
+	ua, _ := ctx.Value(ContextKey("user_agent")).(string)
+	return ua
+}
@@ -5,16 +5,31 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/dkr290/go-advanced-projects/rest-api-school-management/config"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops"
 	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
 	"github.com/golang-jwt/jwt/v5"
 )
 
 type ContextKey string
 
-func JWTMiddleware(next http.Handler, conf config.Config, logger logging.Logger) http.Handler {
+// SessionChecker - checks that the login session of the token is not revoked or expired
+type SessionChecker interface {
+	IsSessionActive(int) (bool, error)
+}
+
+func JWTMiddleware(
+	next http.Handler,
+	conf config.Config,
+	logger logging.Logger,
+	auth AuthChecker,
+	execs ExecLookup,
+	apiKeys APIKeyChecker,
+) http.Handler {
 	logger.Logging.Debugln(strings.Repeat("-", 20) + "JWT Middleware" + strings.Repeat("-", 20))
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -27,13 +42,28 @@ func JWTMiddleware(next http.Handler, conf config.Config, logger logging.Logger)
 			}
 		}
 
-		token, err := r.Cookie("Bearer")
-		if err != nil {
+		if key := r.Header.Get(APIKeyHeader); key != "" {
+			ctx, err := apiKeyContext(r.Context(), key, execs, apiKeys, time.Now())
+			if err != nil {
+				if status, ok := queryErrorStatus(err); ok {
+					http.Error(w, http.StatusText(status), status)
+					return
+				}
+				logger.Logging.Debugf("api key rejected %v", err)
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		tokenString := tokenFromRequest(r, conf.CookieName)
+		if tokenString == "" {
 			logger.Logging.Debugln("No Bearer tioken found")
 			http.Error(w, "Authorization Header Missing ", http.StatusUnauthorized)
 			return
 		}
-		parsedToken, err := jwt.Parse(token.Value, func(token *jwt.Token) (any, error) {
+		parsedToken, err := jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
 			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 				logger.Logging.Debugln("unexpected signing method")
 				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
@@ -78,10 +108,74 @@ func JWTMiddleware(next http.Handler, conf config.Config, logger logging.Logger)
 			return
 		}
 
+		sidClaim, _ := claims["sid"].(string)
+		sid, err := strconv.Atoi(sidClaim)
+		if err != nil {
+			logger.Logging.Debugf("token without valid session id %v", err)
+			http.Error(w, "Token is not valid", http.StatusUnauthorized)
+			return
+		}
+		active, err := auth.IsSessionActive(sid)
+		if err != nil {
+			logger.Logging.Errorf("error checking the session %v", err)
+			http.Error(w, "Internal error", http.StatusInternalServerError)
+			return
+		}
+		if !active {
+			logger.Logging.Debugf("session %d is revoked or expired", sid)
+			http.Error(w, "Session revoked", http.StatusUnauthorized)
+			return
+		}
+
+		// tokens issued before the last password change or for inactive execs are rejected
+		uidClaim, _ := claims["uid"].(string)
+		uid, err := strconv.Atoi(uidClaim)
+		if err != nil {
+			logger.Logging.Debugf("token without valid user id %v", err)
+			http.Error(w, "Token is not valid", http.StatusUnauthorized)
+			return
+		}
+		issuedAt, err := claims.GetIssuedAt()
+		if err != nil || issuedAt == nil {
+			logger.Logging.Debugf("token without issued at %v", err)
+			http.Error(w, "Token is not valid", http.StatusUnauthorized)
+			return
+		}
+		passwordChangedAt, inactive, err := auth.GetAuthStatus(r.Context(), uid)
+		if err != nil {
+			if status, ok := queryErrorStatus(err); ok {
+				http.Error(w, http.StatusText(status), status)
+				return
+			}
+			if strings.Contains(err.Error(), "not found") {
+				logger.Logging.Debugf("exec %d of the token not found", uid)
+				http.Error(w, "Token is not valid", http.StatusUnauthorized)
+				return
+			}
+			logger.Logging.Errorf("error checking the exec status %v", err)
+			http.Error(w, "Internal error", http.StatusInternalServerError)
+			return
+		}
+		if inactive {
+			logger.Logging.Debugf("exec %d is inactive", uid)
+			http.Error(w, "User inactive", http.StatusUnauthorized)
+			return
+		}
+		if passwordChangedAt != "" {
+			changedAt, err := time.Parse(time.RFC3339, passwordChangedAt)
+			if err == nil && issuedAt.Before(changedAt) {
+				logger.Logging.Debugf("token of exec %d issued before the password change", uid)
+				http.Error(w, "Token issued before password change", http.StatusUnauthorized)
+				return
+			}
+		}
+
 		ctx := context.WithValue(r.Context(), ContextKey("role"), claims["role"])
 		ctx = context.WithValue(ctx, ContextKey("expiresAt"), claims["exp"])
 		ctx = context.WithValue(ctx, ContextKey("username"), claims["user"])
 		ctx = context.WithValue(ctx, ContextKey("uid"), claims["uid"])
+		ctx = context.WithValue(ctx, ContextKey("sid"), sid)
+		ctx = dataops.WithActor(ctx, uid)
 
 		logger.Logging.Debug(ctx)
 		next.ServeHTTP(w, r.WithContext(ctx))
@@ -89,3 +183,19 @@ func JWTMiddleware(next http.Handler, conf config.Config, logger logging.Logger)
 		fmt.Println("Responce from JWT Middleware")
 	})
 }
+
+// tokenFromRequest - the token from Authorization: Bearer header, which wins over the cookie
+func tokenFromRequest(r *http.Request, cookieName string) string {
+	if authHeader := r.Header.Get("Authorization"); authHeader != "" {
+		scheme, token, found := strings.Cut(authHeader, " ")
+		if found && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+		return ""
+	}
+	cookie, err := r.Cookie(cookieName)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/config"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/utils"
+)
+
+func TestTokenFromRequest(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		cookie string
+		want   string
+	}{
+		{"header only", "Bearer header-token", "", "header-token"},
+		{"cookie only", "", "cookie-token", "cookie-token"},
+		{"header wins over cookie", "bearer header-token", "cookie-token", "header-token"},
+		{"other scheme", "Basic dXNlcjpwYXNz", "cookie-token", ""},
+		{"nothing", "", "", ""},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/teachers", nil)
+		if tt.header != "" {
+			r.Header.Set("Authorization", tt.header)
+		}
+		if tt.cookie != "" {
+			r.AddCookie(&http.Cookie{Name: "Bearer", Value: tt.cookie})
+		}
+		if got := tokenFromRequest(r, "Bearer"); got != tt.want {
+			t.Fatalf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+type mockAuth struct {
+	mockExecStatus
+}
+
+func (m *mockAuth) IsSessionActive(int) (bool, error) { return true, nil }
+
+// TestJWTMiddlewareExecStatus - the token of the missing exec is rejected, the failed
+// lookup of the exec status is an internal error and does not log the client out
+func TestJWTMiddlewareExecStatus(t *testing.T) {
+	conf := config.Config{JWTSecret: "test-secret", JWTExpiresIn: time.Minute, CookieName: "Bearer"}
+	token, err := utils.SighnToken("7", "admin", RoleAdmin, "1", conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	for name, tt := range map[string]struct {
+		err  error
+		want int
+	}{
+		"active exec":     {nil, http.StatusOK},
+		"missing exec":    {errors.New("user not found"), http.StatusUnauthorized},
+		"database failed": {errors.New("error retreiving the exec status"), http.StatusInternalServerError},
+	} {
+		auth := &mockAuth{mockExecStatus{err: tt.err}}
+		r := httptest.NewRequest(http.MethodGet, "/teachers", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		JWTMiddleware(next, conf, *logging.Init(false), auth, &mockExecs{}, &mockAPIKeys{}).ServeHTTP(w, r)
+		if w.Code != tt.want {
+			t.Fatalf("%s: expected %d, got %d %s", name, tt.want, w.Code, w.Body.String())
+		}
+	}
+}
@@ -1,7 +1,24 @@
 // Package middleware
 package middleware
 
-import "net/http"
+import (
+	"errors"
+	"net/http"
+
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/dataops"
+)
+
+// queryErrorStatus - 504 for the query which timed out and 503 for the canceled one,
+// the other errors are answered by the caller
+func queryErrorStatus(err error) (int, bool) {
+	switch {
+	case errors.Is(err, dataops.ErrQueryTimeout):
+		return http.StatusGatewayTimeout, true
+	case errors.Is(err, dataops.ErrQueryCanceled):
+		return http.StatusServiceUnavailable, true
+	}
+	return 0, false
+}
 
 func SecurityHeaders(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
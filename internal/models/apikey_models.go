@@ -0,0 +1,20 @@
+package models
+
+type APIKey struct {
+	ID         int      `json:"id"                     db:"id,omitempty"`
+	ExecID     int      `json:"exec_id"                db:"exec_id"`
+	Name       string   `json:"name"                   db:"name"`
+	Prefix     string   `json:"prefix"                 db:"key_prefix"`
+	KeyHash    string   `json:"-"                      db:"key_hash"`
+	Scopes     []string `json:"scopes"                 db:"scopes"`
+	LastUsedAt string   `json:"last_used_at,omitempty" db:"last_used_at"`
+	ExpiresAt  string   `json:"expires_at,omitempty"   db:"expires_at"`
+	RevokedAt  string   `json:"revoked_at,omitempty"   db:"revoked_at"`
+	CreatedAt  string   `json:"created_at,omitempty"   db:"created_at"`
+}
+
+type APIKeyInput struct {
+	Name      string   `json:"name"                 required:"true" minLength:"1" maxLength:"255" example:"nightly-sync"         doc:"Name to recognize the key"`
+	Scopes    []string `json:"scopes"               required:"true" minItems:"1"                  example:"[\"students:read\"]" doc:"Permissions of the key, subset of the exec role permissions"`
+	ExpiresAt string   `json:"expires_at,omitempty"                 format:"date-time"                                          doc:"Optional expiry of the key"`
+}
@@ -0,0 +1,30 @@
+package models
+
+// Assignment - the teacher teaches the subject in the class in the term, a subject is
+// taught in a class by one teacher in a term. The names are of the referenced records
+// and only read
+type Assignment struct {
+	ID          int    `json:"id"           db:"id,omitempty"`
+	TeacherID   int    `json:"teacher_id"   db:"teacher_id,omitempty"`
+	SubjectID   int    `json:"subject_id"   db:"subject_id,omitempty"`
+	ClassID     int    `json:"class_id"     db:"class_id,omitempty"`
+	Term        string `json:"term"         db:"term,omitempty"`
+	TeacherName string `json:"teacher_name"`
+	SubjectName string `json:"subject_name"`
+	ClassName   string `json:"class_name"`
+}
+
+type AssignmentInput struct {
+	TeacherID int    `json:"teacher_id"     required:"true" minimum:"1"  example:"100"         doc:"Id of the teacher"`
+	SubjectID int    `json:"subject_id"     required:"true" minimum:"1"  example:"1"           doc:"Id of the subject"`
+	ClassID   int    `json:"class_id"       required:"true" minimum:"1"  example:"1"           doc:"Id of the class"`
+	Term      string `json:"term,omitempty" maxLength:"20"               example:"2025/2026-1" doc:"Term of the assignment"`
+}
+
+type AssignmentsQueryInput struct {
+	TeacherID int      `query:"teacher_id"`
+	SubjectID int      `query:"subject_id"`
+	ClassID   int      `query:"class_id"`
+	Term      string   `query:"term"`
+	SortBy    []string `query:"sort_by" example:"term:asc" doc:"Order by asc or desc of the records"`
+}
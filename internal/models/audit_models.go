@@ -0,0 +1,54 @@
+package models
+
+// types of the authentication events in the audit log
+const (
+	AuthEventLogin                = "login"
+	AuthEventLoginMFA             = "login_mfa"
+	AuthEventLoginOIDC            = "login_oidc"
+	AuthEventLogout               = "logout"
+	AuthEventLogoutAll            = "logout_all"
+	AuthEventRefresh              = "refresh"
+	AuthEventPasswordChange       = "password_change"
+	AuthEventPasswordSet          = "password_set"
+	AuthEventPasswordResetRequest = "password_reset_request"
+	AuthEventPasswordReset        = "password_reset"
+	AuthEventActivation           = "account_activation"
+	AuthEventDeactivate           = "exec_deactivate"
+	AuthEventReactivate           = "exec_reactivate"
+	AuthEventLock                 = "exec_lock"
+	AuthEventUnlock               = "exec_unlock"
+	AuthEventSessionsRevoke       = "sessions_revoke"
+	AuthEventMFAEnable            = "mfa_enable"
+	AuthEventMFADisable           = "mfa_disable"
+)
+
+const (
+	AuthOutcomeSuccess = "success"
+	AuthOutcomeFailure = "failure"
+)
+
+// AuthEvent - entry of the authentication audit log, ExecID is the exec the event is about
+// and ActorID the logged in exec which did it when it is somebody else like an admin
+type AuthEvent struct {
+	ID        int    `json:"id"                 db:"id"`
+	EventType string `json:"event_type"         db:"event_type"`
+	ExecID    int    `json:"exec_id,omitempty"  db:"exec_id"`
+	ActorID   int    `json:"actor_id,omitempty" db:"actor_id"`
+	Username  string `json:"username,omitempty" db:"username"`
+	IPAddress string `json:"ip_address"         db:"ip_address"`
+	UserAgent string `json:"user_agent"         db:"user_agent"`
+	Outcome   string `json:"outcome"            db:"outcome"`
+	Detail    string `json:"detail,omitempty"   db:"detail"`
+	CreatedAt string `json:"created_at"         db:"created_at"`
+}
+
+// AuthEventFilter - the filters of the audit log query, the zero values are not filtered
+type AuthEventFilter struct {
+	ExecID    int
+	EventType string
+	Outcome   string
+	From      string
+	To        string
+	Page      int
+	Limit     int
+}
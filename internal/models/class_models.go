@@ -0,0 +1,47 @@
+package models
+
+// Class - the class the teachers and students belong to, the name is unique in the
+// academic year. HomeroomTeacherID 0 is the class without homeroom teacher
+type Class struct {
+	ID                int    `json:"id"                  db:"id,omitempty"`
+	Name              string `json:"name"                db:"name,omitempty"`
+	GradeLevel        int    `json:"grade_level"         db:"grade_level,omitempty"`
+	AcademicYear      string `json:"academic_year"       db:"academic_year,omitempty"`
+	HomeroomTeacherID int    `json:"homeroom_teacher_id" db:"homeroom_teacher_id,omitempty"`
+	Room              string `json:"room"                db:"room,omitempty"`
+	Capacity          int    `json:"capacity"            db:"capacity,omitempty"`
+}
+
+type ClassInput struct {
+	Name              string `json:"name"                          required:"true" minLength:"1" maxLength:"50" example:"10B"       doc:"Name of the class"`
+	GradeLevel        int    `json:"grade_level,omitempty"         minimum:"0"     maximum:"13"                 example:"10"        doc:"Grade level of the class"`
+	AcademicYear      string `json:"academic_year,omitempty"       maxLength:"20"                               example:"2025/2026" doc:"Academic year of the class"`
+	HomeroomTeacherID int    `json:"homeroom_teacher_id,omitempty" minimum:"0"                                  example:"100"       doc:"Id of the homeroom teacher"`
+	Room              string `json:"room,omitempty"                maxLength:"50"                               example:"B204"      doc:"Room of the class"`
+	Capacity          int    `json:"capacity,omitempty"            minimum:"0"                                  example:"30"        doc:"Number of students the class can take"`
+}
+
+type ClassesQueryInput struct {
+	Name         string   `query:"name"`
+	GradeLevel   string   `query:"grade_level"`
+	AcademicYear string   `query:"academic_year"`
+	SortBy       []string `query:"sort_by" example:"name:asc" doc:"Order by asc or desc of the records"`
+}
+
+type ClassUpdateBody struct {
+	Name              string `json:"name"                minLength:"1" maxLength:"50" example:"10B"       doc:"Name of the class"`
+	GradeLevel        int    `json:"grade_level"         minimum:"0"   maximum:"13"   example:"10"        doc:"Grade level of the class"`
+	AcademicYear      string `json:"academic_year"       maxLength:"20"               example:"2025/2026" doc:"Academic year of the class"`
+	HomeroomTeacherID int    `json:"homeroom_teacher_id" minimum:"0"                  example:"100"       doc:"Id of the homeroom teacher, 0 for none"`
+	Room              string `json:"room"                maxLength:"50"               example:"B204"      doc:"Room of the class"`
+	Capacity          int    `json:"capacity"            minimum:"0"                  example:"30"        doc:"Number of students the class can take"`
+}
+
+type ClassPatchBody struct {
+	Name              string `json:"name,omitempty"                maxLength:"50" example:"10B"       doc:"Name of the class"`
+	GradeLevel        int    `json:"grade_level,omitempty"         minimum:"0"    maximum:"13"        example:"10" doc:"Grade level of the class"`
+	AcademicYear      string `json:"academic_year,omitempty"       maxLength:"20" example:"2025/2026" doc:"Academic year of the class"`
+	HomeroomTeacherID int    `json:"homeroom_teacher_id,omitempty" minimum:"0"    example:"100"       doc:"Id of the homeroom teacher"`
+	Room              string `json:"room,omitempty"                maxLength:"50" example:"B204"      doc:"Room of the class"`
+	Capacity          int    `json:"capacity,omitempty"            minimum:"0"    example:"30"        doc:"Number of students the class can take"`
+}
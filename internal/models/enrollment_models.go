@@ -0,0 +1,30 @@
+package models
+
+// statuses of the enrollments, only the active enrollment has no end date
+const (
+	EnrollmentActive      = "active"
+	EnrollmentTransferred = "transferred"
+	EnrollmentGraduated   = "graduated"
+)
+
+// Enrollment - the student in the class from the start date until the end date, the
+// dates are YYYY-MM-DD. ClassName is of the class and only read
+type Enrollment struct {
+	ID           int    `json:"id"                 db:"id,omitempty"`
+	StudentID    int    `json:"student_id"         db:"student_id,omitempty"`
+	ClassID      int    `json:"class_id"           db:"class_id,omitempty"`
+	ClassName    string `json:"class_name"`
+	AcademicYear string `json:"academic_year"      db:"academic_year,omitempty"`
+	StartDate    string `json:"start_date"         db:"start_date,omitempty"`
+	EndDate      string `json:"end_date,omitempty" db:"end_date,omitempty"`
+	Status       string `json:"status"             db:"status,omitempty"`
+}
+
+type EnrollInput struct {
+	ClassID int    `json:"class_id"       required:"true" minimum:"1" example:"1"          doc:"Id of the class"`
+	Date    string `json:"date,omitempty" format:"date"               example:"2025-09-01" doc:"Day of the enrollment, today when it is not set"`
+}
+
+type GraduateInput struct {
+	Date string `json:"date,omitempty" format:"date" example:"2026-06-30" doc:"Day of the graduation, today when it is not set"`
+}
@@ -15,6 +15,7 @@ type Exec struct {
 	PasswordTokenExpires sql.NullString `json:"password_token_expires"    db:"password_token_expires"`
 	InactiveStatus       bool           `json:"inactive_status,omitempty" db:"inactive_status,omitempty"`
 	Role                 string         `json:"role,omitempty"            db:"role,omitempty"`
+	DeletedAt            string         `json:"deleted_at,omitempty"`
 }
 type ExecLoginInput struct {
 	Username string `json:"username" required:"true" minLength:"2" maxLength:"255" doc:"username" examle:"username"`
@@ -38,11 +39,19 @@ type ExecPatchBody struct {
 	Username  string `json:"username"                                       doc:"username"                  required:"true" minLength:"2" maxLength:"255" examle:"username"`
 }
 
+// ExecMePatchBody - the fields which the exec can change on its own profile
+type ExecMePatchBody struct {
+	FirstName string `json:"first_name,omitempty" maxLength:"255" example:"Alice"          doc:"First name"`
+	LastName  string `json:"last_name,omitempty"  maxLength:"255" example:"Brown"          doc:"Last name"`
+	Email     string `json:"email,omitempty"      maxLength:"255" example:"ac@example.com" doc:"Email"`
+}
+
 type ExecsQueryInput struct {
-	FirstName string   `query:"first_name"`
-	LastName  string   `query:"last_name"`
-	Email     string   `query:"email"`
-	Username  string   `query:"username"`
-	Role      string   `query:"role"`
-	SortBy    []string `query:"sort_by"    example:"first_name:asc" doc:"Order by asc or desc of the records"`
+	FirstName      string   `query:"first_name"`
+	LastName       string   `query:"last_name"`
+	Email          string   `query:"email"`
+	Username       string   `query:"username"`
+	Role           string   `query:"role"`
+	SortBy         []string `query:"sort_by"    example:"first_name:asc" doc:"Order by asc or desc of the records"`
+	IncludeDeleted bool     `query:"include_deleted" doc:"Include the soft deleted records, only for admins"`
 }
@@ -0,0 +1,41 @@
+package models
+
+import "encoding/json"
+
+// the entities which keep the history of their changes
+const (
+	EntityTeacher = "teacher"
+	EntityStudent = "student"
+	EntityExec    = "exec"
+)
+
+const (
+	ChangeCreate  = "create"
+	ChangeUpdate  = "update"
+	ChangeDelete  = "delete"
+	ChangeRestore = "restore"
+	// ChangeUndelete - the soft deleted record was brought back
+	ChangeUndelete = "undelete"
+)
+
+// FieldChange - single field changed by the revision
+type FieldChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+// Revision - one change of the entity with the state before and after it, Before is empty
+// for the create and After for the delete. ActorID is the exec which made the change
+type Revision struct {
+	ID           int             `json:"id"                      db:"id"`
+	Entity       string          `json:"entity"                  db:"entity"`
+	EntityID     int             `json:"entity_id"               db:"entity_id"`
+	Action       string          `json:"action"                  db:"action"`
+	ActorID      int             `json:"actor_id,omitempty"      db:"actor_id"`
+	Before       json.RawMessage `json:"before,omitempty"        db:"before_data"`
+	After        json.RawMessage `json:"after,omitempty"         db:"after_data"`
+	Changes      []FieldChange   `json:"changes"                 db:"changes"`
+	RestoredFrom int             `json:"restored_from,omitempty" db:"restored_from"`
+	CreatedAt    string          `json:"created_at"              db:"created_at"`
+}
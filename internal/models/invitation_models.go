@@ -0,0 +1,17 @@
+package models
+
+// ExecInvitation - pending invitation of exec, only the hash of the activation code is stored
+type ExecInvitation struct {
+	ExecID    int    `json:"exec_id"    db:"exec_id"`
+	TokenHash string `json:"-"          db:"token_hash"`
+	ExpiresAt string `json:"expires_at" db:"expires_at"`
+	CreatedAt string `json:"created_at" db:"created_at"`
+}
+
+type ExecInviteInput struct {
+	Email     string `json:"email"                required:"true"                 maxLength:"50"  example:"exec@example.com" doc:"Email where the activation link is send"`
+	Role      string `json:"role"                 required:"true" enum:"admin,manager,staff"      example:"staff"            doc:"Role of the exec"`
+	FirstName string `json:"first_name,omitempty"                                 maxLength:"255" example:"Tom"              doc:"First name, can be set also by the exec on activation"`
+	LastName  string `json:"last_name,omitempty"                                  maxLength:"255" example:"Last"             doc:"Last name, can be set also by the exec on activation"`
+	Username  string `json:"username,omitempty"                   minLength:"2"   maxLength:"50"                             doc:"Username, the email when it is not set"`
+}
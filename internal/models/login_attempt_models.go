@@ -0,0 +1,12 @@
+package models
+
+type LoginAttempt struct {
+	Key            string `json:"key"             db:"attempt_key"`
+	FailedAttempts int    `json:"failed_attempts" db:"failed_attempts"`
+	LastFailedAt   string `json:"last_failed_at"  db:"last_failed_at"`
+	LockedUntil    string `json:"locked_until"    db:"locked_until"`
+}
+
+type ExecLockInput struct {
+	Duration string `json:"duration,omitempty" example:"24h" doc:"How long the exec is locked, default is the login lock duration"`
+}
@@ -0,0 +1,18 @@
+package models
+
+type ExecMFA struct {
+	ExecID       int    `json:"exec_id"        db:"exec_id"`
+	Secret       string `json:"-"              db:"secret"`
+	Enabled      bool   `json:"enabled"        db:"enabled"`
+	LastUsedStep int64  `json:"-"              db:"last_used_step"`
+}
+
+type MFACodeInput struct {
+	Code string `json:"code" required:"true" minLength:"6" maxLength:"6" example:"123456" doc:"Code from the authenticator app"`
+}
+
+type MFALoginInput struct {
+	MFAToken     string `json:"mfa_token"               required:"true"                              doc:"Challenge token from the login"`
+	Code         string `json:"code,omitempty"                          maxLength:"6"  example:"123456" doc:"Code from the authenticator app"`
+	RecoveryCode string `json:"recovery_code,omitempty"                 maxLength:"32"                 doc:"One of the recovery codes instead of the code"`
+}
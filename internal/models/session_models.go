@@ -0,0 +1,14 @@
+package models
+
+import "database/sql"
+
+type ExecSession struct {
+	ID               int            `json:"id"         db:"id,omitempty"`
+	ExecID           int            `json:"exec_id"    db:"exec_id"`
+	RefreshTokenHash string         `json:"-"          db:"refresh_token_hash"`
+	UserAgent        string         `json:"user_agent" db:"user_agent"`
+	IPAddress        string         `json:"ip_address" db:"ip_address"`
+	ExpiresAt        string         `json:"expires_at" db:"expires_at"`
+	RevokedAt        sql.NullString `json:"revoked_at" db:"revoked_at"`
+	CreatedAt        sql.NullString `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,17 @@
+package models
+
+// SchoolStats - the counts of the records, the deleted ones are only in the Deleted counts.
+// StudentsByClass counts the students of the active enrollments keyed by the class name,
+// the classes of all academic years with the same name are counted together
+type SchoolStats struct {
+	Classes         int            `json:"classes"`
+	Teachers        int            `json:"teachers"`
+	DeletedTeachers int            `json:"deleted_teachers"`
+	Students        int            `json:"students"`
+	DeletedStudents int            `json:"deleted_students"`
+	Execs           int            `json:"execs"`
+	InactiveExecs   int            `json:"inactive_execs"`
+	DeletedExecs    int            `json:"deleted_execs"`
+	ExecsByRole     map[string]int `json:"execs_by_role"`
+	StudentsByClass map[string]int `json:"students_by_class"`
+}
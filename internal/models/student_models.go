@@ -5,29 +5,32 @@ type Student struct {
 	FirstName string `json:"first_name,omitempty" db:"first_name,omitempty"`
 	LastName  string `json:"last_name,omitempty"  db:"last_name,omitempty"`
 	Email     string `json:"email,omitempty"      db:"email,omitempty"`
-	Class     string `json:"class,omitempty"      db:"class,omitempty"`
+	ClassID   int    `json:"class_id,omitempty"   db:"class_id,omitempty"`
+	DeletedAt string `json:"deleted_at,omitempty"`
 }
 
 type StudentInput struct {
 	FirstName string `json:"first_name" required:"true" minLength:"2" maxLength:"255" example:"Tom"                 doc:"First name of the teacher"`
 	LastName  string `json:"last_name"  required:"true" minLength:"2" maxLength:"255" example:"Last"                doc:"Last name of the techer"`
-	Class     string `json:"class"      required:"true" minLength:"2" maxLength:"50"  example:"10B"                 doc:"The class of the teacher"`
+	ClassID   int    `json:"class_id"   required:"true" minimum:"1"                   example:"1"                   doc:"Id of the class of the student"`
 	Email     string `json:"email"      required:"true"               maxLength:"50"  example:"teacher@example.com" doc:"Email"`
 }
 
 type StudentsQueryInput struct {
-	FirstName string   `query:"first_name"`
-	LastName  string   `query:"last_name"`
-	Class     string   `query:"class"`
-	Email     string   `query:"email"`
-	SortBy    []string `query:"sort_by"    example:"first_name:asc" doc:"Order by asc or desc of the records"`
+	FirstName      string   `query:"first_name"`
+	LastName       string   `query:"last_name"`
+	Class          string   `query:"class"      doc:"Name of the class of the active enrollment"`
+	ClassID        int      `query:"class_id"`
+	Email          string   `query:"email"`
+	SortBy         []string `query:"sort_by"    example:"first_name:asc" doc:"Order by asc or desc of the records"`
+	IncludeDeleted bool     `query:"include_deleted" doc:"Include the soft deleted records, only for admins"`
 }
 
 type StudentUpdateBody struct {
 	ID        int    `json:"id"`
 	FirstName string `json:"first_name" example:"Alice"          doc:"First name of the teacher"`
 	LastName  string `json:"last_name"  example:"Brown"          doc:"Last name of the techer"`
-	Class     string `json:"class"      example:"9C"             doc:"The class of the teacher"`
+	ClassID   int    `json:"class_id"   example:"1"              doc:"Id of the class of the student"`
 	Email     string `json:"email"      example:"ac@example.net" doc:"Email"`
 }
 
@@ -36,5 +39,5 @@ type StudentPatchBody struct {
 	FirstName string `json:"first_name,omitempty" example:"Alice"           doc:"First name of the teacher"`
 	LastName  string `json:"last_name,omitempty"  example:"Brown"           doc:"Last name of the techer"`
 	Email     string `json:"email,omitempty"      example:"ac@example.com " doc:"Email"`
-	Class     string `json:"class,omitempty"      example:"11C"             doc:"The class of the teacher"`
+	ClassID   int    `json:"class_id,omitempty"   example:"1"               doc:"Id of the class of the student"`
 }
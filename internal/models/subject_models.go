@@ -0,0 +1,34 @@
+package models
+
+// Subject - the subject of the catalog the teachers are assigned to teach, the name is
+// unique
+type Subject struct {
+	ID          int    `json:"id"          db:"id,omitempty"`
+	Name        string `json:"name"        db:"name,omitempty"`
+	Code        string `json:"code"        db:"code,omitempty"`
+	Description string `json:"description" db:"description,omitempty"`
+}
+
+type SubjectInput struct {
+	Name        string `json:"name"                  required:"true" minLength:"2" maxLength:"255" example:"History"                  doc:"Name of the subject"`
+	Code        string `json:"code,omitempty"        maxLength:"20"                                example:"HIS"                      doc:"Short code of the subject"`
+	Description string `json:"description,omitempty" maxLength:"255"                               example:"World and local history" doc:"Description of the subject"`
+}
+
+type SubjectsQueryInput struct {
+	Name   string   `query:"name"`
+	Code   string   `query:"code"`
+	SortBy []string `query:"sort_by" example:"name:asc" doc:"Order by asc or desc of the records"`
+}
+
+type SubjectUpdateBody struct {
+	Name        string `json:"name"        minLength:"2"  maxLength:"255" example:"History"                  doc:"Name of the subject"`
+	Code        string `json:"code"        maxLength:"20"                 example:"HIS"                      doc:"Short code of the subject"`
+	Description string `json:"description" maxLength:"255"                example:"World and local history" doc:"Description of the subject"`
+}
+
+type SubjectPatchBody struct {
+	Name        string `json:"name,omitempty"        maxLength:"255" example:"History"                  doc:"Name of the subject"`
+	Code        string `json:"code,omitempty"        maxLength:"20"  example:"HIS"                      doc:"Short code of the subject"`
+	Description string `json:"description,omitempty" maxLength:"255" example:"World and local history" doc:"Description of the subject"`
+}
@@ -0,0 +1,172 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
+)
+
+func TestTemplatesRender(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"welcome.subject.tmpl": "Hello {{.Name}}\n",
+		"welcome.text.tmpl":    "Hi {{.Name}}, open {{.Link}}",
+		"welcome.html.tmpl":    `<a href="{{.Link}}">{{.Name}}</a>`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	templates, err := LoadTemplates(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := templates.Render("welcome", map[string]string{"Name": "<Tom>", "Link": "https://x.test/a?b=1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Subject != "Hello <Tom>" || msg.Text != "Hi <Tom>, open https://x.test/a?b=1" {
+		t.Fatalf("Unexpected text parts %+v", msg)
+	}
+	if msg.HTML != `<a href="https://x.test/a?b=1">&lt;Tom&gt;</a>` {
+		t.Fatalf("Expected escaped html, got %s", msg.HTML)
+	}
+	if _, err := templates.Render("missing", nil); err == nil {
+		t.Fatalf("Expected error for missing template")
+	}
+}
+
+func TestRepositoryTemplates(t *testing.T) {
+	templates, err := LoadTemplates("../../templates/email")
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := templates.Render("password_reset", map[string]any{
+		"ResetLink": "https://school.test/reset",
+		"ExpiresIn": "10m0s",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(msg.Text, "https://school.test/reset") || msg.HTML == "" {
+		t.Fatalf("Unexpected password reset email %+v", msg)
+	}
+}
+
+func TestMessageBytes(t *testing.T) {
+	msg := Message{
+		From:    "noreply@school.test",
+		To:      []string{"a@school.test"},
+		Subject: "Grüße",
+		Text:    "plain",
+		HTML:    "<b>html</b>",
+	}
+	b, err := msg.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := string(b)
+	for _, want := range []string{
+		"To: a@school.test\r\n",
+		"Subject: =?utf-8?q?Gr=C3=BC=C3=9Fe?=\r\n",
+		"Content-Type: multipart/alternative; boundary=",
+		"Content-Type: text/html; charset=utf-8",
+		"<b>html</b>",
+	} {
+		if !strings.Contains(raw, want) {
+			t.Fatalf("Expected %q in the message:\n%s", want, raw)
+		}
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	m, err := NewFileMailer(dir, "noreply@school.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Send(context.Background(), Message{To: []string{"a@school.test"}, Text: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) != 1 {
+		t.Fatalf("Expected one .eml file, got %v", files)
+	}
+}
+
+// flakyMailer - fails the first n deliveries
+type flakyMailer struct {
+	mu       sync.Mutex
+	failures int
+	attempts int
+	sent     []Message
+}
+
+func (f *flakyMailer) Send(ctx context.Context, msg Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.attempts++
+	if f.attempts <= f.failures {
+		return errors.New("mail server down")
+	}
+	f.sent = append(f.sent, msg)
+	return nil
+}
+
+func TestQueueRetries(t *testing.T) {
+	flaky := &flakyMailer{failures: 2}
+	q := NewQueue(flaky, QueueConfig{MaxAttempts: 3, Backoff: time.Second}, logging.Init(false))
+	var waits []time.Duration
+	q.sleep = func(ctx context.Context, d time.Duration) bool {
+		waits = append(waits, d)
+		return true
+	}
+	q.Start()
+
+	if err := q.Send(context.Background(), Message{Subject: "retry"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(flaky.sent) != 1 || flaky.attempts != 3 {
+		t.Fatalf("Expected delivery on the third attempt, got %d attempts", flaky.attempts)
+	}
+	if len(waits) != 2 || waits[0] != time.Second || waits[1] != 2*time.Second {
+		t.Fatalf("Expected doubled backoff, got %v", waits)
+	}
+	if err := q.Send(context.Background(), Message{}); !errors.Is(err, ErrQueueClosed) {
+		t.Fatalf("Expected closed queue error, got %v", err)
+	}
+}
+
+func TestQueueGivesUp(t *testing.T) {
+	flaky := &flakyMailer{failures: 10}
+	q := NewQueue(flaky, QueueConfig{MaxAttempts: 2}, logging.Init(false))
+	q.sleep = func(context.Context, time.Duration) bool { return true }
+	q.Start()
+	_ = q.Send(context.Background(), Message{Subject: "lost"})
+	_ = q.Close(context.Background())
+	if flaky.attempts != 2 || len(flaky.sent) != 0 {
+		t.Fatalf("Expected 2 failed attempts, got %d", flaky.attempts)
+	}
+}
+
+func TestQueueFull(t *testing.T) {
+	q := NewQueue(NewMemoryMailer(), QueueConfig{Size: 1}, logging.Init(false))
+	if err := q.Send(context.Background(), Message{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Send(context.Background(), Message{}); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("Expected full queue error, got %v", err)
+	}
+}
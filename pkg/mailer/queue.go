@@ -0,0 +1,162 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
+)
+
+// ErrQueueFull - the message is not accepted as the queue has no space left
+var ErrQueueFull = errors.New("mail queue is full")
+
+// ErrQueueClosed - the message is not accepted as the queue is stopped
+var ErrQueueClosed = errors.New("mail queue is closed")
+
+// QueueConfig - size of the queue and how the failed deliveries are retried
+type QueueConfig struct {
+	Size        int
+	Workers     int
+	MaxAttempts int
+	// Backoff - delay before the second attempt, doubled for every next one
+	Backoff time.Duration
+	// SendTimeout - time limit for one delivery attempt
+	SendTimeout time.Duration
+}
+
+// Queue - Mailer which returns right away and delivers in the background with retries,
+// so the requests do not wait for the mail server
+type Queue struct {
+	mailer Mailer
+	conf   QueueConfig
+	logger *logging.Logger
+
+	mu     sync.RWMutex
+	closed bool
+	jobs   chan Message
+	wg     sync.WaitGroup
+	// sleep - waits between the attempts, replaced in the tests
+	sleep func(context.Context, time.Duration) bool
+	stop  context.CancelFunc
+	ctx   context.Context
+}
+
+func NewQueue(mailer Mailer, conf QueueConfig, logger *logging.Logger) *Queue {
+	if conf.Size <= 0 {
+		conf.Size = 100
+	}
+	if conf.Workers <= 0 {
+		conf.Workers = 1
+	}
+	if conf.MaxAttempts <= 0 {
+		conf.MaxAttempts = 1
+	}
+	if conf.SendTimeout <= 0 {
+		conf.SendTimeout = 30 * time.Second
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	return &Queue{
+		mailer: mailer,
+		conf:   conf,
+		logger: logger,
+		jobs:   make(chan Message, conf.Size),
+		sleep:  sleepContext,
+		ctx:    ctx,
+		stop:   stop,
+	}
+}
+
+// Start - starts the delivery workers
+func (q *Queue) Start() {
+	for range q.conf.Workers {
+		q.wg.Add(1)
+		go q.worker()
+	}
+}
+
+// Send - puts the message to the queue, the delivery errors are only logged
+func (q *Queue) Send(ctx context.Context, msg Message) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return ErrQueueClosed
+	}
+	select {
+	case q.jobs <- msg:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Close - stops accepting messages and waits for the queued ones to be delivered,
+// when the context is done the pending retries are abandoned
+func (q *Queue) Close(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.jobs)
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		q.stop()
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (q *Queue) worker() {
+	defer q.wg.Done()
+	for msg := range q.jobs {
+		q.deliver(msg)
+	}
+}
+
+func (q *Queue) deliver(msg Message) {
+	backoff := q.conf.Backoff
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(q.ctx, q.conf.SendTimeout)
+		err := q.mailer.Send(ctx, msg)
+		cancel()
+		if err == nil {
+			return
+		}
+		if attempt >= q.conf.MaxAttempts {
+			q.logger.Logging.Errorf(
+				"giving up email %q to %v after %d attempts: %v",
+				msg.Subject,
+				msg.To,
+				attempt,
+				err,
+			)
+			return
+		}
+		q.logger.Logging.Warnf("email %q to %v failed, attempt %d: %v", msg.Subject, msg.To, attempt, err)
+		if !q.sleep(q.ctx, backoff) {
+			return
+		}
+		backoff *= 2
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
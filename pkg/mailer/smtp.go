@@ -0,0 +1,106 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"time"
+)
+
+// SMTPConfig - the smtp server, without username the mail is send without authentication
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+	// StartTLS - upgrade the connection with STARTTLS and fail when the server does not support it
+	StartTLS bool
+	Timeout  time.Duration
+}
+
+type SMTPMailer struct {
+	conf SMTPConfig
+}
+
+func NewSMTPMailer(conf SMTPConfig) *SMTPMailer {
+	if conf.Timeout == 0 {
+		conf.Timeout = 10 * time.Second
+	}
+	return &SMTPMailer{conf: conf}
+}
+
+func (s *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	if msg.From == "" {
+		msg.From = s.conf.From
+	}
+	from, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return fmt.Errorf("invalid from address %v", err)
+	}
+	body, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	dialer := net.Dialer{Timeout: s.conf.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(s.conf.Host, s.conf.Port))
+	if err != nil {
+		return fmt.Errorf("smtp dial failed %v", err)
+	}
+	deadline := time.Now().Add(s.conf.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = conn.SetDeadline(deadline)
+
+	c, err := smtp.NewClient(conn, s.conf.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("smtp handshake failed %v", err)
+	}
+	defer c.Close()
+
+	if s.conf.StartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("smtp server %s does not support STARTTLS", s.conf.Host)
+		}
+		if err := c.StartTLS(&tls.Config{ServerName: s.conf.Host, MinVersion: tls.VersionTLS12}); err != nil {
+			return fmt.Errorf("smtp starttls failed %v", err)
+		}
+	}
+	if s.conf.Username != "" {
+		// PlainAuth refuses to send the password over not encrypted connection except to localhost
+		auth := smtp.PlainAuth("", s.conf.Username, s.conf.Password, s.conf.Host)
+		if err := c.Auth(auth); err != nil {
+			return fmt.Errorf("smtp auth failed %v", err)
+		}
+	}
+
+	if err := c.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, to := range msg.To {
+		addr, err := mail.ParseAddress(to)
+		if err != nil {
+			return fmt.Errorf("invalid recipient %v", err)
+		}
+		if err := c.Rcpt(addr.Address); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(body); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
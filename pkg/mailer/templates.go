@@ -0,0 +1,115 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Templates - the email templates from the directory, every email has
+// <name>.subject.tmpl, <name>.text.tmpl and optional <name>.html.tmpl
+type Templates struct {
+	subjects map[string]*texttemplate.Template
+	texts    map[string]*texttemplate.Template
+	htmls    map[string]*htmltemplate.Template
+}
+
+func LoadTemplates(dir string) (*Templates, error) {
+	t := &Templates{
+		subjects: make(map[string]*texttemplate.Template),
+		texts:    make(map[string]*texttemplate.Template),
+		htmls:    make(map[string]*htmltemplate.Template),
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		base := strings.TrimSuffix(filepath.Base(file), ".tmpl")
+		name, kind, found := strings.Cut(base, ".")
+		if !found {
+			return nil, fmt.Errorf("template %s must be named <name>.<subject|text|html>.tmpl", file)
+		}
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		switch kind {
+		case "subject":
+			t.subjects[name], err = texttemplate.New(base).Parse(strings.TrimSpace(string(content)))
+		case "text":
+			t.texts[name], err = texttemplate.New(base).Parse(string(content))
+		case "html":
+			t.htmls[name], err = htmltemplate.New(base).Parse(string(content))
+		default:
+			return nil, fmt.Errorf("unknown template kind %s in %s", kind, file)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("template %s %v", file, err)
+		}
+	}
+	for name := range t.texts {
+		if _, ok := t.subjects[name]; !ok {
+			return nil, fmt.Errorf("template %s has no subject", name)
+		}
+	}
+	return t, nil
+}
+
+// Render - the message from the templates of the name, the recipients are set by the caller
+func (t *Templates) Render(name string, data any) (Message, error) {
+	subject, ok := t.subjects[name]
+	text, textOK := t.texts[name]
+	if !ok || !textOK {
+		return Message{}, fmt.Errorf("email template %s not found", name)
+	}
+
+	var msg Message
+	var buf bytes.Buffer
+	if err := subject.Execute(&buf, data); err != nil {
+		return Message{}, err
+	}
+	msg.Subject = buf.String()
+
+	buf.Reset()
+	if err := text.Execute(&buf, data); err != nil {
+		return Message{}, err
+	}
+	msg.Text = buf.String()
+
+	if html, ok := t.htmls[name]; ok {
+		buf.Reset()
+		if err := html.Execute(&buf, data); err != nil {
+			return Message{}, err
+		}
+		msg.HTML = buf.String()
+	}
+	return msg, nil
+}
+
+// Sender - renders the email templates and hands the message to the mailer
+type Sender struct {
+	mailer    Mailer
+	templates *Templates
+}
+
+func NewSender(mailer Mailer, templates *Templates) *Sender {
+	return &Sender{
+		mailer:    mailer,
+		templates: templates,
+	}
+}
+
+func (s *Sender) SendTemplate(ctx context.Context, name, to string, data any) error {
+	msg, err := s.templates.Render(name, data)
+	if err != nil {
+		return err
+	}
+	msg.To = []string{to}
+	return s.mailer.Send(ctx, msg)
+}
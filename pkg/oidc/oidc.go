@@ -0,0 +1,307 @@
+// Package oidc - minimal OpenID Connect relying party for the authorization code flow
+// with PKCE, discovery and verification of the id token against the JWKS of the provider
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Config - the client registration at the identity provider
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// Claims - the verified claims of the id token used to find the exec
+type Claims struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+}
+
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// Provider - the identity provider, the discovery document and the keys are loaded on first use
+// so the api starts also when the provider is not reachable
+type Provider struct {
+	conf   Config
+	client *http.Client
+
+	mu        sync.Mutex
+	discovery *discoveryDocument
+	keys      map[string]*rsa.PublicKey
+}
+
+func NewProvider(conf Config, client *http.Client) *Provider {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if len(conf.Scopes) == 0 {
+		conf.Scopes = []string{"openid", "email", "profile"}
+	}
+	return &Provider{
+		conf:   conf,
+		client: client,
+		keys:   make(map[string]*rsa.PublicKey),
+	}
+}
+
+// RandomString - random url safe value for the state, nonce and code verifier
+func RandomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CodeChallenge - the S256 PKCE challenge of the code verifier
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func (p *Provider) loadDiscovery(ctx context.Context) (*discoveryDocument, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	wellKnown := strings.TrimSuffix(p.conf.Issuer, "/") + "/.well-known/openid-configuration"
+	var doc discoveryDocument
+	if err := p.getJSON(ctx, wellKnown, &doc); err != nil {
+		return nil, fmt.Errorf("oidc discovery failed %v", err)
+	}
+	if doc.Issuer != p.conf.Issuer {
+		return nil, fmt.Errorf("oidc issuer mismatch, expected %s got %s", p.conf.Issuer, doc.Issuer)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return nil, errors.New("oidc discovery document is incomplete")
+	}
+	p.discovery = &doc
+	return p.discovery, nil
+}
+
+// AuthCodeURL - where to redirect the browser to login at the provider
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error) {
+	doc, err := p.loadDiscovery(ctx)
+	if err != nil {
+		return "", err
+	}
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.conf.ClientID},
+		"redirect_uri":          {p.conf.RedirectURL},
+		"scope":                 {strings.Join(p.conf.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {CodeChallenge(codeVerifier)},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(doc.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return doc.AuthorizationEndpoint + sep + params.Encode(), nil
+}
+
+// Exchange - exchanges the authorization code for the raw id token
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier string) (string, error) {
+	doc, err := p.loadDiscovery(ctx)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.conf.RedirectURL},
+		"client_id":     {p.conf.ClientID},
+		"code_verifier": {codeVerifier},
+	}
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		doc.TokenEndpoint,
+		strings.NewReader(form.Encode()),
+	)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.conf.ClientID), url.QueryEscape(p.conf.ClientSecret))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("oidc token request failed %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("oidc token endpoint returned %d: %s", resp.StatusCode, body)
+	}
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &tokens); err != nil {
+		return "", fmt.Errorf("invalid oidc token response %v", err)
+	}
+	if tokens.IDToken == "" {
+		return "", errors.New("oidc token response without id_token")
+	}
+	return tokens.IDToken, nil
+}
+
+// VerifyIDToken - checks the signature, issuer, audience, expiry and nonce of the id token
+func (p *Provider) VerifyIDToken(
+	ctx context.Context,
+	rawIDToken, nonce string,
+	now time.Time,
+) (*Claims, error) {
+	if _, err := p.loadDiscovery(ctx); err != nil {
+		return nil, err
+	}
+	parsed, err := jwt.Parse(
+		rawIDToken,
+		func(t *jwt.Token) (any, error) {
+			kid, _ := t.Header["kid"].(string)
+			return p.publicKey(ctx, kid)
+		},
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithIssuer(p.conf.Issuer),
+		jwt.WithAudience(p.conf.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithTimeFunc(func() time.Time { return now }),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id token %w", err)
+	}
+	claims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("invalid id token claims")
+	}
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce == "" || tokenNonce != nonce {
+		return nil, errors.New("id token nonce mismatch")
+	}
+
+	subject, _ := claims.GetSubject()
+	if subject == "" {
+		return nil, errors.New("id token without subject")
+	}
+	email, _ := claims["email"].(string)
+	emailVerified, _ := claims["email_verified"].(bool)
+	return &Claims{
+		Issuer:        p.conf.Issuer,
+		Subject:       subject,
+		Email:         email,
+		EmailVerified: emailVerified,
+	}, nil
+}
+
+// publicKey - the signing key by kid, the JWKS is loaded again when the kid is unknown
+// as the provider rotates its keys
+func (p *Provider) publicKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	key, ok := p.keys[kid]
+	jwksURI := p.discovery.JWKSURI
+	p.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := p.getJSON(ctx, jwksURI, &set); err != nil {
+		return nil, fmt.Errorf("oidc jwks request failed %v", err)
+	}
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		pub, err := rsaPublicKey(k)
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = pub
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.mu.Unlock()
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown id token signing key %q", kid)
+}
+
+func rsaPublicKey(k jsonWebKey) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, err
+	}
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}, nil
+}
+
+func (p *Provider) getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
@@ -0,0 +1,87 @@
+package oidc
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/oidc/oidctest"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newTestProvider(t *testing.T) (*Provider, *oidctest.Server) {
+	idp := oidctest.NewServer("school-api", "secret")
+	t.Cleanup(idp.Close)
+	p := NewProvider(Config{
+		Issuer:       idp.Issuer(),
+		ClientID:     "school-api",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost:8082/execs/oidc/callback",
+	}, idp.Client())
+	return p, idp
+}
+
+// authorize - follows the login at the stub provider and returns the code from the redirect
+func authorize(t *testing.T, p *Provider, idp *oidctest.Server, state, nonce, verifier string) string {
+	authURL, err := p.AuthCodeURL(context.Background(), state, nonce, verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := idp.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if location.Query().Get("state") != state {
+		t.Fatalf("Expected state %s in the redirect, got %s", state, location)
+	}
+	return location.Query().Get("code")
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	p, idp := newTestProvider(t)
+	ctx := context.Background()
+
+	code := authorize(t, p, idp, "state-1", "nonce-1", "verifier-1")
+	if _, err := p.Exchange(ctx, code, "wrong-verifier"); err == nil {
+		t.Fatalf("Expected exchange with wrong PKCE verifier to fail")
+	}
+
+	code = authorize(t, p, idp, "state-1", "nonce-1", "verifier-1")
+	idToken, err := p.Exchange(ctx, code, "verifier-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := p.VerifyIDToken(ctx, idToken, "nonce-1", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != idp.Subject || claims.Email != idp.Email || !claims.EmailVerified {
+		t.Fatalf("Unexpected claims %+v", claims)
+	}
+	if _, err := p.VerifyIDToken(ctx, idToken, "other-nonce", time.Now()); err == nil {
+		t.Fatalf("Expected nonce mismatch to fail")
+	}
+	if _, err := p.VerifyIDToken(ctx, idToken, "nonce-1", time.Now().Add(time.Hour)); err == nil {
+		t.Fatalf("Expected expired id token to fail")
+	}
+}
+
+func TestVerifyIDTokenRejectsOtherAudience(t *testing.T) {
+	p, idp := newTestProvider(t)
+	idToken, err := idp.SignIDToken(jwt.MapClaims{"nonce": "n", "aud": "other-client"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.VerifyIDToken(context.Background(), idToken, "n", time.Now()); err == nil {
+		t.Fatalf("Expected id token for other client to fail")
+	}
+}
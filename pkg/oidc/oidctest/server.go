@@ -0,0 +1,185 @@
+// Package oidctest - local stub identity provider for testing the oidc login without network
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const keyID = "oidctest-key"
+
+type authRequest struct {
+	nonce         string
+	codeChallenge string
+	redirectURI   string
+}
+
+// Server - identity provider which logs in the configured user without asking anything
+type Server struct {
+	*httptest.Server
+	ClientID      string
+	ClientSecret  string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	// Now - the clock for the issued id tokens
+	Now func() time.Time
+
+	key   *rsa.PrivateKey
+	mu    sync.Mutex
+	codes map[string]authRequest
+}
+
+// NewServer - starts the stub provider, it has to be closed by the caller
+func NewServer(clientID, clientSecret string) *Server {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	s := &Server{
+		ClientID:      clientID,
+		ClientSecret:  clientSecret,
+		Subject:       "stub-subject",
+		Email:         "admin@example.com",
+		EmailVerified: true,
+		Now:           time.Now,
+		key:           key,
+		codes:         make(map[string]authRequest),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("GET /jwks", s.jwks)
+	mux.HandleFunc("GET /authorize", s.authorize)
+	mux.HandleFunc("POST /token", s.token)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Issuer - the issuer url to configure in the relying party
+func (s *Server) Issuer() string {
+	return s.URL
+}
+
+func (s *Server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]string{
+		"issuer":                 s.URL,
+		"authorization_endpoint": s.URL + "/authorize",
+		"token_endpoint":         s.URL + "/token",
+		"jwks_uri":               s.URL + "/jwks",
+	})
+}
+
+func (s *Server) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := s.key.PublicKey
+	writeJSON(w, map[string]any{
+		"keys": []map[string]string{{
+			"kid": keyID,
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+// authorize - redirects back to the client right away with new code
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != s.ClientID || q.Get("response_type") != "code" {
+		http.Error(w, "invalid client", http.StatusBadRequest)
+		return
+	}
+	redirect, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || redirect.Scheme == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	code := rand.Text()
+	s.mu.Lock()
+	s.codes[code] = authRequest{
+		nonce:         q.Get("nonce"),
+		codeChallenge: q.Get("code_challenge"),
+		redirectURI:   q.Get("redirect_uri"),
+	}
+	s.mu.Unlock()
+
+	params := redirect.Query()
+	params.Set("code", code)
+	params.Set("state", q.Get("state"))
+	redirect.RawQuery = params.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok || clientID != s.ClientID || clientSecret != s.ClientSecret {
+		http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, `{"error":"invalid_request"}`, http.StatusBadRequest)
+		return
+	}
+	code := r.PostForm.Get("code")
+	s.mu.Lock()
+	req, ok := s.codes[code]
+	delete(s.codes, code)
+	s.mu.Unlock()
+
+	verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || req.redirectURI != r.PostForm.Get("redirect_uri") ||
+		base64.RawURLEncoding.EncodeToString(verifier[:]) != req.codeChallenge {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+
+	idToken, err := s.SignIDToken(jwt.MapClaims{"nonce": req.nonce})
+	if err != nil {
+		http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, map[string]any{
+		"access_token": rand.Text(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+// SignIDToken - id token for the configured user, the extra claims override the defaults
+func (s *Server) SignIDToken(extra jwt.MapClaims) (string, error) {
+	now := s.Now()
+	claims := jwt.MapClaims{
+		"iss":            s.URL,
+		"aud":            s.ClientID,
+		"sub":            s.Subject,
+		"email":          s.Email,
+		"email_verified": s.EmailVerified,
+		"iat":            jwt.NewNumericDate(now),
+		"exp":            jwt.NewNumericDate(now.Add(5 * time.Minute)),
+	}
+	for k, v := range extra {
+		claims[k] = v
+	}
+	tkn := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	tkn.Header["kid"] = keyID
+	return tkn.SignedString(s.key)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
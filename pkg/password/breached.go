@@ -0,0 +1,66 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// BreachedList - SHA-1 hashes of known breached passwords, in the format of the
+// Pwned Passwords downloads, one HASH or HASH:COUNT per line
+type BreachedList struct {
+	hashes map[[sha1.Size]byte]struct{}
+}
+
+// LoadBreachedList - reads the list from the file, empty path returns nil list which contains nothing
+func LoadBreachedList(path string) (*BreachedList, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	list := &BreachedList{hashes: make(map[[sha1.Size]byte]struct{})}
+	scanner := bufio.NewScanner(f)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		hash, _, _ := strings.Cut(text, ":")
+		var sum [sha1.Size]byte
+		if n, err := hex.Decode(sum[:], []byte(hash)); err != nil || n != sha1.Size {
+			return nil, fmt.Errorf("%s:%d invalid sha1 hash", path, line)
+		}
+		list.hashes[sum] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Contains reports whether the password is in the list
+func (b *BreachedList) Contains(password string) bool {
+	if b == nil {
+		return false
+	}
+	_, ok := b.hashes[sha1.Sum([]byte(password))]
+	return ok
+}
+
+// Len - number of the hashes in the list
+func (b *BreachedList) Len() int {
+	if b == nil {
+		return 0
+	}
+	return len(b.hashes)
+}
@@ -0,0 +1,38 @@
+package password
+
+import (
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/config"
+)
+
+// PolicyFromConfig - the policy for the new passwords of the execs, the api and schoolctl
+// check the passwords with the same policy
+func PolicyFromConfig(conf config.Config) (Policy, error) {
+	breached, err := LoadBreachedList(conf.BreachedPasswordsFile)
+	if err != nil {
+		return Policy{}, err
+	}
+	return Policy{
+		MinLength:        conf.PasswordMinLength,
+		RequireUpper:     conf.PasswordRequireUpper,
+		RequireLower:     conf.PasswordRequireLower,
+		RequireDigit:     conf.PasswordRequireDigit,
+		RequireSymbol:    conf.PasswordRequireSymbol,
+		DisallowIdentity: conf.PasswordDisallowIdentity,
+		HistorySize:      conf.PasswordHistory,
+		Breached:         breached,
+	}, nil
+}
+
+// ParamsFromConfig - the argon2id parameters from the config, the defaults when they are not set
+func ParamsFromConfig(conf config.Config) Params {
+	if conf.Argon2Iterations == 0 || conf.Argon2Memory == 0 || conf.Argon2Parallelism == 0 {
+		return DefaultParams
+	}
+	return Params{
+		Memory:      uint32(conf.Argon2Memory),
+		Iterations:  uint32(conf.Argon2Iterations),
+		Parallelism: uint8(conf.Argon2Parallelism),
+		SaltLength:  DefaultParams.SaltLength,
+		KeyLength:   DefaultParams.KeyLength,
+	}
+}
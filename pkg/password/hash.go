@@ -0,0 +1,131 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Params - argon2id parameters, they are stored in the encoded hash so they can be
+// raised later without breaking the existing passwords
+type Params struct {
+	// Memory in KiB
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultParams - the parameters used before they were configurable
+var DefaultParams = Params{
+	Memory:      64 * 1024,
+	Iterations:  1,
+	Parallelism: 4,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// legacyParams - the hard coded parameters of the old salt.hash format
+var legacyParams = DefaultParams
+
+var errInvalidHash = errors.New("invalid encoded hash format")
+
+// Hash - argon2id hash in the PHC string format $argon2id$v=19$m=...,t=...,p=...$salt$hash
+func Hash(password string, p Params) (string, error) {
+	salt := make([]byte, p.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		p.Memory,
+		p.Iterations,
+		p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify - compares the password with the encoded hash in constant time. It accepts the PHC
+// format and the legacy salt.hash format, rehash is true when the password matches but the
+// hash should be replaced with new one made with the current parameters
+func Verify(password, encodedHash string, current Params) (match bool, rehash bool, err error) {
+	var p Params
+	var salt, key []byte
+	legacy := !strings.HasPrefix(encodedHash, "$")
+	if legacy {
+		p = legacyParams
+		salt, key, err = decodeLegacy(encodedHash)
+	} else {
+		p, salt, key, err = decodePHC(encodedHash)
+	}
+	if err != nil {
+		return false, false, err
+	}
+
+	inputKey := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, inputKey) != 1 {
+		return false, false, nil
+	}
+	rehash = legacy ||
+		p.Memory != current.Memory ||
+		p.Iterations != current.Iterations ||
+		p.Parallelism != current.Parallelism ||
+		uint32(len(salt)) < current.SaltLength ||
+		uint32(len(key)) != current.KeyLength
+	return true, rehash, nil
+}
+
+func decodeLegacy(encodedHash string) ([]byte, []byte, error) {
+	saltBase64, keyBase64, found := strings.Cut(encodedHash, ".")
+	if !found {
+		return nil, nil, errInvalidHash
+	}
+	salt, err := base64.StdEncoding.DecodeString(saltBase64)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode the salt %v", err)
+	}
+	key, err := base64.StdEncoding.DecodeString(keyBase64)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode the hashed password %v", err)
+	}
+	return salt, key, nil
+}
+
+func decodePHC(encodedHash string) (Params, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=..,t=..,p=..", salt, hash
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return Params{}, nil, nil, errInvalidHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return Params{}, nil, nil, fmt.Errorf("unsupported argon2 version %s", parts[2])
+	}
+	var p Params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil {
+		return Params{}, nil, nil, errInvalidHash
+	}
+	if p.Iterations == 0 || p.Parallelism == 0 {
+		return Params{}, nil, nil, errInvalidHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Params{}, nil, nil, fmt.Errorf("failed to decode the salt %v", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return Params{}, nil, nil, fmt.Errorf("failed to decode the hashed password %v", err)
+	}
+	p.SaltLength = uint32(len(salt))
+	p.KeyLength = uint32(len(key))
+	return p, salt, key, nil
+}
@@ -0,0 +1,70 @@
+package password
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// cheap parameters to keep the tests fast
+var testParams = Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestHashAndVerify(t *testing.T) {
+	encoded, err := Hash("Correct-Horse-7", testParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(encoded, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Fatalf("Expected PHC format, got %s", encoded)
+	}
+
+	match, rehash, err := Verify("Correct-Horse-7", encoded, testParams)
+	if err != nil || !match || rehash {
+		t.Fatalf("Expected match without rehash, got %v %v %v", match, rehash, err)
+	}
+	if match, _, _ := Verify("wrong", encoded, testParams); match {
+		t.Fatalf("Expected wrong password not to match")
+	}
+
+	upgraded := testParams
+	upgraded.Iterations = 2
+	match, rehash, err = Verify("Correct-Horse-7", encoded, upgraded)
+	if err != nil || !match || !rehash {
+		t.Fatalf("Expected rehash after the parameters are upgraded, got %v %v %v", match, rehash, err)
+	}
+}
+
+func TestVerifyLegacyFormat(t *testing.T) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		t.Fatal(err)
+	}
+	key := argon2.IDKey([]byte("secret"), salt, 1, 64*1024, 4, 32)
+	legacy := base64.StdEncoding.EncodeToString(salt) + "." + base64.StdEncoding.EncodeToString(key)
+
+	match, rehash, err := Verify("secret", legacy, DefaultParams)
+	if err != nil || !match || !rehash {
+		t.Fatalf("Expected legacy hash to match and need rehash, got %v %v %v", match, rehash, err)
+	}
+	if match, _, _ := Verify("wrong", legacy, DefaultParams); match {
+		t.Fatalf("Expected wrong password not to match legacy hash")
+	}
+}
+
+func TestVerifyInvalidHash(t *testing.T) {
+	for _, encoded := range []string{
+		"",
+		"nodot",
+		"$argon2i$v=19$m=1024,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=16$m=1024,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=1024,t=0,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$garbage$c2FsdA$a2V5",
+	} {
+		if _, _, err := Verify("secret", encoded, testParams); err == nil {
+			t.Fatalf("Expected error for %q", encoded)
+		}
+	}
+}
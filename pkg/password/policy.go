@@ -0,0 +1,105 @@
+// Package password - the password policy of the execs and the check against breached passwords
+package password
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Policy - rules for new passwords, the zero value accepts everything
+type Policy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// DisallowIdentity - the password must not contain the username or the email
+	DisallowIdentity bool
+	// HistorySize - how many previous passwords can not be used again
+	HistorySize int
+	Breached    *BreachedList
+}
+
+// Check - returns the broken rules as messages for the client, empty when the password is accepted.
+// The identity is the username and the email of the exec
+func (p Policy) Check(password string, identity ...string) []string {
+	var violations []string
+	if n := len([]rune(password)); n < p.MinLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters long", p.MinLength))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if p.RequireUpper && !upper {
+		violations = append(violations, "must contain an upper case letter")
+	}
+	if p.RequireLower && !lower {
+		violations = append(violations, "must contain a lower case letter")
+	}
+	if p.RequireDigit && !digit {
+		violations = append(violations, "must contain a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		violations = append(violations, "must contain a symbol")
+	}
+
+	if p.DisallowIdentity {
+		lowered := strings.ToLower(password)
+		for _, id := range identityParts(identity) {
+			if strings.Contains(lowered, id) {
+				violations = append(violations, "must not contain the username or the email")
+				break
+			}
+		}
+	}
+
+	if p.Breached.Contains(password) {
+		violations = append(violations, "is in a list of breached passwords, choose another one")
+	}
+	return violations
+}
+
+// CheckHistory - the broken rule when the password is one of the previous passwords, the
+// history is the hashes of the last HistorySize passwords of the exec
+func (p Policy) CheckHistory(password string, history []string, params Params) []string {
+	if p.HistorySize <= 0 {
+		return nil
+	}
+	for _, hash := range history {
+		if ok, _, _ := Verify(password, hash, params); ok {
+			return []string{fmt.Sprintf("must not be one of the last %d passwords", p.HistorySize)}
+		}
+	}
+	return nil
+}
+
+// identityParts - the username, the email and its local part in lower case,
+// the too short parts are skipped as they would match too many passwords
+func identityParts(identity []string) []string {
+	var parts []string
+	for _, id := range identity {
+		id = strings.ToLower(strings.TrimSpace(id))
+		candidates := []string{id}
+		if local, _, found := strings.Cut(id, "@"); found {
+			candidates = append(candidates, local)
+		}
+		for _, c := range candidates {
+			if len(c) >= 3 {
+				parts = append(parts, c)
+			}
+		}
+	}
+	return parts
+}
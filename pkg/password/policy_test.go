@@ -0,0 +1,102 @@
+package password
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/config"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	p := Policy{
+		MinLength:        10,
+		RequireUpper:     true,
+		RequireLower:     true,
+		RequireDigit:     true,
+		RequireSymbol:    true,
+		DisallowIdentity: true,
+	}
+	tests := []struct {
+		password string
+		want     []string
+	}{
+		{"Correct-Horse-7", nil},
+		{"Short-1a", []string{"must be at least 10 characters long"}},
+		{"lowercase-only-1", []string{"must contain an upper case letter"}},
+		{"NoDigitsHere!", []string{"must contain a digit"}},
+		{"NoSymbols12345", []string{"must contain a symbol"}},
+		{"My-Jdoe-Password-1", []string{"must not contain the username or the email"}},
+		{"Mail-John.Doe-1", []string{"must not contain the username or the email"}},
+	}
+	for _, tt := range tests {
+		got := p.Check(tt.password, "jdoe", "john.doe@example.com")
+		if !slices.Equal(got, tt.want) {
+			t.Fatalf("%q: expected %v, got %v", tt.password, tt.want, got)
+		}
+	}
+
+	if got := (Policy{}).Check("x"); len(got) != 0 {
+		t.Fatalf("Expected zero policy to accept everything, got %v", got)
+	}
+}
+
+func TestPolicyCheckHistory(t *testing.T) {
+	params := Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	hash, err := Hash("Old-Password-1", params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	history := []string{hash}
+	p := Policy{HistorySize: 3}
+	want := []string{"must not be one of the last 3 passwords"}
+	if got := p.CheckHistory("Old-Password-1", history, params); !slices.Equal(got, want) {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+	if got := p.CheckHistory("New-Password-1", history, params); len(got) != 0 {
+		t.Fatalf("Expected new password accepted, got %v", got)
+	}
+	if got := (Policy{}).CheckHistory("Old-Password-1", history, params); len(got) != 0 {
+		t.Fatalf("Expected the history ignored without history size, got %v", got)
+	}
+}
+
+func TestParamsFromConfig(t *testing.T) {
+	if got := ParamsFromConfig(config.Config{}); got != DefaultParams {
+		t.Fatalf("Expected the default params without config, got %+v", got)
+	}
+	got := ParamsFromConfig(config.Config{Argon2Memory: 1024, Argon2Iterations: 2, Argon2Parallelism: 1})
+	if got.Memory != 1024 || got.Iterations != 2 || got.Parallelism != 1 || got.KeyLength != DefaultParams.KeyLength {
+		t.Fatalf("Expected the params of the config, got %+v", got)
+	}
+}
+
+func TestBreachedList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	// sha1 of "password" and "123456" in the Pwned Passwords format
+	content := "# breached\n5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:3861493\n7c4a8d09ca3762af61e59520943dc26494f8941b\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	list, err := LoadBreachedList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Len() != 2 || !list.Contains("password") || !list.Contains("123456") {
+		t.Fatalf("Expected both breached passwords in the list")
+	}
+	if list.Contains("Correct-Horse-7") {
+		t.Fatalf("Expected password not to be in the list")
+	}
+
+	p := Policy{Breached: list}
+	if got := p.Check("password"); len(got) != 1 {
+		t.Fatalf("Expected breached violation, got %v", got)
+	}
+
+	var empty *BreachedList
+	if empty.Contains("password") {
+		t.Fatalf("Expected nil list to contain nothing")
+	}
+}
@@ -0,0 +1,58 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// secret of the RFC 6238 test vectors for SHA1
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).
+	EncodeToString([]byte("12345678901234567890"))
+
+func TestCodeRFC6238Vectors(t *testing.T) {
+	// the RFC uses 8 digits, the 6 digit code is the last 6 digits of it
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Fatalf("time %d: expected %s, got %s", tt.unix, tt.want, got)
+		}
+	}
+}
+
+func TestValidateSkew(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	code, _ := Code(rfcSecret, now)
+
+	if step, ok := Validate(rfcSecret, code, now.Add(Period)); !ok || step != Step(now) {
+		t.Fatalf("Expected code of previous step to be valid")
+	}
+	if _, ok := Validate(rfcSecret, code, now.Add(3*Period)); ok {
+		t.Fatalf("Expected old code to be rejected")
+	}
+	if _, ok := Validate(rfcSecret, "12345", now); ok {
+		t.Fatalf("Expected short code to be rejected")
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := ProvisioningURI("School API", "admin", "ABCDEF")
+	if !strings.HasPrefix(uri, "otpauth://totp/School%20API:admin?") ||
+		!strings.Contains(uri, "secret=ABCDEF") {
+		t.Fatalf("Unexpected provisioning uri %s", uri)
+	}
+}
@@ -2,10 +2,13 @@
 package utils
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
-	"net/smtp"
 	"reflect"
 	"regexp"
 	"strings"
@@ -13,7 +16,6 @@ import (
 
 	"github.com/dkr290/go-advanced-projects/rest-api-school-management/config"
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/argon2"
 )
 
 func GenereateInsertQuery(model any, name string) string {
@@ -71,20 +73,7 @@ func EmailCheck(email string) error {
 	return nil
 }
 
-func PasswordHash(password string) (string, error) {
-	salt := make([]byte, 16)
-	_, err := rand.Read(salt)
-	if err != nil {
-		return "", err
-	}
-	hash := argon2.IDKey([]byte(password), salt, 1, 64*1024, 4, 32)
-	saltBase64 := base64.StdEncoding.EncodeToString(salt)
-	hashBase64 := base64.StdEncoding.EncodeToString(hash)
-	encodedHash := fmt.Sprintf("%s.%s", saltBase64, hashBase64)
-	return encodedHash, nil
-}
-
-func SighnToken(userID, username, role string, config config.Config) (string, error) {
+func SighnToken(userID, username, role, sessionID string, config config.Config) (string, error) {
 	jwtSecret := config.JWTSecret
 	jwtExpiresIn := config.JWTExpiresIn
 
@@ -92,9 +81,12 @@ func SighnToken(userID, username, role string, config config.Config) (string, er
 		"uid":  userID,
 		"user": username,
 		"role": role,
+		"sid":  sessionID,
 	}
 
-	claims["exp"] = jwt.NewNumericDate(time.Now().Add(jwtExpiresIn))
+	now := time.Now()
+	claims["iat"] = jwt.NewNumericDate(now)
+	claims["exp"] = jwt.NewNumericDate(now.Add(jwtExpiresIn))
 
 	tkn := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
@@ -106,24 +98,146 @@ func SighnToken(userID, username, role string, config config.Config) (string, er
 	return signedToken, nil
 }
 
-func SendResetEmail(
-	to, message string,
-	host string,
-	port string,
-) error {
-	from := "noreply@school-rest-api.example.com"
-	subject := "Your password reset link"
-
-	body := message
-
-	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s",
-		from, to, subject, body)
-
-	return smtp.SendMail(
-		fmt.Sprintf("%s:%s", host, port),
-		nil,
-		from,
-		[]string{to},
-		[]byte(msg),
+// SignMFAChallenge - short lived token which proves the password was correct,
+// it has no session so the jwt middleware does not accept it as access token
+func SignMFAChallenge(userID string, config config.Config, now time.Time) (string, error) {
+	claims := jwt.MapClaims{
+		"uid": userID,
+		"typ": "mfa",
+		"iat": jwt.NewNumericDate(now),
+		"exp": jwt.NewNumericDate(now.Add(config.MFAChallengeExpiresIn)),
+	}
+	tkn := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signedToken, err := tkn.SignedString([]byte(config.JWTSecret))
+	if err != nil {
+		return "", fmt.Errorf("internal error %v", err)
+	}
+	return signedToken, nil
+}
+
+// ParseMFAChallenge - returns the user id from valid not expired mfa challenge token
+func ParseMFAChallenge(token string, config config.Config, now time.Time) (string, error) {
+	parsed, err := jwt.Parse(
+		token,
+		func(t *jwt.Token) (any, error) { return []byte(config.JWTSecret), nil },
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithTimeFunc(func() time.Time { return now }),
+		jwt.WithExpirationRequired(),
 	)
+	if err != nil {
+		return "", err
+	}
+	claims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok || claims["typ"] != "mfa" {
+		return "", fmt.Errorf("not a mfa challenge token")
+	}
+	userID, ok := claims["uid"].(string)
+	if !ok || userID == "" {
+		return "", fmt.Errorf("mfa challenge token without user")
+	}
+	return userID, nil
+}
+
+// SignOIDCState - the state, nonce and pkce verifier of the oidc login kept in signed cookie
+// between the redirect to the identity provider and the callback
+func SignOIDCState(state, nonce, verifier string, config config.Config, now time.Time) (string, error) {
+	claims := jwt.MapClaims{
+		"typ":      "oidc_state",
+		"state":    state,
+		"nonce":    nonce,
+		"verifier": verifier,
+		"exp":      jwt.NewNumericDate(now.Add(10 * time.Minute)),
+	}
+	tkn := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signedToken, err := tkn.SignedString([]byte(config.JWTSecret))
+	if err != nil {
+		return "", fmt.Errorf("internal error %v", err)
+	}
+	return signedToken, nil
+}
+
+// ParseOIDCState - returns the state, nonce and pkce verifier from the cookie
+func ParseOIDCState(token string, config config.Config, now time.Time) (string, string, string, error) {
+	parsed, err := jwt.Parse(
+		token,
+		func(t *jwt.Token) (any, error) { return []byte(config.JWTSecret), nil },
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithTimeFunc(func() time.Time { return now }),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return "", "", "", err
+	}
+	claims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok || claims["typ"] != "oidc_state" {
+		return "", "", "", fmt.Errorf("not a oidc state token")
+	}
+	state, _ := claims["state"].(string)
+	nonce, _ := claims["nonce"].(string)
+	verifier, _ := claims["verifier"].(string)
+	return state, nonce, verifier, nil
+}
+
+// EncryptSecret - AES-GCM encryption with key derived from the passphrase, the nonce is prepended
+func EncryptSecret(plaintext, passphrase string) (string, error) {
+	gcm, err := newGCM(passphrase)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptSecret - reverse of EncryptSecret
+func DecryptSecret(ciphertext, passphrase string) (string, error) {
+	gcm, err := newGCM(passphrase)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("encrypted secret too short")
+	}
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+func newGCM(passphrase string) (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte(passphrase))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// GenerateToken - random token in hex for the client and its sha256 hash in hex for the database
+func GenerateToken() (string, string, error) {
+	tokenBytes := make([]byte, 32)
+	_, err := rand.Read(tokenBytes)
+	if err != nil {
+		return "", "", err
+	}
+	hashedToken := sha256.Sum256(tokenBytes)
+	return hex.EncodeToString(tokenBytes), hex.EncodeToString(hashedToken[:]), nil
+}
+
+// HashToken - sha256 hash in hex of the hex token received from the client
+func HashToken(token string) (string, error) {
+	tokenBytes, err := hex.DecodeString(token)
+	if err != nil {
+		return "", err
+	}
+	hashedToken := sha256.Sum256(tokenBytes)
+	return hex.EncodeToString(hashedToken[:]), nil
 }
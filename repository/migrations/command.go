@@ -0,0 +1,55 @@
+package migrations
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+)
+
+// Usage - the arguments of the migrate command
+const Usage = "migrate up|down [steps]|status"
+
+// Run - the migrate command of the binaries, args are the arguments after "migrate"
+// and the results are written to w
+func (m *Migrator) Run(args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: %s", Usage)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := m.Up()
+		fmt.Fprintf(w, "applied %d migrations\n", applied)
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid steps %q, usage: %s", args[1], Usage)
+			}
+			steps = n
+		}
+		reverted, err := m.Down(steps)
+		fmt.Fprintf(w, "reverted %d migrations\n", reverted)
+		return err
+	case "status":
+		statuses, err := m.Status()
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range statuses {
+			appliedAt := s.AppliedAt
+			if !s.Applied {
+				appliedAt = "pending"
+			}
+			fmt.Fprintf(tw, "%04d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+		}
+		return tw.Flush()
+	default:
+		return fmt.Errorf("unknown migrate command %q, usage: %s", args[0], Usage)
+	}
+}
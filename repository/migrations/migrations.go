@@ -0,0 +1,387 @@
+// Package migrations - versioned schema migrations of the database, the sql files of each
+// dialect are embedded in the binary and the applied versions are kept in schema_migrations
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/repository/sqlconnect"
+)
+
+// files - the migrations of every dialect in sql/<dialect>, all dialects have the same
+// versions
+//
+//go:embed sql
+var files embed.FS
+
+// lockName - the named lock (the advisory lock on postgres) taken while migrating so the
+// replicas starting together don't apply the same migration twice
+const lockName = "school_schema_migrations"
+
+// Migration - the up and down sql of one schema version, the files are named
+// <version>_<name>.up.sql and <version>_<name>.down.sql
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status - a migration and when it was applied, AppliedAt is empty for pending ones
+type Status struct {
+	Version   int64  `json:"version"`
+	Name      string `json:"name"`
+	Applied   bool   `json:"applied"`
+	AppliedAt string `json:"applied_at,omitempty"`
+}
+
+// Load - the embedded migrations of the dialect ordered by version
+func Load(dialect sqlconnect.Dialect) ([]Migration, error) {
+	return load(files, path.Join("sql", string(dialect)))
+}
+
+func load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		fileName := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(fileName, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(fileName, ".down.sql"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("migration %s is not .up.sql or .down.sql", fileName)
+		}
+		base := strings.TrimSuffix(fileName, "."+direction+".sql")
+		versionPart, name, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s has no name after the version", fileName)
+		}
+		version, err := strconv.ParseInt(versionPart, 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s has invalid version %q", fileName, versionPart)
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, fileName))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if m.Name != name {
+			return nil, fmt.Errorf("migration version %d is used by %s and %s", version, m.Name, name)
+		}
+		if direction == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if strings.TrimSpace(m.Up) == "" || strings.TrimSpace(m.Down) == "" {
+			return nil, fmt.Errorf("migration %04d_%s needs both up and down sql", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// statements - the sql of a migration split on the lines ending with ";", the lines
+// starting with "--" are comments. The ";" inside of a $$ quoted function body of
+// postgres doesn't end the statement
+func statements(script string) []string {
+	var (
+		stmts   []string
+		current strings.Builder
+		body    bool
+	)
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.Count(line, "$$")%2 == 1 {
+			body = !body
+		}
+		if !body && strings.HasSuffix(trimmed, ";") {
+			stmts = append(stmts, strings.TrimSpace(current.String()))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		stmts = append(stmts, rest)
+	}
+	return stmts
+}
+
+type Migrator struct {
+	db          *sqlconnect.DB
+	logger      *logging.Logger
+	migrations  []Migration
+	lockTimeout time.Duration
+}
+
+func NewMigrator(db *sqlconnect.DB, logger *logging.Logger, lockTimeout time.Duration) (*Migrator, error) {
+	migrations, err := Load(db.Dialect)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		db:          db,
+		logger:      logger,
+		migrations:  migrations,
+		lockTimeout: lockTimeout,
+	}, nil
+}
+
+// Up - applies all pending migrations and returns how many were applied
+func (m *Migrator) Up() (int, error) {
+	applied := 0
+	err := m.withLock(func(conn *sql.Conn) error {
+		done, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+			m.logger.Logging.Infof("applying migration %04d_%s", migration.Version, migration.Name)
+			err := m.exec(
+				conn,
+				migration.Up,
+				"INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+				migration.Version,
+				migration.Name,
+				time.Now().UTC().Format(time.RFC3339),
+			)
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s up: %w", migration.Version, migration.Name, err)
+			}
+			applied++
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down - reverts the last applied migrations, steps is how many
+func (m *Migrator) Down(steps int) (int, error) {
+	if steps < 1 {
+		return 0, errors.New("steps must be at least 1")
+	}
+	reverted := 0
+	err := m.withLock(func(conn *sql.Conn) error {
+		done, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && reverted < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := done[migration.Version]; !ok {
+				continue
+			}
+			m.logger.Logging.Infof("reverting migration %04d_%s", migration.Version, migration.Name)
+			err := m.exec(
+				conn,
+				migration.Down,
+				"DELETE FROM schema_migrations WHERE version = ?",
+				migration.Version,
+			)
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s down: %w", migration.Version, migration.Name, err)
+			}
+			reverted++
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status - every known migration and whether it is applied
+func (m *Migrator) Status() ([]Status, error) {
+	conn, err := m.db.Conn(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	done, err := m.applied(conn)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		appliedAt, ok := done[migration.Version]
+		statuses = append(statuses, Status{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Applied:   ok,
+			AppliedAt: appliedAt,
+		})
+	}
+	return statuses, nil
+}
+
+// withLock - runs fn holding the migration lock, the named lock belongs to the
+// connection so everything is done on the same one. Sqlite has a single writer and
+// needs no lock
+func (m *Migrator) withLock(fn func(*sql.Conn) error) error {
+	ctx := context.Background()
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var unlock string
+	switch m.db.Dialect {
+	case sqlconnect.MariaDB:
+		err = m.lockMariaDB(ctx, conn)
+		unlock = "SELECT RELEASE_LOCK(?)"
+	case sqlconnect.Postgres:
+		err = m.lockPostgres(ctx, conn)
+		unlock = "SELECT pg_advisory_unlock(hashtext($1))"
+	}
+	if err != nil {
+		return err
+	}
+	if unlock != "" {
+		defer func() {
+			if _, err := conn.ExecContext(ctx, unlock, lockName); err != nil {
+				m.logger.Logging.Errorf("failed to release the migration lock %v", err)
+			}
+		}()
+	}
+
+	return fn(conn)
+}
+
+func (m *Migrator) lockMariaDB(ctx context.Context, conn *sql.Conn) error {
+	var locked sql.NullInt64
+	err := conn.QueryRowContext(
+		ctx,
+		"SELECT GET_LOCK(?, ?)",
+		lockName,
+		int(m.lockTimeout.Seconds()),
+	).Scan(&locked)
+	if err != nil {
+		return fmt.Errorf("taking the migration lock: %w", err)
+	}
+	if !locked.Valid || locked.Int64 != 1 {
+		return fmt.Errorf("the migration lock is held by another process for more than %s", m.lockTimeout)
+	}
+	return nil
+}
+
+// lockPostgres - postgres has no timeout for the advisory lock, the lock is tried
+// every second until the lock timeout
+func (m *Migrator) lockPostgres(ctx context.Context, conn *sql.Conn) error {
+	deadline := time.Now().Add(m.lockTimeout)
+	for {
+		var locked bool
+		err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock(hashtext($1))", lockName).Scan(&locked)
+		if err != nil {
+			return fmt.Errorf("taking the migration lock: %w", err)
+		}
+		if locked {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("the migration lock is held by another process for more than %s", m.lockTimeout)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// applied - the applied versions with the time they were applied
+func (m *Migrator) applied(conn *sql.Conn) (map[int64]string, error) {
+	ctx := context.Background()
+	_, err := conn.ExecContext(ctx, `
+    CREATE TABLE IF NOT EXISTS schema_migrations (
+      version BIGINT PRIMARY KEY,
+      name VARCHAR(255) NOT NULL,
+      applied_at VARCHAR(255) NOT NULL
+    )`)
+	if err != nil {
+		return nil, fmt.Errorf("creating schema_migrations: %w", err)
+	}
+
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	done := map[int64]string{}
+	for rows.Next() {
+		var (
+			version   int64
+			appliedAt string
+		)
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		done[version] = appliedAt
+	}
+	return done, rows.Err()
+}
+
+// exec - the statements of a migration and the query recording it in schema_migrations.
+// Postgres and sqlite run them in a transaction, mariadb commits the DDL implicitly so a
+// failed migration is not rolled back there and has to be written to be run again
+func (m *Migrator) exec(conn *sql.Conn, script string, record string, args ...any) error {
+	ctx := context.Background()
+	if m.db.Dialect == sqlconnect.MariaDB {
+		for _, stmt := range statements(script) {
+			m.logger.Logging.Debugf("migration statement: %s", stmt)
+			if _, err := conn.ExecContext(ctx, stmt); err != nil {
+				return err
+			}
+		}
+		_, err := conn.ExecContext(ctx, record, args...)
+		return err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, stmt := range statements(script) {
+		m.logger.Logging.Debugf("migration statement: %s", stmt)
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, m.db.Dialect.Rebind(record), args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,151 @@
+package migrations
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/pkg/logging"
+	"github.com/dkr290/go-advanced-projects/rest-api-school-management/repository/sqlconnect"
+)
+
+func TestLoadEmbedded(t *testing.T) {
+	count := 0
+	for _, dialect := range sqlconnect.Dialects {
+		migrations, err := Load(dialect)
+		if err != nil {
+			t.Fatal(dialect, err)
+		}
+		if len(migrations) == 0 {
+			t.Fatalf("Expected embedded migrations for %s", dialect)
+		}
+		// the versions are sequential so two branches adding the same version are noticed
+		for i, m := range migrations {
+			if m.Version != int64(i+1) {
+				t.Fatalf("Expected %s version %d, got %d_%s", dialect, i+1, m.Version, m.Name)
+			}
+			// sqlite has nothing to do for some of the migrations
+			if dialect != sqlconnect.SQLite && (len(statements(m.Up)) == 0 || len(statements(m.Down)) == 0) {
+				t.Fatalf("Expected statements in up and down of %s %d_%s", dialect, m.Version, m.Name)
+			}
+		}
+		if count == 0 {
+			count = len(migrations)
+		} else if len(migrations) != count {
+			t.Fatalf("Expected %d migrations for %s like the other dialects, got %d", count, dialect, len(migrations))
+		}
+	}
+}
+
+func TestUpDownSQLite(t *testing.T) {
+	db, err := sqlconnect.Open(sqlconnect.SQLite, ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	m, err := NewMigrator(db, logging.Init(false), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := m.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if applied != len(m.migrations) {
+		t.Fatalf("Expected %d applied migrations, got %d", len(m.migrations), applied)
+	}
+	if applied, err := m.Up(); err != nil || applied != 0 {
+		t.Fatalf("Expected nothing to apply the second time, got %d %v", applied, err)
+	}
+	if reverted, err := m.Down(len(m.migrations)); err != nil || reverted != len(m.migrations) {
+		t.Fatalf("Expected all migrations reverted, got %d %v", reverted, err)
+	}
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range statuses {
+		if s.Applied {
+			t.Fatalf("Expected %04d_%s pending after down", s.Version, s.Name)
+		}
+	}
+	if _, err := m.Up(); err != nil {
+		t.Fatalf("Expected the migrations to apply again after down, got %v", err)
+	}
+}
+
+// the database created before the migrations has the tables of 0001 without the
+// schema_migrations table, the soft delete column is added to its tables
+func TestUpExistingDatabaseSQLite(t *testing.T) {
+	db, err := sqlconnect.Open(sqlconnect.SQLite, ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	m, err := NewMigrator(db, logging.Init(false), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range statements(m.migrations[0].Up) {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range []string{"execs", "teachers", "students"} {
+		var count int
+		if err := db.QueryRow("SELECT COUNT(*) FROM " + table + " WHERE deleted_at IS NULL").Scan(&count); err != nil {
+			t.Fatalf("Expected the deleted_at column in %s, got %v", table, err)
+		}
+	}
+}
+
+func TestLoadRequiresUpAndDown(t *testing.T) {
+	fsys := fstest.MapFS{
+		"sql/0001_first.up.sql":   {Data: []byte("CREATE TABLE a (id INT);")},
+		"sql/0001_first.down.sql": {Data: []byte("DROP TABLE a;")},
+		"sql/0002_second.up.sql":  {Data: []byte("CREATE TABLE b (id INT);")},
+	}
+	if _, err := load(fsys, "sql"); err == nil || !strings.Contains(err.Error(), "0002_second") {
+		t.Fatalf("Expected error for the missing down migration, got %v", err)
+	}
+}
+
+func TestStatements(t *testing.T) {
+	script := `-- comment
+CREATE TABLE a (
+  id INT
+);
+
+ALTER TABLE a ADD COLUMN name VARCHAR(255);
+`
+	got := statements(script)
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 statements, got %q", got)
+	}
+	if !strings.HasPrefix(got[0], "CREATE TABLE a (") || !strings.HasSuffix(got[0], ");") {
+		t.Fatalf("Expected the whole create statement, got %q", got[0])
+	}
+}
+
+func TestStatementsFunctionBody(t *testing.T) {
+	script := `CREATE FUNCTION f() RETURNS trigger AS $$
+BEGIN
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+CREATE TRIGGER t BEFORE INSERT ON a FOR EACH ROW EXECUTE FUNCTION f();
+`
+	got := statements(script)
+	if len(got) != 2 {
+		t.Fatalf("Expected the function and the trigger, got %q", got)
+	}
+	if !strings.HasSuffix(got[0], "$$ LANGUAGE plpgsql;") {
+		t.Fatalf("Expected the whole function body, got %q", got[0])
+	}
+}
@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS change_history;
+DROP TABLE IF EXISTS auth_audit_log;
+DROP TABLE IF EXISTS exec_invitations;
+DROP TABLE IF EXISTS exec_password_history;
+DROP TABLE IF EXISTS exec_identities;
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS exec_mfa_recovery_codes;
+DROP TABLE IF EXISTS exec_mfa;
+DROP TABLE IF EXISTS login_attempts;
+DROP TABLE IF EXISTS exec_sessions;
+DROP TABLE IF EXISTS students;
+DROP TABLE IF EXISTS teachers;
+DROP TABLE IF EXISTS execs;
@@ -0,0 +1,147 @@
+-- the schema created by CreateTables before the migrations, IF NOT EXISTS keeps the
+-- databases which already have the tables
+CREATE TABLE IF NOT EXISTS execs (
+  id INT AUTO_INCREMENT PRIMARY KEY,
+  first_name VARCHAR(255) NOT NULL,
+  last_name VARCHAR(255) NOT NULL,
+  email VARCHAR(50) NOT NULL UNIQUE,
+  username VARCHAR(50) NOT NULL UNIQUE,
+  password VARCHAR(255) NOT NULL,
+  password_changed_at VARCHAR(255),
+  user_created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  password_reset_token VARCHAR(255),
+  inactive_status BOOLEAN NOT NULL,
+  role VARCHAR(255) NOT NULL,
+  password_token_expires VARCHAR(255),
+  INDEX idx_email (email),
+  INDEX idx_username (username)
+);
+
+CREATE TABLE IF NOT EXISTS teachers (
+  id INT AUTO_INCREMENT PRIMARY KEY,
+  first_name VARCHAR(255) NOT NULL,
+  last_name VARCHAR(255) NOT NULL,
+  email VARCHAR(255) NOT NULL UNIQUE,
+  class VARCHAR(255) NOT NULL,
+  subject VARCHAR(255) NOT NULL,
+  INDEX (email),
+  INDEX (class)
+) AUTO_INCREMENT=100;
+
+CREATE TABLE IF NOT EXISTS students (
+  id INT AUTO_INCREMENT PRIMARY KEY NOT NULL,
+  first_name VARCHAR(255) NOT NULL,
+  last_name VARCHAR(255) NOT NULL,
+  email VARCHAR(255) NOT NULL UNIQUE,
+  class VARCHAR(50) NOT NULL,
+  INDEX (email),
+  FOREIGN KEY (class) REFERENCES teachers(class)
+) AUTO_INCREMENT=100;
+
+CREATE TABLE IF NOT EXISTS exec_sessions (
+  id INT AUTO_INCREMENT PRIMARY KEY,
+  exec_id INT NOT NULL,
+  refresh_token_hash VARCHAR(255) NOT NULL UNIQUE,
+  user_agent VARCHAR(255) NOT NULL DEFAULT '',
+  ip_address VARCHAR(64) NOT NULL DEFAULT '',
+  expires_at VARCHAR(255) NOT NULL,
+  revoked_at VARCHAR(255),
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  INDEX idx_exec_id (exec_id),
+  FOREIGN KEY (exec_id) REFERENCES execs(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS login_attempts (
+  attempt_key VARCHAR(300) PRIMARY KEY,
+  failed_attempts INT NOT NULL DEFAULT 0,
+  last_failed_at VARCHAR(255) NOT NULL,
+  locked_until VARCHAR(255) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS exec_mfa (
+  exec_id INT PRIMARY KEY,
+  secret VARCHAR(255) NOT NULL,
+  enabled BOOLEAN NOT NULL DEFAULT FALSE,
+  last_used_step BIGINT NOT NULL DEFAULT 0,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (exec_id) REFERENCES execs(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS exec_mfa_recovery_codes (
+  id INT AUTO_INCREMENT PRIMARY KEY,
+  exec_id INT NOT NULL,
+  code_hash VARCHAR(255) NOT NULL,
+  used_at VARCHAR(255),
+  INDEX idx_exec_code (exec_id, code_hash),
+  FOREIGN KEY (exec_id) REFERENCES execs(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+  id INT AUTO_INCREMENT PRIMARY KEY,
+  exec_id INT NOT NULL,
+  name VARCHAR(255) NOT NULL,
+  key_prefix VARCHAR(16) NOT NULL,
+  key_hash VARCHAR(255) NOT NULL UNIQUE,
+  scopes VARCHAR(255) NOT NULL,
+  last_used_at VARCHAR(255),
+  expires_at VARCHAR(255),
+  revoked_at VARCHAR(255),
+  created_at VARCHAR(255),
+  FOREIGN KEY (exec_id) REFERENCES execs(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS exec_identities (
+  issuer VARCHAR(255) NOT NULL,
+  subject VARCHAR(255) NOT NULL,
+  exec_id INT NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (issuer, subject),
+  FOREIGN KEY (exec_id) REFERENCES execs(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS exec_password_history (
+  id INT AUTO_INCREMENT PRIMARY KEY,
+  exec_id INT NOT NULL,
+  password_hash VARCHAR(255) NOT NULL,
+  created_at VARCHAR(255) NOT NULL,
+  INDEX idx_exec_id (exec_id),
+  FOREIGN KEY (exec_id) REFERENCES execs(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS exec_invitations (
+  exec_id INT PRIMARY KEY,
+  token_hash VARCHAR(255) NOT NULL UNIQUE,
+  expires_at VARCHAR(255) NOT NULL,
+  created_at VARCHAR(255) NOT NULL,
+  FOREIGN KEY (exec_id) REFERENCES execs(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS auth_audit_log (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  event_type VARCHAR(50) NOT NULL,
+  exec_id INT,
+  actor_id INT,
+  username VARCHAR(255) NOT NULL DEFAULT '',
+  ip_address VARCHAR(255) NOT NULL DEFAULT '',
+  user_agent VARCHAR(255) NOT NULL DEFAULT '',
+  outcome VARCHAR(20) NOT NULL,
+  detail VARCHAR(255) NOT NULL DEFAULT '',
+  created_at VARCHAR(255) NOT NULL,
+  INDEX idx_exec_id (exec_id),
+  INDEX idx_event_type (event_type),
+  INDEX idx_created_at (created_at)
+);
+
+CREATE TABLE IF NOT EXISTS change_history (
+  id BIGINT AUTO_INCREMENT PRIMARY KEY,
+  entity VARCHAR(50) NOT NULL,
+  entity_id INT NOT NULL,
+  action VARCHAR(20) NOT NULL,
+  actor_id INT,
+  before_data TEXT,
+  after_data TEXT,
+  changes TEXT,
+  restored_from BIGINT,
+  created_at VARCHAR(255) NOT NULL,
+  INDEX idx_entity (entity, entity_id)
+);
@@ -0,0 +1 @@
+ALTER TABLE execs MODIFY COLUMN email VARCHAR(50) NOT NULL;
@@ -0,0 +1,2 @@
+-- the emails of the execs were limited to 50 characters while all other emails have 255
+ALTER TABLE execs MODIFY COLUMN email VARCHAR(255) NOT NULL;
@@ -0,0 +1,14 @@
+-- the classes without teacher can't go back to the foreign key of students.class so the
+-- down fails while students are in such a class
+ALTER TABLE teachers ADD COLUMN IF NOT EXISTS class VARCHAR(255);
+ALTER TABLE students ADD COLUMN IF NOT EXISTS class VARCHAR(50);
+UPDATE teachers SET class = (SELECT name FROM classes WHERE classes.id = teachers.class_id);
+UPDATE students SET class = (SELECT name FROM classes WHERE classes.id = students.class_id);
+
+ALTER TABLE students DROP FOREIGN KEY IF EXISTS fk_students_class;
+ALTER TABLE teachers DROP FOREIGN KEY IF EXISTS fk_teachers_class;
+ALTER TABLE students DROP COLUMN IF EXISTS class_id, MODIFY COLUMN class VARCHAR(50) NOT NULL;
+ALTER TABLE teachers DROP COLUMN IF EXISTS class_id, MODIFY COLUMN class VARCHAR(255) NOT NULL, ADD INDEX (class);
+ALTER TABLE students ADD CONSTRAINT students_ibfk_1 FOREIGN KEY (class) REFERENCES teachers(class);
+
+DROP TABLE IF EXISTS classes;
@@ -0,0 +1,44 @@
+-- the class was free text on the teachers and the students referenced it with a foreign
+-- key on a column which is not unique, the classes table gives every class an id and its
+-- own details and the teachers and students reference the id
+CREATE TABLE IF NOT EXISTS classes (
+  id INT AUTO_INCREMENT PRIMARY KEY,
+  name VARCHAR(255) NOT NULL,
+  grade_level INT NOT NULL DEFAULT 0,
+  academic_year VARCHAR(20) NOT NULL DEFAULT '',
+  homeroom_teacher_id INT,
+  room VARCHAR(50) NOT NULL DEFAULT '',
+  capacity INT NOT NULL DEFAULT 0,
+  UNIQUE KEY uq_classes_name_year (name, academic_year),
+  CONSTRAINT fk_classes_homeroom_teacher FOREIGN KEY (homeroom_teacher_id) REFERENCES teachers(id) ON DELETE SET NULL
+);
+
+-- mariadb commits every statement so a failed run is run again from the start, the
+-- backfill reads the class columns only while they are there and skips the classes and
+-- the class_id already set by the failed run
+SET @teachers_class = (SELECT COUNT(*) FROM information_schema.columns
+  WHERE table_schema = DATABASE() AND table_name = 'teachers' AND column_name = 'class');
+SET @students_class = (SELECT COUNT(*) FROM information_schema.columns
+  WHERE table_schema = DATABASE() AND table_name = 'students' AND column_name = 'class');
+
+EXECUTE IMMEDIATE IF(@teachers_class > 0,
+  'INSERT IGNORE INTO classes (name) SELECT DISTINCT class FROM teachers', 'DO 0');
+EXECUTE IMMEDIATE IF(@students_class > 0,
+  'INSERT IGNORE INTO classes (name) SELECT DISTINCT class FROM students', 'DO 0');
+
+ALTER TABLE teachers ADD COLUMN IF NOT EXISTS class_id INT;
+ALTER TABLE students ADD COLUMN IF NOT EXISTS class_id INT;
+EXECUTE IMMEDIATE IF(@teachers_class > 0,
+  'UPDATE teachers SET class_id = (SELECT id FROM classes WHERE classes.name = teachers.class) WHERE class_id IS NULL',
+  'DO 0');
+EXECUTE IMMEDIATE IF(@students_class > 0,
+  'UPDATE students SET class_id = (SELECT id FROM classes WHERE classes.name = students.class) WHERE class_id IS NULL',
+  'DO 0');
+
+ALTER TABLE students DROP FOREIGN KEY IF EXISTS students_ibfk_1;
+ALTER TABLE students DROP COLUMN IF EXISTS class;
+ALTER TABLE teachers DROP COLUMN IF EXISTS class;
+ALTER TABLE teachers MODIFY COLUMN class_id INT NOT NULL,
+  ADD CONSTRAINT fk_teachers_class FOREIGN KEY IF NOT EXISTS (class_id) REFERENCES classes(id);
+ALTER TABLE students MODIFY COLUMN class_id INT NOT NULL,
+  ADD CONSTRAINT fk_students_class FOREIGN KEY IF NOT EXISTS (class_id) REFERENCES classes(id);
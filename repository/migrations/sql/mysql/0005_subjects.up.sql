@@ -0,0 +1,30 @@
+-- the subject of the teachers is free text and one per teacher, the subjects table is
+-- the catalog and the teaching assignments give the teachers their subjects in the
+-- classes per term. A subject is taught in a class by one teacher in a term
+CREATE TABLE IF NOT EXISTS subjects (
+  id INT AUTO_INCREMENT PRIMARY KEY,
+  name VARCHAR(255) NOT NULL UNIQUE,
+  code VARCHAR(20) NOT NULL DEFAULT '',
+  description VARCHAR(255) NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS teaching_assignments (
+  id INT AUTO_INCREMENT PRIMARY KEY,
+  teacher_id INT NOT NULL,
+  subject_id INT NOT NULL,
+  class_id INT NOT NULL,
+  term VARCHAR(20) NOT NULL DEFAULT '',
+  UNIQUE KEY uq_assignments_subject_class_term (subject_id, class_id, term),
+  INDEX idx_assignments_teacher (teacher_id),
+  INDEX idx_assignments_class (class_id),
+  CONSTRAINT fk_assignments_teacher FOREIGN KEY (teacher_id) REFERENCES teachers(id) ON DELETE CASCADE,
+  CONSTRAINT fk_assignments_subject FOREIGN KEY (subject_id) REFERENCES subjects(id),
+  CONSTRAINT fk_assignments_class FOREIGN KEY (class_id) REFERENCES classes(id)
+);
+
+-- the subject of every teacher in the class of the teacher, the first teacher keeps the
+-- subject when more teachers of the class teach it
+INSERT INTO subjects (name) SELECT DISTINCT subject FROM teachers WHERE subject <> '';
+INSERT INTO teaching_assignments (teacher_id, subject_id, class_id)
+SELECT MIN(t.id), s.id, t.class_id FROM teachers t JOIN subjects s ON s.name = t.subject
+GROUP BY s.id, t.class_id;
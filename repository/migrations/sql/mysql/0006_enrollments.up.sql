@@ -0,0 +1,21 @@
+-- the class of the student is only the current one, the enrollments keep the classes of
+-- the student over the academic years. A student has at most one active enrollment, its
+-- class is the class_id of the student
+CREATE TABLE IF NOT EXISTS enrollments (
+  id INT AUTO_INCREMENT PRIMARY KEY,
+  student_id INT NOT NULL,
+  class_id INT NOT NULL,
+  academic_year VARCHAR(20) NOT NULL DEFAULT '',
+  start_date VARCHAR(10) NOT NULL,
+  end_date VARCHAR(10),
+  status VARCHAR(20) NOT NULL DEFAULT 'active',
+  INDEX idx_enrollments_student (student_id),
+  INDEX idx_enrollments_class_status (class_id, status),
+  CONSTRAINT fk_enrollments_student FOREIGN KEY (student_id) REFERENCES students(id) ON DELETE CASCADE,
+  CONSTRAINT fk_enrollments_class FOREIGN KEY (class_id) REFERENCES classes(id)
+);
+
+-- the students are enrolled in their class from the day of the migration
+INSERT INTO enrollments (student_id, class_id, academic_year, start_date, status)
+SELECT s.id, s.class_id, c.academic_year, DATE_FORMAT(CURRENT_DATE, '%Y-%m-%d'), 'active'
+FROM students s JOIN classes c ON c.id = s.class_id;
@@ -0,0 +1,170 @@
+-- the schema of 0001 for postgres, the same tables as the mariadb one with identity ids
+-- and the indexes created separately
+CREATE TABLE IF NOT EXISTS execs (
+  id INT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  first_name VARCHAR(255) NOT NULL,
+  last_name VARCHAR(255) NOT NULL,
+  email VARCHAR(50) NOT NULL UNIQUE,
+  username VARCHAR(50) NOT NULL UNIQUE,
+  password VARCHAR(255) NOT NULL,
+  password_changed_at VARCHAR(255),
+  user_created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  password_reset_token VARCHAR(255),
+  inactive_status BOOLEAN NOT NULL,
+  role VARCHAR(255) NOT NULL,
+  password_token_expires VARCHAR(255)
+);
+
+CREATE TABLE IF NOT EXISTS teachers (
+  id INT GENERATED BY DEFAULT AS IDENTITY (START WITH 100) PRIMARY KEY,
+  first_name VARCHAR(255) NOT NULL,
+  last_name VARCHAR(255) NOT NULL,
+  email VARCHAR(255) NOT NULL UNIQUE,
+  class VARCHAR(255) NOT NULL,
+  subject VARCHAR(255) NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_teachers_class ON teachers (class);
+
+CREATE TABLE IF NOT EXISTS students (
+  id INT GENERATED BY DEFAULT AS IDENTITY (START WITH 100) PRIMARY KEY,
+  first_name VARCHAR(255) NOT NULL,
+  last_name VARCHAR(255) NOT NULL,
+  email VARCHAR(255) NOT NULL UNIQUE,
+  class VARCHAR(50) NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_students_class ON students (class);
+
+-- teachers.class is not unique so postgres can't reference it with a foreign key, the
+-- triggers keep the rule of the mariadb key: the class of a student needs a teacher and
+-- the last teacher of a class can't go while students are in it
+CREATE OR REPLACE FUNCTION students_class_exists() RETURNS trigger AS $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM teachers WHERE class = NEW.class) THEN
+    RAISE EXCEPTION 'class % has no teacher', NEW.class USING ERRCODE = 'foreign_key_violation';
+  END IF;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS students_class_fk ON students;
+CREATE TRIGGER students_class_fk BEFORE INSERT OR UPDATE OF class ON students
+  FOR EACH ROW EXECUTE FUNCTION students_class_exists();
+
+CREATE OR REPLACE FUNCTION teachers_class_restrict() RETURNS trigger AS $$
+BEGIN
+  IF (TG_OP = 'DELETE' OR NEW.class IS DISTINCT FROM OLD.class)
+    AND EXISTS (SELECT 1 FROM students WHERE class = OLD.class)
+    AND NOT EXISTS (SELECT 1 FROM teachers WHERE class = OLD.class AND id <> OLD.id) THEN
+    RAISE EXCEPTION 'class % still has students', OLD.class USING ERRCODE = 'foreign_key_violation';
+  END IF;
+  IF TG_OP = 'DELETE' THEN
+    RETURN OLD;
+  END IF;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS teachers_class_fk ON teachers;
+CREATE TRIGGER teachers_class_fk BEFORE DELETE OR UPDATE OF class ON teachers
+  FOR EACH ROW EXECUTE FUNCTION teachers_class_restrict();
+
+CREATE TABLE IF NOT EXISTS exec_sessions (
+  id INT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  exec_id INT NOT NULL REFERENCES execs(id) ON DELETE CASCADE,
+  refresh_token_hash VARCHAR(255) NOT NULL UNIQUE,
+  user_agent VARCHAR(255) NOT NULL DEFAULT '',
+  ip_address VARCHAR(64) NOT NULL DEFAULT '',
+  expires_at VARCHAR(255) NOT NULL,
+  revoked_at VARCHAR(255),
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_exec_sessions_exec_id ON exec_sessions (exec_id);
+
+CREATE TABLE IF NOT EXISTS login_attempts (
+  attempt_key VARCHAR(300) PRIMARY KEY,
+  failed_attempts INT NOT NULL DEFAULT 0,
+  last_failed_at VARCHAR(255) NOT NULL,
+  locked_until VARCHAR(255) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS exec_mfa (
+  exec_id INT PRIMARY KEY REFERENCES execs(id) ON DELETE CASCADE,
+  secret VARCHAR(255) NOT NULL,
+  enabled BOOLEAN NOT NULL DEFAULT FALSE,
+  last_used_step BIGINT NOT NULL DEFAULT 0,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS exec_mfa_recovery_codes (
+  id INT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  exec_id INT NOT NULL REFERENCES execs(id) ON DELETE CASCADE,
+  code_hash VARCHAR(255) NOT NULL,
+  used_at VARCHAR(255)
+);
+CREATE INDEX IF NOT EXISTS idx_exec_mfa_recovery_codes_exec_code ON exec_mfa_recovery_codes (exec_id, code_hash);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+  id INT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  exec_id INT NOT NULL REFERENCES execs(id) ON DELETE CASCADE,
+  name VARCHAR(255) NOT NULL,
+  key_prefix VARCHAR(16) NOT NULL,
+  key_hash VARCHAR(255) NOT NULL UNIQUE,
+  scopes VARCHAR(255) NOT NULL,
+  last_used_at VARCHAR(255),
+  expires_at VARCHAR(255),
+  revoked_at VARCHAR(255),
+  created_at VARCHAR(255)
+);
+
+CREATE TABLE IF NOT EXISTS exec_identities (
+  issuer VARCHAR(255) NOT NULL,
+  subject VARCHAR(255) NOT NULL,
+  exec_id INT NOT NULL REFERENCES execs(id) ON DELETE CASCADE,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (issuer, subject)
+);
+
+CREATE TABLE IF NOT EXISTS exec_password_history (
+  id INT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  exec_id INT NOT NULL REFERENCES execs(id) ON DELETE CASCADE,
+  password_hash VARCHAR(255) NOT NULL,
+  created_at VARCHAR(255) NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_exec_password_history_exec_id ON exec_password_history (exec_id);
+
+CREATE TABLE IF NOT EXISTS exec_invitations (
+  exec_id INT PRIMARY KEY REFERENCES execs(id) ON DELETE CASCADE,
+  token_hash VARCHAR(255) NOT NULL UNIQUE,
+  expires_at VARCHAR(255) NOT NULL,
+  created_at VARCHAR(255) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS auth_audit_log (
+  id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  event_type VARCHAR(50) NOT NULL,
+  exec_id INT,
+  actor_id INT,
+  username VARCHAR(255) NOT NULL DEFAULT '',
+  ip_address VARCHAR(255) NOT NULL DEFAULT '',
+  user_agent VARCHAR(255) NOT NULL DEFAULT '',
+  outcome VARCHAR(20) NOT NULL,
+  detail VARCHAR(255) NOT NULL DEFAULT '',
+  created_at VARCHAR(255) NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_auth_audit_log_exec_id ON auth_audit_log (exec_id);
+CREATE INDEX IF NOT EXISTS idx_auth_audit_log_event_type ON auth_audit_log (event_type);
+CREATE INDEX IF NOT EXISTS idx_auth_audit_log_created_at ON auth_audit_log (created_at);
+
+CREATE TABLE IF NOT EXISTS change_history (
+  id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  entity VARCHAR(50) NOT NULL,
+  entity_id INT NOT NULL,
+  action VARCHAR(20) NOT NULL,
+  actor_id INT,
+  before_data TEXT,
+  after_data TEXT,
+  changes TEXT,
+  restored_from BIGINT,
+  created_at VARCHAR(255) NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_change_history_entity ON change_history (entity, entity_id);
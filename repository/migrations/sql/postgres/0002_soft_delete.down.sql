@@ -0,0 +1,3 @@
+ALTER TABLE students DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE teachers DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE execs DROP COLUMN IF EXISTS deleted_at;
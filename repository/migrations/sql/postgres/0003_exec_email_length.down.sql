@@ -0,0 +1 @@
+ALTER TABLE execs ALTER COLUMN email TYPE VARCHAR(50);
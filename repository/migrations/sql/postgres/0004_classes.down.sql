@@ -0,0 +1,45 @@
+-- the classes without teacher break the rule of the triggers, the down keeps the students
+-- of such a class and the triggers check only the later changes
+ALTER TABLE teachers ADD COLUMN IF NOT EXISTS class VARCHAR(255);
+ALTER TABLE students ADD COLUMN IF NOT EXISTS class VARCHAR(50);
+UPDATE teachers SET class = (SELECT name FROM classes WHERE classes.id = teachers.class_id);
+UPDATE students SET class = (SELECT name FROM classes WHERE classes.id = students.class_id);
+ALTER TABLE teachers ALTER COLUMN class SET NOT NULL;
+ALTER TABLE students ALTER COLUMN class SET NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_teachers_class ON teachers (class);
+CREATE INDEX IF NOT EXISTS idx_students_class ON students (class);
+
+ALTER TABLE teachers DROP COLUMN IF EXISTS class_id;
+ALTER TABLE students DROP COLUMN IF EXISTS class_id;
+DROP TABLE IF EXISTS classes;
+
+CREATE OR REPLACE FUNCTION students_class_exists() RETURNS trigger AS $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM teachers WHERE class = NEW.class) THEN
+    RAISE EXCEPTION 'class % has no teacher', NEW.class USING ERRCODE = 'foreign_key_violation';
+  END IF;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS students_class_fk ON students;
+CREATE TRIGGER students_class_fk BEFORE INSERT OR UPDATE OF class ON students
+  FOR EACH ROW EXECUTE FUNCTION students_class_exists();
+
+CREATE OR REPLACE FUNCTION teachers_class_restrict() RETURNS trigger AS $$
+BEGIN
+  IF (TG_OP = 'DELETE' OR NEW.class IS DISTINCT FROM OLD.class)
+    AND EXISTS (SELECT 1 FROM students WHERE class = OLD.class)
+    AND NOT EXISTS (SELECT 1 FROM teachers WHERE class = OLD.class AND id <> OLD.id) THEN
+    RAISE EXCEPTION 'class % still has students', OLD.class USING ERRCODE = 'foreign_key_violation';
+  END IF;
+  IF TG_OP = 'DELETE' THEN
+    RETURN OLD;
+  END IF;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS teachers_class_fk ON teachers;
+CREATE TRIGGER teachers_class_fk BEFORE DELETE OR UPDATE OF class ON teachers
+  FOR EACH ROW EXECUTE FUNCTION teachers_class_restrict();
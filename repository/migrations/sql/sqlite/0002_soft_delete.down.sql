@@ -0,0 +1,3 @@
+ALTER TABLE students DROP COLUMN deleted_at;
+ALTER TABLE teachers DROP COLUMN deleted_at;
+ALTER TABLE execs DROP COLUMN deleted_at;
@@ -0,0 +1,3 @@
+ALTER TABLE execs ADD COLUMN deleted_at VARCHAR(255);
+ALTER TABLE teachers ADD COLUMN deleted_at VARCHAR(255);
+ALTER TABLE students ADD COLUMN deleted_at VARCHAR(255);
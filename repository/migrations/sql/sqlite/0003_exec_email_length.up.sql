@@ -0,0 +1,2 @@
+-- sqlite doesn't enforce the length of VARCHAR, the version is kept so the migrations of
+-- all databases have the same numbers
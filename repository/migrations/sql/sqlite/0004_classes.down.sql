@@ -0,0 +1,28 @@
+DROP INDEX IF EXISTS idx_teachers_class_id;
+DROP INDEX IF EXISTS idx_students_class_id;
+ALTER TABLE teachers ADD COLUMN class VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE students ADD COLUMN class VARCHAR(50) NOT NULL DEFAULT '';
+UPDATE teachers SET class = (SELECT name FROM classes WHERE classes.id = teachers.class_id);
+UPDATE students SET class = (SELECT name FROM classes WHERE classes.id = students.class_id);
+CREATE INDEX IF NOT EXISTS idx_teachers_class ON teachers (class);
+CREATE INDEX IF NOT EXISTS idx_students_class ON students (class);
+
+ALTER TABLE teachers DROP COLUMN class_id;
+ALTER TABLE students DROP COLUMN class_id;
+DROP TABLE IF EXISTS classes;
+
+CREATE TRIGGER IF NOT EXISTS students_class_insert BEFORE INSERT ON students
+  WHEN NOT EXISTS (SELECT 1 FROM teachers WHERE class = NEW.class)
+  BEGIN SELECT RAISE(ABORT, 'FOREIGN KEY constraint failed'); END;
+CREATE TRIGGER IF NOT EXISTS students_class_update BEFORE UPDATE OF class ON students
+  WHEN NOT EXISTS (SELECT 1 FROM teachers WHERE class = NEW.class)
+  BEGIN SELECT RAISE(ABORT, 'FOREIGN KEY constraint failed'); END;
+CREATE TRIGGER IF NOT EXISTS teachers_class_delete BEFORE DELETE ON teachers
+  WHEN EXISTS (SELECT 1 FROM students WHERE class = OLD.class)
+    AND NOT EXISTS (SELECT 1 FROM teachers WHERE class = OLD.class AND id <> OLD.id)
+  BEGIN SELECT RAISE(ABORT, 'FOREIGN KEY constraint failed'); END;
+CREATE TRIGGER IF NOT EXISTS teachers_class_update BEFORE UPDATE OF class ON teachers
+  WHEN NEW.class <> OLD.class
+    AND EXISTS (SELECT 1 FROM students WHERE class = OLD.class)
+    AND NOT EXISTS (SELECT 1 FROM teachers WHERE class = OLD.class AND id <> OLD.id)
+  BEGIN SELECT RAISE(ABORT, 'FOREIGN KEY constraint failed'); END;
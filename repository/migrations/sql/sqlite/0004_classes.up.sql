@@ -0,0 +1,34 @@
+-- the class was free text on the teachers and the students needed the triggers to keep
+-- it consistent, the classes table gives every class an id and its own details and the
+-- teachers and students reference the id
+CREATE TABLE IF NOT EXISTS classes (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name VARCHAR(255) NOT NULL,
+  grade_level INT NOT NULL DEFAULT 0,
+  academic_year VARCHAR(20) NOT NULL DEFAULT '',
+  homeroom_teacher_id INT REFERENCES teachers(id) ON DELETE SET NULL,
+  room VARCHAR(50) NOT NULL DEFAULT '',
+  capacity INT NOT NULL DEFAULT 0,
+  UNIQUE (name, academic_year)
+);
+
+INSERT INTO classes (name) SELECT class FROM teachers UNION SELECT class FROM students;
+
+DROP TRIGGER IF EXISTS students_class_insert;
+DROP TRIGGER IF EXISTS students_class_update;
+DROP TRIGGER IF EXISTS teachers_class_delete;
+DROP TRIGGER IF EXISTS teachers_class_update;
+DROP INDEX IF EXISTS idx_teachers_class;
+DROP INDEX IF EXISTS idx_students_class;
+
+-- sqlite adds a column with a foreign key only when it can be null, the api requires the
+-- class of the teachers and students
+ALTER TABLE teachers ADD COLUMN class_id INTEGER REFERENCES classes(id);
+ALTER TABLE students ADD COLUMN class_id INTEGER REFERENCES classes(id);
+UPDATE teachers SET class_id = (SELECT id FROM classes WHERE classes.name = teachers.class);
+UPDATE students SET class_id = (SELECT id FROM classes WHERE classes.name = students.class);
+
+ALTER TABLE teachers DROP COLUMN class;
+ALTER TABLE students DROP COLUMN class;
+CREATE INDEX IF NOT EXISTS idx_teachers_class_id ON teachers (class_id);
+CREATE INDEX IF NOT EXISTS idx_students_class_id ON students (class_id);
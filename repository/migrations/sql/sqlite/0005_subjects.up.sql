@@ -0,0 +1,27 @@
+-- the subject of the teachers is free text and one per teacher, the subjects table is
+-- the catalog and the teaching assignments give the teachers their subjects in the
+-- classes per term. A subject is taught in a class by one teacher in a term
+CREATE TABLE IF NOT EXISTS subjects (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name VARCHAR(255) NOT NULL UNIQUE,
+  code VARCHAR(20) NOT NULL DEFAULT '',
+  description VARCHAR(255) NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS teaching_assignments (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  teacher_id INT NOT NULL REFERENCES teachers(id) ON DELETE CASCADE,
+  subject_id INT NOT NULL REFERENCES subjects(id),
+  class_id INT NOT NULL REFERENCES classes(id),
+  term VARCHAR(20) NOT NULL DEFAULT '',
+  UNIQUE (subject_id, class_id, term)
+);
+CREATE INDEX IF NOT EXISTS idx_assignments_teacher ON teaching_assignments (teacher_id);
+CREATE INDEX IF NOT EXISTS idx_assignments_class ON teaching_assignments (class_id);
+
+-- the subject of every teacher in the class of the teacher, the first teacher keeps the
+-- subject when more teachers of the class teach it
+INSERT INTO subjects (name) SELECT DISTINCT subject FROM teachers WHERE subject <> '';
+INSERT INTO teaching_assignments (teacher_id, subject_id, class_id)
+SELECT MIN(t.id), s.id, t.class_id FROM teachers t JOIN subjects s ON s.name = t.subject
+GROUP BY s.id, t.class_id;
@@ -0,0 +1,18 @@
+-- the class of the student is only the current one, the enrollments keep the classes of
+-- the student over the academic years. A student has at most one active enrollment, its
+-- class is the class_id of the student
+CREATE TABLE IF NOT EXISTS enrollments (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  student_id INT NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+  class_id INT NOT NULL REFERENCES classes(id),
+  academic_year VARCHAR(20) NOT NULL DEFAULT '',
+  start_date VARCHAR(10) NOT NULL,
+  end_date VARCHAR(10),
+  status VARCHAR(20) NOT NULL DEFAULT 'active'
+);
+CREATE INDEX IF NOT EXISTS idx_enrollments_student ON enrollments (student_id);
+CREATE INDEX IF NOT EXISTS idx_enrollments_class_status ON enrollments (class_id, status);
+
+-- the students are enrolled in their class from the day of the migration
+INSERT INTO enrollments (student_id, class_id, academic_year, start_date, status)
+SELECT s.id, s.class_id, c.academic_year, date('now'), 'active' FROM students s JOIN classes c ON c.id = s.class_id;
@@ -0,0 +1,108 @@
+package sqlconnect
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Dialect - the sql flavour of the database. The queries of dataops are written for
+// mariadb with ? placeholders, the dialect adjusts the few places where the databases differ
+type Dialect string
+
+const (
+	MariaDB  Dialect = "mysql"
+	Postgres Dialect = "postgres"
+	SQLite   Dialect = "sqlite"
+)
+
+// Dialects - all supported dialects
+var Dialects = []Dialect{MariaDB, Postgres, SQLite}
+
+// ParseDialect - the dialect of the --database-driver flag, the usual driver names are
+// accepted for each
+func ParseDialect(name string) (Dialect, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "mysql", "mariadb":
+		return MariaDB, nil
+	case "postgres", "postgresql", "pgx":
+		return Postgres, nil
+	case "sqlite", "sqlite3":
+		return SQLite, nil
+	}
+	return "", fmt.Errorf("unknown database driver %q, mysql, postgres or sqlite", name)
+}
+
+// DriverName - the database/sql driver registered for the dialect
+func (d Dialect) DriverName() string {
+	switch d {
+	case Postgres:
+		return "pgx"
+	case SQLite:
+		return "sqlite"
+	}
+	return "mysql"
+}
+
+// Rebind - the query with the placeholders of the dialect, postgres numbers them $1, $2...
+// The ? inside of quoted strings is kept
+func (d Dialect) Rebind(query string) string {
+	if d != Postgres || !strings.Contains(query, "?") {
+		return query
+	}
+	var (
+		b      strings.Builder
+		n      int
+		quoted bool
+	)
+	b.Grow(len(query) + 8)
+	for _, r := range query {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case r == '?' && !quoted:
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// ForUpdate - the clause at the end of the SELECT which locks the read rows until the
+// end of the transaction, sqlite has none as its write transactions lock the database
+func (d Dialect) ForUpdate() string {
+	if d == SQLite {
+		return ""
+	}
+	return " FOR UPDATE"
+}
+
+// Upsert - the clause after INSERT ... VALUES (...) which updates the columns of the row
+// with the same key instead of failing on the duplicate
+func (d Dialect) Upsert(key string, columns ...string) string {
+	set := make([]string, 0, len(columns))
+	for _, c := range columns {
+		set = append(set, c+" = "+d.Inserted(c))
+	}
+	return d.UpsertSet(key, set...)
+}
+
+// UpsertSet - the upsert clause with the assignments written out, the columns of the
+// existing row are prefixed with the table name and the new values come from Inserted.
+// Mariadb applies the assignments in order, so the later ones see the earlier updates
+func (d Dialect) UpsertSet(key string, set ...string) string {
+	if d == MariaDB {
+		return "ON DUPLICATE KEY UPDATE " + strings.Join(set, ", ")
+	}
+	return "ON CONFLICT (" + key + ") DO UPDATE SET " + strings.Join(set, ", ")
+}
+
+// Inserted - the value of the column in the row which the upsert did not insert
+func (d Dialect) Inserted(column string) string {
+	if d == MariaDB {
+		return "VALUES(" + column + ")"
+	}
+	return "excluded." + column
+}
@@ -0,0 +1,57 @@
+package sqlconnect
+
+import "testing"
+
+func TestParseDialect(t *testing.T) {
+	for name, want := range map[string]Dialect{
+		"mysql":      MariaDB,
+		"MariaDB":    MariaDB,
+		"postgresql": Postgres,
+		"pgx":        Postgres,
+		"sqlite3":    SQLite,
+	} {
+		got, err := ParseDialect(name)
+		if err != nil || got != want {
+			t.Fatalf("Expected %s for %q, got %s %v", want, name, got, err)
+		}
+	}
+	if _, err := ParseDialect("oracle"); err == nil {
+		t.Fatal("Expected an error for the unknown driver")
+	}
+}
+
+func TestRebind(t *testing.T) {
+	query := "SELECT id FROM t WHERE a = ? AND b = '?' AND c = ?"
+	if got := MariaDB.Rebind(query); got != query {
+		t.Fatalf("Expected the mariadb query unchanged, got %q", got)
+	}
+	want := "SELECT id FROM t WHERE a = $1 AND b = '?' AND c = $2"
+	if got := Postgres.Rebind(query); got != want {
+		t.Fatalf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	if got := MariaDB.Upsert("k", "a", "b"); got != "ON DUPLICATE KEY UPDATE a = VALUES(a), b = VALUES(b)" {
+		t.Fatalf("Unexpected mariadb upsert %q", got)
+	}
+	if got := SQLite.Upsert("k", "a"); got != "ON CONFLICT (k) DO UPDATE SET a = excluded.a" {
+		t.Fatalf("Unexpected sqlite upsert %q", got)
+	}
+	set := "a = t.a + 1"
+	if got := Postgres.UpsertSet("k", set, "b = "+Postgres.Inserted("b")); got != "ON CONFLICT (k) DO UPDATE SET a = t.a + 1, b = excluded.b" {
+		t.Fatalf("Unexpected postgres upsert %q", got)
+	}
+}
+
+func TestForUpdate(t *testing.T) {
+	if MariaDB.ForUpdate() != " FOR UPDATE" || Postgres.ForUpdate() != " FOR UPDATE" || SQLite.ForUpdate() != "" {
+		t.Fatal("Expected FOR UPDATE for mariadb and postgres only")
+	}
+}
+
+func TestSQLiteDSN(t *testing.T) {
+	if got := sqliteDSN(sqliteMemory); got != "file::memory:?_pragma=foreign_keys%281%29&_pragma=busy_timeout%285000%29" {
+		t.Fatalf("Unexpected in-memory dsn %q", got)
+	}
+}
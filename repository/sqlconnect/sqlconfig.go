@@ -1,25 +1,29 @@
-// Package sqlconnect - for connecting and testing connection to the mariadb database server
+// Package sqlconnect - for connecting and testing connection to the database server, mariadb,
+// postgres or the sqlite file selected with the database driver
 package sqlconnect
 
 import (
 	"database/sql"
 	"fmt"
+	"net"
+	"net/url"
 	"time"
 
 	"github.com/dkr290/go-advanced-projects/rest-api-school-management/config"
 	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
 )
 
-func ConnectDB(conf *config.Config) (*sql.DB, error) {
-	connectionString := fmt.Sprintf(
-		"%s:%s@tcp(%s:%s)/%s",
-		conf.DBUser,
-		conf.DBPassword,
-		conf.DBHost,
-		conf.DBPort,
-		conf.DBDatabase,
-	)
-	db, err := sql.Open("mysql", connectionString)
+// sqliteMemory - the database name for the sqlite database which lives only in memory
+const sqliteMemory = ":memory:"
+
+func ConnectDB(conf *config.Config) (*DB, error) {
+	dialect, err := ParseDialect(conf.DBDriver)
+	if err != nil {
+		return nil, err
+	}
+	db, err := Open(dialect, DSN(dialect, conf))
 	if err != nil {
 		return nil, err
 	}
@@ -28,7 +32,7 @@ func ConnectDB(conf *config.Config) (*sql.DB, error) {
 
 		err := db.Ping()
 		if err != nil {
-			fmt.Println("Error connection to mariadb", err)
+			fmt.Println("Error connection to", dialect, err)
 			if count > 10 {
 				return nil, fmt.Errorf("error connection for more then 10 times %v", err)
 			}
@@ -36,73 +40,65 @@ func ConnectDB(conf *config.Config) (*sql.DB, error) {
 			time.Sleep(2 * time.Second)
 			continue
 		} else {
-			fmt.Println("Connected to mariadb")
+			fmt.Println("Connected to", dialect)
 			break
 		}
 
 	}
 
-	CreateTables(conf.DBDatabase, db)
 	return db, nil
 }
 
-func CreateTables(database string, db *sql.DB) {
-	createDBIfNotExists := `
-    CREATE DATABASE IF NOT EXISTS school;
-	`
-	var tables []string
+// DSN - the data source name of the dialect from the database flags, for sqlite the
+// database is the file name or :memory:
+func DSN(dialect Dialect, conf *config.Config) string {
+	switch dialect {
+	case Postgres:
+		dsn := url.URL{
+			Scheme: "postgres",
+			User:   url.UserPassword(conf.DBUser, conf.DBPassword),
+			Host:   net.JoinHostPort(conf.DBHost, conf.DBPort),
+			Path:   "/" + conf.DBDatabase,
+		}
+		return dsn.String()
+	case SQLite:
+		return conf.DBDatabase
+	}
+	return fmt.Sprintf(
+		"%s:%s@tcp(%s:%s)/%s",
+		conf.DBUser,
+		conf.DBPassword,
+		conf.DBHost,
+		conf.DBPort,
+		conf.DBDatabase,
+	)
+}
 
-	createExecTable := `
-    CREATE TABLE IF NOT EXISTS execs (
-      id INT AUTO_INCREMENT PRIMARY KEY,
-	    first_name VARCHAR(255) NOT NULL,
-	    last_name VARCHAR(255) NOT NULL,
-	    email VARCHAR(50) NOT NULL UNIQUE,
-	    username VARCHAR(50) NOT NULL UNIQUE,
-	    password VARCHAR(255) NOT NULL,
-	    password_changed_at VARCHAR(255),
-	    user_created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-	    password_reset_token VARCHAR(255),
-	    inactive_status BOOLEAN NOT NULL,
-	    role VARCHAR(255) NOT NULL,
-	    password_token_expires VARCHAR(255),
-	    INDEX idx_email (email),
-	    INDEX idx_username (username)
-	);
-	`
-	createTeachersTable := `
-    CREATE TABLE IF NOT EXISTS teachers (
-      id INT AUTO_INCREMENT PRIMARY KEY,
-	    first_name VARCHAR(255) NOT NULL,
-	    last_name VARCHAR(255) NOT NULL,
-	    email VARCHAR(255) NOT NULL UNIQUE,
-	    class VARCHAR(255) NOT NULL,
-	    subject VARCHAR(255) NOT NULL,
-	    INDEX (email),
-	    INDEX(class)
-	) AUTO_INCREMENT=100;
-	`
-	createStudentsTable := `
-   CREATE TABLE IF NOT EXISTS students (
-    id INT AUTO_INCREMENT PRIMARY KEY NOT NULL,
-	  first_name VARCHAR(255) NOT NULL,
-	  last_name VARCHAR(255) NOT NULL,
-	  email   VARCHAR(255) NOT NULL UNIQUE,
-	  class VARCHAR(50) NOT NULL,
-	  INDEX (email),
-	  FOREIGN KEY (class) REFERENCES teachers(class)
-) AUTO_INCREMENT=100;
-	`
-	tables = append(tables, createExecTable, createTeachersTable, createStudentsTable)
-	_, err := db.Exec(createDBIfNotExists)
+// Open - the database of the dialect without waiting for it. The sqlite database gets the
+// foreign keys enabled and the in-memory one a single connection, as every connection
+// would have its own empty database
+func Open(dialect Dialect, dsn string) (*DB, error) {
+	if dialect == SQLite {
+		dsn = sqliteDSN(dsn)
+	}
+	db, err := sql.Open(dialect.DriverName(), dsn)
 	if err != nil {
-		panic("could not create database" + err.Error())
+		return nil, err
+	}
+	if dialect == SQLite && dsn == sqliteDSN(sqliteMemory) {
+		db.SetMaxOpenConns(1)
 	}
+	return &DB{DB: db, Dialect: dialect}, nil
+}
 
-	for _, table := range tables {
-		_, err := db.Exec(table)
-		if err != nil {
-			panic("could not create tables" + err.Error())
-		}
+func sqliteDSN(name string) string {
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "busy_timeout(5000)")
+	if name == sqliteMemory {
+		return "file::memory:?" + params.Encode()
 	}
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Set("_txlock", "immediate")
+	return "file:" + name + "?" + params.Encode()
 }